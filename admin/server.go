@@ -0,0 +1,118 @@
+// Package admin 提供远程运维 HTTP 接口：查询运行状态、暂停/恢复开仓、平仓、重置风控熔断
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Controller 管理接口操作的引擎
+type Controller interface {
+	// Snapshot 返回可序列化为 JSON 的运行状态
+	Snapshot() interface{}
+	// Pause 暂停开仓（不影响已有持仓与后台任务）
+	Pause()
+	// Resume 恢复开仓
+	Resume()
+	// ResetRisk 人工重置风控熔断
+	ResetRisk()
+	// Flatten 暂停开仓并以 reduce-only 平掉两所持仓，平仓完成（或超时）后返回
+	Flatten()
+}
+
+// Server 管理 HTTP 服务
+type Server struct {
+	srv   *http.Server
+	ctrl  Controller
+	token string
+}
+
+// Serve 在 addr 上启动管理服务，监听失败时返回错误
+// token 为空时修改类接口（pause / resume / flatten / risk/reset）一律拒绝，只提供 /status
+func Serve(addr, token string, ctrl Controller) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("管理服务监听 %s 失败: %w", addr, err)
+	}
+
+	s := &Server{ctrl: ctrl, token: token}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/pause", s.mutating("暂停开仓", ctrl.Pause))
+	mux.HandleFunc("/resume", s.mutating("恢复开仓", ctrl.Resume))
+	mux.HandleFunc("/flatten", s.mutating("平仓", ctrl.Flatten))
+	mux.HandleFunc("/risk/reset", s.mutating("重置风控熔断", ctrl.ResetRisk))
+	s.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("[管理] 服务异常退出", "err", err)
+		}
+	}()
+	if token == "" {
+		slog.Info("[管理] 管理接口已启动（未配置 token，只开放 /status）", "addr", ln.Addr().String())
+	} else {
+		slog.Info("[管理] 管理接口已启动", "addr", ln.Addr().String())
+	}
+	return s, nil
+}
+
+// Close 关闭管理服务
+func (s *Server) Close() {
+	if s == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	s.srv.Shutdown(ctx)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s.ctrl.Snapshot()); err != nil {
+		slog.Warn("[管理] 输出状态失败", "err", err)
+	}
+}
+
+// mutating 包装修改类接口：只接受 POST，校验 token 并记录操作日志
+func (s *Server) mutating(action string, fn func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !s.authorized(r) {
+			slog.Warn("[管理] 拒绝未授权请求", "method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		slog.Info("[管理] "+action, "path", r.URL.Path, "remote", r.RemoteAddr)
+		fn()
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "action": r.URL.Path})
+	}
+}
+
+// authorized 校验 Authorization: Bearer <token>，未配置 token 时一律拒绝
+func (s *Server) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return false
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) == 1
+}
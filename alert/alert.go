@@ -0,0 +1,289 @@
+// Package alert 关键事件告警推送（Telegram / 通用 JSON Webhook），按事件类型限频
+//
+// 与 metrics 一样使用进程级默认实例：Setup 之前或未启用时所有告警调用都是空操作，
+// 发送在后台 goroutine 中异步进行，不阻塞交易路径
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"arb/config"
+)
+
+// Severity 告警级别
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "INFO"
+	case SeverityWarning:
+		return "WARNING"
+	default:
+		return "CRITICAL"
+	}
+}
+
+// ParseSeverity 解析配置中的级别名称（info / warning / critical），空字符串为 warning
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(s) {
+	case "info":
+		return SeverityInfo, nil
+	case "", "warning", "warn":
+		return SeverityWarning, nil
+	case "critical":
+		return SeverityCritical, nil
+	default:
+		return 0, fmt.Errorf("未知的告警级别 %q（可选: info, warning, critical）", s)
+	}
+}
+
+// Alert 一条告警
+type Alert struct {
+	Severity Severity
+	Key      string // 事件类型，用于限频（如 risk_halt、hedge_failed:Bybit）
+	Text     string
+	Time     time.Time
+	Symbol   string  // 交易对，SetContext 之前为空
+	PnL      float64 // 发送时的累计盈亏（USDC）
+}
+
+// Notifier 告警发送渠道
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, a Alert) error
+}
+
+const (
+	defaultMinIntervalSec = 300
+	sendTimeout           = 10 * time.Second
+	queueSize             = 64
+)
+
+// dispatcher 按级别过滤、按事件类型限频并异步分发告警
+type dispatcher struct {
+	notifiers   []Notifier
+	minSeverity Severity
+	minInterval time.Duration
+
+	mu         sync.Mutex
+	lastSent   map[string]time.Time
+	suppressed map[string]int // 限频期间被抑制的次数，下次发送时附带
+
+	queue chan Alert
+}
+
+var (
+	defaultMu sync.RWMutex
+	current   *dispatcher
+
+	// 附加到每条告警的上下文，由引擎在启动时通过 SetContext 设置
+	symbol    string
+	pnlSource func() float64
+)
+
+// SetContext 设置附加到每条告警的交易对与累计盈亏来源（pnl 可为 nil）
+func SetContext(sym string, pnl func() float64) {
+	defaultMu.Lock()
+	symbol, pnlSource = sym, pnl
+	defaultMu.Unlock()
+}
+
+// Setup 按配置初始化默认告警实例，未启用或未配置任何渠道时告警为空操作
+func Setup(cfg config.AlertsConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	minSev, err := ParseSeverity(cfg.MinSeverity)
+	if err != nil {
+		return err
+	}
+
+	var notifiers []Notifier
+	if cfg.TelegramToken != "" && cfg.TelegramChatID != "" {
+		notifiers = append(notifiers, NewTelegram(cfg.TelegramToken, cfg.TelegramChatID))
+	}
+	if cfg.WebhookURL != "" {
+		notifiers = append(notifiers, NewWebhook(cfg.WebhookURL))
+	}
+	if len(notifiers) == 0 {
+		slog.Warn("[告警] 已启用但未配置 Telegram 或 Webhook，告警不会发送")
+		return nil
+	}
+
+	interval := cfg.MinIntervalSec
+	if interval <= 0 {
+		interval = defaultMinIntervalSec
+	}
+	d := &dispatcher{
+		notifiers:   notifiers,
+		minSeverity: minSev,
+		minInterval: time.Duration(interval) * time.Second,
+		lastSent:    make(map[string]time.Time),
+		suppressed:  make(map[string]int),
+		queue:       make(chan Alert, queueSize),
+	}
+	go d.run()
+
+	defaultMu.Lock()
+	current = d
+	defaultMu.Unlock()
+
+	names := make([]string, len(notifiers))
+	for i, n := range notifiers {
+		names[i] = n.Name()
+	}
+	slog.Info("[告警] 已启用", "channels", strings.Join(names, ","), "min_severity", minSev, "min_interval", d.minInterval)
+	return nil
+}
+
+// Info 发送 INFO 级别告警
+func Info(key, format string, args ...interface{}) { send(SeverityInfo, key, format, args...) }
+
+// Warn 发送 WARNING 级别告警
+func Warn(key, format string, args ...interface{}) { send(SeverityWarning, key, format, args...) }
+
+// Critical 发送 CRITICAL 级别告警
+func Critical(key, format string, args ...interface{}) { send(SeverityCritical, key, format, args...) }
+
+func send(sev Severity, key, format string, args ...interface{}) {
+	defaultMu.RLock()
+	d, sym, pnl := current, symbol, pnlSource
+	defaultMu.RUnlock()
+	if d == nil || sev < d.minSeverity {
+		return
+	}
+
+	a := Alert{Severity: sev, Key: key, Text: fmt.Sprintf(format, args...), Time: time.Now(), Symbol: sym}
+	if pnl != nil {
+		a.PnL = pnl()
+	}
+	if !d.allow(&a) {
+		return
+	}
+	select {
+	case d.queue <- a:
+	default:
+		slog.Warn("[告警] 发送队列已满，丢弃告警", "text", a.Text)
+	}
+}
+
+// allow 同一事件类型在 minInterval 内只发送一次，被抑制的次数附在下一条告警中
+func (d *dispatcher) allow(a *Alert) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if last, ok := d.lastSent[a.Key]; ok && a.Time.Sub(last) < d.minInterval {
+		d.suppressed[a.Key]++
+		return false
+	}
+	d.lastSent[a.Key] = a.Time
+	if n := d.suppressed[a.Key]; n > 0 {
+		a.Text += fmt.Sprintf("（此前 %v 内同类告警已抑制 %d 次）", d.minInterval, n)
+		delete(d.suppressed, a.Key)
+	}
+	return true
+}
+
+func (d *dispatcher) run() {
+	for a := range d.queue {
+		for _, n := range d.notifiers {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			if err := n.Notify(ctx, a); err != nil {
+				slog.Warn("[告警] 发送失败", "channel", n.Name(), "err", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// ---------- 发送渠道 ----------
+
+// Telegram 通过 Bot API sendMessage 发送告警
+type Telegram struct {
+	token  string
+	chatID string
+	client *http.Client
+}
+
+// NewTelegram 创建 Telegram 渠道
+func NewTelegram(token, chatID string) *Telegram {
+	return &Telegram{token: token, chatID: chatID, client: &http.Client{Timeout: sendTimeout}}
+}
+
+func (t *Telegram) Name() string { return "Telegram" }
+
+func (t *Telegram) Notify(ctx context.Context, a Alert) error {
+	body := map[string]string{
+		"chat_id": t.chatID,
+		"text":    fmt.Sprintf("[%s] %s\n%s", a.Severity, a.Text, a.Time.Format("2006-01-02 15:04:05 MST")),
+	}
+	err := postJSON(ctx, t.client, "https://api.telegram.org/bot"+t.token+"/sendMessage", body)
+	if err != nil {
+		// 网络错误信息包含请求地址，避免 bot token 写入日志
+		return fmt.Errorf("%s", strings.ReplaceAll(err.Error(), t.token, "***"))
+	}
+	return nil
+}
+
+// Webhook 向通用地址 POST JSON：{"severity","key","text","time","symbol","pnl"}，key 为事件类型
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook 创建 Webhook 渠道
+func NewWebhook(url string) *Webhook {
+	return &Webhook{url: url, client: &http.Client{Timeout: sendTimeout}}
+}
+
+func (w *Webhook) Name() string { return "Webhook" }
+
+func (w *Webhook) Notify(ctx context.Context, a Alert) error {
+	return postJSON(ctx, w.client, w.url, map[string]interface{}{
+		"severity": a.Severity.String(),
+		"key":      a.Key,
+		"text":     a.Text,
+		"time":     a.Time,
+		"symbol":   a.Symbol,
+		"pnl":      a.PnL,
+	})
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
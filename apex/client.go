@@ -2,14 +2,19 @@ package apex
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
+	"math"
+	"math/rand"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -20,6 +25,14 @@ type Client struct {
 	apiSecret  string
 	passphrase string
 	httpClient *http.Client
+	retry      RetryPolicy
+	limiter    *rateLimiter // 本地限频，nil 表示不限制
+	idPrefix   string       // 自定义订单ID前缀
+	logger     *slog.Logger
+
+	// 单次请求超时：下单/撤单使用 orderTimeout，其余查询使用 queryTimeout，0 表示只受 httpClient 超时限制
+	orderTimeout time.Duration
+	queryTimeout time.Duration
 }
 
 // NewClient 创建 Apex REST 客户端
@@ -30,9 +43,36 @@ func NewClient(baseURL, apiKey, apiSecret, passphrase string) *Client {
 		apiSecret:  apiSecret,
 		passphrase: passphrase,
 		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     slog.Default(),
 	}
 }
 
+// SetLogger 设置日志输出（默认 slog.Default()），nil 时忽略
+func (c *Client) SetLogger(l *slog.Logger) {
+	if l != nil {
+		c.logger = l
+	}
+}
+
+// SetTimeouts 设置单次请求超时：order 用于下单/撤单，query 用于其余查询，0 表示不单独限制
+func (c *Client) SetTimeouts(order, query time.Duration) {
+	c.orderTimeout = order
+	c.queryTimeout = query
+}
+
+// callTimeout 返回单次请求的超时：订单分组的写请求（下单、撤单）使用 orderTimeout
+func (c *Client) callTimeout(method, path string) time.Duration {
+	if method != http.MethodGet && endpointGroup(path) == GroupOrder {
+		return c.orderTimeout
+	}
+	return c.queryTimeout
+}
+
+// SetRetryPolicy 设置瞬时错误的重试策略（默认不重试）
+func (c *Client) SetRetryPolicy(p RetryPolicy) {
+	c.retry = p
+}
+
 // ---------- 公共数据结构 ----------
 
 // OrderBook 订单簿快照
@@ -49,6 +89,23 @@ type BestPrice struct {
 	AskSize  float64 // 卖一量
 }
 
+// FundingRate 资金费率
+type FundingRate struct {
+	Symbol          string
+	Rate            float64   // 当期预测资金费率，正数表示多头向空头支付
+	NextFundingTime time.Time // 下一次结算时间
+}
+
+// InstrumentInfo 交易对规格
+type InstrumentInfo struct {
+	Symbol      string
+	TickSize    float64 // 价格最小变动单位
+	QtyStep     float64 // 数量最小变动单位
+	MinOrderQty float64 // 最小下单量
+	MaxOrderQty float64 // 最大下单量
+	MinNotional float64 // 最小下单名义价值（0=交易所未提供）
+}
+
 // Position 持仓信息
 type Position struct {
 	Symbol        string  `json:"symbol"`
@@ -68,12 +125,14 @@ type Account struct {
 type Order struct {
 	ID         string  `json:"id"`
 	Symbol     string  `json:"symbol"`
-	Side       string  `json:"side"`   // BUY / SELL
-	Type       string  `json:"type"`   // LIMIT / MARKET
+	Side       string  `json:"side"` // BUY / SELL
+	Type       string  `json:"type"` // LIMIT / MARKET
 	Price      float64 `json:"price,string"`
 	Size       float64 `json:"size,string"`
 	FilledSize float64 `json:"filledSize,string"`
-	Status     string  `json:"status"` // OPEN / FILLED / CANCELED
+	AvgPrice   float64 `json:"avgPrice,string"` // 成交均价
+	Fee        float64 `json:"fee,string"`      // 累计手续费
+	Status     string  `json:"status"`          // OPEN / FILLED / CANCELED
 	CreatedAt  int64   `json:"createdAt"`
 }
 
@@ -87,6 +146,15 @@ type PlaceOrderReq struct {
 	TimeInForce   string `json:"timeInForce"` // GTT / IOC / FOK / POST_ONLY
 	ReduceOnly    bool   `json:"reduceOnly"`
 	ClientOrderID string `json:"clientOrderId,omitempty"`
+	ExpireTime    int64  `json:"expireTime,omitempty"` // 挂单到期时间（毫秒），GTT / POST_ONLY 为 0 时按 orderMaxExpiry 填充
+}
+
+// AmendOrderReq 改单请求，OrderID 与 ClientOrderID 二选一，Size / Price 为空表示不修改
+type AmendOrderReq struct {
+	OrderID       string
+	ClientOrderID string
+	Size          string
+	Price         string
 }
 
 // ---------- 签名工具 ----------
@@ -99,8 +167,74 @@ func (c *Client) sign(timestamp, method, path, body string) string {
 	return hex.EncodeToString(mac.Sum(nil))
 }
 
-// request 发送带签名的 HTTP 请求
-func (c *Client) request(method, path string, payload interface{}) ([]byte, error) {
+// RetryPolicy 瞬时错误（网络错误、HTTP 5xx、429）的重试策略
+type RetryPolicy struct {
+	MaxAttempts int           // 最大尝试次数（含首次），<=1 表示不重试
+	BaseDelay   time.Duration // 首次重试前的等待，之后每次翻倍
+	MaxDelay    time.Duration // 单次等待上限，0 表示不限制
+	Jitter      float64       // 随机抖动比例（0~1），避免多个请求同时重试
+}
+
+// backoff 返回第 n 次失败后的等待时长（指数退避 + 随机抖动）
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.BaseDelay << (n - 1)
+	if p.MaxDelay > 0 && (d > p.MaxDelay || d <= 0) {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d += time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// defaultClientIDPrefix 未设置前缀时自定义订单ID的前缀
+const defaultClientIDPrefix = "arb"
+
+// SetClientIDPrefix 设置自动生成的自定义订单ID前缀，多实例共用账户时用于区分
+func (c *Client) SetClientIDPrefix(prefix string) {
+	c.idPrefix = prefix
+}
+
+// newClientOrderID 生成自定义订单ID，下单重试时用于去重
+func (c *Client) newClientOrderID() string {
+	prefix := c.idPrefix
+	if prefix == "" {
+		prefix = defaultClientIDPrefix
+	}
+	return fmt.Sprintf("%s-%d-%04d", prefix, time.Now().UnixNano(), rand.Intn(10000))
+}
+
+// request 发送带签名的 HTTP 请求，瞬时错误按重试策略重试
+func (c *Client) request(ctx context.Context, method, path string, payload interface{}) ([]byte, error) {
+	data, _, err := c.requestAttempts(ctx, method, path, payload)
+	return data, err
+}
+
+// requestAttempts 同 request，额外返回实际尝试次数
+// 只重试网络错误、HTTP 5xx 与 429，业务拒单（4xx 等）立即返回
+func (c *Client) requestAttempts(ctx context.Context, method, path string, payload interface{}) ([]byte, int, error) {
+	for n := 1; ; n++ {
+		data, err := c.doRequest(ctx, method, path, payload)
+		if err == nil || !IsRetryable(err) || n >= c.retry.MaxAttempts {
+			return data, n, err
+		}
+
+		delay := c.retry.backoff(n)
+		c.logger.Warn("[Apex REST] 请求失败，稍后重试", "method", method, "path", path, "attempt", n, "err", err, "delay", delay.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return nil, n, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// doRequest 发送一次带签名的 HTTP 请求
+func (c *Client) doRequest(ctx context.Context, method, path string, payload interface{}) ([]byte, error) {
+	if err := c.limiter.wait(ctx, endpointGroup(path)); err != nil {
+		return nil, err
+	}
+
 	var bodyStr string
 	var bodyReader io.Reader
 
@@ -116,7 +250,15 @@ func (c *Client) request(method, path string, payload interface{}) ([]byte, erro
 	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
 	sig := c.sign(timestamp, method, path, bodyStr)
 
-	req, err := http.NewRequest(method, c.baseURL+path, bodyReader)
+	// 单次请求超时只作用于本次 HTTP 请求：超时视为瞬时错误可重试，ctx 取消则立即返回
+	reqCtx := ctx
+	if d := c.callTimeout(method, path); d > 0 {
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(reqCtx, method, c.baseURL+path, bodyReader)
 	if err != nil {
 		return nil, err
 	}
@@ -129,28 +271,37 @@ func (c *Client) request(method, path string, payload interface{}) ([]byte, erro
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, &transientError{err}
 	}
 	defer resp.Body.Close()
 
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, &transientError{err}
 	}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(data))
+	if err := checkResponse(resp.StatusCode, data); err != nil {
+		return nil, err
 	}
-
 	return data, nil
 }
 
 // ---------- 公开接口 ----------
 
 // GetOrderBook 获取订单簿（公开接口，无需签名）
-func (c *Client) GetOrderBook(symbol string) (*OrderBook, error) {
+func (c *Client) GetOrderBook(ctx context.Context, symbol string) (*OrderBook, error) {
 	url := fmt.Sprintf("%s/api/v1/depth?symbol=%s&limit=5", c.baseURL, symbol)
-	resp, err := c.httpClient.Get(url)
+	if err := c.limiter.wait(ctx, GroupMarket); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -170,9 +321,60 @@ func (c *Client) GetOrderBook(symbol string) (*OrderBook, error) {
 	return result.Data, nil
 }
 
+// GetInstrumentInfo 获取交易对的价格/数量步长与下单量限制（公开接口，无需签名）
+func (c *Client) GetInstrumentInfo(ctx context.Context, symbol string) (*InstrumentInfo, error) {
+	if err := c.limiter.wait(ctx, GroupMarket); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/v1/symbols", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data struct {
+			PerpetualContract []struct {
+				Symbol          string  `json:"symbol"`
+				CrossSymbolName string  `json:"crossSymbolName"`
+				TickSize        float64 `json:"tickSize,string"`
+				StepSize        float64 `json:"stepSize,string"`
+				MinOrderSize    float64 `json:"minOrderSize,string"`
+				MaxOrderSize    float64 `json:"maxOrderSize,string"`
+			} `json:"perpetualContract"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	for _, s := range result.Data.PerpetualContract {
+		if s.Symbol != symbol && s.CrossSymbolName != symbol {
+			continue
+		}
+		return &InstrumentInfo{
+			Symbol:      symbol,
+			TickSize:    s.TickSize,
+			QtyStep:     s.StepSize,
+			MinOrderQty: s.MinOrderSize,
+			MaxOrderQty: s.MaxOrderSize,
+		}, nil
+	}
+	return nil, fmt.Errorf("Apex 交易对 %s 不存在", symbol)
+}
+
 // GetBestPrice 获取最优买卖价
-func (c *Client) GetBestPrice(symbol string) (*BestPrice, error) {
-	ob, err := c.GetOrderBook(symbol)
+func (c *Client) GetBestPrice(ctx context.Context, symbol string) (*BestPrice, error) {
+	ob, err := c.GetOrderBook(ctx, symbol)
 	if err != nil {
 		return nil, err
 	}
@@ -181,18 +383,68 @@ func (c *Client) GetBestPrice(symbol string) (*BestPrice, error) {
 	}
 
 	bp := &BestPrice{}
-	fmt.Sscanf(ob.Bids[0][0], "%f", &bp.BidPrice)
-	fmt.Sscanf(ob.Bids[0][1], "%f", &bp.BidSize)
-	fmt.Sscanf(ob.Asks[0][0], "%f", &bp.AskPrice)
-	fmt.Sscanf(ob.Asks[0][1], "%f", &bp.AskSize)
+	if bp.BidPrice, bp.BidSize, err = ParsePriceLevel(ob.Bids[0]); err != nil {
+		return nil, fmt.Errorf("买一%w", err)
+	}
+	if bp.AskPrice, bp.AskSize, err = ParsePriceLevel(ob.Asks[0]); err != nil {
+		return nil, fmt.Errorf("卖一%w", err)
+	}
 	return bp, nil
 }
 
+// GetFundingRate 获取当期资金费率与下一次结算时间（公开接口，无需签名）
+// ticker 接口的交易对不带连字符（BTC-USDC → BTCUSDC）
+func (c *Client) GetFundingRate(ctx context.Context, symbol string) (*FundingRate, error) {
+	url := fmt.Sprintf("%s/api/v1/ticker?symbol=%s", c.baseURL, strings.ReplaceAll(symbol, "-", ""))
+	if err := c.limiter.wait(ctx, GroupMarket); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data []struct {
+			Symbol          string `json:"symbol"`
+			FundingRate     string `json:"fundingRate"`
+			NextFundingTime string `json:"nextFundingTime"` // ISO8601 时间
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("Apex 交易对 %s 不存在", symbol)
+	}
+
+	t := result.Data[0]
+	rate, err := strconv.ParseFloat(t.FundingRate, 64)
+	if err != nil {
+		return nil, fmt.Errorf("解析资金费率 %q 失败: %w", t.FundingRate, err)
+	}
+	next, err := time.Parse(time.RFC3339, t.NextFundingTime)
+	if err != nil {
+		return nil, fmt.Errorf("解析资金费结算时间 %q 失败: %w", t.NextFundingTime, err)
+	}
+	return &FundingRate{Symbol: symbol, Rate: rate, NextFundingTime: next}, nil
+}
+
 // ---------- 私有接口 ----------
 
 // GetAccount 获取账户信息
-func (c *Client) GetAccount() (*Account, error) {
-	data, err := c.request("GET", "/api/v1/account", nil)
+func (c *Client) GetAccount(ctx context.Context) (*Account, error) {
+	data, err := c.request(ctx, "GET", "/api/v1/account", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -202,12 +454,15 @@ func (c *Client) GetAccount() (*Account, error) {
 	if err := json.Unmarshal(data, &result); err != nil {
 		return nil, err
 	}
+	if result.Data == nil {
+		return nil, fmt.Errorf("Apex 账户数据为空")
+	}
 	return result.Data, nil
 }
 
 // GetPositions 获取所有持仓
-func (c *Client) GetPositions() ([]Position, error) {
-	data, err := c.request("GET", "/api/v1/positions", nil)
+func (c *Client) GetPositions(ctx context.Context) ([]Position, error) {
+	data, err := c.request(ctx, "GET", "/api/v1/positions", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -220,10 +475,28 @@ func (c *Client) GetPositions() ([]Position, error) {
 	return result.Data, nil
 }
 
+// orderMaxExpiry Apex 挂单（GTT / POST_ONLY）未指定到期时间时使用的有效期（交易所允许的最长有效期）
+const orderMaxExpiry = 28 * 24 * time.Hour
+
 // PlaceOrder 下单
-func (c *Client) PlaceOrder(req *PlaceOrderReq) (*Order, error) {
-	data, err := c.request("POST", "/api/v1/order", req)
+// 未指定 ClientOrderID 时自动生成，重试时交易所按 ClientOrderID 去重，不会重复下单
+// GTT / POST_ONLY 挂单未指定 ExpireTime 时按 orderMaxExpiry 填充（Apex 要求挂单携带到期时间）
+func (c *Client) PlaceOrder(ctx context.Context, req *PlaceOrderReq) (*Order, error) {
+	if req.ClientOrderID == "" {
+		req.ClientOrderID = c.newClientOrderID()
+	}
+	if req.ExpireTime == 0 && (req.TimeInForce == "GTT" || req.TimeInForce == "POST_ONLY") {
+		req.ExpireTime = time.Now().Add(orderMaxExpiry).UnixMilli()
+	}
+	data, attempts, err := c.requestAttempts(ctx, "POST", "/api/v1/order", req)
 	if err != nil {
+		// 重试过程中订单可能已提交成功（如首次请求已到达交易所但响应丢失），按 ClientOrderID 确认
+		if attempts > 1 {
+			if o, qerr := c.GetOrderByClientID(ctx, req.ClientOrderID); qerr == nil {
+				c.logger.Warn("[Apex REST] 下单重试失败，但订单已存在", "client_order_id", req.ClientOrderID, "order_id", o.ID)
+				return o, nil
+			}
+		}
 		return nil, err
 	}
 	var result struct {
@@ -232,27 +505,129 @@ func (c *Client) PlaceOrder(req *PlaceOrderReq) (*Order, error) {
 	if err := json.Unmarshal(data, &result); err != nil {
 		return nil, err
 	}
+	if result.Data == nil || result.Data.ID == "" {
+		return nil, fmt.Errorf("Apex 下单响应缺少订单数据: %s", string(data))
+	}
 	return result.Data, nil
 }
 
+// GetOrder 查询单个订单（含成交量、成交均价、手续费）
+func (c *Client) GetOrder(ctx context.Context, orderID string) (*Order, error) {
+	path := fmt.Sprintf("/api/v1/order?id=%s", orderID)
+	data, err := c.request(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Data *Order `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	if result.Data == nil {
+		return nil, fmt.Errorf("Apex 订单 %s 不存在", orderID)
+	}
+	return result.Data, nil
+}
+
+// GetOrderByClientID 按自定义订单ID查询订单
+func (c *Client) GetOrderByClientID(ctx context.Context, clientOrderID string) (*Order, error) {
+	path := fmt.Sprintf("/api/v1/order-by-client-order-id?id=%s", clientOrderID)
+	data, err := c.request(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Data *Order `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	if result.Data == nil {
+		return nil, fmt.Errorf("Apex 订单 ClientOrderID=%s 不存在", clientOrderID)
+	}
+	return result.Data, nil
+}
+
+// AmendOrder 修改挂单的价格和/或数量，返回修改后的新订单
+// Apex 没有原生改单接口，按撤单 + 以相同方向重新挂 GTT 限价单实现（新订单使用新的订单ID，不保留 reduceOnly）
+// 原订单已结束（已成交、已撤销或不存在）时返回 ErrOrderGone，调用方应重新报价而不是视为故障
+func (c *Client) AmendOrder(ctx context.Context, req *AmendOrderReq) (*Order, error) {
+	if req.Size == "" && req.Price == "" {
+		return nil, fmt.Errorf("改单需指定新价格或新数量")
+	}
+
+	var orig *Order
+	var err error
+	switch {
+	case req.OrderID != "":
+		orig, err = c.GetOrder(ctx, req.OrderID)
+	case req.ClientOrderID != "":
+		orig, err = c.GetOrderByClientID(ctx, req.ClientOrderID)
+	default:
+		return nil, fmt.Errorf("改单需指定 id 或 clientOrderId")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if orig.Status != "OPEN" && orig.Status != "PENDING" {
+		return nil, fmt.Errorf("%w: OrderID=%s 状态=%s", ErrOrderGone, orig.ID, orig.Status)
+	}
+
+	if err := c.CancelOrder(ctx, orig.ID); err != nil {
+		// 撤单失败时确认订单是否已在此期间成交或撤销
+		if o, qerr := c.GetOrder(ctx, orig.ID); qerr == nil && o.Status != "OPEN" && o.Status != "PENDING" {
+			return nil, fmt.Errorf("%w: OrderID=%s 状态=%s", ErrOrderGone, o.ID, o.Status)
+		}
+		return nil, err
+	}
+
+	// 未指定新数量时按剩余未成交量重新挂单
+	size := req.Size
+	if size == "" {
+		remaining := math.Round((orig.Size-orig.FilledSize)*1e8) / 1e8
+		if remaining <= 0 {
+			return nil, fmt.Errorf("%w: OrderID=%s 已全部成交", ErrOrderGone, orig.ID)
+		}
+		size = strconv.FormatFloat(remaining, 'f', -1, 64)
+	}
+	price := req.Price
+	if price == "" {
+		price = strconv.FormatFloat(orig.Price, 'f', -1, 64)
+	}
+
+	o, err := c.PlaceOrder(ctx, &PlaceOrderReq{
+		Symbol:      orig.Symbol,
+		Side:        orig.Side,
+		Type:        "LIMIT",
+		Size:        size,
+		Price:       price,
+		TimeInForce: "GTT",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("原订单 %s 已撤销，重新挂单失败: %w", orig.ID, err)
+	}
+	return o, nil
+}
+
 // CancelOrder 撤销单个订单
-func (c *Client) CancelOrder(orderID string) error {
+func (c *Client) CancelOrder(ctx context.Context, orderID string) error {
 	path := fmt.Sprintf("/api/v1/order?id=%s", orderID)
-	_, err := c.request("DELETE", path, nil)
+	_, err := c.request(ctx, "DELETE", path, nil)
 	return err
 }
 
 // CancelAllOrders 撤销某交易对所有订单
-func (c *Client) CancelAllOrders(symbol string) error {
+func (c *Client) CancelAllOrders(ctx context.Context, symbol string) error {
 	path := fmt.Sprintf("/api/v1/open-orders?symbol=%s", symbol)
-	_, err := c.request("DELETE", path, nil)
+	_, err := c.request(ctx, "DELETE", path, nil)
 	return err
 }
 
 // GetOpenOrders 获取当前挂单
-func (c *Client) GetOpenOrders(symbol string) ([]Order, error) {
+func (c *Client) GetOpenOrders(ctx context.Context, symbol string) ([]Order, error) {
 	path := fmt.Sprintf("/api/v1/open-orders?symbol=%s", symbol)
-	data, err := c.request("GET", path, nil)
+	data, err := c.request(ctx, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
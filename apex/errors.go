@@ -0,0 +1,140 @@
+package apex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Apex 业务错误码
+const (
+	CodeInsufficientBalance = 1008 // 余额不足
+)
+
+// ErrOrderGone 订单已结束（已成交、已撤销或不存在），改单时据此重新报价
+var ErrOrderGone = errors.New("Apex 订单已结束")
+
+// ExchangeError Apex 返回的错误（HTTP 非成功状态或响应 code 非 0）
+type ExchangeError struct {
+	HTTPStatus int    // HTTP 状态码
+	Code       int    // 业务错误码，HTTP 层错误时为 0
+	Msg        string // 错误信息
+}
+
+func (e *ExchangeError) Error() string {
+	if e.Code == 0 {
+		return fmt.Sprintf("Apex HTTP %d: %s", e.HTTPStatus, e.Msg)
+	}
+	return fmt.Sprintf("Apex 错误 %d: %s", e.Code, e.Msg)
+}
+
+// Retryable 是否为可重试的瞬时错误：HTTP 5xx 与 429
+func (e *ExchangeError) Retryable() bool {
+	return e.HTTPStatus >= http.StatusInternalServerError || e.HTTPStatus == http.StatusTooManyRequests
+}
+
+// InsufficientBalance 是否为余额不足
+func (e *ExchangeError) InsufficientBalance() bool {
+	return e.Code == CodeInsufficientBalance
+}
+
+// PermissionDenied 是否为鉴权或权限错误（API Key 无效、签名错误、权限不足）
+func (e *ExchangeError) PermissionDenied() bool {
+	return e.HTTPStatus == http.StatusUnauthorized || e.HTTPStatus == http.StatusForbidden
+}
+
+// errorEnvelope Apex 响应的错误外层：{"code": 1008, "msg": "insufficient balance", "key": "...", "success": false}
+// code 在部分接口中为字符串，按 json.RawMessage 读取后再解析
+type errorEnvelope struct {
+	Code    json.RawMessage `json:"code"`
+	Msg     string          `json:"msg"`
+	Key     string          `json:"key"`
+	Success *bool           `json:"success"`
+}
+
+// checkResponse 检查 HTTP 状态与 Apex 错误外层，HTTP 非成功状态、code 非 0 或 success=false 时返回 *ExchangeError
+func checkResponse(status int, data []byte) error {
+	var env errorEnvelope
+	parsed := json.Unmarshal(data, &env) == nil
+	code, codeSet := parseCode(env.Code)
+
+	if status != http.StatusOK && status != http.StatusCreated {
+		ee := &ExchangeError{HTTPStatus: status, Msg: string(data)}
+		if parsed && (codeSet || env.Msg != "") {
+			ee.Code, ee.Msg = code, envelopeMsg(env)
+		}
+		return ee
+	}
+	if !parsed {
+		return nil
+	}
+	if codeSet && code != 0 || env.Success != nil && !*env.Success {
+		return &ExchangeError{HTTPStatus: status, Code: code, Msg: envelopeMsg(env)}
+	}
+	return nil
+}
+
+// parseCode 解析数字或字符串形式的错误码，字段缺失、为 null 或无法解析为整数时 ok=false
+func parseCode(raw json.RawMessage) (code int, ok bool) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, false
+	}
+	if err := json.Unmarshal(raw, &code); err == nil {
+		return code, true
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return 0, false
+	}
+	code, err := strconv.Atoi(s)
+	return code, err == nil
+}
+
+// envelopeMsg 返回错误信息，附带错误标识 key（如 ORDER_PRICE_INVALID）便于排查
+func envelopeMsg(env errorEnvelope) string {
+	switch {
+	case env.Key == "":
+		return env.Msg
+	case env.Msg == "":
+		return env.Key
+	default:
+		return env.Msg + " (" + env.Key + ")"
+	}
+}
+
+// transientError 可重试的网络层错误（连接失败、读取响应失败）
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// IsRetryable 判断错误是否可重试：网络错误或 ExchangeError.Retryable
+func IsRetryable(err error) bool {
+	var te *transientError
+	if errors.As(err, &te) {
+		return true
+	}
+	var ee *ExchangeError
+	return errors.As(err, &ee) && ee.Retryable()
+}
+
+// IsInsufficientBalance 判断错误是否为 Apex 余额不足
+func IsInsufficientBalance(err error) bool {
+	var ee *ExchangeError
+	return errors.As(err, &ee) && ee.InsufficientBalance()
+}
+
+// IsPermissionDenied 判断错误是否为 Apex 鉴权或权限错误
+func IsPermissionDenied(err error) bool {
+	var ee *ExchangeError
+	return errors.As(err, &ee) && ee.PermissionDenied()
+}
+
+// IsOrderGone 判断错误是否为 Apex 订单已结束（已成交、已撤销或不存在）
+func IsOrderGone(err error) bool {
+	return errors.Is(err, ErrOrderGone)
+}
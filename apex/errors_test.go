@@ -0,0 +1,80 @@
+package apex
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestErrorClassification(t *testing.T) {
+	cases := []struct {
+		name       string
+		err        error
+		retryable  bool
+		balance    bool
+		permission bool
+	}{
+		{"HTTP 500", &ExchangeError{HTTPStatus: 500}, true, false, false},
+		{"HTTP 429", &ExchangeError{HTTPStatus: 429}, true, false, false},
+		{"网络错误", &transientError{err: io.ErrUnexpectedEOF}, true, false, false},
+		{"余额不足", &ExchangeError{HTTPStatus: 200, Code: CodeInsufficientBalance}, false, true, false},
+		{"HTTP 401", &ExchangeError{HTTPStatus: 401}, false, false, true},
+		{"HTTP 403", &ExchangeError{HTTPStatus: 403}, false, false, true},
+		{"价格无效", &ExchangeError{HTTPStatus: 200, Code: 3, Msg: "ORDER_PRICE_INVALID"}, false, false, false},
+		{"HTTP 400", &ExchangeError{HTTPStatus: 400}, false, false, false},
+		{"被包装的余额不足", fmt.Errorf("下单失败: %w", &ExchangeError{HTTPStatus: 200, Code: CodeInsufficientBalance}), false, true, false},
+		{"普通错误", errors.New("其它错误"), false, false, false},
+		{"nil", nil, false, false, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsRetryable(tc.err); got != tc.retryable {
+				t.Errorf("IsRetryable = %v，期望 %v", got, tc.retryable)
+			}
+			if got := IsInsufficientBalance(tc.err); got != tc.balance {
+				t.Errorf("IsInsufficientBalance = %v，期望 %v", got, tc.balance)
+			}
+			if got := IsPermissionDenied(tc.err); got != tc.permission {
+				t.Errorf("IsPermissionDenied = %v，期望 %v", got, tc.permission)
+			}
+		})
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   int
+		body     string
+		wantCode int
+		wantErr  bool
+	}{
+		{"成功", 200, `{"data":{"id":"1"}}`, 0, false},
+		{"code 为 0", 200, `{"code":0,"data":{}}`, 0, false},
+		{"数字错误码", 200, `{"code":1008,"msg":"insufficient balance"}`, CodeInsufficientBalance, true},
+		{"字符串错误码", 200, `{"code":"1008","msg":"insufficient balance"}`, CodeInsufficientBalance, true},
+		{"success=false", 200, `{"success":false,"msg":"rejected","key":"ORDER_REJECTED"}`, 0, true},
+		{"HTTP 500 非 JSON", 500, `bad gateway`, 0, true},
+		{"HTTP 400 带错误码", 400, `{"code":3,"msg":"invalid price"}`, 3, true},
+		{"非 JSON 成功响应", 200, `ok`, 0, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkResponse(tc.status, []byte(tc.body))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("checkResponse 错误 = %v，期望出错 %v", err, tc.wantErr)
+			}
+			if err == nil {
+				return
+			}
+			var ee *ExchangeError
+			if !errors.As(err, &ee) {
+				t.Fatalf("错误类型 %T，期望 *ExchangeError", err)
+			}
+			if ee.HTTPStatus != tc.status || ee.Code != tc.wantCode {
+				t.Fatalf("HTTP / code = %d / %d，期望 %d / %d", ee.HTTPStatus, ee.Code, tc.status, tc.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package apex
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"arb/internal/ratelimit"
+)
+
+// 接口分组，各组独立限频
+const (
+	GroupMarket  = "market"  // 行情、交易对信息
+	GroupOrder   = "order"   // 下单、撤单、订单查询
+	GroupAccount = "account" // 账户、持仓
+)
+
+// ErrRateLimited 本地限频：令牌不足且等待超过 MaxWait，请求未发出
+var ErrRateLimited = errors.New("Apex 本地限频，请求未发出")
+
+// RateLimit 本地限频配置，各分组每秒请求数，<=0 表示不限制
+type RateLimit struct {
+	MarketRPS  float64
+	OrderRPS   float64
+	AccountRPS float64
+
+	// 令牌不足时最长等待，超过则返回 ErrRateLimited；0 表示一直等待
+	MaxWait time.Duration
+}
+
+// ThrottleEvent 限频事件，供调用方记录
+type ThrottleEvent struct {
+	Group    string
+	Wait     time.Duration // 本地等待时长（Rejected 时为需要等待的时长）
+	Rejected bool          // 等待超过 MaxWait，请求被拒绝
+}
+
+// rateLimiter 按接口分组限频
+type rateLimiter struct {
+	limiter *ratelimit.Limiter
+}
+
+func newRateLimiter(rl RateLimit, hook func(ThrottleEvent)) *rateLimiter {
+	var onEvent func(ratelimit.Event)
+	if hook != nil {
+		onEvent = func(ev ratelimit.Event) {
+			hook(ThrottleEvent{Group: ev.Group, Wait: ev.Wait, Rejected: ev.Rejected})
+		}
+	}
+	return &rateLimiter{limiter: ratelimit.New(map[string]float64{
+		GroupMarket:  rl.MarketRPS,
+		GroupOrder:   rl.OrderRPS,
+		GroupAccount: rl.AccountRPS,
+	}, rl.MaxWait, onEvent)}
+}
+
+// wait 等待 group 的令牌，等待超过 MaxWait 时返回 ErrRateLimited
+func (l *rateLimiter) wait(ctx context.Context, group string) error {
+	if l == nil {
+		return nil
+	}
+	if err := l.limiter.Wait(ctx, group); err != nil {
+		if errors.Is(err, ratelimit.ErrExceeded) {
+			return ErrRateLimited
+		}
+		return err
+	}
+	return nil
+}
+
+// endpointGroup 按请求路径归类接口分组
+func endpointGroup(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/api/v1/order"), strings.HasPrefix(path, "/api/v1/open-orders"):
+		return GroupOrder
+	case strings.HasPrefix(path, "/api/v1/account"), strings.HasPrefix(path, "/api/v1/positions"):
+		return GroupAccount
+	default:
+		return GroupMarket
+	}
+}
+
+// SetRateLimit 启用本地限频，hook 在请求等待或被拒绝时调用（可为 nil）
+func (c *Client) SetRateLimit(rl RateLimit, hook func(ThrottleEvent)) {
+	c.limiter = newRateLimiter(rl, hook)
+}
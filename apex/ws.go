@@ -2,8 +2,10 @@ package apex
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -17,20 +19,52 @@ type WsOrderBook struct {
 	Bids   [][]string `json:"bids"`
 	Asks   [][]string `json:"asks"`
 	Ts     int64      `json:"ts"`
+	U      int64      `json:"u"` // 更新序号，连续推送递增 1；为 0 时交易所未提供，不做连续性校验
+}
+
+// WsTrade WebSocket 推送的逐笔成交
+type WsTrade struct {
+	Symbol string `json:"s"`
+	Side   string `json:"S"` // 主动方：BUY / SELL
+	Price  string `json:"p"`
+	Size   string `json:"v"`
+	Ts     int64  `json:"T"` // 成交时间（毫秒）
+}
+
+// ParsePriceLevel 解析订单簿单档 [价格, 数量]，格式错误时返回 error（不会静默返回 0）
+func ParsePriceLevel(level []string) (price, size float64, err error) {
+	if len(level) < 2 {
+		return 0, 0, fmt.Errorf("订单簿档位格式错误: %v", level)
+	}
+	if price, err = strconv.ParseFloat(level[0], 64); err != nil {
+		return 0, 0, fmt.Errorf("解析价格 %q 失败: %w", level[0], err)
+	}
+	if size, err = strconv.ParseFloat(level[1], 64); err != nil {
+		return 0, 0, fmt.Errorf("解析数量 %q 失败: %w", level[1], err)
+	}
+	return price, size, nil
 }
 
 // subscription 保存一个订阅的元数据，用于断线后恢复
 type subscription struct {
 	topic string
+	depth int // 订单簿订阅的档位上限，0 表示不截取（非订单簿订阅为 0）
 	cb    func(data []byte)
 }
 
+// MaxOrderBookDepth Apex 订单簿频道推送的最大档位数，订阅档位超过此值时报错
+const MaxOrderBookDepth = 200
+
+// ErrUnsupportedDepth 订阅的订单簿档位不受支持
+var ErrUnsupportedDepth = errors.New("Apex 不支持该订单簿档位")
+
 // WsClient Apex Pro WebSocket 客户端（支持断线重连）
 type WsClient struct {
-	wsURL string
+	wsURL  string
+	logger *slog.Logger
 
-	mu   sync.Mutex
-	conn *websocket.Conn
+	mu     sync.Mutex
+	writer *wsWriter // 当前连接的写协程，所有出站帧经其发送
 
 	// 订阅注册表（断线后自动恢复）
 	subsMu sync.RWMutex
@@ -39,6 +73,10 @@ type WsClient struct {
 	// 连接状态
 	connected      atomic.Bool
 	reconnectCount atomic.Int64
+	seqGaps        atomic.Int64 // 订单簿序号缺口累计次数
+	resyncing      atomic.Bool  // 检测到序号缺口后等待重新订阅的推送，期间 IsReady 返回 false
+	failedDials    atomic.Int64 // 连续重连失败次数，重连成功后清零
+	maxDials       atomic.Int64 // 连续重连失败上限，0 表示不限制
 	lastPongAt     atomic.Value // time.Time
 	lastMsgAt      atomic.Value // time.Time
 	pingSeq        atomic.Int64
@@ -62,6 +100,7 @@ const (
 func NewWsClient(wsURL string) *WsClient {
 	w := &WsClient{
 		wsURL:    wsURL,
+		logger:   slog.Default(),
 		done:     make(chan struct{}),
 		reconnCh: make(chan struct{}, 1),
 	}
@@ -70,28 +109,72 @@ func NewWsClient(wsURL string) *WsClient {
 	return w
 }
 
+// SetLogger 设置日志输出（默认 slog.Default()），nil 时忽略；需在 Connect 前调用
+func (w *WsClient) SetLogger(l *slog.Logger) {
+	if l != nil {
+		w.logger = l
+	}
+}
+
 // Connect 建立初始连接并启动后台 goroutine
+// 初次连接失败时同样交给重连循环按退避重试（连接建立后自动恢复订阅），并返回初次连接的错误
 func (w *WsClient) Connect() error {
-	if err := w.dial(); err != nil {
-		return err
-	}
+	err := w.dial()
 	go w.reconnectLoop()
-	return nil
+	if err != nil {
+		select {
+		case w.reconnCh <- struct{}{}:
+		default:
+		}
+	}
+	return err
 }
 
 // SubscribeOrderBook 订阅订单簿频道（断线重连后自动恢复）
-func (w *WsClient) SubscribeOrderBook(symbol string, cb func(ob *WsOrderBook)) error {
+// Apex 频道按固定档位推送，depth 为回调收到的档位上限（1~200），0 表示不截取
+// 推送带序号 u 时校验连续性：重复或乱序的推送直接丢弃，出现缺口时丢弃本条、计数并重新订阅
+func (w *WsClient) SubscribeOrderBook(symbol string, depth int, cb func(ob *WsOrderBook)) error {
+	if depth < 0 || depth > MaxOrderBookDepth {
+		return fmt.Errorf("%w: %d（可选 1~%d，0 = 交易所默认）", ErrUnsupportedDepth, depth, MaxOrderBookDepth)
+	}
 	topic := fmt.Sprintf("orderbook.%s", symbol)
 
+	// lastU / epoch 只由读循环访问；重连后序号可能重置，从新连接的第一条推送重新开始校验
+	var lastU, epoch int64
 	w.subsMu.Lock()
 	w.subs = append(w.subs, subscription{
 		topic: topic,
+		depth: depth,
 		cb: func(data []byte) {
 			var ob WsOrderBook
 			if err := json.Unmarshal(data, &ob); err != nil {
-				log.Printf("[Apex WS] 解析订单簿数据失败: %v", err)
+				w.logger.Warn("[Apex WS] 解析订单簿数据失败", "err", err)
 				return
 			}
+			if ep := w.reconnectCount.Load(); ep != epoch {
+				epoch, lastU = ep, 0
+			}
+			if ob.U > 0 && lastU > 0 {
+				if ob.U <= lastU {
+					return
+				}
+				if ob.U != lastU+1 {
+					w.seqGaps.Add(1)
+					w.resyncing.Store(true)
+					w.logger.Warn("[Apex WS] 订单簿序号不连续，重新订阅", "topic", topic, "expected", lastU+1, "got", ob.U)
+					lastU = 0
+					if err := w.resubscribe(topic); err != nil {
+						w.logger.Warn("[Apex WS] 重新订阅失败", "topic", topic, "err", err)
+					}
+					return
+				}
+			}
+			lastU = ob.U
+			w.resyncing.Store(false)
+			if depth > 0 {
+				ob.Bids = truncateLevels(ob.Bids, depth)
+				ob.Asks = truncateLevels(ob.Asks, depth)
+			}
 			cb(&ob)
 		},
 	})
@@ -100,9 +183,80 @@ func (w *WsClient) SubscribeOrderBook(symbol string, cb func(ob *WsOrderBook)) e
 	return w.sendSubscribe(topic)
 }
 
-// IsReady 返回当前是否已连接且可用
+// truncateLevels 截取订单簿前 depth 档
+func truncateLevels(levels [][]string, depth int) [][]string {
+	if len(levels) > depth {
+		return levels[:depth]
+	}
+	return levels
+}
+
+// SubscribeTrades 订阅逐笔成交（trade.{symbol}），每条推送可能包含多笔成交
+func (w *WsClient) SubscribeTrades(symbol string, cb func(t *WsTrade)) error {
+	topic := fmt.Sprintf("trade.%s", symbol)
+
+	w.subsMu.Lock()
+	w.subs = append(w.subs, subscription{
+		topic: topic,
+		cb: func(data []byte) {
+			var trades []WsTrade
+			if err := json.Unmarshal(data, &trades); err != nil {
+				w.logger.Warn("[Apex WS] 解析成交数据失败", "err", err)
+				return
+			}
+			for i := range trades {
+				cb(&trades[i])
+			}
+		},
+	})
+	w.subsMu.Unlock()
+
+	return w.sendSubscribe(topic)
+}
+
+// WsStats WebSocket 连接健康状况
+type WsStats struct {
+	Connected      bool          // 当前是否已连接
+	ReconnectCount int64         // 累计重连次数
+	Failed         bool          // 连续重连失败次数已达到上限（见 SetMaxReconnectAttempts）
+	RTT            time.Duration // 最近一次 ping/pong 往返时延
+	LastMessageAt  time.Time     // 最近一次收到消息的时间
+	LastMessageAge time.Duration // 距最近一次消息的时长（从未收到时为 0）
+	SeqGaps        int64         // 订单簿序号缺口累计次数（每次缺口触发一次重新订阅）
+	Resyncing      bool          // 正在重新订阅以恢复订单簿连续性
+}
+
+// SetMaxReconnectAttempts 设置连续重连失败上限，达到后 Failed 返回 true（重连循环仍继续尝试，成功后清除）；n <= 0 不限制
+func (w *WsClient) SetMaxReconnectAttempts(n int) {
+	w.maxDials.Store(int64(n))
+}
+
+// Failed 连续重连失败次数是否已达到上限：交易所接口可能已不可用，调用方不应继续依赖该行情
+func (w *WsClient) Failed() bool {
+	limit := w.maxDials.Load()
+	return limit > 0 && w.failedDials.Load() >= limit
+}
+
+// Stats 返回连接健康状况与延迟
+func (w *WsClient) Stats() WsStats {
+	st := WsStats{
+		Connected:      w.connected.Load(),
+		ReconnectCount: w.reconnectCount.Load(),
+		RTT:            time.Duration(w.rtt.Load()),
+		Failed:         w.Failed(),
+		SeqGaps:        w.seqGaps.Load(),
+		Resyncing:      w.resyncing.Load(),
+	}
+	if t, ok := w.lastMsgAt.Load().(time.Time); ok && !t.IsZero() {
+		st.LastMessageAt = t
+		st.LastMessageAge = time.Since(t)
+	}
+	return st
+}
+
+// IsReady 返回当前是否已连接且可用（订单簿因序号缺口重新订阅期间不可用）
 func (w *WsClient) IsReady() bool {
-	return w.connected.Load()
+	return w.connected.Load() && !w.resyncing.Load()
 }
 
 // Close 关闭客户端
@@ -112,11 +266,16 @@ func (w *WsClient) Close() {
 	default:
 		close(w.done)
 	}
-	w.mu.Lock()
-	if w.conn != nil {
-		_ = w.conn.Close()
+	if writer := w.currentWriter(); writer != nil {
+		writer.close()
 	}
-	w.mu.Unlock()
+}
+
+// currentWriter 返回当前连接的写协程，尚未连接时返回 nil
+func (w *WsClient) currentWriter() *wsWriter {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writer
 }
 
 // ---- 内部方法 ----
@@ -140,15 +299,16 @@ func (w *WsClient) dial() error {
 		return nil
 	})
 
+	writer := newWsWriter(conn)
 	w.mu.Lock()
-	w.conn = conn
+	w.writer = writer
 	w.mu.Unlock()
 
 	w.connected.Store(true)
-	log.Printf("[Apex WS] 连接成功: %s", w.wsURL)
+	w.logger.Info("[Apex WS] 连接成功", "url", w.wsURL)
 
-	go w.readLoop(conn)
-	go w.pingLoop(conn)
+	go w.readLoop(conn, writer)
+	go w.pingLoop(writer)
 	return nil
 }
 
@@ -161,7 +321,7 @@ func (w *WsClient) reconnectLoop() {
 		case <-w.reconnCh:
 			w.connected.Store(false)
 			count := w.reconnectCount.Add(1)
-			log.Printf("[Apex WS] 检测到断线，第 %d 次重连，等待 %v ...", count, backoff)
+			w.logger.Warn("[Apex WS] 检测到断线，等待后重连", "attempt", count, "backoff", backoff)
 
 			select {
 			case <-w.done:
@@ -170,7 +330,10 @@ func (w *WsClient) reconnectLoop() {
 			}
 
 			if err := w.dial(); err != nil {
-				log.Printf("[Apex WS] 重连失败: %v", err)
+				w.logger.Warn("[Apex WS] 重连失败", "err", err)
+				if n := w.failedDials.Add(1); n == w.maxDials.Load() {
+					w.logger.Error("[Apex WS] 连续重连失败次数达到上限，标记为不可用", "attempts", n)
+				}
 				backoff *= 2
 				if backoff > wsMaxBackoff {
 					backoff = wsMaxBackoff
@@ -183,13 +346,15 @@ func (w *WsClient) reconnectLoop() {
 			}
 
 			backoff = wsInitialBackoff
+			w.failedDials.Store(0)
 			w.resubscribeAll()
 		}
 	}
 }
 
-func (w *WsClient) readLoop(conn *websocket.Conn) {
+func (w *WsClient) readLoop(conn *websocket.Conn, writer *wsWriter) {
 	defer func() {
+		writer.close()
 		select {
 		case <-w.done:
 			return
@@ -213,7 +378,7 @@ func (w *WsClient) readLoop(conn *websocket.Conn) {
 			select {
 			case <-w.done:
 			default:
-				log.Printf("[Apex WS] 读取错误（将触发重连）: %v", err)
+				w.logger.Warn("[Apex WS] 读取错误（将触发重连）", "err", err)
 			}
 			return
 		}
@@ -242,7 +407,7 @@ func (w *WsClient) readLoop(conn *websocket.Conn) {
 	}
 }
 
-func (w *WsClient) pingLoop(conn *websocket.Conn) {
+func (w *WsClient) pingLoop(writer *wsWriter) {
 	ticker := time.NewTicker(wsPingInterval)
 	defer ticker.Stop()
 
@@ -253,8 +418,8 @@ func (w *WsClient) pingLoop(conn *websocket.Conn) {
 		case <-ticker.C:
 			if lastPong, ok := w.lastPongAt.Load().(time.Time); ok && !lastPong.IsZero() {
 				if time.Since(lastPong) > wsPingInterval+wsPongTimeout {
-					log.Printf("[Apex WS] Pong 超时，主动断线触发重连")
-					_ = conn.Close()
+					w.logger.Warn("[Apex WS] Pong 超时，主动断线触发重连")
+					writer.close()
 					return
 				}
 			}
@@ -262,12 +427,10 @@ func (w *WsClient) pingLoop(conn *websocket.Conn) {
 			seq := fmt.Sprintf("%d", w.pingSeq.Add(1))
 			w.pingSentAt.Store(seq, time.Now())
 
-			w.mu.Lock()
-			err := conn.WriteMessage(websocket.PingMessage, []byte(seq))
-			w.mu.Unlock()
+			err := writer.write(websocket.PingMessage, []byte(seq))
 
 			if err != nil {
-				log.Printf("[Apex WS] Ping 发送失败: %v", err)
+				w.logger.Warn("[Apex WS] Ping 发送失败", "err", err)
 				return
 			}
 		}
@@ -279,22 +442,37 @@ func (w *WsClient) resubscribeAll() {
 	defer w.subsMu.RUnlock()
 	for _, s := range w.subs {
 		if err := w.sendSubscribe(s.topic); err != nil {
-			log.Printf("[Apex WS] 恢复订阅 %s 失败: %v", s.topic, err)
+			w.logger.Warn("[Apex WS] 恢复订阅失败", "topic", s.topic, "err", err)
 		} else {
-			log.Printf("[Apex WS] 已恢复订阅: %s", s.topic)
+			w.logger.Info("[Apex WS] 已恢复订阅", "topic", s.topic, "depth", s.depth)
 		}
 	}
 }
 
+// resubscribe 取消并重新订阅频道，用于序号缺口后恢复推送连续性
+func (w *WsClient) resubscribe(topic string) error {
+	msg := map[string]interface{}{
+		"op":   "unsubscribe",
+		"args": []string{topic},
+	}
+	writer := w.currentWriter()
+	if writer == nil {
+		return fmt.Errorf("连接尚未建立")
+	}
+	if err := writer.writeJSON(msg); err != nil {
+		return err
+	}
+	return w.sendSubscribe(topic)
+}
+
 func (w *WsClient) sendSubscribe(topic string) error {
 	msg := map[string]interface{}{
 		"op":   "subscribe",
 		"args": []string{topic},
 	}
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	if w.conn == nil {
+	writer := w.currentWriter()
+	if writer == nil {
 		return fmt.Errorf("连接尚未建立")
 	}
-	return w.conn.WriteJSON(msg)
+	return writer.writeJSON(msg)
 }
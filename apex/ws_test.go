@@ -0,0 +1,159 @@
+package apex
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestParsePriceLevel(t *testing.T) {
+	cases := []struct {
+		name        string
+		level       []string
+		price, size float64
+		wantErr     bool
+	}{
+		{"正常", []string{"100010.5", "0.25"}, 100010.5, 0.25, false},
+		{"科学计数法", []string{"1.0001e5", "2.5E-2"}, 100010, 0.025, false},
+		{"整数", []string{"100000", "1"}, 100000, 1, false},
+		{"价格为空", []string{"", "0.25"}, 0, 0, true},
+		{"数量为空", []string{"100010.5", ""}, 0, 0, true},
+		{"价格乱码", []string{"abc", "0.25"}, 0, 0, true},
+		{"数量乱码", []string{"100010.5", "0.2x"}, 0, 0, true},
+		{"前缀数字", []string{"100010.5USDT", "0.25"}, 0, 0, true},
+		{"缺少数量", []string{"100010.5"}, 0, 0, true},
+		{"空档位", nil, 0, 0, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			price, size, err := ParsePriceLevel(tc.level)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("ParsePriceLevel(%q) 应返回错误，得到 %v / %v", tc.level, price, size)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePriceLevel(%q) 返回错误: %v", tc.level, err)
+			}
+			if price != tc.price || size != tc.size {
+				t.Fatalf("ParsePriceLevel(%q) = %v / %v，期望 %v / %v", tc.level, price, size, tc.price, tc.size)
+			}
+		})
+	}
+}
+
+var benchLevel = []string{"100010.5", "0.25"}
+
+func BenchmarkParsePriceLevel(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, _, err := ParsePriceLevel(benchLevel); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSscanfPriceLevel 旧实现（fmt.Sscanf）作为对照
+func BenchmarkSscanfPriceLevel(b *testing.B) {
+	var price, size float64
+	for i := 0; i < b.N; i++ {
+		fmt.Sscanf(benchLevel[0], "%f", &price)
+		fmt.Sscanf(benchLevel[1], "%f", &size)
+	}
+}
+
+// tradeServer 模拟 Apex 公共频道：每次收到订阅后推送一条逐笔成交，第一个连接推送后立即断开
+type tradeServer struct {
+	srv        *httptest.Server
+	conns      atomic.Int32
+	subscribed chan string
+}
+
+func newTradeServer(t *testing.T) *tradeServer {
+	ts := &tradeServer{subscribed: make(chan string, 8)}
+	upgrader := websocket.Upgrader{}
+	ts.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		n := ts.conns.Add(1)
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var req struct {
+				Op   string   `json:"op"`
+				Args []string `json:"args"`
+			}
+			if json.Unmarshal(msg, &req) != nil || req.Op != "subscribe" {
+				continue
+			}
+			ts.subscribed <- req.Args[0]
+			// 格式错误的推送应被跳过，不影响后续消息
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"topic":"trade.BTCUSDTM","data":{}}`))
+			side, price := "BUY", "100000.5"
+			if n > 1 {
+				side, price = "SELL", "99999"
+			}
+			conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(
+				`{"topic":"trade.BTCUSDTM","ts":1,"data":[{"T":1700000000000,"s":"BTCUSDTM","S":"%s","v":"0.01","p":"%s"}]}`, side, price)))
+			if n == 1 {
+				return
+			}
+		}
+	}))
+	t.Cleanup(ts.srv.Close)
+	return ts
+}
+
+func (ts *tradeServer) url() string { return "ws" + strings.TrimPrefix(ts.srv.URL, "http") }
+
+// TestSubscribeTradesResubscribesAfterReconnect 逐笔成交订阅复用断线重连与恢复订阅
+func TestSubscribeTradesResubscribesAfterReconnect(t *testing.T) {
+	ts := newTradeServer(t)
+	w := NewWsClient(ts.url())
+	if err := w.Connect(); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer w.Close()
+
+	trades := make(chan WsTrade, 8)
+	if err := w.SubscribeTrades("BTCUSDTM", func(tr *WsTrade) { trades <- *tr }); err != nil {
+		t.Fatalf("订阅失败: %v", err)
+	}
+
+	for i, want := range []WsTrade{
+		{Symbol: "BTCUSDTM", Side: "BUY", Price: "100000.5", Size: "0.01", Ts: 1700000000000},
+		{Symbol: "BTCUSDTM", Side: "SELL", Price: "99999", Size: "0.01", Ts: 1700000000000},
+	} {
+		select {
+		case topic := <-ts.subscribed:
+			if topic != "trade.BTCUSDTM" {
+				t.Fatalf("第 %d 次订阅频道 = %s", i+1, topic)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("等待第 %d 次订阅超时", i+1)
+		}
+		select {
+		case got := <-trades:
+			if got != want {
+				t.Fatalf("第 %d 笔成交 = %+v，期望 %+v", i+1, got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("等待第 %d 笔成交超时", i+1)
+		}
+	}
+	if n := w.Stats().ReconnectCount; n < 1 {
+		t.Fatalf("重连次数 = %d，期望至少 1", n)
+	}
+}
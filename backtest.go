@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"arb/backtest"
+	"arb/config"
+)
+
+// runBacktest 回放两个行情记录文件并打印回测汇总，按 backtest.output_csv 输出逐笔交易
+// 回放期间屏蔽决策过程日志（每次机会都会打印），verbose 为 true 时保留
+func runBacktest(cfg *config.Config, fileA, fileB string, verbose bool) int {
+	if fileA == "" || fileB == "" {
+		fmt.Fprintln(os.Stderr, "回测需要两个行情记录文件：-backtest file_a file_b 或配置 backtest.file_a / backtest.file_b")
+		return 2
+	}
+
+	if !verbose {
+		prev := slog.Default()
+		slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+		defer slog.SetDefault(prev)
+	}
+	sum, trades, err := backtest.Run(cfg, fileA, fileB)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "回测失败: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("=== 回测结果 %s ~ %s ===\n", sum.Start.Format("2006-01-02 15:04:05"), sum.End.Format("2006-01-02 15:04:05"))
+	fmt.Printf("盘口更新: %d  达到阈值: %d  模拟下单: %d（减仓 %d）  未成交: %d  成交笔数: %d\n",
+		sum.Events, sum.Opportunities, sum.Trades, sum.Reduces, sum.Unfilled, len(trades))
+	fmt.Printf("成交量: %.4f  未对冲: %.4f  最大持仓: %.4f\n", sum.Volume, sum.Unhedged, sum.MaxPosition)
+	fmt.Printf("毛利: %.4f USDC  手续费: %.4f USDC  净PnL: %.4f USDC\n", sum.GrossPnL, sum.Fees, sum.NetPnL)
+	if sum.Halted != "" {
+		fmt.Printf("停止开仓: %s\n", sum.Halted)
+	}
+
+	if path := cfg.Backtest.OutputCSV; path != "" {
+		if err := backtest.WriteCSV(path, trades); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("逐笔交易已写入 %s\n", path)
+	}
+	return 0
+}
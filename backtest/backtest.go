@@ -0,0 +1,357 @@
+// Package backtest 用行情记录（recorder 输出的 NDJSON）回放两所盘口，驱动与实盘相同的价差决策，并按简化撮合模型统计结果
+//
+// 决策与实盘 checkAndTrade 共用 strategy.Decider：价差回归平仓、止盈止损、反向机会减仓与开仓的判断顺序一致，
+// 减仓数量不超过当时持仓；止盈止损后停止开仓、继续按价差回归平仓
+//
+// 撮合模型：决策后经过 latency_ms 再按当时盘口成交——A所腿在限价仍可成交时按盘口价成交，数量不超过盘口挂单量；
+// 对冲腿按当时 B所盘口价成交，数量不超过 A所成交量与盘口挂单量。两腿按配置费率收取 taker 手续费
+package backtest
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"arb/config"
+	"arb/exchange"
+	"arb/recorder"
+	"arb/strategy"
+)
+
+// Summary 回测汇总
+type Summary struct {
+	Events        int // 回放的盘口更新条数
+	Opportunities int // 净价差达到开仓或平仓阈值的次数
+	Trades        int // 深度与滑点检查通过后模拟下单的次数
+	Reduces       int // 其中 reduce-only 下单（价差回归平仓与反向机会减仓）的次数
+	Unfilled      int // A所腿因延迟后盘口变化未成交的次数
+
+	Volume      float64 // A所成交量合计
+	Unhedged    float64 // 对冲腿因盘口挂单量不足未成交的数量合计
+	GrossPnL    float64
+	Fees        float64
+	NetPnL      float64
+	MaxPosition float64 // A所净持仓绝对值的最大值
+
+	Halted string // 止盈 / 止损停止开仓的原因，未触发时为空
+
+	Start, End time.Time
+}
+
+// Trade 单笔模拟交易
+type Trade struct {
+	Time      time.Time
+	Action    string // entry / reduce / unwind
+	Direction string
+	Size      float64 // 计划下单量
+	QtyA      float64 // A所成交量
+	PriceA    float64
+	QtyB      float64 // 对冲成交量
+	PriceB    float64
+	Gross     float64
+	Fee       float64
+	Net       float64
+	Position  float64 // 成交后 A所净持仓
+}
+
+// pending 等待延迟到期后撮合的订单
+type pending struct {
+	at       time.Time
+	decision strategy.Decision
+}
+
+// Run 回放 fileA / fileB 中两所的盘口记录（按本地接收时间合并），返回汇总与逐笔交易
+// 两个文件可以是同一个 recorder 输出文件：每个文件只取与对应交易所名称匹配的记录
+func Run(cfg *config.Config, fileA, fileB string) (*Summary, []Trade, error) {
+	dec, err := strategy.NewDecider(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	feeA, feeB := dec.FeeRates()
+	if cfg.Backtest.FeeRateA > 0 {
+		feeA = cfg.Backtest.FeeRateA
+	}
+	if cfg.Backtest.FeeRateB > 0 {
+		feeB = cfg.Backtest.FeeRateB
+	}
+	dec.SetFeeRates(feeA, feeB)
+
+	nameA, nameB := venueName(cfg.ExchangeA, exchange.Apex), venueName(cfg.ExchangeB, exchange.Bybit)
+	sa, err := openStream(fileA, nameA)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer sa.Close()
+	sb, err := openStream(fileB, nameB)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer sb.Close()
+
+	latency := time.Duration(cfg.Backtest.LatencyMs) * time.Millisecond
+	hedge := cfg.Strategy.HedgeMode
+
+	var (
+		sum      Summary
+		trades   []Trade
+		bookA    *exchange.OrderBook
+		bookB    *exchange.OrderBook
+		position float64
+		order    *pending
+	)
+
+	// settle 撮合一笔决策并计入汇总
+	settle := func(d strategy.Decision, now time.Time) {
+		tr, filled := fill(d, bookA, bookB, hedge, feeA, feeB)
+		if !filled {
+			sum.Unfilled++
+			return
+		}
+		position += posDelta(tr)
+		tr.Time, tr.Position = now, position
+		dec.Record(tr.Net)
+		sum.Volume += tr.QtyA
+		if hedge {
+			sum.Unhedged += tr.QtyA - tr.QtyB
+		}
+		sum.GrossPnL += tr.Gross
+		sum.Fees += tr.Fee
+		sum.NetPnL += tr.Net
+		sum.MaxPosition = math.Max(sum.MaxPosition, math.Abs(position))
+		trades = append(trades, tr)
+	}
+
+	for {
+		t, fromA, ok, err := next(sa, sb)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			break
+		}
+		now := time.UnixMilli(t.RecvTs)
+		if sum.Events == 0 {
+			sum.Start = now
+		}
+		sum.End = now
+		sum.Events++
+
+		if fromA {
+			bookA = tickBook(t)
+		} else {
+			bookB = tickBook(t)
+		}
+		if bookA == nil || bookB == nil {
+			continue
+		}
+
+		// 延迟到期：按当前盘口撮合；撮合前不做新决策（与实盘串行执行一致）
+		if order != nil {
+			if now.Before(order.at) {
+				continue
+			}
+			settle(order.decision, now)
+			order = nil
+			continue
+		}
+
+		d, tradable, err := dec.Decide(bookA, bookB, position)
+		if err != nil {
+			continue // 异常盘口，实盘同样丢弃
+		}
+		if d.Action == strategy.ActionTakeProfit || d.Action == strategy.ActionStopLoss {
+			// 与实盘一致：停止开仓，价差回归平仓继续
+			sum.Halted = d.Reason
+			dec.Halt(d.Reason)
+			continue
+		}
+		if d.Direction == strategy.DirectionNone {
+			continue
+		}
+		sum.Opportunities++
+		if !tradable {
+			continue
+		}
+		sum.Trades++
+		if d.Action != strategy.ActionEntry {
+			sum.Reduces++
+		}
+		if latency == 0 {
+			// 无延迟：按决策时的盘口立即撮合
+			settle(d, now)
+			continue
+		}
+		order = &pending{at: now.Add(latency), decision: d}
+	}
+	return &sum, trades, nil
+}
+
+// fill 按模拟撮合模型成交一笔决策
+func fill(d strategy.Decision, a, b *exchange.OrderBook, hedge bool, feeA, feeB float64) (Trade, bool) {
+	tr := Trade{Action: d.Action.String(), Direction: d.Direction.Tag(), Size: d.Size}
+
+	// A所腿：IOC 限价，盘口价优于或等于限价时按盘口价成交，数量不超过挂单量
+	if d.Direction == strategy.DirectionLong {
+		if top := a.Asks[0]; top.Price <= d.PriceA {
+			tr.PriceA, tr.QtyA = top.Price, math.Min(d.Size, top.Size)
+		}
+	} else {
+		if top := a.Bids[0]; top.Price >= d.PriceA {
+			tr.PriceA, tr.QtyA = top.Price, math.Min(d.Size, top.Size)
+		}
+	}
+	if tr.QtyA <= 0 {
+		return Trade{}, false
+	}
+	tr.Fee = tr.PriceA * tr.QtyA * feeA
+
+	// 单腿模式：与实盘一致按决策时的净价差估算
+	if !hedge {
+		tr.Net = d.NetSpread * tr.QtyA
+		tr.Gross = tr.Net + tr.Fee
+		return tr, true
+	}
+
+	// 对冲腿：按当前 B所盘口价成交，数量不超过 A所成交量与挂单量
+	top := b.Bids[0]
+	if d.Direction == strategy.DirectionShort {
+		top = b.Asks[0]
+	}
+	tr.PriceB, tr.QtyB = top.Price, math.Min(tr.QtyA, top.Size)
+	tr.Fee += tr.PriceB * tr.QtyB * feeB
+
+	// 已实现盈亏按两腿匹配数量计算（与实盘 realizedPnL 一致）
+	tr.Gross = (tr.PriceB - tr.PriceA) * tr.QtyB * d.Direction.Sign()
+	tr.Net = tr.Gross - tr.Fee
+	return tr, true
+}
+
+// posDelta 返回成交对 A所净持仓的影响
+func posDelta(tr Trade) float64 {
+	if tr.Direction == strategy.DirectionShort.Tag() {
+		return -tr.QtyA
+	}
+	return tr.QtyA
+}
+
+// tickBook 将一条记录转为一档订单簿
+func tickBook(t recorder.Tick) *exchange.OrderBook {
+	return &exchange.OrderBook{
+		Bids: []exchange.Level{{Price: t.Bid, Size: t.BidSize}},
+		Asks: []exchange.Level{{Price: t.Ask, Size: t.AskSize}},
+		Ts:   t.Ts,
+	}
+}
+
+// venueName 返回交易所配置名称，未配置时使用默认值
+func venueName(name, def string) string {
+	if name == "" {
+		return def
+	}
+	return name
+}
+
+// WriteCSV 将逐笔交易写入 CSV 文件
+func WriteCSV(path string, trades []Trade) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建回测交易文件 %s 失败: %w", path, err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"time", "action", "direction", "size", "qty_a", "price_a", "qty_b", "price_b", "gross", "fee", "net", "position"})
+	num := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	for _, t := range trades {
+		w.Write([]string{
+			t.Time.Format(time.RFC3339Nano), t.Action, t.Direction, num(t.Size),
+			num(t.QtyA), num(t.PriceA), num(t.QtyB), num(t.PriceB),
+			num(t.Gross), num(t.Fee), num(t.Net), num(t.Position),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("写入回测交易文件 %s 失败: %w", path, err)
+	}
+	return f.Close()
+}
+
+// ---------- 记录读取 ----------
+
+// stream 按行读取 NDJSON 记录，只保留指定交易所的记录
+type stream struct {
+	f       *os.File
+	sc      *bufio.Scanner
+	venue   string
+	path    string
+	line    int
+	head    recorder.Tick
+	hasHead bool
+	eof     bool
+}
+
+func openStream(path, venue string) (*stream, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开行情记录 %s 失败: %w", path, err)
+	}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	return &stream{f: f, sc: sc, venue: venue, path: path}, nil
+}
+
+func (s *stream) Close() { s.f.Close() }
+
+// peek 返回下一条匹配的记录（不消费）
+func (s *stream) peek() (recorder.Tick, bool, error) {
+	for !s.hasHead && !s.eof {
+		if !s.sc.Scan() {
+			s.eof = true
+			if err := s.sc.Err(); err != nil {
+				return recorder.Tick{}, false, fmt.Errorf("读取行情记录 %s 失败: %w", s.path, err)
+			}
+			break
+		}
+		s.line++
+		raw := s.sc.Bytes()
+		if len(strings.TrimSpace(string(raw))) == 0 {
+			continue
+		}
+		var t recorder.Tick
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return recorder.Tick{}, false, fmt.Errorf("解析行情记录 %s 第 %d 行失败: %w", s.path, s.line, err)
+		}
+		if !strings.EqualFold(t.Exchange, s.venue) {
+			continue
+		}
+		s.head, s.hasHead = t, true
+	}
+	return s.head, s.hasHead, nil
+}
+
+// next 按本地接收时间合并两路记录，返回时间最早的一条
+func next(a, b *stream) (t recorder.Tick, fromA, ok bool, err error) {
+	ta, okA, err := a.peek()
+	if err != nil {
+		return t, false, false, err
+	}
+	tb, okB, err := b.peek()
+	if err != nil {
+		return t, false, false, err
+	}
+	switch {
+	case okA && (!okB || ta.RecvTs <= tb.RecvTs):
+		a.hasHead = false
+		return ta, true, true, nil
+	case okB:
+		b.hasHead = false
+		return tb, false, true, nil
+	}
+	return t, false, false, nil
+}
@@ -0,0 +1,558 @@
+package binance
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client Binance U 本位合约 REST 客户端
+type Client struct {
+	baseURL    string
+	apiKey     string
+	apiSecret  string
+	httpClient *http.Client
+	retry      RetryPolicy
+	limiter    *rateLimiter // 本地限频，nil 表示不限制
+	idPrefix   string       // 自定义订单ID前缀
+	logger     *slog.Logger
+
+	// 单次请求超时：下单/撤单使用 orderTimeout，其余查询使用 queryTimeout，0 表示只受 httpClient 超时限制
+	orderTimeout time.Duration
+	queryTimeout time.Duration
+}
+
+// NewClient 创建 Binance 合约 REST 客户端
+func NewClient(baseURL, apiKey, apiSecret string) *Client {
+	return &Client{
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		apiSecret:  apiSecret,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     slog.Default(),
+	}
+}
+
+// SetLogger 设置日志输出（默认 slog.Default()），nil 时忽略
+func (c *Client) SetLogger(l *slog.Logger) {
+	if l != nil {
+		c.logger = l
+	}
+}
+
+// SetTimeouts 设置单次请求超时：order 用于下单/撤单，query 用于其余查询，0 表示不单独限制
+func (c *Client) SetTimeouts(order, query time.Duration) {
+	c.orderTimeout = order
+	c.queryTimeout = query
+}
+
+// callTimeout 返回单次请求的超时：订单分组的写请求（下单、撤单）使用 orderTimeout
+func (c *Client) callTimeout(method, path string) time.Duration {
+	if method != http.MethodGet && endpointGroup(path) == GroupOrder {
+		return c.orderTimeout
+	}
+	return c.queryTimeout
+}
+
+// SetRetryPolicy 设置瞬时错误的重试策略（默认不重试）
+func (c *Client) SetRetryPolicy(p RetryPolicy) {
+	c.retry = p
+}
+
+// NormalizeSymbol 将交易对转换为 Binance 格式：去掉分隔符并大写（BTC-USDT / btcusdt → BTCUSDT）
+func NormalizeSymbol(symbol string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", "_", "", "/", "").Replace(symbol))
+}
+
+// ---------- 公共数据结构 ----------
+
+// OrderBook 订单簿快照
+type OrderBook struct {
+	Bids [][]string `json:"bids"` // [[price, size], ...]
+	Asks [][]string `json:"asks"`
+}
+
+// BestPrice 最优买卖价
+type BestPrice struct {
+	BidPrice float64
+	BidSize  float64
+	AskPrice float64
+	AskSize  float64
+}
+
+// InstrumentInfo 交易对规格
+type InstrumentInfo struct {
+	Symbol      string
+	TickSize    float64 // 价格最小变动单位
+	QtyStep     float64 // 数量最小变动单位
+	MinOrderQty float64 // 最小下单量
+	MaxOrderQty float64 // 最大下单量
+	MinNotional float64 // 最小下单名义价值（0=交易所未提供）
+}
+
+// Position 持仓信息（单向持仓模式下 PositionSide 为 BOTH）
+type Position struct {
+	Symbol           string  `json:"symbol"`
+	PositionSide     string  `json:"positionSide"`            // BOTH / LONG / SHORT
+	PositionAmt      float64 `json:"positionAmt,string"`      // 带方向的数量：多头为正，空头为负
+	EntryPrice       float64 `json:"entryPrice,string"`       // 持仓均价
+	UnrealizedProfit float64 `json:"unRealizedProfit,string"` // 未实现盈亏
+}
+
+// Account 账户信息
+type Account struct {
+	TotalMarginBalance float64 `json:"totalMarginBalance,string"` // 保证金余额（含未实现盈亏）
+	AvailableBalance   float64 `json:"availableBalance,string"`   // 可用余额
+}
+
+// Order 订单信息
+type Order struct {
+	OrderID       int64   `json:"orderId"`
+	ClientOrderID string  `json:"clientOrderId"`
+	Symbol        string  `json:"symbol"`
+	Side          string  `json:"side"` // BUY / SELL
+	Type          string  `json:"type"` // LIMIT / MARKET
+	Price         float64 `json:"price,string"`
+	OrigQty       float64 `json:"origQty,string"`
+	ExecutedQty   float64 `json:"executedQty,string"`
+	AvgPrice      float64 `json:"avgPrice,string"` // 成交均价
+	Status        string  `json:"status"`          // NEW / PARTIALLY_FILLED / FILLED / CANCELED / EXPIRED
+	Time          int64   `json:"time"`            // 创建时间（毫秒），下单响应中为 0
+	UpdateTime    int64   `json:"updateTime"`
+}
+
+// Trade 账户成交记录（用于统计订单手续费）
+type Trade struct {
+	OrderID         int64   `json:"orderId"`
+	Price           float64 `json:"price,string"`
+	Qty             float64 `json:"qty,string"`
+	Commission      float64 `json:"commission,string"`
+	CommissionAsset string  `json:"commissionAsset"`
+}
+
+// PlaceOrderReq 下单请求
+type PlaceOrderReq struct {
+	Symbol        string
+	Side          string // BUY / SELL
+	Type          string // LIMIT / MARKET
+	Quantity      string
+	Price         string // 市价单为空
+	TimeInForce   string // GTC / IOC / FOK / GTX，市价单为空
+	ReduceOnly    bool
+	ClientOrderID string // newClientOrderId
+}
+
+// params 转换为请求参数
+func (r *PlaceOrderReq) params() url.Values {
+	v := url.Values{}
+	v.Set("symbol", r.Symbol)
+	v.Set("side", r.Side)
+	v.Set("type", r.Type)
+	v.Set("quantity", r.Quantity)
+	if r.Price != "" {
+		v.Set("price", r.Price)
+	}
+	if r.TimeInForce != "" {
+		v.Set("timeInForce", r.TimeInForce)
+	}
+	if r.ReduceOnly {
+		v.Set("reduceOnly", "true")
+	}
+	v.Set("newClientOrderId", r.ClientOrderID)
+	// RESULT：响应直接返回成交量与成交均价（IOC/市价单为最终状态）
+	v.Set("newOrderRespType", "RESULT")
+	return v
+}
+
+// ---------- 签名工具 ----------
+
+// sign 生成 HMAC-SHA256 签名：对完整查询字符串签名
+func (c *Client) sign(query string) string {
+	mac := hmac.New(sha256.New, []byte(c.apiSecret))
+	mac.Write([]byte(query))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// RetryPolicy 瞬时错误（网络错误、HTTP 5xx、429/418、Binance 服务端错误码）的重试策略
+type RetryPolicy struct {
+	MaxAttempts int           // 最大尝试次数（含首次），<=1 表示不重试
+	BaseDelay   time.Duration // 首次重试前的等待，之后每次翻倍
+	MaxDelay    time.Duration // 单次等待上限，0 表示不限制
+	Jitter      float64       // 随机抖动比例（0~1），避免多个请求同时重试
+}
+
+// backoff 返回第 n 次失败后的等待时长（指数退避 + 随机抖动）
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.BaseDelay << (n - 1)
+	if p.MaxDelay > 0 && (d > p.MaxDelay || d <= 0) {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d += time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// defaultClientIDPrefix 未设置前缀时自定义订单ID的前缀
+const defaultClientIDPrefix = "arb"
+
+// SetClientIDPrefix 设置自动生成的自定义订单ID前缀，多实例共用账户时用于区分
+func (c *Client) SetClientIDPrefix(prefix string) {
+	c.idPrefix = prefix
+}
+
+// newClientOrderID 生成自定义订单ID，下单重试时用于去重
+func (c *Client) newClientOrderID() string {
+	prefix := c.idPrefix
+	if prefix == "" {
+		prefix = defaultClientIDPrefix
+	}
+	return fmt.Sprintf("%s-%d-%04d", prefix, time.Now().UnixNano(), rand.Intn(10000))
+}
+
+// request 发送带签名的 HTTP 请求，瞬时错误按重试策略重试
+func (c *Client) request(ctx context.Context, method, path string, params url.Values) ([]byte, error) {
+	data, _, err := c.requestAttempts(ctx, method, path, params)
+	return data, err
+}
+
+// requestAttempts 同 request，额外返回实际尝试次数
+// 只重试网络错误、HTTP 5xx、429/418 与服务端错误码，业务拒单立即返回
+func (c *Client) requestAttempts(ctx context.Context, method, path string, params url.Values) ([]byte, int, error) {
+	for n := 1; ; n++ {
+		data, err := c.doRequest(ctx, method, path, params, true)
+		if err == nil || !IsRetryable(err) || n >= c.retry.MaxAttempts {
+			return data, n, err
+		}
+
+		delay := c.retry.backoff(n)
+		c.logger.Warn("[Binance REST] 请求失败，稍后重试", "method", method, "path", path, "attempt", n, "err", err, "delay", delay.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return nil, n, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// doRequest 发送一次 HTTP 请求，signed=true 时附加 timestamp/recvWindow 与签名
+// 参数统一放在查询字符串中（Binance 对 GET/POST/DELETE 均支持）
+func (c *Client) doRequest(ctx context.Context, method, path string, params url.Values, signed bool) ([]byte, error) {
+	if err := c.limiter.wait(ctx, endpointGroup(path)); err != nil {
+		return nil, err
+	}
+
+	if params == nil {
+		params = url.Values{}
+	}
+	query := params.Encode()
+	if signed {
+		params.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+		params.Set("recvWindow", "5000")
+		query = params.Encode()
+		query += "&signature=" + c.sign(query)
+	}
+
+	// 单次请求超时只作用于本次 HTTP 请求：超时视为瞬时错误可重试，ctx 取消则立即返回
+	reqCtx := ctx
+	if d := c.callTimeout(method, path); d > 0 {
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	u := c.baseURL + path
+	if query != "" {
+		u += "?" + query
+	}
+	req, err := http.NewRequestWithContext(reqCtx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if signed {
+		req.Header.Set("X-MBX-APIKEY", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, &transientError{err}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &transientError{err}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		ee := &ExchangeError{HTTPStatus: resp.StatusCode, Msg: string(data)}
+		var body struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if json.Unmarshal(data, &body) == nil && body.Code != 0 {
+			ee.Code, ee.Msg = body.Code, body.Msg
+		}
+		return nil, ee
+	}
+	return data, nil
+}
+
+// ---------- 公开接口 ----------
+
+// GetOrderBook 获取 5 档订单簿（公开接口，无需签名）
+func (c *Client) GetOrderBook(ctx context.Context, symbol string) (*OrderBook, error) {
+	params := url.Values{}
+	params.Set("symbol", NormalizeSymbol(symbol))
+	params.Set("limit", "5")
+	data, err := c.doRequest(ctx, http.MethodGet, "/fapi/v1/depth", params, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var ob OrderBook
+	if err := json.Unmarshal(data, &ob); err != nil {
+		return nil, err
+	}
+	return &ob, nil
+}
+
+// GetInstrumentInfo 获取交易对的价格/数量步长与下单量限制（公开接口，无需签名）
+func (c *Client) GetInstrumentInfo(ctx context.Context, symbol string) (*InstrumentInfo, error) {
+	data, err := c.doRequest(ctx, http.MethodGet, "/fapi/v1/exchangeInfo", nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Symbols []struct {
+			Symbol  string `json:"symbol"`
+			Filters []struct {
+				FilterType string `json:"filterType"`
+				TickSize   string `json:"tickSize"`
+				StepSize   string `json:"stepSize"`
+				MinQty     string `json:"minQty"`
+				MaxQty     string `json:"maxQty"`
+				Notional   string `json:"notional"`
+			} `json:"filters"`
+		} `json:"symbols"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	symbol = NormalizeSymbol(symbol)
+	for _, s := range result.Symbols {
+		if s.Symbol != symbol {
+			continue
+		}
+		info := &InstrumentInfo{Symbol: s.Symbol}
+		// 步长解析失败时返回 error，避免按 0 步长取整价格与数量
+		type field struct {
+			name string
+			raw  string
+			dst  *float64
+		}
+		var fields []field
+		for _, f := range s.Filters {
+			switch f.FilterType {
+			case "PRICE_FILTER":
+				fields = append(fields, field{"tickSize", f.TickSize, &info.TickSize})
+			case "LOT_SIZE":
+				fields = append(fields, field{"stepSize", f.StepSize, &info.QtyStep},
+					field{"minQty", f.MinQty, &info.MinOrderQty}, field{"maxQty", f.MaxQty, &info.MaxOrderQty})
+			case "MIN_NOTIONAL":
+				fields = append(fields, field{"notional", f.Notional, &info.MinNotional})
+			}
+		}
+		for _, f := range fields {
+			v, err := strconv.ParseFloat(f.raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("解析 Binance %s 规格 %s=%q 失败: %w", symbol, f.name, f.raw, err)
+			}
+			*f.dst = v
+		}
+		return info, nil
+	}
+	return nil, fmt.Errorf("Binance 交易对 %s 不存在", symbol)
+}
+
+// GetBestPrice 获取最优买卖价
+func (c *Client) GetBestPrice(ctx context.Context, symbol string) (*BestPrice, error) {
+	ob, err := c.GetOrderBook(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if len(ob.Bids) == 0 || len(ob.Asks) == 0 {
+		return nil, fmt.Errorf("Binance 订单簿为空")
+	}
+
+	bp := &BestPrice{}
+	if bp.BidPrice, bp.BidSize, err = ParsePriceLevel(ob.Bids[0]); err != nil {
+		return nil, fmt.Errorf("买一%w", err)
+	}
+	if bp.AskPrice, bp.AskSize, err = ParsePriceLevel(ob.Asks[0]); err != nil {
+		return nil, fmt.Errorf("卖一%w", err)
+	}
+	return bp, nil
+}
+
+// ---------- 私有接口 ----------
+
+// GetAccount 获取合约账户余额
+func (c *Client) GetAccount(ctx context.Context) (*Account, error) {
+	data, err := c.request(ctx, http.MethodGet, "/fapi/v2/account", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var acc Account
+	if err := json.Unmarshal(data, &acc); err != nil {
+		return nil, err
+	}
+	return &acc, nil
+}
+
+// GetPositions 获取交易对持仓（含数量为 0 的记录）
+func (c *Client) GetPositions(ctx context.Context, symbol string) ([]Position, error) {
+	params := url.Values{}
+	params.Set("symbol", NormalizeSymbol(symbol))
+	data, err := c.request(ctx, http.MethodGet, "/fapi/v2/positionRisk", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var positions []Position
+	if err := json.Unmarshal(data, &positions); err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+// PlaceOrder 下单
+// 未指定 ClientOrderID 时自动生成，重试时交易所按 newClientOrderId 去重，不会重复下单
+func (c *Client) PlaceOrder(ctx context.Context, req *PlaceOrderReq) (*Order, error) {
+	if req.ClientOrderID == "" {
+		req.ClientOrderID = c.newClientOrderID()
+	}
+	req.Symbol = NormalizeSymbol(req.Symbol)
+	data, attempts, err := c.requestAttempts(ctx, http.MethodPost, "/fapi/v1/order", req.params())
+	if err != nil {
+		// 重试过程中订单可能已提交成功（如首次请求已到达交易所但响应丢失），按 ClientOrderID 确认
+		if attempts > 1 {
+			if o, qerr := c.GetOrderByClientID(ctx, req.Symbol, req.ClientOrderID); qerr == nil {
+				c.logger.Warn("[Binance REST] 下单重试失败，但订单已存在", "client_order_id", req.ClientOrderID, "order_id", o.OrderID)
+				return o, nil
+			}
+		}
+		return nil, err
+	}
+
+	var o Order
+	if err := json.Unmarshal(data, &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// GetOrder 查询单个订单（含成交量、成交均价，手续费需通过 GetOrderTrades 查询）
+func (c *Client) GetOrder(ctx context.Context, symbol string, orderID int64) (*Order, error) {
+	params := url.Values{}
+	params.Set("symbol", NormalizeSymbol(symbol))
+	params.Set("orderId", strconv.FormatInt(orderID, 10))
+	return c.getOrder(ctx, params)
+}
+
+// GetOrderByClientID 按自定义订单ID查询订单
+func (c *Client) GetOrderByClientID(ctx context.Context, symbol, clientOrderID string) (*Order, error) {
+	params := url.Values{}
+	params.Set("symbol", NormalizeSymbol(symbol))
+	params.Set("origClientOrderId", clientOrderID)
+	return c.getOrder(ctx, params)
+}
+
+func (c *Client) getOrder(ctx context.Context, params url.Values) (*Order, error) {
+	data, err := c.request(ctx, http.MethodGet, "/fapi/v1/order", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var o Order
+	if err := json.Unmarshal(data, &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// GetOrderTrades 查询订单的成交明细（含手续费）
+func (c *Client) GetOrderTrades(ctx context.Context, symbol string, orderID int64) ([]Trade, error) {
+	params := url.Values{}
+	params.Set("symbol", NormalizeSymbol(symbol))
+	params.Set("orderId", strconv.FormatInt(orderID, 10))
+	data, err := c.request(ctx, http.MethodGet, "/fapi/v1/userTrades", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var trades []Trade
+	if err := json.Unmarshal(data, &trades); err != nil {
+		return nil, err
+	}
+	return trades, nil
+}
+
+// SetLeverage 设置交易对杠杆倍数（Binance 只支持整数倍）
+func (c *Client) SetLeverage(ctx context.Context, symbol string, leverage int) error {
+	params := url.Values{}
+	params.Set("symbol", NormalizeSymbol(symbol))
+	params.Set("leverage", strconv.Itoa(leverage))
+	_, err := c.request(ctx, http.MethodPost, "/fapi/v1/leverage", params)
+	return err
+}
+
+// CancelOrder 撤销单个订单
+func (c *Client) CancelOrder(ctx context.Context, symbol string, orderID int64) error {
+	params := url.Values{}
+	params.Set("symbol", NormalizeSymbol(symbol))
+	params.Set("orderId", strconv.FormatInt(orderID, 10))
+	_, err := c.request(ctx, http.MethodDelete, "/fapi/v1/order", params)
+	return err
+}
+
+// CancelAllOrders 撤销某交易对所有订单
+func (c *Client) CancelAllOrders(ctx context.Context, symbol string) error {
+	params := url.Values{}
+	params.Set("symbol", NormalizeSymbol(symbol))
+	_, err := c.request(ctx, http.MethodDelete, "/fapi/v1/allOpenOrders", params)
+	return err
+}
+
+// GetOpenOrders 获取当前挂单
+func (c *Client) GetOpenOrders(ctx context.Context, symbol string) ([]Order, error) {
+	params := url.Values{}
+	params.Set("symbol", NormalizeSymbol(symbol))
+	data, err := c.request(ctx, http.MethodGet, "/fapi/v1/openOrders", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []Order
+	if err := json.Unmarshal(data, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
@@ -0,0 +1,88 @@
+package binance
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Binance 合约业务错误码（code）
+const (
+	CodeUnknown             = -1000 // 未知错误
+	CodeDisconnected        = -1001 // 服务端内部错误
+	CodeTooManyRequests     = -1003 // 请求权重超限
+	CodeTimeout             = -1007 // 等待后端响应超时，订单状态未知
+	CodeInvalidSignature    = -1022 // 签名错误
+	CodeInvalidAPIKeyFormat = -2014 // API Key 格式错误
+	CodeRejectedAPIKey      = -2015 // API Key 无效、IP 不在白名单或无权限
+	CodeBalanceInsufficient = -2018 // 余额不足
+	CodeMarginInsufficient  = -2019 // 保证金不足
+)
+
+// ExchangeError Binance 返回的错误（HTTP 非成功状态，响应体带 code/msg）
+type ExchangeError struct {
+	HTTPStatus int    // HTTP 状态码
+	Code       int    // 业务错误码，响应体无法解析时为 0
+	Msg        string // msg 或响应内容
+}
+
+func (e *ExchangeError) Error() string {
+	if e.Code == 0 {
+		return fmt.Sprintf("Binance HTTP %d: %s", e.HTTPStatus, e.Msg)
+	}
+	return fmt.Sprintf("Binance 错误 %d: %s", e.Code, e.Msg)
+}
+
+// Retryable 是否为可重试的瞬时错误：HTTP 5xx、429/418 与服务端/限频错误码
+func (e *ExchangeError) Retryable() bool {
+	switch e.Code {
+	case CodeUnknown, CodeDisconnected, CodeTooManyRequests, CodeTimeout:
+		return true
+	}
+	return e.HTTPStatus >= http.StatusInternalServerError ||
+		e.HTTPStatus == http.StatusTooManyRequests || e.HTTPStatus == http.StatusTeapot
+}
+
+// InsufficientBalance 是否为余额或保证金不足
+func (e *ExchangeError) InsufficientBalance() bool {
+	return e.Code == CodeBalanceInsufficient || e.Code == CodeMarginInsufficient
+}
+
+// PermissionDenied 是否为鉴权或权限错误（签名错误、API Key 无效、IP 限制）
+func (e *ExchangeError) PermissionDenied() bool {
+	switch e.Code {
+	case CodeInvalidSignature, CodeInvalidAPIKeyFormat, CodeRejectedAPIKey:
+		return true
+	}
+	return e.HTTPStatus == http.StatusUnauthorized || e.HTTPStatus == http.StatusForbidden
+}
+
+// transientError 可重试的网络层错误（连接失败、读取响应失败）
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// IsRetryable 判断错误是否可重试：网络错误或 ExchangeError.Retryable
+func IsRetryable(err error) bool {
+	var te *transientError
+	if errors.As(err, &te) {
+		return true
+	}
+	var ee *ExchangeError
+	return errors.As(err, &ee) && ee.Retryable()
+}
+
+// IsInsufficientBalance 判断错误是否为 Binance 余额或保证金不足
+func IsInsufficientBalance(err error) bool {
+	var ee *ExchangeError
+	return errors.As(err, &ee) && ee.InsufficientBalance()
+}
+
+// IsPermissionDenied 判断错误是否为 Binance 鉴权或权限错误
+func IsPermissionDenied(err error) bool {
+	var ee *ExchangeError
+	return errors.As(err, &ee) && ee.PermissionDenied()
+}
@@ -0,0 +1,390 @@
+package binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WsOrderBook Binance 合约部分深度推送（<symbol>@depth5@100ms）
+type WsOrderBook struct {
+	Symbol string     `json:"s"`
+	Bids   [][]string `json:"b"`
+	Asks   [][]string `json:"a"`
+	Ts     int64      `json:"T"` // 撮合时间（毫秒）
+}
+
+// ParsePriceLevel 解析订单簿单档 [价格, 数量]，格式错误时返回 error（不会静默返回 0）
+func ParsePriceLevel(level []string) (price, size float64, err error) {
+	if len(level) < 2 {
+		return 0, 0, fmt.Errorf("订单簿档位格式错误: %v", level)
+	}
+	if price, err = strconv.ParseFloat(level[0], 64); err != nil {
+		return 0, 0, fmt.Errorf("解析价格 %q 失败: %w", level[0], err)
+	}
+	if size, err = strconv.ParseFloat(level[1], 64); err != nil {
+		return 0, 0, fmt.Errorf("解析数量 %q 失败: %w", level[1], err)
+	}
+	return price, size, nil
+}
+
+// subscription 保存一个订阅的元数据，用于断线后恢复
+type subscription struct {
+	stream string
+	cb     func(data []byte)
+}
+
+// WsClient Binance 合约 WebSocket 客户端（组合流 /stream，支持断线重连）
+type WsClient struct {
+	wsURL  string
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	writer *wsWriter // 当前连接的写协程，所有出站帧经其发送
+
+	// 订阅注册表（断线后自动恢复）
+	subsMu sync.RWMutex
+	subs   []subscription
+
+	// 连接状态
+	connected      atomic.Bool
+	reconnectCount atomic.Int64
+	failedDials    atomic.Int64 // 连续重连失败次数，重连成功后清零
+	maxDials       atomic.Int64 // 连续重连失败上限，0 表示不限制
+	lastPongAt     atomic.Value // time.Time
+	lastMsgAt      atomic.Value // time.Time
+	pingSeq        atomic.Int64
+	reqSeq         atomic.Int64
+	rtt            atomic.Int64 // nanoseconds
+	pingSentAt     sync.Map     // seq(string) → time.Time
+
+	// 内部控制
+	done     chan struct{}
+	reconnCh chan struct{}
+}
+
+const (
+	wsInitialBackoff = 1 * time.Second
+	wsMaxBackoff     = 30 * time.Second
+	wsPingInterval   = 20 * time.Second
+	wsPongTimeout    = 10 * time.Second
+	wsDialTimeout    = 10 * time.Second
+)
+
+// NewWsClient 创建 WebSocket 客户端，wsURL 为组合流地址（如 wss://fstream.binance.com/stream）
+func NewWsClient(wsURL string) *WsClient {
+	w := &WsClient{
+		wsURL:    wsURL,
+		logger:   slog.Default(),
+		done:     make(chan struct{}),
+		reconnCh: make(chan struct{}, 1),
+	}
+	w.lastPongAt.Store(time.Time{})
+	w.lastMsgAt.Store(time.Time{})
+	return w
+}
+
+// SetLogger 设置日志输出（默认 slog.Default()），nil 时忽略；需在 Connect 前调用
+func (w *WsClient) SetLogger(l *slog.Logger) {
+	if l != nil {
+		w.logger = l
+	}
+}
+
+// Connect 建立初始连接并启动后台 goroutine
+// 初次连接失败时同样交给重连循环按退避重试（连接建立后自动恢复订阅），并返回初次连接的错误
+func (w *WsClient) Connect() error {
+	err := w.dial()
+	go w.reconnectLoop()
+	if err != nil {
+		select {
+		case w.reconnCh <- struct{}{}:
+		default:
+		}
+	}
+	return err
+}
+
+// SubscribeOrderBook 订阅 5 档部分深度（100ms 推送，断线重连后自动恢复）
+func (w *WsClient) SubscribeOrderBook(symbol string, cb func(ob *WsOrderBook)) error {
+	// 流名称要求小写交易对：btcusdt@depth5@100ms
+	stream := fmt.Sprintf("%s@depth5@100ms", strings.ToLower(NormalizeSymbol(symbol)))
+
+	w.subsMu.Lock()
+	w.subs = append(w.subs, subscription{
+		stream: stream,
+		cb: func(data []byte) {
+			var ob WsOrderBook
+			if err := json.Unmarshal(data, &ob); err != nil {
+				w.logger.Warn("[Binance WS] 解析订单簿数据失败", "err", err)
+				return
+			}
+			cb(&ob)
+		},
+	})
+	w.subsMu.Unlock()
+
+	return w.sendSubscribe(stream)
+}
+
+// WsStats WebSocket 连接健康状况
+type WsStats struct {
+	Connected      bool          // 当前是否已连接
+	ReconnectCount int64         // 累计重连次数
+	Failed         bool          // 连续重连失败次数已达到上限（见 SetMaxReconnectAttempts）
+	RTT            time.Duration // 最近一次 ping/pong 往返时延
+	LastMessageAt  time.Time     // 最近一次收到消息的时间
+	LastMessageAge time.Duration // 距最近一次消息的时长（从未收到时为 0）
+}
+
+// SetMaxReconnectAttempts 设置连续重连失败上限，达到后 Failed 返回 true（重连循环仍继续尝试，成功后清除）；n <= 0 不限制
+func (w *WsClient) SetMaxReconnectAttempts(n int) {
+	w.maxDials.Store(int64(n))
+}
+
+// Failed 连续重连失败次数是否已达到上限：交易所接口可能已不可用，调用方不应继续依赖该行情
+func (w *WsClient) Failed() bool {
+	limit := w.maxDials.Load()
+	return limit > 0 && w.failedDials.Load() >= limit
+}
+
+// Stats 返回连接健康状况与延迟
+func (w *WsClient) Stats() WsStats {
+	st := WsStats{
+		Connected:      w.connected.Load(),
+		ReconnectCount: w.reconnectCount.Load(),
+		RTT:            time.Duration(w.rtt.Load()),
+		Failed:         w.Failed(),
+	}
+	if t, ok := w.lastMsgAt.Load().(time.Time); ok && !t.IsZero() {
+		st.LastMessageAt = t
+		st.LastMessageAge = time.Since(t)
+	}
+	return st
+}
+
+// IsReady 返回当前是否已连接且可用
+func (w *WsClient) IsReady() bool {
+	return w.connected.Load()
+}
+
+// Close 关闭客户端
+func (w *WsClient) Close() {
+	select {
+	case <-w.done:
+	default:
+		close(w.done)
+	}
+	if writer := w.currentWriter(); writer != nil {
+		writer.close()
+	}
+}
+
+// currentWriter 返回当前连接的写协程，尚未连接时返回 nil
+func (w *WsClient) currentWriter() *wsWriter {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writer
+}
+
+// ---- 内部方法 ----
+
+func (w *WsClient) dial() error {
+	dialer := websocket.Dialer{HandshakeTimeout: wsDialTimeout}
+	conn, _, err := dialer.Dial(w.wsURL, nil)
+	if err != nil {
+		return fmt.Errorf("[Binance WS] 连接失败: %w", err)
+	}
+
+	// 服务端每 3 分钟发送 ping，gorilla 默认自动回复 pong；客户端 ping 用于测量往返时延
+	conn.SetPongHandler(func(appData string) error {
+		now := time.Now()
+		w.lastPongAt.Store(now)
+		if sentVal, ok := w.pingSentAt.LoadAndDelete(appData); ok {
+			if sentTime, ok2 := sentVal.(time.Time); ok2 {
+				w.rtt.Store(int64(now.Sub(sentTime)))
+			}
+		}
+		return nil
+	})
+
+	writer := newWsWriter(conn)
+	w.mu.Lock()
+	w.writer = writer
+	w.mu.Unlock()
+
+	w.connected.Store(true)
+	w.logger.Info("[Binance WS] 连接成功", "url", w.wsURL)
+
+	go w.readLoop(conn, writer)
+	go w.pingLoop(writer)
+	return nil
+}
+
+func (w *WsClient) reconnectLoop() {
+	backoff := wsInitialBackoff
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.reconnCh:
+			w.connected.Store(false)
+			count := w.reconnectCount.Add(1)
+			w.logger.Warn("[Binance WS] 检测到断线，等待后重连", "attempt", count, "backoff", backoff)
+
+			select {
+			case <-w.done:
+				return
+			case <-time.After(backoff):
+			}
+
+			if err := w.dial(); err != nil {
+				w.logger.Warn("[Binance WS] 重连失败", "err", err)
+				if n := w.failedDials.Add(1); n == w.maxDials.Load() {
+					w.logger.Error("[Binance WS] 连续重连失败次数达到上限，标记为不可用", "attempts", n)
+				}
+				backoff *= 2
+				if backoff > wsMaxBackoff {
+					backoff = wsMaxBackoff
+				}
+				select {
+				case w.reconnCh <- struct{}{}:
+				default:
+				}
+				continue
+			}
+
+			backoff = wsInitialBackoff
+			w.failedDials.Store(0)
+			w.resubscribeAll()
+		}
+	}
+}
+
+func (w *WsClient) readLoop(conn *websocket.Conn, writer *wsWriter) {
+	defer func() {
+		writer.close()
+		select {
+		case <-w.done:
+			return
+		default:
+			select {
+			case w.reconnCh <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		default:
+		}
+
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-w.done:
+			default:
+				w.logger.Warn("[Binance WS] 读取错误（将触发重连）", "err", err)
+			}
+			return
+		}
+
+		w.lastMsgAt.Store(time.Now())
+
+		// 组合流消息格式：{"stream":"btcusdt@depth5@100ms","data":{...}}
+		// 订阅回复格式：{"result":null,"id":1}，失败时带 error
+		var envelope struct {
+			Stream string          `json:"stream"`
+			Data   json.RawMessage `json:"data"`
+			ID     int64           `json:"id"`
+			Error  *struct {
+				Code int    `json:"code"`
+				Msg  string `json:"msg"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(msg, &envelope); err != nil {
+			continue
+		}
+		if envelope.Error != nil {
+			w.logger.Warn("[Binance WS] 请求失败", "id", envelope.ID, "code", envelope.Error.Code, "msg", envelope.Error.Msg)
+			continue
+		}
+		if envelope.Stream == "" {
+			continue
+		}
+
+		w.subsMu.RLock()
+		for _, s := range w.subs {
+			if s.stream == envelope.Stream {
+				s.cb(envelope.Data)
+				break
+			}
+		}
+		w.subsMu.RUnlock()
+	}
+}
+
+func (w *WsClient) pingLoop(writer *wsWriter) {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			if lastPong, ok := w.lastPongAt.Load().(time.Time); ok && !lastPong.IsZero() {
+				if time.Since(lastPong) > wsPingInterval+wsPongTimeout {
+					w.logger.Warn("[Binance WS] Pong 超时，主动断线触发重连")
+					writer.close()
+					return
+				}
+			}
+
+			seq := fmt.Sprintf("%d", w.pingSeq.Add(1))
+			w.pingSentAt.Store(seq, time.Now())
+
+			err := writer.write(websocket.PingMessage, []byte(seq))
+
+			if err != nil {
+				w.logger.Warn("[Binance WS] Ping 发送失败", "err", err)
+				return
+			}
+		}
+	}
+}
+
+func (w *WsClient) resubscribeAll() {
+	w.subsMu.RLock()
+	defer w.subsMu.RUnlock()
+	for _, s := range w.subs {
+		if err := w.sendSubscribe(s.stream); err != nil {
+			w.logger.Warn("[Binance WS] 恢复订阅失败", "stream", s.stream, "err", err)
+		} else {
+			w.logger.Info("[Binance WS] 已恢复订阅", "stream", s.stream)
+		}
+	}
+}
+
+func (w *WsClient) sendSubscribe(stream string) error {
+	msg := map[string]interface{}{
+		"method": "SUBSCRIBE",
+		"params": []string{stream},
+		"id":     w.reqSeq.Add(1),
+	}
+	writer := w.currentWriter()
+	if writer == nil {
+		return fmt.Errorf("连接尚未建立")
+	}
+	return writer.writeJSON(msg)
+}
@@ -0,0 +1,62 @@
+package binance
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParsePriceLevel(t *testing.T) {
+	cases := []struct {
+		name        string
+		level       []string
+		price, size float64
+		wantErr     bool
+	}{
+		{"正常", []string{"100010.5", "0.25"}, 100010.5, 0.25, false},
+		{"科学计数法", []string{"1.0001e5", "2.5E-2"}, 100010, 0.025, false},
+		{"整数", []string{"100000", "1"}, 100000, 1, false},
+		{"价格为空", []string{"", "0.25"}, 0, 0, true},
+		{"数量为空", []string{"100010.5", ""}, 0, 0, true},
+		{"价格乱码", []string{"abc", "0.25"}, 0, 0, true},
+		{"数量乱码", []string{"100010.5", "0.2x"}, 0, 0, true},
+		{"前缀数字", []string{"100010.5USDT", "0.25"}, 0, 0, true},
+		{"缺少数量", []string{"100010.5"}, 0, 0, true},
+		{"空档位", nil, 0, 0, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			price, size, err := ParsePriceLevel(tc.level)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("ParsePriceLevel(%q) 应返回错误，得到 %v / %v", tc.level, price, size)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePriceLevel(%q) 返回错误: %v", tc.level, err)
+			}
+			if price != tc.price || size != tc.size {
+				t.Fatalf("ParsePriceLevel(%q) = %v / %v，期望 %v / %v", tc.level, price, size, tc.price, tc.size)
+			}
+		})
+	}
+}
+
+var benchLevel = []string{"100010.5", "0.25"}
+
+func BenchmarkParsePriceLevel(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, _, err := ParsePriceLevel(benchLevel); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSscanfPriceLevel 旧实现（fmt.Sscanf）作为对照
+func BenchmarkSscanfPriceLevel(b *testing.B) {
+	var price, size float64
+	for i := 0; i < b.N; i++ {
+		fmt.Sscanf(benchLevel[0], "%f", &price)
+		fmt.Sscanf(benchLevel[1], "%f", &size)
+	}
+}
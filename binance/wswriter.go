@@ -0,0 +1,86 @@
+package binance
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsWriteTimeout 单帧写入超时，超时视为连接异常
+const wsWriteTimeout = 10 * time.Second
+
+// errWsClosed 连接已关闭，帧未发送
+var errWsClosed = errors.New("连接已关闭")
+
+// wsFrame 待发送的一帧消息，写入结果经 result 返回
+type wsFrame struct {
+	msgType int
+	data    []byte
+	result  chan error
+}
+
+// wsWriter 每个连接唯一的写协程：订阅、鉴权、心跳等所有出站帧经通道排队后串行写入，
+// gorilla/websocket 不支持并发写，重连边界上的心跳与恢复订阅也不会交错。
+// 控制帧回复（收到 ping 时的 pong）由 gorilla 通过 WriteControl 发送，可与写协程并发
+type wsWriter struct {
+	conn   *websocket.Conn
+	frames chan wsFrame
+	closed chan struct{}
+	once   sync.Once
+}
+
+// newWsWriter 为连接启动写协程，连接关闭（close）后协程退出
+func newWsWriter(conn *websocket.Conn) *wsWriter {
+	ww := &wsWriter{conn: conn, frames: make(chan wsFrame), closed: make(chan struct{})}
+	go ww.run()
+	return ww
+}
+
+func (ww *wsWriter) run() {
+	for {
+		select {
+		case <-ww.closed:
+			return
+		case f := <-ww.frames:
+			_ = ww.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			err := ww.conn.WriteMessage(f.msgType, f.data)
+			f.result <- err
+			if err != nil {
+				// 写失败后连接不可再用，关闭后由读循环触发重连
+				ww.close()
+				return
+			}
+		}
+	}
+}
+
+// write 排队发送一帧并等待写入结果
+func (ww *wsWriter) write(msgType int, data []byte) error {
+	f := wsFrame{msgType: msgType, data: data, result: make(chan error, 1)}
+	select {
+	case ww.frames <- f:
+	case <-ww.closed:
+		return errWsClosed
+	}
+	return <-f.result
+}
+
+// writeJSON 排队发送一条 JSON 文本消息
+func (ww *wsWriter) writeJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ww.write(websocket.TextMessage, data)
+}
+
+// close 停止写协程并关闭连接，可重复调用
+func (ww *wsWriter) close() {
+	ww.once.Do(func() {
+		close(ww.closed)
+		_ = ww.conn.Close()
+	})
+}
@@ -2,14 +2,19 @@ package bybit
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"math/rand"
 	"net/http"
 	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -19,6 +24,17 @@ type Client struct {
 	apiKey     string
 	apiSecret  string
 	httpClient *http.Client
+	retry      RetryPolicy
+	limiter    *rateLimiter // 本地限频，nil 表示不限制
+	idPrefix   string       // 自定义订单ID前缀
+	logger     *slog.Logger
+
+	// 单次请求超时：下单/撤单使用 orderTimeout，其余查询使用 queryTimeout，0 表示只受 httpClient 超时限制
+	orderTimeout time.Duration
+	queryTimeout time.Duration
+
+	// 服务器时间与本地时钟的偏差（毫秒，服务器 - 本地），遇到 10002 时间戳错误后校准，之后所有签名请求使用
+	timeOffset atomic.Int64
 }
 
 // NewClient 创建 Bybit REST 客户端
@@ -28,7 +44,34 @@ func NewClient(baseURL, apiKey, apiSecret string) *Client {
 		apiKey:     apiKey,
 		apiSecret:  apiSecret,
 		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     slog.Default(),
+	}
+}
+
+// SetLogger 设置日志输出（默认 slog.Default()），nil 时忽略
+func (c *Client) SetLogger(l *slog.Logger) {
+	if l != nil {
+		c.logger = l
+	}
+}
+
+// SetTimeouts 设置单次请求超时：order 用于下单/撤单，query 用于其余查询，0 表示不单独限制
+func (c *Client) SetTimeouts(order, query time.Duration) {
+	c.orderTimeout = order
+	c.queryTimeout = query
+}
+
+// callTimeout 返回单次请求的超时：订单分组的写请求（下单、撤单）使用 orderTimeout
+func (c *Client) callTimeout(method, path string) time.Duration {
+	if method != http.MethodGet && endpointGroup(path) == GroupOrder {
+		return c.orderTimeout
 	}
+	return c.queryTimeout
+}
+
+// SetRetryPolicy 设置瞬时错误的重试策略（默认不重试）
+func (c *Client) SetRetryPolicy(p RetryPolicy) {
+	c.retry = p
 }
 
 // ---------- 公共数据结构 ----------
@@ -47,6 +90,23 @@ type BestPrice struct {
 	AskSize  float64
 }
 
+// FundingRate 资金费率
+type FundingRate struct {
+	Symbol          string
+	Rate            float64   // 当期预测资金费率，正数表示多头向空头支付
+	NextFundingTime time.Time // 下一次结算时间
+}
+
+// InstrumentInfo 交易对规格
+type InstrumentInfo struct {
+	Symbol      string
+	TickSize    float64 // 价格最小变动单位
+	QtyStep     float64 // 数量最小变动单位
+	MinOrderQty float64 // 最小下单量
+	MaxOrderQty float64 // 最大下单量
+	MinNotional float64 // 最小下单名义价值（0=交易所未提供）
+}
+
 // Position 持仓信息
 type Position struct {
 	Symbol        string  `json:"symbol"`
@@ -54,6 +114,7 @@ type Position struct {
 	Size          string  `json:"size"`
 	EntryPrice    string  `json:"avgPrice"`
 	UnrealizedPnl string  `json:"unrealisedPnl"`
+	PositionIdx   int     `json:"positionIdx"` // 0=单向持仓，1/2=双向持仓的多/空仓位
 	SizeFloat     float64 // 解析后的数量
 }
 
@@ -72,6 +133,8 @@ type Order struct {
 	Price       string `json:"price"`
 	Qty         string `json:"qty"`
 	CumExecQty  string `json:"cumExecQty"`
+	AvgPrice    string `json:"avgPrice"`    // 成交均价
+	CumExecFee  string `json:"cumExecFee"`  // 累计手续费
 	OrderStatus string `json:"orderStatus"` // New / Filled / Cancelled
 	CreatedTime string `json:"createdTime"`
 }
@@ -87,6 +150,30 @@ type PlaceOrderReq struct {
 	TimeInForce string `json:"timeInForce,omitempty"` // GTC / IOC / FOK / PostOnly
 	ReduceOnly  bool   `json:"reduceOnly"`
 	OrderLinkID string `json:"orderLinkId,omitempty"` // 自定义订单ID
+	PositionIdx int    `json:"positionIdx"`           // 0 单向持仓；双向持仓时 1 多头、2 空头
+}
+
+// 下单 positionIdx 取值
+const (
+	PositionIdxOneWay = 0 // 单向持仓
+	PositionIdxLong   = 1 // 双向持仓的多头仓位
+	PositionIdxShort  = 2 // 双向持仓的空头仓位
+)
+
+// 持仓模式（switch-mode 接口的 mode 参数）
+const (
+	PositionModeOneWay = 0 // 单向持仓（merged single）
+	PositionModeHedge  = 3 // 双向持仓（both sides）
+)
+
+// AmendOrderReq 改单请求，OrderID 与 OrderLinkID 二选一，Qty / Price 为空表示不修改
+type AmendOrderReq struct {
+	Category    string `json:"category"` // linear（USDT永续）
+	Symbol      string `json:"symbol"`
+	OrderID     string `json:"orderId,omitempty"`
+	OrderLinkID string `json:"orderLinkId,omitempty"`
+	Qty         string `json:"qty,omitempty"`
+	Price       string `json:"price,omitempty"`
 }
 
 // ---------- 签名工具 ----------
@@ -100,8 +187,88 @@ func (c *Client) sign(timestamp, recvWindow, payload string) string {
 	return hex.EncodeToString(mac.Sum(nil))
 }
 
-// request 发送带签名的 HTTP 请求（Bybit V5 API）
-func (c *Client) request(method, path string, payload interface{}) ([]byte, error) {
+// RetryPolicy 瞬时错误（网络错误、HTTP 5xx、429、Bybit 限频错误码）的重试策略
+type RetryPolicy struct {
+	MaxAttempts int           // 最大尝试次数（含首次），<=1 表示不重试
+	BaseDelay   time.Duration // 首次重试前的等待，之后每次翻倍
+	MaxDelay    time.Duration // 单次等待上限，0 表示不限制
+	Jitter      float64       // 随机抖动比例（0~1），避免多个请求同时重试
+}
+
+// backoff 返回第 n 次失败后的等待时长（指数退避 + 随机抖动）
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.BaseDelay << (n - 1)
+	if p.MaxDelay > 0 && (d > p.MaxDelay || d <= 0) {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d += time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// defaultClientIDPrefix 未设置前缀时自定义订单ID的前缀
+const defaultClientIDPrefix = "arb"
+
+// SetClientIDPrefix 设置自动生成的自定义订单ID前缀，多实例共用账户时用于区分
+func (c *Client) SetClientIDPrefix(prefix string) {
+	c.idPrefix = prefix
+}
+
+// newClientOrderID 生成自定义订单ID，下单重试时用于去重
+func (c *Client) newClientOrderID() string {
+	prefix := c.idPrefix
+	if prefix == "" {
+		prefix = defaultClientIDPrefix
+	}
+	return fmt.Sprintf("%s-%d-%04d", prefix, time.Now().UnixNano(), rand.Intn(10000))
+}
+
+// request 发送带签名的 HTTP 请求，瞬时错误按重试策略重试
+func (c *Client) request(ctx context.Context, method, path string, payload interface{}) ([]byte, error) {
+	data, _, err := c.requestAttempts(ctx, method, path, payload)
+	return data, err
+}
+
+// requestAttempts 同 request，额外返回实际尝试次数
+// 只重试网络错误、HTTP 5xx 与 429，业务拒单（4xx 等）立即返回
+func (c *Client) requestAttempts(ctx context.Context, method, path string, payload interface{}) ([]byte, int, error) {
+	synced := false
+	for n := 1; ; n++ {
+		data, err := c.doRequest(ctx, method, path, payload)
+
+		// 时间戳超出 recv_window：校准服务器时间后立即重试一次（不计入重试次数）
+		var ee *ExchangeError
+		if !synced && errors.As(err, &ee) && ee.TimestampExpired() {
+			synced = true
+			if syncErr := c.SyncServerTime(ctx); syncErr != nil {
+				c.logger.Warn("[Bybit REST] 校准服务器时间失败", "err", syncErr)
+				return nil, n, err
+			}
+			n--
+			continue
+		}
+
+		if err == nil || !IsRetryable(err) || n >= c.retry.MaxAttempts {
+			return data, n, err
+		}
+
+		delay := c.retry.backoff(n)
+		c.logger.Warn("[Bybit REST] 请求失败，稍后重试", "method", method, "path", path, "attempt", n, "err", err, "delay", delay.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return nil, n, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// doRequest 发送一次带签名的 HTTP 请求（Bybit V5 API）
+func (c *Client) doRequest(ctx context.Context, method, path string, payload interface{}) ([]byte, error) {
+	if err := c.limiter.wait(ctx, endpointGroup(path)); err != nil {
+		return nil, err
+	}
+
 	var bodyStr string
 	var bodyReader io.Reader
 
@@ -114,11 +281,19 @@ func (c *Client) request(method, path string, payload interface{}) ([]byte, erro
 		bodyReader = bytes.NewBufferString(bodyStr)
 	}
 
-	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	timestamp := strconv.FormatInt(time.Now().UnixMilli()+c.timeOffset.Load(), 10)
 	recvWindow := "5000"
 	sig := c.sign(timestamp, recvWindow, bodyStr)
 
-	req, err := http.NewRequest(method, c.baseURL+path, bodyReader)
+	// 单次请求超时只作用于本次 HTTP 请求：超时视为瞬时错误可重试，ctx 取消则立即返回
+	reqCtx := ctx
+	if d := c.callTimeout(method, path); d > 0 {
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(reqCtx, method, c.baseURL+path, bodyReader)
 	if err != nil {
 		return nil, err
 	}
@@ -131,17 +306,21 @@ func (c *Client) request(method, path string, payload interface{}) ([]byte, erro
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, &transientError{err}
 	}
 	defer resp.Body.Close()
+	c.limiter.observe(endpointGroup(path), resp.Header)
 
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, &transientError{err}
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(data))
+		return nil, &ExchangeError{HTTPStatus: resp.StatusCode, Msg: string(data)}
 	}
 
 	// 检查 Bybit 业务错误码
@@ -149,10 +328,8 @@ func (c *Client) request(method, path string, payload interface{}) ([]byte, erro
 		RetCode int    `json:"retCode"`
 		RetMsg  string `json:"retMsg"`
 	}
-	if err := json.Unmarshal(data, &baseResp); err == nil {
-		if baseResp.RetCode != 0 {
-			return nil, fmt.Errorf("Bybit 错误 %d: %s", baseResp.RetCode, baseResp.RetMsg)
-		}
+	if err := json.Unmarshal(data, &baseResp); err == nil && baseResp.RetCode != 0 {
+		return nil, &ExchangeError{HTTPStatus: resp.StatusCode, Code: baseResp.RetCode, Msg: baseResp.RetMsg}
 	}
 
 	return data, nil
@@ -160,14 +337,66 @@ func (c *Client) request(method, path string, payload interface{}) ([]byte, erro
 
 // ---------- 公开接口 ----------
 
+// SyncServerTime 查询服务器时间（/v5/market/time）并缓存与本地时钟的偏差，之后的签名请求按偏差修正时间戳
+// 偏差按请求往返的中点估算
+func (c *Client) SyncServerTime(ctx context.Context) error {
+	if err := c.limiter.wait(ctx, GroupMarket); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/v5/market/time", nil)
+	if err != nil {
+		return err
+	}
+	sent := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	received := time.Now()
+	c.limiter.observe(GroupMarket, resp.Header)
+
+	var result struct {
+		RetCode int    `json:"retCode"`
+		RetMsg  string `json:"retMsg"`
+		Result  struct {
+			TimeNano string `json:"timeNano"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if result.RetCode != 0 {
+		return &ExchangeError{HTTPStatus: resp.StatusCode, Code: result.RetCode, Msg: result.RetMsg}
+	}
+	nano, err := strconv.ParseInt(result.Result.TimeNano, 10, 64)
+	if err != nil {
+		return fmt.Errorf("解析服务器时间失败: %w", err)
+	}
+
+	local := sent.Add(received.Sub(sent) / 2)
+	offset := time.Unix(0, nano).Sub(local).Milliseconds()
+	prev := c.timeOffset.Swap(offset)
+	c.logger.Warn("[Bybit REST] 已按服务器时间校准时间戳", "offset_ms", offset, "previous_ms", prev, "rtt", received.Sub(sent).Round(time.Millisecond))
+	return nil
+}
+
 // GetOrderBook 获取订单簿（公开接口，无需签名）
-func (c *Client) GetOrderBook(symbol string) (*OrderBook, error) {
+func (c *Client) GetOrderBook(ctx context.Context, symbol string) (*OrderBook, error) {
 	url := fmt.Sprintf("%s/v5/market/orderbook?category=linear&symbol=%s&limit=5", c.baseURL, symbol)
-	resp, err := c.httpClient.Get(url)
+	if err := c.limiter.wait(ctx, GroupMarket); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	c.limiter.observe(GroupMarket, resp.Header)
 
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -194,9 +423,70 @@ func (c *Client) GetOrderBook(symbol string) (*OrderBook, error) {
 	}, nil
 }
 
+// GetInstrumentInfo 获取交易对的价格/数量步长与下单量限制（公开接口，无需签名）
+func (c *Client) GetInstrumentInfo(ctx context.Context, symbol string) (*InstrumentInfo, error) {
+	url := fmt.Sprintf("%s/v5/market/instruments-info?category=linear&symbol=%s", c.baseURL, symbol)
+	if err := c.limiter.wait(ctx, GroupMarket); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	c.limiter.observe(GroupMarket, resp.Header)
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		RetCode int    `json:"retCode"`
+		RetMsg  string `json:"retMsg"`
+		Result  struct {
+			List []struct {
+				Symbol      string `json:"symbol"`
+				PriceFilter struct {
+					TickSize float64 `json:"tickSize,string"`
+				} `json:"priceFilter"`
+				LotSizeFilter struct {
+					QtyStep     float64 `json:"qtyStep,string"`
+					MinOrderQty float64 `json:"minOrderQty,string"`
+					MaxOrderQty float64 `json:"maxOrderQty,string"`
+					MinNotional float64 `json:"minNotionalValue,string"`
+				} `json:"lotSizeFilter"`
+			} `json:"list"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	if result.RetCode != 0 {
+		return nil, fmt.Errorf("Bybit 获取交易对信息失败，retCode=%d retMsg=%s", result.RetCode, result.RetMsg)
+	}
+	if len(result.Result.List) == 0 {
+		return nil, fmt.Errorf("Bybit 交易对 %s 不存在", symbol)
+	}
+
+	s := result.Result.List[0]
+	return &InstrumentInfo{
+		Symbol:      s.Symbol,
+		TickSize:    s.PriceFilter.TickSize,
+		QtyStep:     s.LotSizeFilter.QtyStep,
+		MinOrderQty: s.LotSizeFilter.MinOrderQty,
+		MaxOrderQty: s.LotSizeFilter.MaxOrderQty,
+		MinNotional: s.LotSizeFilter.MinNotional,
+	}, nil
+}
+
 // GetBestPrice 获取最优买卖价
-func (c *Client) GetBestPrice(symbol string) (*BestPrice, error) {
-	ob, err := c.GetOrderBook(symbol)
+func (c *Client) GetBestPrice(ctx context.Context, symbol string) (*BestPrice, error) {
+	ob, err := c.GetOrderBook(ctx, symbol)
 	if err != nil {
 		return nil, err
 	}
@@ -205,19 +495,121 @@ func (c *Client) GetBestPrice(symbol string) (*BestPrice, error) {
 	}
 
 	bp := &BestPrice{}
-	fmt.Sscanf(ob.Bids[0][0], "%f", &bp.BidPrice)
-	fmt.Sscanf(ob.Bids[0][1], "%f", &bp.BidSize)
-	fmt.Sscanf(ob.Asks[0][0], "%f", &bp.AskPrice)
-	fmt.Sscanf(ob.Asks[0][1], "%f", &bp.AskSize)
+	if bp.BidPrice, bp.BidSize, err = ParsePriceLevel(ob.Bids[0]); err != nil {
+		return nil, fmt.Errorf("买一%w", err)
+	}
+	if bp.AskPrice, bp.AskSize, err = ParsePriceLevel(ob.Asks[0]); err != nil {
+		return nil, fmt.Errorf("卖一%w", err)
+	}
 	return bp, nil
 }
 
+// GetFundingRate 获取当期资金费率与下一次结算时间（公开接口，无需签名）
+func (c *Client) GetFundingRate(ctx context.Context, symbol string) (*FundingRate, error) {
+	url := fmt.Sprintf("%s/v5/market/tickers?category=linear&symbol=%s", c.baseURL, symbol)
+	if err := c.limiter.wait(ctx, GroupMarket); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	c.limiter.observe(GroupMarket, resp.Header)
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		RetCode int `json:"retCode"`
+		Result  struct {
+			List []struct {
+				Symbol          string `json:"symbol"`
+				FundingRate     string `json:"fundingRate"`
+				NextFundingTime string `json:"nextFundingTime"` // 毫秒时间戳
+			} `json:"list"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	if result.RetCode != 0 {
+		return nil, fmt.Errorf("Bybit 获取资金费率失败，retCode=%d", result.RetCode)
+	}
+	if len(result.Result.List) == 0 {
+		return nil, fmt.Errorf("Bybit 交易对 %s 不存在", symbol)
+	}
+
+	t := result.Result.List[0]
+	rate, err := strconv.ParseFloat(t.FundingRate, 64)
+	if err != nil {
+		return nil, fmt.Errorf("解析资金费率 %q 失败: %w", t.FundingRate, err)
+	}
+	next, err := strconv.ParseInt(t.NextFundingTime, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("解析资金费结算时间 %q 失败: %w", t.NextFundingTime, err)
+	}
+	return &FundingRate{Symbol: t.Symbol, Rate: rate, NextFundingTime: time.UnixMilli(next)}, nil
+}
+
+// GetSpotPrice 获取现货交易对最新成交价（公开接口，无需签名），如 USDCUSDT
+func (c *Client) GetSpotPrice(ctx context.Context, symbol string) (float64, error) {
+	url := fmt.Sprintf("%s/v5/market/tickers?category=spot&symbol=%s", c.baseURL, symbol)
+	if err := c.limiter.wait(ctx, GroupMarket); err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	c.limiter.observe(GroupMarket, resp.Header)
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		RetCode int `json:"retCode"`
+		Result  struct {
+			List []struct {
+				LastPrice string `json:"lastPrice"`
+			} `json:"list"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return 0, err
+	}
+	if result.RetCode != 0 {
+		return 0, fmt.Errorf("Bybit 获取现货行情失败，retCode=%d", result.RetCode)
+	}
+	if len(result.Result.List) == 0 {
+		return 0, fmt.Errorf("Bybit 现货交易对 %s 不存在", symbol)
+	}
+	price, err := strconv.ParseFloat(result.Result.List[0].LastPrice, 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析现货价格 %q 失败: %w", result.Result.List[0].LastPrice, err)
+	}
+	return price, nil
+}
+
 // ---------- 私有接口 ----------
 
 // GetAccount 获取统一账户余额
-func (c *Client) GetAccount() (*Account, error) {
+func (c *Client) GetAccount(ctx context.Context) (*Account, error) {
 	path := "/v5/account/wallet-balance?accountType=UNIFIED"
-	data, err := c.request("GET", path, nil)
+	data, err := c.request(ctx, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -238,15 +630,19 @@ func (c *Client) GetAccount() (*Account, error) {
 	}
 
 	acc := &Account{}
-	fmt.Sscanf(result.Result.List[0].TotalEquity, "%f", &acc.TotalEquity)
-	fmt.Sscanf(result.Result.List[0].AvailableMargin, "%f", &acc.AvailableMargin)
+	if acc.TotalEquity, err = strconv.ParseFloat(result.Result.List[0].TotalEquity, 64); err != nil {
+		return nil, fmt.Errorf("解析账户权益失败: %w", err)
+	}
+	if acc.AvailableMargin, err = strconv.ParseFloat(result.Result.List[0].AvailableMargin, 64); err != nil {
+		return nil, fmt.Errorf("解析可用余额失败: %w", err)
+	}
 	return acc, nil
 }
 
 // GetPositions 获取持仓列表
-func (c *Client) GetPositions(symbol string) ([]Position, error) {
+func (c *Client) GetPositions(ctx context.Context, symbol string) ([]Position, error) {
 	path := fmt.Sprintf("/v5/position/list?category=linear&symbol=%s", symbol)
-	data, err := c.request("GET", path, nil)
+	data, err := c.request(ctx, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -262,16 +658,30 @@ func (c *Client) GetPositions(symbol string) ([]Position, error) {
 
 	// 解析 Size 字段
 	for i := range result.Result.List {
-		fmt.Sscanf(result.Result.List[i].Size, "%f", &result.Result.List[i].SizeFloat)
+		p := &result.Result.List[i]
+		if p.SizeFloat, err = strconv.ParseFloat(p.Size, 64); err != nil {
+			return nil, fmt.Errorf("解析持仓数量 %q 失败: %w", p.Size, err)
+		}
 	}
 
 	return result.Result.List, nil
 }
 
 // PlaceOrder 下单（B所执行套利）
-func (c *Client) PlaceOrder(req *PlaceOrderReq) (*Order, error) {
-	data, err := c.request("POST", "/v5/order/create", req)
+// 未指定 OrderLinkID 时自动生成，重试时交易所按 OrderLinkID 去重，不会重复下单
+func (c *Client) PlaceOrder(ctx context.Context, req *PlaceOrderReq) (*Order, error) {
+	if req.OrderLinkID == "" {
+		req.OrderLinkID = c.newClientOrderID()
+	}
+	data, attempts, err := c.requestAttempts(ctx, "POST", "/v5/order/create", req)
 	if err != nil {
+		// 重试过程中订单可能已提交成功（如首次请求已到达交易所但响应丢失），按 OrderLinkID 确认
+		if attempts > 1 {
+			if o, qerr := c.GetOrderByLinkID(ctx, req.Symbol, req.OrderLinkID); qerr == nil {
+				c.logger.Warn("[Bybit REST] 下单重试失败，但订单已存在", "client_order_id", req.OrderLinkID, "order_id", o.OrderID)
+				return o, nil
+			}
+		}
 		return nil, err
 	}
 
@@ -292,31 +702,229 @@ func (c *Client) PlaceOrder(req *PlaceOrderReq) (*Order, error) {
 	}, nil
 }
 
+// maxBatchOrders 单次批量下单的最大订单数（线性合约）
+const maxBatchOrders = 20
+
+// BatchOrderResult 批量下单中单笔订单的结果，Order 与 Err 二选一
+type BatchOrderResult struct {
+	Order *Order // 提交成功的订单（只含 OrderID / Symbol / Side）
+	Err   error  // 单笔失败原因（*ExchangeError）
+}
+
+// PlaceBatchOrders 通过 /v5/order/create-batch 一次提交多笔订单（最多 20 笔，category 取第一笔），
+// 按请求顺序返回每笔的结果；整个请求失败（网络、鉴权等）时返回 error。
+// 未填写 OrderLinkID 的订单自动生成并回写；重试后单笔失败的订单按 OrderLinkID 确认是否已提交
+func (c *Client) PlaceBatchOrders(ctx context.Context, reqs []PlaceOrderReq) ([]BatchOrderResult, error) {
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+	if len(reqs) > maxBatchOrders {
+		return nil, fmt.Errorf("批量下单最多 %d 笔，实际 %d 笔", maxBatchOrders, len(reqs))
+	}
+
+	type batchItem struct {
+		Symbol      string `json:"symbol"`
+		Side        string `json:"side"`
+		OrderType   string `json:"orderType"`
+		Qty         string `json:"qty"`
+		Price       string `json:"price,omitempty"`
+		TimeInForce string `json:"timeInForce,omitempty"`
+		ReduceOnly  bool   `json:"reduceOnly"`
+		OrderLinkID string `json:"orderLinkId"`
+		PositionIdx int    `json:"positionIdx"`
+	}
+	items := make([]batchItem, len(reqs))
+	for i := range reqs {
+		r := &reqs[i]
+		if r.OrderLinkID == "" {
+			r.OrderLinkID = c.newClientOrderID()
+		}
+		items[i] = batchItem{
+			Symbol: r.Symbol, Side: r.Side, OrderType: r.OrderType, Qty: r.Qty, Price: r.Price,
+			TimeInForce: r.TimeInForce, ReduceOnly: r.ReduceOnly, OrderLinkID: r.OrderLinkID, PositionIdx: r.PositionIdx,
+		}
+	}
+	body := map[string]interface{}{"category": reqs[0].Category, "request": items}
+
+	data, attempts, err := c.requestAttempts(ctx, "POST", "/v5/order/create-batch", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Result struct {
+			List []struct {
+				OrderID     string `json:"orderId"`
+				OrderLinkID string `json:"orderLinkId"`
+			} `json:"list"`
+		} `json:"result"`
+		RetExtInfo struct {
+			List []struct {
+				Code int    `json:"code"`
+				Msg  string `json:"msg"`
+			} `json:"list"`
+		} `json:"retExtInfo"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	results := make([]BatchOrderResult, len(reqs))
+	for i, r := range reqs {
+		if i < len(result.RetExtInfo.List) && result.RetExtInfo.List[i].Code != 0 {
+			ext := result.RetExtInfo.List[i]
+			results[i].Err = &ExchangeError{HTTPStatus: http.StatusOK, Code: ext.Code, Msg: ext.Msg}
+			// 重试时首次请求可能已提交成功，重复的 OrderLinkID 被拒，按 OrderLinkID 确认
+			if attempts > 1 {
+				if o, qerr := c.GetOrderByLinkID(ctx, r.Symbol, r.OrderLinkID); qerr == nil {
+					results[i] = BatchOrderResult{Order: o}
+				}
+			}
+			continue
+		}
+		if i >= len(result.Result.List) || result.Result.List[i].OrderID == "" {
+			results[i].Err = fmt.Errorf("批量下单响应缺少第 %d 笔订单结果", i+1)
+			continue
+		}
+		results[i].Order = &Order{OrderID: result.Result.List[i].OrderID, Symbol: r.Symbol, Side: r.Side}
+	}
+	return results, nil
+}
+
+// GetOrder 查询单个订单（含成交量、成交均价、手续费）
+func (c *Client) GetOrder(ctx context.Context, symbol, orderID string) (*Order, error) {
+	path := fmt.Sprintf("/v5/order/realtime?category=linear&symbol=%s&orderId=%s", symbol, orderID)
+	data, err := c.request(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Result struct {
+			List []Order `json:"list"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Result.List) == 0 {
+		return nil, fmt.Errorf("Bybit 订单 %s 不存在", orderID)
+	}
+	return &result.Result.List[0], nil
+}
+
+// GetOrderByLinkID 按自定义订单ID查询订单
+func (c *Client) GetOrderByLinkID(ctx context.Context, symbol, orderLinkID string) (*Order, error) {
+	path := fmt.Sprintf("/v5/order/realtime?category=linear&symbol=%s&orderLinkId=%s", symbol, orderLinkID)
+	data, err := c.request(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Result struct {
+			List []Order `json:"list"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Result.List) == 0 {
+		return nil, fmt.Errorf("Bybit 订单 OrderLinkID=%s 不存在", orderLinkID)
+	}
+	return &result.Result.List[0], nil
+}
+
+// SetLeverage 设置交易对的买/卖方向杠杆倍数
+// 杠杆已是目标值时交易所返回 110043，视为成功
+func (c *Client) SetLeverage(ctx context.Context, symbol string, buyLeverage, sellLeverage float64) error {
+	req := map[string]string{
+		"category":     "linear",
+		"symbol":       symbol,
+		"buyLeverage":  strconv.FormatFloat(buyLeverage, 'f', -1, 64),
+		"sellLeverage": strconv.FormatFloat(sellLeverage, 'f', -1, 64),
+	}
+	_, err := c.request(ctx, "POST", "/v5/position/set-leverage", req)
+	var ee *ExchangeError
+	if errors.As(err, &ee) && ee.Code == CodeLeverageNotModified {
+		return nil
+	}
+	return err
+}
+
+// SwitchPositionMode 切换交易对的持仓模式（PositionModeOneWay / PositionModeHedge）
+// 已是目标模式时交易所返回 110025，视为成功；有持仓或挂单时交易所拒绝切换
+func (c *Client) SwitchPositionMode(ctx context.Context, symbol string, mode int) error {
+	req := map[string]interface{}{
+		"category": "linear",
+		"symbol":   symbol,
+		"mode":     mode,
+	}
+	_, err := c.request(ctx, "POST", "/v5/position/switch-mode", req)
+	var ee *ExchangeError
+	if errors.As(err, &ee) && ee.Code == CodeModeNotModified {
+		return nil
+	}
+	return err
+}
+
+// AmendOrder 修改挂单的价格和/或数量，返回修改后的订单
+// 订单已不存在、已成交或已撤销时返回的错误满足 IsOrderGone，调用方应重新报价而不是视为故障
+func (c *Client) AmendOrder(ctx context.Context, req *AmendOrderReq) (*Order, error) {
+	if req.OrderID == "" && req.OrderLinkID == "" {
+		return nil, fmt.Errorf("改单需指定 orderId 或 orderLinkId")
+	}
+	if req.Qty == "" && req.Price == "" {
+		return nil, fmt.Errorf("改单需指定新价格或新数量")
+	}
+	if req.Category == "" {
+		req.Category = "linear"
+	}
+	data, err := c.request(ctx, "POST", "/v5/order/amend", req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Result struct {
+			OrderID     string `json:"orderId"`
+			OrderLinkID string `json:"orderLinkId"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	if result.Result.OrderID != "" {
+		return c.GetOrder(ctx, req.Symbol, result.Result.OrderID)
+	}
+	return c.GetOrderByLinkID(ctx, req.Symbol, req.OrderLinkID)
+}
+
 // CancelOrder 撤销单个订单
-func (c *Client) CancelOrder(symbol, orderID string) error {
+func (c *Client) CancelOrder(ctx context.Context, symbol, orderID string) error {
 	req := map[string]string{
 		"category": "linear",
 		"symbol":   symbol,
 		"orderId":  orderID,
 	}
-	_, err := c.request("POST", "/v5/order/cancel", req)
+	_, err := c.request(ctx, "POST", "/v5/order/cancel", req)
 	return err
 }
 
 // CancelAllOrders 撤销某交易对所有订单
-func (c *Client) CancelAllOrders(symbol string) error {
+func (c *Client) CancelAllOrders(ctx context.Context, symbol string) error {
 	req := map[string]string{
 		"category": "linear",
 		"symbol":   symbol,
 	}
-	_, err := c.request("POST", "/v5/order/cancel-all", req)
+	_, err := c.request(ctx, "POST", "/v5/order/cancel-all", req)
 	return err
 }
 
 // GetOpenOrders 获取当前挂单
-func (c *Client) GetOpenOrders(symbol string) ([]Order, error) {
+func (c *Client) GetOpenOrders(ctx context.Context, symbol string) ([]Order, error) {
 	path := fmt.Sprintf("/v5/order/realtime?category=linear&symbol=%s", symbol)
-	data, err := c.request("GET", path, nil)
+	data, err := c.request(ctx, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
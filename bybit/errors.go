@@ -0,0 +1,125 @@
+package bybit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Bybit V5 业务错误码（retCode）
+const (
+	CodeTimestampExpired    = 10002  // 请求时间戳超出 recv_window（本地时钟偏差）
+	CodeInvalidAPIKey       = 10003  // API Key 无效
+	CodeInvalidSign         = 10004  // 签名错误
+	CodePermissionDenied    = 10005  // 权限不足
+	CodeRateLimit           = 10006  // 请求过于频繁
+	CodeIPNotAllowed        = 10010  // IP 不在白名单
+	CodeServerError         = 10016  // 服务端内部错误
+	CodeInsufficientBalance = 110007 // 可用余额不足
+	CodeInsufficientWallet  = 110004 // 钱包余额不足
+	CodeLeverageNotModified = 110043 // 杠杆未变化（已是目标杠杆）
+	CodeModeNotModified     = 110025 // 持仓模式未变化（已是目标模式）
+	CodeModeHasPosition     = 110024 // 有持仓，不能切换持仓模式
+	CodeModeHasOrders       = 110028 // 有挂单，不能切换持仓模式
+	CodeOrderNotExist       = 110001 // 订单不存在或已来不及修改
+	CodeOrderFinished       = 110008 // 订单已完成或已撤销
+	CodeOrderCancelled      = 110010 // 订单已撤销
+)
+
+// ExchangeError Bybit 返回的错误（HTTP 非成功状态或 retCode 非 0）
+type ExchangeError struct {
+	HTTPStatus int    // HTTP 状态码
+	Code       int    // retCode，HTTP 层错误时为 0
+	Msg        string // retMsg 或响应内容
+}
+
+func (e *ExchangeError) Error() string {
+	if e.Code == 0 {
+		return fmt.Sprintf("Bybit HTTP %d: %s", e.HTTPStatus, e.Msg)
+	}
+	return fmt.Sprintf("Bybit 错误 %d: %s", e.Code, e.Msg)
+}
+
+// Retryable 是否为可重试的瞬时错误：HTTP 5xx、429 与限频/服务端错误码
+func (e *ExchangeError) Retryable() bool {
+	switch e.Code {
+	case CodeRateLimit, CodeServerError:
+		return true
+	}
+	return e.HTTPStatus >= http.StatusInternalServerError || e.HTTPStatus == http.StatusTooManyRequests
+}
+
+// TimestampExpired 是否为请求时间戳超出 recv_window
+func (e *ExchangeError) TimestampExpired() bool {
+	return e.Code == CodeTimestampExpired
+}
+
+// InsufficientBalance 是否为余额不足
+func (e *ExchangeError) InsufficientBalance() bool {
+	return e.Code == CodeInsufficientBalance || e.Code == CodeInsufficientWallet
+}
+
+// PermissionDenied 是否为鉴权或权限错误（API Key 无效、签名错误、权限不足、IP 限制）
+func (e *ExchangeError) PermissionDenied() bool {
+	switch e.Code {
+	case CodeInvalidAPIKey, CodeInvalidSign, CodePermissionDenied, CodeIPNotAllowed:
+		return true
+	}
+	return e.HTTPStatus == http.StatusUnauthorized || e.HTTPStatus == http.StatusForbidden
+}
+
+// OrderGone 是否为订单已结束（不存在、已成交或已撤销），改单时据此重新报价
+func (e *ExchangeError) OrderGone() bool {
+	switch e.Code {
+	case CodeOrderNotExist, CodeOrderFinished, CodeOrderCancelled:
+		return true
+	}
+	return false
+}
+
+// ModeLocked 是否为因有持仓或挂单而拒绝切换持仓模式
+func (e *ExchangeError) ModeLocked() bool {
+	return e.Code == CodeModeHasPosition || e.Code == CodeModeHasOrders
+}
+
+// transientError 可重试的网络层错误（连接失败、读取响应失败）
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// IsRetryable 判断错误是否可重试：网络错误或 ExchangeError.Retryable
+func IsRetryable(err error) bool {
+	var te *transientError
+	if errors.As(err, &te) {
+		return true
+	}
+	var ee *ExchangeError
+	return errors.As(err, &ee) && ee.Retryable()
+}
+
+// IsInsufficientBalance 判断错误是否为 Bybit 余额不足
+func IsInsufficientBalance(err error) bool {
+	var ee *ExchangeError
+	return errors.As(err, &ee) && ee.InsufficientBalance()
+}
+
+// IsPermissionDenied 判断错误是否为 Bybit 鉴权或权限错误
+func IsPermissionDenied(err error) bool {
+	var ee *ExchangeError
+	return errors.As(err, &ee) && ee.PermissionDenied()
+}
+
+// IsOrderGone 判断错误是否为 Bybit 订单已结束（不存在、已成交或已撤销）
+func IsOrderGone(err error) bool {
+	var ee *ExchangeError
+	return errors.As(err, &ee) && ee.OrderGone()
+}
+
+// IsModeLocked 判断错误是否为 Bybit 因有持仓或挂单拒绝切换持仓模式
+func IsModeLocked(err error) bool {
+	var ee *ExchangeError
+	return errors.As(err, &ee) && ee.ModeLocked()
+}
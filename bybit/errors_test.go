@@ -0,0 +1,57 @@
+package bybit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestErrorClassification(t *testing.T) {
+	cases := []struct {
+		name       string
+		err        error
+		retryable  bool
+		balance    bool
+		permission bool
+		orderGone  bool
+	}{
+		{"限频", &ExchangeError{HTTPStatus: 200, Code: CodeRateLimit}, true, false, false, false},
+		{"服务端错误码", &ExchangeError{HTTPStatus: 200, Code: CodeServerError}, true, false, false, false},
+		{"HTTP 502", &ExchangeError{HTTPStatus: 502}, true, false, false, false},
+		{"HTTP 429", &ExchangeError{HTTPStatus: 429}, true, false, false, false},
+		{"网络错误", &transientError{err: io.ErrUnexpectedEOF}, true, false, false, false},
+		{"可用余额不足", &ExchangeError{HTTPStatus: 200, Code: CodeInsufficientBalance}, false, true, false, false},
+		{"钱包余额不足", &ExchangeError{HTTPStatus: 200, Code: CodeInsufficientWallet}, false, true, false, false},
+		{"API Key 无效", &ExchangeError{HTTPStatus: 200, Code: CodeInvalidAPIKey}, false, false, true, false},
+		{"签名错误", &ExchangeError{HTTPStatus: 200, Code: CodeInvalidSign}, false, false, true, false},
+		{"权限不足", &ExchangeError{HTTPStatus: 200, Code: CodePermissionDenied}, false, false, true, false},
+		{"IP 限制", &ExchangeError{HTTPStatus: 200, Code: CodeIPNotAllowed}, false, false, true, false},
+		{"HTTP 401", &ExchangeError{HTTPStatus: 401}, false, false, true, false},
+		{"HTTP 403", &ExchangeError{HTTPStatus: 403}, false, false, true, false},
+		{"订单不存在", &ExchangeError{HTTPStatus: 200, Code: CodeOrderNotExist}, false, false, false, true},
+		{"订单已撤销", &ExchangeError{HTTPStatus: 200, Code: CodeOrderCancelled}, false, false, false, true},
+		{"数量无效", &ExchangeError{HTTPStatus: 200, Code: 10001, Msg: "invalid qty"}, false, false, false, false},
+		{"HTTP 400", &ExchangeError{HTTPStatus: 400}, false, false, false, false},
+		{"被包装的限频", fmt.Errorf("下单失败: %w", &ExchangeError{HTTPStatus: 200, Code: CodeRateLimit}), true, false, false, false},
+		{"被包装的余额不足", fmt.Errorf("下单失败: %w", &ExchangeError{HTTPStatus: 200, Code: CodeInsufficientBalance}), false, true, false, false},
+		{"普通错误", errors.New("其它错误"), false, false, false, false},
+		{"nil", nil, false, false, false, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsRetryable(tc.err); got != tc.retryable {
+				t.Errorf("IsRetryable = %v，期望 %v", got, tc.retryable)
+			}
+			if got := IsInsufficientBalance(tc.err); got != tc.balance {
+				t.Errorf("IsInsufficientBalance = %v，期望 %v", got, tc.balance)
+			}
+			if got := IsPermissionDenied(tc.err); got != tc.permission {
+				t.Errorf("IsPermissionDenied = %v，期望 %v", got, tc.permission)
+			}
+			if got := IsOrderGone(tc.err); got != tc.orderGone {
+				t.Errorf("IsOrderGone = %v，期望 %v", got, tc.orderGone)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package bybit
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// BookManager 按 snapshot + delta 维护 Bybit 本地订单簿
+//
+// Bybit 订单簿频道先推送 snapshot，之后推送 delta：
+//   - delta 中数量为 "0" 的档位表示删除
+//   - u 为更新序号，正常情况下每条 delta 递增 1；u=1 表示服务重启后的新快照
+//   - 序号不连续时本地簿已不可信，需要重新订阅获取快照；序号不大于已合并序号的 delta 为重复推送，直接忽略
+//   - 合并后买一 ≥ 卖一（交叉盘口）说明本地簿与交易所不一致，同样重新订阅获取快照
+//
+// 非并发安全，只应由 WS 读循环访问
+type BookManager struct {
+	bids map[string]string // price → size
+	asks map[string]string
+
+	lastU   int64
+	lastSeq int64
+	synced  bool // 已收到快照且序号连续
+}
+
+// ErrSequenceGap 增量序号不连续，需重新获取快照
+type ErrSequenceGap struct {
+	Expected, Got int64
+}
+
+func (e *ErrSequenceGap) Error() string {
+	return fmt.Sprintf("订单簿序号不连续：期望 u=%d，收到 u=%d", e.Expected, e.Got)
+}
+
+// ErrCrossedBook 合并后的本地订单簿买一 ≥ 卖一，需重新获取快照
+type ErrCrossedBook struct {
+	Bid, Ask string
+	U        int64
+}
+
+func (e *ErrCrossedBook) Error() string {
+	return fmt.Sprintf("本地订单簿交叉：买一=%s ≥ 卖一=%s（u=%d）", e.Bid, e.Ask, e.U)
+}
+
+// Apply 合并一条推送，成功时将 ob 的 Bids/Asks 替换为排序后的完整订单簿并返回 true
+// 未同步（尚未收到快照）时忽略 delta 并返回 false；检测到序号缺口时返回 *ErrSequenceGap
+func (m *BookManager) Apply(msgType string, ob *WsOrderBook) (bool, error) {
+	switch {
+	case msgType == "snapshot" || ob.U == 1:
+		m.bids = make(map[string]string, len(ob.Bids))
+		m.asks = make(map[string]string, len(ob.Asks))
+		m.synced = true
+	case !m.synced:
+		return false, nil
+	case ob.U <= m.lastU:
+		return false, nil
+	case ob.U != m.lastU+1:
+		m.synced = false
+		return false, &ErrSequenceGap{Expected: m.lastU + 1, Got: ob.U}
+	}
+
+	mergeLevels(m.bids, ob.Bids)
+	mergeLevels(m.asks, ob.Asks)
+	m.lastU = ob.U
+	m.lastSeq = ob.Seq
+
+	ob.Bids = sortedLevels(m.bids, true)
+	ob.Asks = sortedLevels(m.asks, false)
+	if len(ob.Bids) > 0 && len(ob.Asks) > 0 && levelPrice(ob.Bids[0]) >= levelPrice(ob.Asks[0]) {
+		m.synced = false
+		return false, &ErrCrossedBook{Bid: ob.Bids[0][0], Ask: ob.Asks[0][0], U: ob.U}
+	}
+	return true, nil
+}
+
+// Reset 丢弃本地订单簿，等待下一条快照
+func (m *BookManager) Reset() {
+	m.synced = false
+}
+
+// mergeLevels 合并档位，数量为 0 的档位删除
+func mergeLevels(side map[string]string, levels [][]string) {
+	for _, l := range levels {
+		if len(l) < 2 {
+			continue
+		}
+		if size, err := strconv.ParseFloat(l[1], 64); err == nil && size == 0 {
+			delete(side, l[0])
+		} else {
+			side[l[0]] = l[1]
+		}
+	}
+}
+
+// levelPrice 解析档位价格，格式异常时返回 0
+func levelPrice(l []string) float64 {
+	p, _ := strconv.ParseFloat(l[0], 64)
+	return p
+}
+
+// sortedLevels 将档位按价格排序：买盘降序，卖盘升序
+func sortedLevels(side map[string]string, desc bool) [][]string {
+	levels := make([][]string, 0, len(side))
+	for price, size := range side {
+		levels = append(levels, []string{price, size})
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		pi, pj := levelPrice(levels[i]), levelPrice(levels[j])
+		if desc {
+			return pi > pj
+		}
+		return pi < pj
+	})
+	return levels
+}
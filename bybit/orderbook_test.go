@@ -0,0 +1,112 @@
+package bybit
+
+import (
+	"errors"
+	"testing"
+)
+
+// bookMsg 一条回放的订单簿推送
+type bookMsg struct {
+	typ  string
+	u    int64
+	bids [][]string
+	asks [][]string
+}
+
+// TestBookManagerReplay 回放 snapshot + delta 序列：删除档位、重复推送、序号缺口与重新快照
+func TestBookManagerReplay(t *testing.T) {
+	steps := []struct {
+		name     string
+		msg      bookMsg
+		ok       bool
+		gap      bool
+		bid, ask string
+	}{
+		{
+			name: "快照",
+			msg: bookMsg{"snapshot", 100,
+				[][]string{{"100000.1", "1"}, {"100000.0", "2"}, {"99999.9", "3"}},
+				[][]string{{"100000.2", "1"}, {"100000.3", "2"}}},
+			ok: true, bid: "100000.1", ask: "100000.2",
+		},
+		{
+			name: "删除买一、新增卖一",
+			msg: bookMsg{"delta", 101,
+				[][]string{{"100000.1", "0"}},
+				[][]string{{"100000.15", "0.5"}}},
+			ok: true, bid: "100000.0", ask: "100000.15",
+		},
+		{
+			name: "重复推送忽略",
+			msg:  bookMsg{"delta", 101, [][]string{{"100000.1", "5"}}, nil},
+			bid:  "100000.0", ask: "100000.15",
+		},
+		{
+			name: "更新数量、删除卖一",
+			msg: bookMsg{"delta", 102,
+				[][]string{{"100000.0", "4"}},
+				[][]string{{"100000.15", "0"}}},
+			ok: true, bid: "100000.0", ask: "100000.2",
+		},
+		{
+			name: "序号缺口",
+			msg:  bookMsg{"delta", 104, [][]string{{"100000.05", "1"}}, nil},
+			gap:  true,
+		},
+		{
+			name: "缺口后等待快照，忽略后续增量",
+			msg:  bookMsg{"delta", 105, [][]string{{"100000.05", "1"}}, nil},
+		},
+		{
+			name: "重新快照恢复同步",
+			msg: bookMsg{"snapshot", 200,
+				[][]string{{"100001.0", "1"}},
+				[][]string{{"100001.5", "1"}}},
+			ok: true, bid: "100001.0", ask: "100001.5",
+		},
+		{
+			name: "快照后增量继续合并",
+			msg:  bookMsg{"delta", 201, [][]string{{"100001.2", "1"}}, nil},
+			ok:   true, bid: "100001.2", ask: "100001.5",
+		},
+	}
+
+	var m BookManager
+	for _, s := range steps {
+		ob := &WsOrderBook{Symbol: "BTCUSDT", U: s.msg.u, Bids: s.msg.bids, Asks: s.msg.asks}
+		ok, err := m.Apply(s.msg.typ, ob)
+
+		var gap *ErrSequenceGap
+		if s.gap != errors.As(err, &gap) {
+			t.Fatalf("%s: err = %v，期望序号缺口 = %v", s.name, err, s.gap)
+		}
+		if !s.gap && err != nil {
+			t.Fatalf("%s: 意外错误 %v", s.name, err)
+		}
+		if ok != s.ok {
+			t.Fatalf("%s: ok = %v，期望 %v", s.name, ok, s.ok)
+		}
+		if !ok {
+			continue
+		}
+		if ob.Bids[0][0] != s.bid || ob.Asks[0][0] != s.ask {
+			t.Fatalf("%s: 买一 / 卖一 = %s / %s，期望 %s / %s", s.name, ob.Bids[0][0], ob.Asks[0][0], s.bid, s.ask)
+		}
+	}
+}
+
+func TestBookManagerCrossedBook(t *testing.T) {
+	var m BookManager
+	m.Apply("snapshot", &WsOrderBook{U: 1,
+		Bids: [][]string{{"100000.0", "1"}},
+		Asks: [][]string{{"100000.5", "1"}}})
+
+	_, err := m.Apply("delta", &WsOrderBook{U: 2, Bids: [][]string{{"100000.5", "1"}}})
+	var crossed *ErrCrossedBook
+	if !errors.As(err, &crossed) {
+		t.Fatalf("买一 ≥ 卖一 应返回 ErrCrossedBook，得到 %v", err)
+	}
+	if ok, err := m.Apply("delta", &WsOrderBook{U: 3, Bids: [][]string{{"100000.5", "0"}}}); ok || err != nil {
+		t.Fatalf("交叉后应等待快照，得到 ok=%v err=%v", ok, err)
+	}
+}
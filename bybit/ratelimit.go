@@ -0,0 +1,132 @@
+package bybit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"arb/internal/ratelimit"
+)
+
+// 接口分组，各组独立限频
+const (
+	GroupMarket  = "market"  // 行情、交易对信息
+	GroupOrder   = "order"   // 下单、撤单、订单查询
+	GroupAccount = "account" // 账户、持仓
+)
+
+// ErrRateLimited 本地限频：令牌不足且等待超过 MaxWait，请求未发出
+var ErrRateLimited = errors.New("Bybit 本地限频，请求未发出")
+
+// maxHeaderBackoff 按 X-Bapi-Limit-Status 退避的最长时间
+const maxHeaderBackoff = 5 * time.Second
+
+// RateLimit 本地限频配置，各分组每秒请求数，<=0 表示不限制
+type RateLimit struct {
+	MarketRPS  float64
+	OrderRPS   float64
+	AccountRPS float64
+
+	// 令牌不足时最长等待，超过则返回 ErrRateLimited；0 表示一直等待
+	MaxWait time.Duration
+
+	// X-Bapi-Limit-Status 剩余额度低于此值时，该分组暂停到额度重置；0 表示不按响应头退避
+	MinRemaining int
+}
+
+// ThrottleEvent 限频事件，供调用方记录
+type ThrottleEvent struct {
+	Group    string
+	Wait     time.Duration // 本地等待时长（Rejected 时为需要等待的时长）
+	Rejected bool          // 等待超过 MaxWait，请求被拒绝
+
+	// 以下字段仅在按响应头退避时设置
+	Remaining int
+	Limit     int
+	Until     time.Time
+}
+
+// rateLimiter 按接口分组限频，额外按响应头退避
+type rateLimiter struct {
+	limiter      *ratelimit.Limiter
+	minRemaining int
+	hook         func(ThrottleEvent)
+}
+
+func newRateLimiter(rl RateLimit, hook func(ThrottleEvent)) *rateLimiter {
+	l := &rateLimiter{minRemaining: rl.MinRemaining, hook: hook}
+	var onEvent func(ratelimit.Event)
+	if hook != nil {
+		onEvent = func(ev ratelimit.Event) {
+			hook(ThrottleEvent{Group: ev.Group, Wait: ev.Wait, Rejected: ev.Rejected})
+		}
+	}
+	l.limiter = ratelimit.New(map[string]float64{
+		GroupMarket:  rl.MarketRPS,
+		GroupOrder:   rl.OrderRPS,
+		GroupAccount: rl.AccountRPS,
+	}, rl.MaxWait, onEvent)
+	return l
+}
+
+// wait 等待 group 的令牌，等待超过 MaxWait 时返回 ErrRateLimited
+func (l *rateLimiter) wait(ctx context.Context, group string) error {
+	if l == nil {
+		return nil
+	}
+	if err := l.limiter.Wait(ctx, group); err != nil {
+		if errors.Is(err, ratelimit.ErrExceeded) {
+			return ErrRateLimited
+		}
+		return err
+	}
+	return nil
+}
+
+// observe 解析 X-Bapi-Limit-Status 等响应头，剩余额度过低时暂停该分组到额度重置
+func (l *rateLimiter) observe(group string, h http.Header) {
+	if l == nil || l.minRemaining <= 0 {
+		return
+	}
+	remaining, err := strconv.Atoi(h.Get("X-Bapi-Limit-Status"))
+	if err != nil || remaining >= l.minRemaining {
+		return
+	}
+	limit, _ := strconv.Atoi(h.Get("X-Bapi-Limit"))
+
+	now := time.Now()
+	until := now.Add(time.Second)
+	if ms, err := strconv.ParseInt(h.Get("X-Bapi-Limit-Reset-Timestamp"), 10, 64); err == nil {
+		until = time.UnixMilli(ms)
+	}
+	if until.After(now.Add(maxHeaderBackoff)) {
+		until = now.Add(maxHeaderBackoff)
+	}
+	if !until.After(now) {
+		return
+	}
+
+	if l.limiter.Block(group, until) && l.hook != nil {
+		l.hook(ThrottleEvent{Group: group, Wait: until.Sub(now), Remaining: remaining, Limit: limit, Until: until})
+	}
+}
+
+// endpointGroup 按请求路径归类接口分组
+func endpointGroup(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/v5/order/"):
+		return GroupOrder
+	case strings.HasPrefix(path, "/v5/account/"), strings.HasPrefix(path, "/v5/position/"):
+		return GroupAccount
+	default:
+		return GroupMarket
+	}
+}
+
+// SetRateLimit 启用本地限频，hook 在请求等待、被拒绝或按响应头退避时调用（可为 nil）
+func (c *Client) SetRateLimit(rl RateLimit, hook func(ThrottleEvent)) {
+	c.limiter = newRateLimiter(rl, hook)
+}
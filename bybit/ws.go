@@ -1,9 +1,15 @@
 package bybit
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"slices"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -17,14 +23,58 @@ type WsOrderBook struct {
 	Bids   [][]string `json:"b"`
 	Asks   [][]string `json:"a"`
 	Ts     int64      `json:"ts"`
+	U      int64      `json:"u"`   // 更新序号
+	Seq    int64      `json:"seq"` // 跨频道序号
+}
+
+// ParsePriceLevel 解析订单簿单档 [价格, 数量]，格式错误时返回 error（不会静默返回 0）
+func ParsePriceLevel(level []string) (price, size float64, err error) {
+	if len(level) < 2 {
+		return 0, 0, fmt.Errorf("订单簿档位格式错误: %v", level)
+	}
+	if price, err = strconv.ParseFloat(level[0], 64); err != nil {
+		return 0, 0, fmt.Errorf("解析价格 %q 失败: %w", level[0], err)
+	}
+	if size, err = strconv.ParseFloat(level[1], 64); err != nil {
+		return 0, 0, fmt.Errorf("解析数量 %q 失败: %w", level[1], err)
+	}
+	return price, size, nil
+}
+
+// WsTrade Bybit 公共频道推送的逐笔成交
+type WsTrade struct {
+	Symbol string `json:"s"`
+	Side   string `json:"S"` // 主动方：Buy / Sell
+	Price  string `json:"p"`
+	Size   string `json:"v"`
+	Ts     int64  `json:"T"` // 成交时间（毫秒）
+}
+
+// WsExecution Bybit 私有频道推送的成交事件
+type WsExecution struct {
+	Symbol      string `json:"symbol"`
+	OrderID     string `json:"orderId"`
+	OrderLinkID string `json:"orderLinkId"`
+	Side        string `json:"side"` // Buy / Sell
+	ExecPrice   string `json:"execPrice"`
+	ExecQty     string `json:"execQty"`
+	ExecFee     string `json:"execFee"`
+	LeavesQty   string `json:"leavesQty"` // 订单剩余未成交量，为 0 表示订单已全部成交
+	ExecType    string `json:"execType"`  // Trade / Funding / ...
+	ExecTime    string `json:"execTime"`
 }
 
 // WsClient Bybit WebSocket 客户端（支持断线重连）
 type WsClient struct {
-	wsURL string
+	wsURL  string
+	logger *slog.Logger
+
+	// 私有频道鉴权（为空表示公共频道）
+	apiKey    string
+	apiSecret string
 
-	mu   sync.Mutex
-	conn *websocket.Conn
+	mu     sync.Mutex
+	writer *wsWriter // 当前连接的写协程，所有出站帧经其发送
 
 	// 订阅注册表
 	subsMu sync.RWMutex
@@ -33,7 +83,15 @@ type WsClient struct {
 	// 连接状态
 	connected      atomic.Bool
 	reconnectCount atomic.Int64
+	seqGaps        atomic.Int64 // 订单簿序号缺口累计次数
+	resyncing      atomic.Bool  // 订单簿序号缺口或交叉后等待新快照，期间 IsReady 返回 false
+	failedDials    atomic.Int64 // 连续重连失败次数，重连成功后清零
+	maxDials       atomic.Int64 // 连续重连失败上限，0 表示不限制
+	lastPongAt     atomic.Value // time.Time
 	lastMsgAt      atomic.Value // time.Time
+	pingSeq        atomic.Int64
+	rtt            atomic.Int64 // nanoseconds
+	pingSentAt     sync.Map     // req_id(string) → time.Time
 
 	// 内部控制
 	done     chan struct{}
@@ -42,13 +100,21 @@ type WsClient struct {
 
 type wsSubscription struct {
 	topic string
-	cb    func(data []byte)
+	depth int // 订单簿订阅档位（已编码在 topic 中），非订单簿订阅为 0
+	cb    func(msgType string, data []byte)
 }
 
+// orderBookDepths 线性合约订单簿频道支持的档位
+var orderBookDepths = []int{1, 50, 200, 500}
+
+// ErrUnsupportedDepth 订阅的订单簿档位不受支持
+var ErrUnsupportedDepth = errors.New("Bybit 不支持该订单簿档位")
+
 const (
 	bybitWsInitialBackoff = 1 * time.Second
 	bybitWsMaxBackoff     = 30 * time.Second
 	bybitWsPingInterval   = 20 * time.Second
+	bybitWsPongTimeout    = 10 * time.Second
 	bybitWsDialTimeout    = 10 * time.Second
 )
 
@@ -56,37 +122,130 @@ const (
 func NewWsClient(wsURL string) *WsClient {
 	w := &WsClient{
 		wsURL:    wsURL,
+		logger:   slog.Default(),
 		done:     make(chan struct{}),
 		reconnCh: make(chan struct{}, 1),
 	}
+	w.lastPongAt.Store(time.Time{})
 	w.lastMsgAt.Store(time.Time{})
 	return w
 }
 
+// SetLogger 设置日志输出（默认 slog.Default()），nil 时忽略；需在 Connect 前调用
+func (w *WsClient) SetLogger(l *slog.Logger) {
+	if l != nil {
+		w.logger = l
+	}
+}
+
+// NewPrivateWsClient 创建 Bybit 私有频道 WebSocket 客户端，每次连接（含重连）后自动鉴权
+func NewPrivateWsClient(wsURL, apiKey, apiSecret string) *WsClient {
+	w := NewWsClient(wsURL)
+	w.apiKey = apiKey
+	w.apiSecret = apiSecret
+	return w
+}
+
 // Connect 建立初始连接并启动后台 goroutine
+// 初次连接失败时同样交给重连循环按退避重试（连接建立后自动恢复订阅），并返回初次连接的错误
 func (w *WsClient) Connect() error {
-	if err := w.dial(); err != nil {
-		return err
-	}
+	err := w.dial()
 	go w.reconnectLoop()
-	return nil
+	if err != nil {
+		select {
+		case w.reconnCh <- struct{}{}:
+		default:
+		}
+	}
+	return err
 }
 
-// SubscribeOrderBook 订阅订单簿频道
-func (w *WsClient) SubscribeOrderBook(symbol string, cb func(ob *WsOrderBook)) error {
-	// Bybit V5 公共频道格式：orderbook.1.BTCUSDT
-	topic := fmt.Sprintf("orderbook.1.%s", symbol)
+// SubscribeOrderBook 订阅订单簿频道，depth 为档位数（线性合约支持 1/50/200/500，0 = 1 档）
+// depth>1 时推送为 snapshot + delta，回调收到的是合并后的完整订单簿
+func (w *WsClient) SubscribeOrderBook(symbol string, depth int, cb func(ob *WsOrderBook)) error {
+	if depth == 0 {
+		depth = 1
+	}
+	if !slices.Contains(orderBookDepths, depth) {
+		return fmt.Errorf("%w: %d（可选 %v）", ErrUnsupportedDepth, depth, orderBookDepths)
+	}
+	// Bybit V5 公共频道格式：orderbook.{depth}.BTCUSDT
+	topic := fmt.Sprintf("orderbook.%d.%s", depth, symbol)
 
+	var book BookManager
 	w.subsMu.Lock()
 	w.subs = append(w.subs, wsSubscription{
 		topic: topic,
-		cb: func(data []byte) {
+		depth: depth,
+		cb: func(msgType string, data []byte) {
 			var ob WsOrderBook
 			if err := json.Unmarshal(data, &ob); err != nil {
-				log.Printf("[Bybit WS] 解析订单簿数据失败: %v", err)
+				w.logger.Warn("[Bybit WS] 解析订单簿数据失败", "err", err)
+				return
+			}
+			ok, err := book.Apply(msgType, &ob)
+			if err != nil {
+				var gap *ErrSequenceGap
+				if errors.As(err, &gap) {
+					w.seqGaps.Add(1)
+				}
+				w.resyncing.Store(true)
+				w.logger.Warn("[Bybit WS] 订单簿增量异常或本地簿交叉，重新订阅获取快照", "topic", topic, "err", err)
+				if err := w.resubscribe(topic); err != nil {
+					w.logger.Warn("[Bybit WS] 重新订阅失败", "topic", topic, "err", err)
+				}
+				return
+			}
+			if ok {
+				w.resyncing.Store(false)
+				cb(&ob)
+			}
+		},
+	})
+	w.subsMu.Unlock()
+
+	return w.sendSubscribe(topic)
+}
+
+// SubscribeTrades 订阅逐笔成交（publicTrade.{symbol}），每条推送可能包含多笔成交
+func (w *WsClient) SubscribeTrades(symbol string, cb func(t *WsTrade)) error {
+	topic := fmt.Sprintf("publicTrade.%s", symbol)
+
+	w.subsMu.Lock()
+	w.subs = append(w.subs, wsSubscription{
+		topic: topic,
+		cb: func(_ string, data []byte) {
+			var trades []WsTrade
+			if err := json.Unmarshal(data, &trades); err != nil {
+				w.logger.Warn("[Bybit WS] 解析成交数据失败", "err", err)
+				return
+			}
+			for i := range trades {
+				cb(&trades[i])
+			}
+		},
+	})
+	w.subsMu.Unlock()
+
+	return w.sendSubscribe(topic)
+}
+
+// SubscribeExecutions 订阅线性合约成交推送（需使用 NewPrivateWsClient 创建的客户端）
+func (w *WsClient) SubscribeExecutions(cb func(ex *WsExecution)) error {
+	const topic = "execution.linear"
+
+	w.subsMu.Lock()
+	w.subs = append(w.subs, wsSubscription{
+		topic: topic,
+		cb: func(_ string, data []byte) {
+			var execs []WsExecution
+			if err := json.Unmarshal(data, &execs); err != nil {
+				w.logger.Warn("[Bybit WS] 解析成交推送失败", "err", err)
 				return
 			}
-			cb(&ob)
+			for i := range execs {
+				cb(&execs[i])
+			}
 		},
 	})
 	w.subsMu.Unlock()
@@ -94,9 +253,49 @@ func (w *WsClient) SubscribeOrderBook(symbol string, cb func(ob *WsOrderBook)) e
 	return w.sendSubscribe(topic)
 }
 
-// IsReady 返回当前是否已连接
+// WsStats WebSocket 连接健康状况
+type WsStats struct {
+	Connected      bool          // 当前是否已连接
+	ReconnectCount int64         // 累计重连次数
+	Failed         bool          // 连续重连失败次数已达到上限（见 SetMaxReconnectAttempts）
+	RTT            time.Duration // 最近一次 ping/pong 往返时延
+	LastMessageAt  time.Time     // 最近一次收到消息的时间
+	LastMessageAge time.Duration // 距最近一次消息的时长（从未收到时为 0）
+	SeqGaps        int64         // 订单簿序号缺口累计次数（每次缺口触发一次重新订阅）
+	Resyncing      bool          // 正在重新订阅等待订单簿快照
+}
+
+// SetMaxReconnectAttempts 设置连续重连失败上限，达到后 Failed 返回 true（重连循环仍继续尝试，成功后清除）；n <= 0 不限制
+func (w *WsClient) SetMaxReconnectAttempts(n int) {
+	w.maxDials.Store(int64(n))
+}
+
+// Failed 连续重连失败次数是否已达到上限：交易所接口可能已不可用，调用方不应继续依赖该行情
+func (w *WsClient) Failed() bool {
+	limit := w.maxDials.Load()
+	return limit > 0 && w.failedDials.Load() >= limit
+}
+
+// Stats 返回连接健康状况与延迟
+func (w *WsClient) Stats() WsStats {
+	st := WsStats{
+		Connected:      w.connected.Load(),
+		ReconnectCount: w.reconnectCount.Load(),
+		RTT:            time.Duration(w.rtt.Load()),
+		Failed:         w.Failed(),
+		SeqGaps:        w.seqGaps.Load(),
+		Resyncing:      w.resyncing.Load(),
+	}
+	if t, ok := w.lastMsgAt.Load().(time.Time); ok && !t.IsZero() {
+		st.LastMessageAt = t
+		st.LastMessageAge = time.Since(t)
+	}
+	return st
+}
+
+// IsReady 返回当前是否已连接且可用（订单簿等待重新同步快照期间不可用）
 func (w *WsClient) IsReady() bool {
-	return w.connected.Load()
+	return w.connected.Load() && !w.resyncing.Load()
 }
 
 // Close 关闭客户端
@@ -106,11 +305,16 @@ func (w *WsClient) Close() {
 	default:
 		close(w.done)
 	}
-	w.mu.Lock()
-	if w.conn != nil {
-		_ = w.conn.Close()
+	if writer := w.currentWriter(); writer != nil {
+		writer.close()
 	}
-	w.mu.Unlock()
+}
+
+// currentWriter 返回当前连接的写协程，尚未连接时返回 nil
+func (w *WsClient) currentWriter() *wsWriter {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writer
 }
 
 // ---- 内部方法 ----
@@ -122,15 +326,23 @@ func (w *WsClient) dial() error {
 		return fmt.Errorf("[Bybit WS] 连接失败: %w", err)
 	}
 
+	writer := newWsWriter(conn)
 	w.mu.Lock()
-	w.conn = conn
+	w.writer = writer
 	w.mu.Unlock()
 
+	if w.apiKey != "" {
+		if err := w.sendAuth(writer); err != nil {
+			writer.close()
+			return fmt.Errorf("[Bybit WS] 发送鉴权失败: %w", err)
+		}
+	}
+
 	w.connected.Store(true)
-	log.Printf("[Bybit WS] 连接成功: %s", w.wsURL)
+	w.logger.Info("[Bybit WS] 连接成功", "url", w.wsURL)
 
-	go w.readLoop(conn)
-	go w.pingLoop(conn)
+	go w.readLoop(conn, writer)
+	go w.pingLoop(writer)
 	return nil
 }
 
@@ -143,7 +355,7 @@ func (w *WsClient) reconnectLoop() {
 		case <-w.reconnCh:
 			w.connected.Store(false)
 			count := w.reconnectCount.Add(1)
-			log.Printf("[Bybit WS] 检测到断线，第 %d 次重连，等待 %v ...", count, backoff)
+			w.logger.Warn("[Bybit WS] 检测到断线，等待后重连", "attempt", count, "backoff", backoff)
 
 			select {
 			case <-w.done:
@@ -152,7 +364,10 @@ func (w *WsClient) reconnectLoop() {
 			}
 
 			if err := w.dial(); err != nil {
-				log.Printf("[Bybit WS] 重连失败: %v", err)
+				w.logger.Warn("[Bybit WS] 重连失败", "err", err)
+				if n := w.failedDials.Add(1); n == w.maxDials.Load() {
+					w.logger.Error("[Bybit WS] 连续重连失败次数达到上限，标记为不可用", "attempts", n)
+				}
 				backoff *= 2
 				if backoff > bybitWsMaxBackoff {
 					backoff = bybitWsMaxBackoff
@@ -165,13 +380,15 @@ func (w *WsClient) reconnectLoop() {
 			}
 
 			backoff = bybitWsInitialBackoff
+			w.failedDials.Store(0)
 			w.resubscribeAll()
 		}
 	}
 }
 
-func (w *WsClient) readLoop(conn *websocket.Conn) {
+func (w *WsClient) readLoop(conn *websocket.Conn, writer *wsWriter) {
 	defer func() {
+		writer.close()
 		select {
 		case <-w.done:
 			return
@@ -195,7 +412,7 @@ func (w *WsClient) readLoop(conn *websocket.Conn) {
 			select {
 			case <-w.done:
 			default:
-				log.Printf("[Bybit WS] 读取错误（将触发重连）: %v", err)
+				w.logger.Warn("[Bybit WS] 读取错误（将触发重连）", "err", err)
 			}
 			return
 		}
@@ -203,14 +420,31 @@ func (w *WsClient) readLoop(conn *websocket.Conn) {
 		w.lastMsgAt.Store(time.Now())
 
 		// Bybit V5 消息格式：{"topic":"orderbook.1.BTCUSDT","type":"snapshot","data":{...}}
+		// 心跳回复格式：{"success":true,"ret_msg":"pong","req_id":"1","op":"ping"}
 		var envelope struct {
-			Topic string          `json:"topic"`
-			Type  string          `json:"type"`
-			Data  json.RawMessage `json:"data"`
+			Topic   string          `json:"topic"`
+			Type    string          `json:"type"`
+			Data    json.RawMessage `json:"data"`
+			Op      string          `json:"op"`
+			Success bool            `json:"success"`
+			RetMsg  string          `json:"ret_msg"`
+			ReqID   string          `json:"req_id"`
 		}
 		if err := json.Unmarshal(msg, &envelope); err != nil {
 			continue
 		}
+		if envelope.Op == "auth" {
+			if envelope.Success {
+				w.logger.Info("[Bybit WS] 私有频道鉴权成功")
+			} else {
+				w.logger.Error("[Bybit WS] 私有频道鉴权失败", "msg", envelope.RetMsg)
+			}
+			continue
+		}
+		if envelope.Op == "ping" || envelope.Op == "pong" || envelope.RetMsg == "pong" {
+			w.onPong(envelope.ReqID)
+			continue
+		}
 		if envelope.Topic == "" {
 			continue
 		}
@@ -218,7 +452,7 @@ func (w *WsClient) readLoop(conn *websocket.Conn) {
 		w.subsMu.RLock()
 		for _, s := range w.subs {
 			if s.topic == envelope.Topic {
-				s.cb(envelope.Data)
+				s.cb(envelope.Type, envelope.Data)
 				break
 			}
 		}
@@ -227,49 +461,108 @@ func (w *WsClient) readLoop(conn *websocket.Conn) {
 }
 
 // pingLoop 定时发送 Bybit 心跳（Bybit 要求发送 JSON ping）
-func (w *WsClient) pingLoop(conn *websocket.Conn) {
+// 超过 pingInterval + pongTimeout 未收到 pong 或任何消息时视为僵死连接，主动断线触发重连
+func (w *WsClient) pingLoop(writer *wsWriter) {
 	ticker := time.NewTicker(bybitWsPingInterval)
 	defer ticker.Stop()
+	dialedAt := time.Now()
 
 	for {
 		select {
 		case <-w.done:
 			return
 		case <-ticker.C:
-			ping := map[string]string{"op": "ping"}
-			w.mu.Lock()
-			err := conn.WriteJSON(ping)
-			w.mu.Unlock()
+			if idle := time.Since(w.lastAliveAt(dialedAt)); idle > bybitWsPingInterval+bybitWsPongTimeout {
+				w.logger.Warn("[Bybit WS] Pong 超时，主动断线触发重连", "idle", idle.Round(time.Second))
+				writer.close()
+				return
+			}
+
+			seq := fmt.Sprintf("%d", w.pingSeq.Add(1))
+			w.pingSentAt.Store(seq, time.Now())
+
+			ping := map[string]string{"op": "ping", "req_id": seq}
+			err := writer.writeJSON(ping)
 
 			if err != nil {
-				log.Printf("[Bybit WS] Ping 发送失败: %v", err)
+				w.logger.Warn("[Bybit WS] Ping 发送失败", "err", err)
 				return
 			}
 		}
 	}
 }
 
+// lastAliveAt 返回最近一次收到 pong 或任意消息的时间，都早于 since（本次连接建立时间）时返回 since
+func (w *WsClient) lastAliveAt(since time.Time) time.Time {
+	alive := since
+	for _, v := range []*atomic.Value{&w.lastPongAt, &w.lastMsgAt} {
+		if t, ok := v.Load().(time.Time); ok && t.After(alive) {
+			alive = t
+		}
+	}
+	return alive
+}
+
+// onPong 记录心跳回复时间，并根据 req_id 计算往返时延
+func (w *WsClient) onPong(reqID string) {
+	now := time.Now()
+	w.lastPongAt.Store(now)
+	if sentVal, ok := w.pingSentAt.LoadAndDelete(reqID); ok {
+		if sentTime, ok2 := sentVal.(time.Time); ok2 {
+			w.rtt.Store(int64(now.Sub(sentTime)))
+		}
+	}
+}
+
 func (w *WsClient) resubscribeAll() {
 	w.subsMu.RLock()
 	defer w.subsMu.RUnlock()
 	for _, s := range w.subs {
 		if err := w.sendSubscribe(s.topic); err != nil {
-			log.Printf("[Bybit WS] 恢复订阅 %s 失败: %v", s.topic, err)
+			w.logger.Warn("[Bybit WS] 恢复订阅失败", "topic", s.topic, "err", err)
 		} else {
-			log.Printf("[Bybit WS] 已恢复订阅: %s", s.topic)
+			w.logger.Info("[Bybit WS] 已恢复订阅", "topic", s.topic, "depth", s.depth)
 		}
 	}
 }
 
+// sendAuth 发送私有频道鉴权：signature = HMAC_SHA256(secret, "GET/realtime" + expires)
+func (w *WsClient) sendAuth(writer *wsWriter) error {
+	expires := time.Now().Add(10 * time.Second).UnixMilli()
+	mac := hmac.New(sha256.New, []byte(w.apiSecret))
+	mac.Write([]byte(fmt.Sprintf("GET/realtime%d", expires)))
+
+	msg := map[string]interface{}{
+		"op":   "auth",
+		"args": []interface{}{w.apiKey, expires, hex.EncodeToString(mac.Sum(nil))},
+	}
+	return writer.writeJSON(msg)
+}
+
+// resubscribe 取消并重新订阅频道，Bybit 会重新推送快照
+func (w *WsClient) resubscribe(topic string) error {
+	msg := map[string]interface{}{
+		"op":   "unsubscribe",
+		"args": []string{topic},
+	}
+	writer := w.currentWriter()
+	if writer == nil {
+		return fmt.Errorf("连接尚未建立")
+	}
+	if err := writer.writeJSON(msg); err != nil {
+		return err
+	}
+	return w.sendSubscribe(topic)
+}
+
 func (w *WsClient) sendSubscribe(topic string) error {
 	msg := map[string]interface{}{
 		"op":   "subscribe",
 		"args": []string{topic},
 	}
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	if w.conn == nil {
+	writer := w.currentWriter()
+	if writer == nil {
 		return fmt.Errorf("连接尚未建立")
 	}
-	return w.conn.WriteJSON(msg)
+	return writer.writeJSON(msg)
 }
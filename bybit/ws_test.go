@@ -0,0 +1,246 @@
+package bybit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestParsePriceLevel(t *testing.T) {
+	cases := []struct {
+		name        string
+		level       []string
+		price, size float64
+		wantErr     bool
+	}{
+		{"正常", []string{"100010.5", "0.25"}, 100010.5, 0.25, false},
+		{"科学计数法", []string{"1.0001e5", "2.5E-2"}, 100010, 0.025, false},
+		{"整数", []string{"100000", "1"}, 100000, 1, false},
+		{"价格为空", []string{"", "0.25"}, 0, 0, true},
+		{"数量为空", []string{"100010.5", ""}, 0, 0, true},
+		{"价格乱码", []string{"abc", "0.25"}, 0, 0, true},
+		{"数量乱码", []string{"100010.5", "0.2x"}, 0, 0, true},
+		{"前缀数字", []string{"100010.5USDT", "0.25"}, 0, 0, true},
+		{"缺少数量", []string{"100010.5"}, 0, 0, true},
+		{"空档位", nil, 0, 0, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			price, size, err := ParsePriceLevel(tc.level)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("ParsePriceLevel(%q) 应返回错误，得到 %v / %v", tc.level, price, size)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePriceLevel(%q) 返回错误: %v", tc.level, err)
+			}
+			if price != tc.price || size != tc.size {
+				t.Fatalf("ParsePriceLevel(%q) = %v / %v，期望 %v / %v", tc.level, price, size, tc.price, tc.size)
+			}
+		})
+	}
+}
+
+var benchLevel = []string{"100010.5", "0.25"}
+
+func BenchmarkParsePriceLevel(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, _, err := ParsePriceLevel(benchLevel); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSscanfPriceLevel 旧实现（fmt.Sscanf）作为对照
+func BenchmarkSscanfPriceLevel(b *testing.B) {
+	var price, size float64
+	for i := 0; i < b.N; i++ {
+		fmt.Sscanf(benchLevel[0], "%f", &price)
+		fmt.Sscanf(benchLevel[1], "%f", &size)
+	}
+}
+
+// tradeServer 模拟 Bybit 公共频道：每次收到订阅后推送一条逐笔成交，第一个连接推送后立即断开
+type tradeServer struct {
+	srv        *httptest.Server
+	conns      atomic.Int32
+	subscribed chan string
+}
+
+func newTradeServer(t *testing.T) *tradeServer {
+	ts := &tradeServer{subscribed: make(chan string, 8)}
+	upgrader := websocket.Upgrader{}
+	ts.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		n := ts.conns.Add(1)
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var req struct {
+				Op   string   `json:"op"`
+				Args []string `json:"args"`
+			}
+			if json.Unmarshal(msg, &req) != nil || req.Op != "subscribe" {
+				continue
+			}
+			ts.subscribed <- req.Args[0]
+			// 格式错误的推送应被跳过，不影响后续消息
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"topic":"publicTrade.BTCUSDT","type":"snapshot","data":{}}`))
+			side, price := "Buy", "100000.5"
+			if n > 1 {
+				side, price = "Sell", "99999"
+			}
+			conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(
+				`{"topic":"publicTrade.BTCUSDT","type":"snapshot","ts":1,"data":[{"T":1700000000000,"s":"BTCUSDT","S":"%s","v":"0.01","p":"%s"}]}`, side, price)))
+			if n == 1 {
+				return
+			}
+		}
+	}))
+	t.Cleanup(ts.srv.Close)
+	return ts
+}
+
+func (ts *tradeServer) url() string { return "ws" + strings.TrimPrefix(ts.srv.URL, "http") }
+
+// TestSubscribeTradesResubscribesAfterReconnect 逐笔成交订阅复用断线重连与恢复订阅
+func TestSubscribeTradesResubscribesAfterReconnect(t *testing.T) {
+	ts := newTradeServer(t)
+	w := NewWsClient(ts.url())
+	if err := w.Connect(); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer w.Close()
+
+	trades := make(chan WsTrade, 8)
+	if err := w.SubscribeTrades("BTCUSDT", func(tr *WsTrade) { trades <- *tr }); err != nil {
+		t.Fatalf("订阅失败: %v", err)
+	}
+
+	for i, want := range []WsTrade{
+		{Symbol: "BTCUSDT", Side: "Buy", Price: "100000.5", Size: "0.01", Ts: 1700000000000},
+		{Symbol: "BTCUSDT", Side: "Sell", Price: "99999", Size: "0.01", Ts: 1700000000000},
+	} {
+		select {
+		case topic := <-ts.subscribed:
+			if topic != "publicTrade.BTCUSDT" {
+				t.Fatalf("第 %d 次订阅频道 = %s", i+1, topic)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("等待第 %d 次订阅超时", i+1)
+		}
+		select {
+		case got := <-trades:
+			if got != want {
+				t.Fatalf("第 %d 笔成交 = %+v，期望 %+v", i+1, got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("等待第 %d 笔成交超时", i+1)
+		}
+	}
+	if n := w.Stats().ReconnectCount; n < 1 {
+		t.Fatalf("重连次数 = %d，期望至少 1", n)
+	}
+}
+
+// TestOrderBookResyncOnSequenceGap 增量序号缺口时取消并重新订阅，收到新快照后恢复推送合并后的订单簿
+func TestOrderBookResyncOnSequenceGap(t *testing.T) {
+	const topic = "orderbook.50.BTCUSDT"
+	ops := make(chan string, 8)
+	var subs atomic.Int32
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		send := func(typ string, u int, bids, asks string) {
+			conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(
+				`{"topic":%q,"type":%q,"ts":1,"data":{"s":"BTCUSDT","b":%s,"a":%s,"u":%d,"seq":%d}}`, topic, typ, bids, asks, u, u)))
+		}
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var req struct {
+				Op   string   `json:"op"`
+				Args []string `json:"args"`
+			}
+			if json.Unmarshal(msg, &req) != nil || len(req.Args) == 0 || req.Args[0] != topic {
+				continue
+			}
+			ops <- req.Op
+			if req.Op != "subscribe" {
+				continue
+			}
+			if subs.Add(1) == 1 {
+				send("snapshot", 100, `[["100000.1","1"],["100000.0","2"]]`, `[["100000.2","1"]]`)
+				send("delta", 101, `[["100000.1","0"]]`, `[]`)
+				send("delta", 103, `[["100000.15","1"]]`, `[]`) // 跳过 u=102
+			} else {
+				send("snapshot", 200, `[["100001.0","1"]]`, `[["100001.5","1"]]`)
+			}
+		}
+	}))
+	defer srv.Close()
+
+	w := NewWsClient("ws" + strings.TrimPrefix(srv.URL, "http"))
+	if err := w.Connect(); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer w.Close()
+
+	books := make(chan [2]string, 8)
+	if err := w.SubscribeOrderBook("BTCUSDT", 50, func(ob *WsOrderBook) {
+		books <- [2]string{ob.Bids[0][0], ob.Asks[0][0]}
+	}); err != nil {
+		t.Fatalf("订阅失败: %v", err)
+	}
+
+	for i, want := range [][2]string{
+		{"100000.1", "100000.2"}, // 快照
+		{"100000.0", "100000.2"}, // 删除买一
+		{"100001.0", "100001.5"}, // 缺口后的新快照；u=103 的增量不应推送
+	} {
+		select {
+		case got := <-books:
+			if got != want {
+				t.Fatalf("第 %d 次推送 买一 / 卖一 = %v，期望 %v", i+1, got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("等待第 %d 次订单簿推送超时", i+1)
+		}
+	}
+
+	var got []string
+	for len(ops) > 0 {
+		got = append(got, <-ops)
+	}
+	if want := []string{"subscribe", "unsubscribe", "subscribe"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("订阅操作 = %v，期望 %v", got, want)
+	}
+	st := w.Stats()
+	if st.SeqGaps != 1 || st.Resyncing || !w.IsReady() {
+		t.Fatalf("序号缺口 = %d，重新同步中 = %v，期望 1 / false", st.SeqGaps, st.Resyncing)
+	}
+	if st.ReconnectCount != 0 {
+		t.Fatalf("序号缺口不应触发重连，重连次数 = %d", st.ReconnectCount)
+	}
+}
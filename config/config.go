@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 
 	"gopkg.in/yaml.v3"
@@ -14,13 +15,23 @@ type Config struct {
 	// Bybit（B所）配置
 	Bybit BybitConfig `yaml:"bybit"`
 
+	// Binance U 本位合约配置（exchange_a / exchange_b 选择 binance 时使用）
+	Binance BinanceConfig `yaml:"binance"`
+
 	// Apex 交易对，例如 BTC-USDC
 	ApexSymbol string `yaml:"apex_symbol"`
 
 	// Bybit 交易对，例如 BTCUSDT
 	BybitSymbol string `yaml:"bybit_symbol"`
 
-	// 运行模式：1=模型一（被动价差套利），2=模型二（联动推价套利）
+	// Binance 交易对，例如 BTCUSDT
+	BinanceSymbol string `yaml:"binance_symbol"`
+
+	// A所（流动性来源）与 B所（对冲）使用的交易所：apex | bybit | binance，默认 apex / bybit，两者不能相同
+	ExchangeA string `yaml:"exchange_a"`
+	ExchangeB string `yaml:"exchange_b"`
+
+	// 运行模式：0=只记录行情，1=模型一（被动价差套利），2=模型二（联动推价套利），9=回测；未配置时为 1
 	Mode int `yaml:"mode"`
 
 	// 模型一套利策略参数
@@ -31,6 +42,48 @@ type Config struct {
 
 	// 风控参数
 	RiskControl RiskConfig `yaml:"risk_control"`
+
+	// 套利机会推送（供外部执行系统消费）
+	Opportunity OpportunityConfig `yaml:"opportunity"`
+
+	// 日终维护任务
+	Hygiene HygieneConfig `yaml:"hygiene"`
+
+	// 交易时段：定时平仓与恢复开仓
+	Session SessionConfig `yaml:"session"`
+
+	// 计价币汇率：A所（USDC）价格换算为 B所计价币（USDT）
+	QuoteRate QuoteRateConfig `yaml:"quote_rate"`
+
+	// REST 瞬时错误重试策略（两所共用）
+	RestRetry RetryConfig `yaml:"rest_retry"`
+
+	// REST 单次请求超时（两所共用）
+	RestTimeout TimeoutConfig `yaml:"rest_timeout"`
+
+	// REST 本地限频（两所共用，各所独立计数）
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+
+	// Prometheus 指标服务
+	Metrics MetricsConfig `yaml:"metrics"`
+
+	// 远程管理接口
+	Admin AdminConfig `yaml:"admin"`
+
+	// 关键事件告警推送
+	Alerts AlertsConfig `yaml:"alerts"`
+
+	// 交易流水（SQLite）
+	Journal JournalConfig `yaml:"journal"`
+
+	// 行情记录（NDJSON）
+	Recorder RecorderConfig `yaml:"recorder"`
+
+	// 回测（mode: 9 或 -backtest 命令行参数）
+	Backtest BacktestConfig `yaml:"backtest"`
+
+	// 日志级别与输出格式
+	Logging LoggingConfig `yaml:"logging"`
 }
 
 // ApexConfig Apex Pro REST/WS 接口配置（A所）
@@ -40,6 +93,9 @@ type ApexConfig struct {
 	APIKey     string `yaml:"api_key"`
 	APISecret  string `yaml:"api_secret"`
 	Passphrase string `yaml:"passphrase"`
+
+	// 行情中断处置策略
+	FeedLoss FeedLossPolicy `yaml:"feed_loss"`
 }
 
 // BybitConfig Bybit REST/WS 接口配置（B所）
@@ -48,20 +104,144 @@ type BybitConfig struct {
 	WsURL     string `yaml:"ws_url"`
 	APIKey    string `yaml:"api_key"`
 	APISecret string `yaml:"api_secret"`
+
+	// 私有 WS 地址（成交推送），为空时对冲成交通过 REST 查询
+	PrivateWsURL string `yaml:"private_ws_url"`
+
+	// 杠杆倍数，启动时设置到交易对的买/卖两个方向；0 表示不修改交易所当前设置
+	Leverage float64 `yaml:"leverage"`
+
+	// 持仓模式：one_way（单向，默认）/ hedge（双向），启动时切换到该模式，下单按模式填写 positionIdx
+	// 套利逻辑按单向净持仓计算；双向模式下开仓单进入对应方向仓位，reduce-only 单平掉反向仓位
+	PositionMode string `yaml:"position_mode"`
+
+	// 行情中断处置策略
+	FeedLoss FeedLossPolicy `yaml:"feed_loss"`
+}
+
+// Bybit 持仓模式（bybit.position_mode 的取值）
+const (
+	PositionModeOneWay = "one_way"
+	PositionModeHedge  = "hedge"
+)
+
+// BinanceConfig Binance U 本位合约 REST/WS 接口配置
+type BinanceConfig struct {
+	BaseURL   string `yaml:"base_url"`
+	WsURL     string `yaml:"ws_url"` // 组合流地址，例如 wss://fstream.binance.com/stream
+	APIKey    string `yaml:"api_key"`
+	APISecret string `yaml:"api_secret"`
+
+	// 杠杆倍数（整数），启动时设置到交易对；0 表示不修改交易所当前设置
+	Leverage int `yaml:"leverage"`
+
+	// 行情中断处置策略
+	FeedLoss FeedLossPolicy `yaml:"feed_loss"`
+}
+
+// 对冲腿下单方式
+const (
+	HedgeOrderLimit  = "limit"  // 按报价 IOC 限价
+	HedgeOrderMarket = "market" // 按最新盘口加滑点上限的保护价 IOC 吃单
+)
+
+// A所 maker 挂单未完全成交时的处置
+const (
+	MakerFallbackIOC    = "ioc"    // 剩余部分按原报价 IOC 吃单
+	MakerFallbackCancel = "cancel" // 撤单后放弃剩余部分，按已成交部分对冲
+)
+
+// A所开仓腿的有效方式
+const (
+	TimeInForceIOC = "ioc" // 立即成交，剩余撤销
+	TimeInForceGTC = "gtc" // 挂单直到成交、撤销或到期（Apex 为 GTT）
+)
+
+// 资金费结算前的处置动作
+const (
+	FundingActionNone    = "none"    // 只记录资金费率
+	FundingActionReduce  = "reduce"  // 按 funding_reduce_ratio 减仓
+	FundingActionFlatten = "flatten" // 全部平仓
+)
+
+// 行情中断处置动作
+const (
+	FeedLossPause     = "pause"                 // 暂停开仓，保留现有持仓
+	FeedLossFlatten   = "flatten_after_seconds" // 暂停开仓，超过 flatten_after_sec 后双腿平仓
+	FeedLossHedgeElse = "hedge_elsewhere"       // 暂停开仓，失联所腿无法处理时把对冲转移到健康所
+)
+
+// FeedLossPolicy 单个交易所行情中断（断线或长时间无有效行情）时的处置策略
+type FeedLossPolicy struct {
+	// 处置动作：pause | flatten_after_seconds | hedge_elsewhere
+	OnFeedLoss string `yaml:"on_feed_loss"`
+
+	// 超过该时长（秒）未收到有效行情即视为中断
+	TimeoutSec int `yaml:"timeout_sec"`
+
+	// flatten_after_seconds 模式下，中断持续超过该时长（秒）后平仓；0 使用默认 30
+	FlattenAfterSec int `yaml:"flatten_after_sec"`
+
+	// WS 连续重连失败达到该次数时视为交易所接口不可用，引擎触发风控熔断并告警；0 不限制（一直重试）
+	MaxReconnectAttempts int `yaml:"max_reconnect_attempts"`
 }
 
 // StrategyConfig 套利策略参数
 type StrategyConfig struct {
-	// 触发套利的最小价差（USDC）
+	// 触发套利的最小价差（USDC，扣除两腿手续费后的净价差）
 	MinSpreadUSDC float64 `yaml:"min_spread_usdc"`
 
+	// 触发套利的最小净价差（基点，净价差 / 两所成交价中间价 × 10000），0 不启用；与 min_spread_usdc 都非 0 时须同时满足
+	MinSpreadBps float64 `yaml:"min_spread_bps"`
+
+	// 按波动率动态调整开仓阈值：有效阈值 = max(min_spread_usdc, vol_multiplier × sigma)，
+	// sigma 为 vol_window_sec 内 A所中间价每秒变化的标准差；vol_multiplier 为 0 不启用，样本不足时使用 min_spread_usdc
+	VolMultiplier float64 `yaml:"vol_multiplier"`
+	VolWindowSec  int     `yaml:"vol_window_sec"`
+
+	// 价差回归平仓：持有多头且反向毛价差 apexBid - bybitAsk ≥ unwind_spread_usdc 时（空头对称），两腿以 reduce-only 平仓
+	// 阈值为未扣手续费的毛价差，可为 0 或小幅负数；平仓盈亏与开仓盈亏分开统计
+	Unwind           bool    `yaml:"unwind"`
+	UnwindSpreadUSDC float64 `yaml:"unwind_spread_usdc"`
+	// 平仓阈值（基点），0 不启用；与 unwind_spread_usdc 都非 0 时须同时满足
+	UnwindSpreadBps float64 `yaml:"unwind_spread_bps"`
+
+	// B所与 A所的数量比例：每 1 单位 A所数量对应的 B所数量（合约乘数不同时，如 1000PEPEUSDT 对 PEPE-USDC 为 0.001），默认 1
+	// 引擎内部统一使用 A所单位，B所盘口、持仓与成交按比例换算，下单时换算回 B所单位并按 B所步长独立取整
+	SizeRatioBybitPerApex *float64 `yaml:"size_ratio_bybit_per_apex"`
+
+	// 开平仓价差带（可选，按开仓方向的价差表示）：entry_spread_usdc 覆盖 min_spread_usdc；
+	// 配置 exit_spread_usdc 时开启 unwind，开仓方向的毛价差回落到 exit 以下（即反向毛价差 ≥ -exit）时平仓，要求 exit < entry
+	EntrySpreadUSDC *float64 `yaml:"entry_spread_usdc"`
+	ExitSpreadUSDC  *float64 `yaml:"exit_spread_usdc"`
+
+	// Apex taker 手续费率（例如 0.0005 = 0.05%）
+	ApexTakerFeeRate float64 `yaml:"apex_taker_fee_rate"`
+
+	// Bybit taker 手续费率（例如 0.00055 = 0.055%）
+	BybitTakerFeeRate float64 `yaml:"bybit_taker_fee_rate"`
+
+	// Binance taker 手续费率（例如 0.0005 = 0.05%）
+	BinanceTakerFeeRate float64 `yaml:"binance_taker_fee_rate"`
+
 	// 单笔下单量（合约张数）
 	OrderSize float64 `yaml:"order_size"`
 
 	// 最大净持仓量（合约张数）
 	MaxPosition float64 `yaml:"max_position"`
 
-	// 套利方向检查间隔（毫秒）
+	// 分方向最大持仓（合约张数）：多头（A所买入方向）/ 空头（A所卖出方向）上限，0 表示使用 max_position
+	MaxLongPosition  float64 `yaml:"max_long_position"`
+	MaxShortPosition float64 `yaml:"max_short_position"`
+
+	// 是否允许对应方向开仓，未配置时为 true；关闭后反方向（减仓）机会不受影响
+	EnableLong  *bool `yaml:"enable_long"`
+	EnableShort *bool `yaml:"enable_short"`
+
+	// 交易所最小下单量（合约张数）：按盘口深度限制后低于此值放弃机会，Apex 成交量低于此值时不对冲
+	MinOrderSize float64 `yaml:"min_order_size"`
+
+	// 兜底检查间隔（毫秒），行情更新时会立即检查
 	CheckIntervalMs int `yaml:"check_interval_ms"`
 
 	// 盈利目标（USDC）
@@ -70,17 +250,193 @@ type StrategyConfig struct {
 	// 止损（USDC）
 	StopLossUSDC float64 `yaml:"stop_loss_usdc"`
 
-	// 价格精度（小数位数）
+	// 按 B所账户权益百分比设置的盈利目标与止损（如 2 = 权益的 2%），设置后优先于 take_profit_usdc / stop_loss_usdc；0 表示不启用
+	// 基准为本次运行首次取得的权益（不随盈亏变化），与本次运行的盈亏比较（不含状态文件恢复的历史盈亏）；
+	// 权益尚未取得时按 USDC 绝对值判断（与累计PnL比较），USDC 值也为 0 时暂不检查
+	TakeProfitPct float64 `yaml:"take_profit_pct"`
+	StopLossPct   float64 `yaml:"stop_loss_pct"`
+
+	// 价格精度（小数位数），仅在无法从交易所获取交易对规格时使用
 	PricePrecision int `yaml:"price_precision"`
 
-	// 数量精度（小数位数）
+	// 数量精度（小数位数），仅在无法从交易所获取交易对规格时使用
 	SizePrecision int `yaml:"size_precision"`
 
 	// 对冲模式：true=双腿对冲，false=单腿
 	HedgeMode bool `yaml:"hedge_mode"`
 
+	// 先下 B所对冲腿并确认成交，再按实际对冲成交量下 A所腿（仅 hedge_mode 生效）
+	// 执行风险由 A所承担：A所腿未完全成交时重试，仍失败则平掉 B所腿；适合 B所流动性更好的市场
+	HedgeFirst bool `yaml:"hedge_first"`
+
 	// 对冲滑点容忍（USDC）
 	HedgeSlippageUSDC float64 `yaml:"hedge_slippage_usdc"`
+
+	// 对冲滑点容忍（基点，相对对手价），0 不启用；与 hedge_slippage_usdc 都配置时取较小者
+	HedgeSlippageBps float64 `yaml:"hedge_slippage_bps"`
+
+	// 对冲腿下单方式：limit=按报价 IOC 限价（默认），market=按最新盘口加 slippage_tolerance_usdc 的保护价 IOC 吃单，优先保证成交
+	HedgeOrderType string `yaml:"hedge_order_type"`
+
+	// A所 maker 模式：开仓腿先以 POST_ONLY 挂在己方最优价（买单挂买一、卖单挂卖一）赚取 maker 返佣，
+	// 等待 apex_maker_wait_ms 后撤单，剩余部分按 apex_maker_fallback 处置：ioc（默认）| cancel
+	// hedge_first 模式下 A所腿仍使用 IOC
+	ApexMakerMode     bool   `yaml:"apex_maker_mode"`
+	ApexMakerWaitMs   int    `yaml:"apex_maker_wait_ms"`
+	ApexMakerFallback string `yaml:"apex_maker_fallback"`
+
+	// A所开仓腿的有效方式：ioc（默认，立即成交剩余撤销）| gtc（挂单，Apex 映射为 GTT），
+	// gtc 时按 apex_maker_wait_ms 等待成交后撤单；apex_maker_mode 使用 POST_ONLY 挂单，不能配置为 ioc
+	TimeInForce string `yaml:"time_in_force"`
+
+	// A所挂单（gtc / maker 模式）在交易所的有效期（秒），到期由交易所自动撤销，作为撤单失败时的兜底；0 使用交易所默认有效期
+	ExpirySeconds int `yaml:"expiry_seconds"`
+
+	// market 对冲模式下允许偏离最新盘口的最大滑点（USDC），0 时使用 hedge_slippage_usdc
+	SlippageToleranceUSDC float64 `yaml:"slippage_tolerance_usdc"`
+
+	// 对冲失败后的重试次数（用最新 Bybit 报价），全部失败则平掉 Apex 腿
+	HedgeRetryCount int `yaml:"hedge_retry_count"`
+
+	// 对冲重试间隔（毫秒）
+	HedgeRetryDelayMs int `yaml:"hedge_retry_delay_ms"`
+
+	// 监控模式：只检测并推送套利机会，不下单
+	MonitorOnly bool `yaml:"monitor_only"`
+
+	// 最小成交量（合约张数）：按盘口深度与可盈利深度限制后的下单量低于此值时放弃机会
+	MinFillSize float64 `yaml:"min_fill_size"`
+
+	// 计算可执行价格时使用的订单簿档位数（1=只用最优一档，最大 50）
+	// 下单量超过最优档挂单量时逐档计算 VWAP，最差档偏离最优价超过 HedgeSlippageUSDC 则放弃
+	BookLevels int `yaml:"book_levels"`
+
+	// 订单簿订阅档位（Bybit 支持 1/50/200/500，Apex 支持 1~200），0 按 book_levels 自动选择（1 档或 50 档）
+	// 不能小于 book_levels；断线重连后按同一档位恢复订阅
+	OrderbookDepth int `yaml:"orderbook_depth"`
+
+	// 盘口失衡过滤：开仓方向要吃的一侧（场景1 为 A所卖盘与 B所买盘）在前 imbalance_levels 档挂单量中的占比
+	// 都不低于 min_imbalance_confirm 才开仓，过滤对手盘即将被扫空时出现的价差；imbalance_filter 为 false 时不过滤
+	ImbalanceFilter     bool    `yaml:"imbalance_filter"`
+	MinImbalanceConfirm float64 `yaml:"min_imbalance_confirm"`
+	ImbalanceLevels     int     `yaml:"imbalance_levels"` // 0 使用 book_levels 档全部
+
+	// 动量过滤：开仓方向买入的交易所（场景1 为 A所，场景2 为 B所）最近 adverse_flow_window_sec 秒内
+	// 主动卖出减主动买入的名义价值超过 max_adverse_flow_usdc 时放弃机会；0 不启用，需交易所支持逐笔成交推送
+	MaxAdverseFlowUSDC   float64 `yaml:"max_adverse_flow_usdc"`
+	AdverseFlowWindowSec int     `yaml:"adverse_flow_window_sec"` // 默认 5
+
+	// 账户信息刷新间隔（毫秒），风控检查读取缓存值；默认 5000
+	// 连续 3 次刷新失败或超过 3 个周期未更新时暂停开仓
+	AccountRefreshMs int `yaml:"account_refresh_ms"`
+
+	// 盘口最大有效时长（毫秒），任一所超过此时长未更新则不检测、不下单；0 使用默认 2000，<0 表示不检查
+	MaxQuoteAgeMs int `yaml:"max_quote_age_ms"`
+
+	// 单次行情更新允许的最大中间价跳变（百分比，如 2 = 2%），超过时丢弃该更新并保留上一次有效盘口；0 表示不检查
+	// 连续 3 次跳变时视为真实行情变化并接受
+	MaxPriceJumpPct float64 `yaml:"max_price_jump_pct"`
+
+	// 盘口中间价偏离最新成交价的最大百分比（如 0.5 = 0.5%），任一所超过时不检测套利机会；0 表示不检查
+	// 依赖逐笔成交推送，成交价超过 1 分钟未更新时不参与检查
+	MaxTradeDeviationPct float64 `yaml:"max_trade_deviation_pct"`
+
+	// WS 未就绪时通过 REST 轮询最优价的间隔（毫秒），保持降级的行情视图；0 使用默认 2000，<0 表示不启用
+	RestFallbackIntervalMs int `yaml:"rest_fallback_interval_ms"`
+
+	// 是否允许使用 REST 兜底行情交易（仅一档深度），默认 false 只用于状态展示
+	AllowRestTrading bool `yaml:"allow_rest_trading"`
+
+	// 启动时通过 REST 比较两所中间价，偏差超过 symbol_price_diff_pct（百分比，0 使用默认 5）时拒绝启动，防止交易对配置错误
+	// 两所交易对确为不同价格标的时设置 skip_symbol_check 跳过
+	SymbolPriceDiffPct float64 `yaml:"symbol_price_diff_pct"`
+	SkipSymbolCheck    bool    `yaml:"skip_symbol_check"`
+
+	// 同方向两次开仓之间的最小间隔（毫秒），冷却期内跳过同方向机会，反方向（减仓）不受影响；0 表示不限制
+	// 冷却状态只保存在内存，重启后重新计时
+	TradeCooldownMs int `yaml:"trade_cooldown_ms"`
+
+	// 任意两笔交易之间的最小间隔（毫秒），不区分方向，独立于 check_interval_ms；0 表示不限制
+	// 用于薄盘口：价差持续存在时避免按检测周期连续下单、迅速加满 max_position
+	MinTradeIntervalMs int `yaml:"min_trade_interval_ms"`
+
+	// 两腿下单返回时间差上限（毫秒），超过后暂停开仓 leg_latency_pause_sec 秒；0 表示只统计不限制
+	MaxLegLatencyMs int `yaml:"max_leg_latency_ms"`
+
+	// 两腿时间差超限后的暂停时长（秒），默认 60
+	LegLatencyPauseSec int `yaml:"leg_latency_pause_sec"`
+
+	// 资金费率查询间隔（秒），0 使用默认 60，负数不启用
+	FundingCheckIntervalSec int `yaml:"funding_check_interval_sec"`
+
+	// 资金费结算前的处置窗口（秒），默认 600
+	FundingWindowSec int `yaml:"funding_window_sec"`
+
+	// 窗口内资金费方向对当前持仓不利时的处置：none（只记录）/ reduce（按 funding_reduce_ratio 减仓）/ flatten（平仓）
+	// reduce / flatten 时窗口内同时暂停开仓
+	FundingAction string `yaml:"funding_action"`
+
+	// reduce 动作的减仓比例（0~1），默认 0.5
+	FundingReduceRatio float64 `yaml:"funding_reduce_ratio"`
+
+	// 开仓判断计入资金费：净价差扣除持有 max_holding_seconds 期间（未配置时为持有到下一次结算）预计支付的资金费后
+	// 再与 min_spread_usdc 比较；false 保持原有行为。依赖资金费率查询（funding_check_interval_sec 不为负）
+	FundingAware bool `yaml:"funding_aware"`
+
+	// 两所真实持仓核对间隔（秒，仅对冲模式），0 使用默认 30，负数不启用
+	ImbalanceCheckIntervalSec int `yaml:"imbalance_check_interval_sec"`
+
+	// 最长持仓时间（秒）：净持仓从建立起超过该时长后，不论价差以 reduce-only 市价单平掉两所持仓；0 表示不限制
+	MaxHoldingSeconds int `yaml:"max_holding_seconds"`
+
+	// 两所净持仓之和的允许上限（合约张数），超过时暂停开仓并告警；0 表示任何偏差（≥ 一个数量步长）都暂停
+	MaxImbalance float64 `yaml:"max_imbalance"`
+
+	// 两所净持仓之和按中间价折算的名义敞口上限（USDC），超过时同样暂停开仓并告警；0 表示只按 max_imbalance 判断
+	MaxUnhedgedNotionalUSDC float64 `yaml:"max_unhedged_notional_usdc"`
+
+	// 名义敞口超过 max_unhedged_notional_usdc 时自动以 reduce-only 市价单在持有多余敞口的一所补对冲
+	AutoRehedge bool `yaml:"auto_rehedge"`
+
+	// 本地持仓对账间隔（秒）：比较本地记录的持仓与交易所真实持仓，0 使用默认 60，负数不启用
+	// reconcile_autocorrect 为 true 时以交易所持仓修正本地记录；连续 reconcile_halt_count 次（0 使用默认 3，负数不熔断）不一致时触发风控熔断
+	ReconcileIntervalSec int  `yaml:"reconcile_interval_sec"`
+	ReconcileAutocorrect bool `yaml:"reconcile_autocorrect"`
+	ReconcileHaltCount   int  `yaml:"reconcile_halt_count"`
+
+	// 引擎状态文件路径（JSON），为空时不持久化
+	// 每笔交易后写入累计PnL与持仓，重启时恢复累计PnL（止盈/止损继续生效），并与交易所持仓核对
+	StateFile string `yaml:"state_file"`
+
+	// 自定义订单ID前缀（最长 8 个字符），多个实例共用账户时设置为不同值避免冲突；默认 "arb"
+	ClientIDPrefix string `yaml:"client_id_prefix"`
+
+	// 停止时平仓：撤单后按两所真实持仓下 reduce-only 市价单平仓，并等待持仓归零确认
+	FlattenOnStop bool `yaml:"flatten_on_stop"`
+
+	// 启动时平仓：启动核对（撤销遗留挂单、记录两所持仓）后以 reduce-only 市价单平掉两所已有持仓，再开始交易
+	StartupFlatten bool `yaml:"startup_flatten"`
+
+	// 停止平仓总超时（秒），0 使用默认 15
+	FlattenTimeoutSec int `yaml:"flatten_timeout_sec"`
+}
+
+// OpportunityConfig 套利机会推送配置
+type OpportunityConfig struct {
+	// 是否开启本地监听推送
+	Enabled bool `yaml:"enabled"`
+
+	// 监听类型：unix / tcp
+	Network string `yaml:"network"`
+
+	// 监听地址：unix 为 socket 文件路径，tcp 为 host:port
+	Address string `yaml:"address"`
+
+	// 每个消费者的缓冲条数，满时丢弃最旧的消息
+	BufferSize int `yaml:"buffer_size"`
+
+	// 接近阈值推送比例：价差 >= min_spread_usdc × 该比例时也推送（0=只推送可执行机会）
+	NearMissRatio float64 `yaml:"near_miss_ratio"`
 }
 
 // Model2Config 模型二策略参数（跨交易所联动套利 + 做市商被动抬价）
@@ -122,6 +478,185 @@ type Model2Config struct {
 	CheckIntervalMs int `yaml:"check_interval_ms"`
 }
 
+// HygieneConfig 日终维护任务配置
+type HygieneConfig struct {
+	// 是否开启日终维护
+	Enabled bool `yaml:"enabled"`
+
+	// 每日执行时间（risk_control.reset_timezone 时区，HH:MM），应早于日切以覆盖完整交易日
+	RunAt string `yaml:"run_at"`
+
+	// 挂单超过该时长（秒）视为过期并撤销
+	StaleOrderSec int `yaml:"stale_order_sec"`
+
+	// 本地与交易所持仓偏差不超过该值（合约张数）时自动以交易所为准修正
+	MaxRepairDelta float64 `yaml:"max_repair_delta"`
+
+	// 当日交易流水归档与状态文件快照的输出目录，默认 archive
+	ArchiveDir string `yaml:"archive_dir"`
+
+	// 交易流水数据库保留最近 N 个交易日（含当日），更早的记录在归档后删除并 VACUUM；0 表示不删除（仍执行 VACUUM）
+	JournalRetentionDays int `yaml:"journal_retention_days"`
+}
+
+// SessionConfig 交易时段：到达 flatten_at 时停止开仓、撤销两所挂单、reduce-only 平仓并输出时段汇总，
+// 之后保持暂停，直到人工恢复（POST /resume）或到达 resume_at
+type SessionConfig struct {
+	// 定时平仓时间列表（HH:MM），为空时不启用
+	FlattenAt []string `yaml:"flatten_at"`
+
+	// 定时平仓后自动恢复开仓的时间列表（HH:MM），为空时只能人工恢复
+	ResumeAt []string `yaml:"resume_at"`
+
+	// flatten_at / resume_at 使用的时区（IANA 名称，如 Asia/Shanghai），为空时使用本地时区
+	Timezone string `yaml:"timezone"`
+}
+
+// QuoteRateSourceBybitSpot 定时从 Bybit 现货 USDCUSDT 最新成交价获取汇率
+const QuoteRateSourceBybitSpot = "bybit_spot"
+
+// QuoteRateConfig 计价币汇率：两所以不同稳定币计价（Apex USDC、Bybit USDT）时，
+// 将 A所价格按 USDC/USDT 汇率换算为 USDT 后再计算价差与盈亏；usdc_usdt_rate 与 source 二选一，都不配置时按 1:1
+type QuoteRateConfig struct {
+	// 固定汇率（1 USDC 兑换的 USDT），>0 时启用
+	USDCUSDTRate float64 `yaml:"usdc_usdt_rate"`
+
+	// 汇率来源：bybit_spot 定时查询 Bybit 现货 USDCUSDT，为空不启用
+	Source string `yaml:"source"`
+
+	// 汇率刷新间隔（秒），0 使用默认值 60
+	RefreshSec int `yaml:"refresh_sec"`
+
+	// 汇率超过该秒数未刷新成功时暂停开仓，0 使用默认值 300
+	MaxAgeSec int `yaml:"max_age_sec"`
+}
+
+// RetryConfig REST 请求重试策略，只重试网络错误、HTTP 5xx 与 429，不重试业务拒单
+// 下单请求自动携带自定义订单ID，重试不会重复下单
+type RetryConfig struct {
+	// 最大尝试次数（含首次），<=1 表示不重试
+	MaxAttempts int `yaml:"max_attempts"`
+
+	// 首次重试前的等待（毫秒），之后每次翻倍
+	BaseDelayMs int `yaml:"base_delay_ms"`
+
+	// 单次等待上限（毫秒），0 表示不限制
+	MaxDelayMs int `yaml:"max_delay_ms"`
+
+	// 随机抖动比例（0~1）
+	Jitter float64 `yaml:"jitter"`
+}
+
+// TimeoutConfig REST 单次请求超时，超时按瞬时错误重试；0 表示只受 10 秒总超时限制
+type TimeoutConfig struct {
+	// 下单/撤单超时（毫秒），对冲腿挂起过久风险极大，应远小于查询超时
+	OrderMs int `yaml:"order_ms"`
+
+	// 账户、持仓、订单等查询超时（毫秒）
+	QueryMs int `yaml:"query_ms"`
+}
+
+// RateLimitConfig REST 本地限频（令牌桶），按接口分组限制每秒请求数，<=0 表示不限制
+type RateLimitConfig struct {
+	// 行情接口（订单簿、交易对信息）每秒请求数
+	MarketRPS float64 `yaml:"market_rps"`
+
+	// 订单接口（下单、撤单、订单查询）每秒请求数
+	OrderRPS float64 `yaml:"order_rps"`
+
+	// 账户接口（余额、持仓）每秒请求数
+	AccountRPS float64 `yaml:"account_rps"`
+
+	// 令牌不足时最长等待（毫秒），超过则放弃请求并返回本地限频错误；0 表示一直等待
+	MaxWaitMs int `yaml:"max_wait_ms"`
+
+	// Bybit 响应头 X-Bapi-Limit-Status 剩余额度低于此值时，该分组暂停到额度重置；0 表示不按响应头退避
+	BybitMinRemaining int `yaml:"bybit_min_remaining"`
+}
+
+// MetricsConfig Prometheus 指标服务配置，启用后在 Address 上提供 /metrics
+type MetricsConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// 监听地址，例如 ":9100"
+	Address string `yaml:"address"`
+}
+
+// AdminConfig 管理 HTTP 接口配置：GET /status、POST /pause、/resume、/risk/reset
+type AdminConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// 监听地址，例如 "127.0.0.1:9200"
+	Address string `yaml:"address"`
+
+	// 修改类接口的鉴权 token（请求头 Authorization: Bearer <token>），为空时只开放 /status
+	Token string `yaml:"token"`
+}
+
+// AlertsConfig 关键事件告警（风控熔断、对冲失败、止盈/止损、行情中断、WS 频繁重连）
+type AlertsConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// 最低发送级别：info | warning | critical，默认 warning
+	MinSeverity string `yaml:"min_severity"`
+
+	// Telegram Bot token 与会话 ID，两者都配置时启用
+	TelegramToken  string `yaml:"telegram_token"`
+	TelegramChatID string `yaml:"telegram_chat_id"`
+
+	// 通用 Webhook 地址（POST JSON），为空时不启用
+	WebhookURL string `yaml:"webhook_url"`
+
+	// 同一类告警的最小发送间隔（秒），期间重复的告警被抑制；默认 300
+	MinIntervalSec int `yaml:"min_interval_sec"`
+
+	// WS 频繁重连告警：reconnect_storm_minutes 分钟内重连超过 reconnect_storm_count 次时告警；默认 5 次 / 5 分钟
+	ReconnectStormCount   int `yaml:"reconnect_storm_count"`
+	ReconnectStormMinutes int `yaml:"reconnect_storm_minutes"`
+}
+
+// JournalConfig 交易流水配置：每次套利尝试写入一条 SQLite 记录，可用 arb report 查看日汇总
+type JournalConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// SQLite 数据库文件路径
+	Path string `yaml:"path"`
+
+	// 写入队列长度，队列满时丢弃记录（不阻塞下单）；默认 1024
+	BufferSize int `yaml:"buffer_size"`
+}
+
+// RecorderConfig 行情记录配置：两所最优买卖价按小时滚动写入 NDJSON 文件
+type RecorderConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// 输出目录，文件名为 md-YYYYMMDD-HH.ndjson
+	Path string `yaml:"path"`
+
+	// 单个文件大小上限（MB），超过后在同一小时内滚动为 md-YYYYMMDD-HH.1.ndjson；0 表示只按小时滚动
+	MaxFileMB int `yaml:"max_file_mb"`
+
+	// 写入队列长度，磁盘写入跟不上时丢弃记录（不阻塞行情接收）；默认 8192
+	BufferSize int `yaml:"buffer_size"`
+}
+
+// BacktestConfig 回测配置：回放 recorder 记录的两所盘口，按与实盘相同的决策逻辑模拟交易
+type BacktestConfig struct {
+	// A所 / B所行情记录文件（可以是同一个文件，按交易所名称筛选）
+	FileA string `yaml:"file_a"`
+	FileB string `yaml:"file_b"`
+
+	// 决策到成交的模拟延迟（毫秒），成交按延迟后的盘口计算；0 表示按决策时盘口立即成交
+	LatencyMs int `yaml:"latency_ms"`
+
+	// 两所 taker 手续费率，0 时使用 strategy 中对应交易所的费率
+	FeeRateA float64 `yaml:"fee_rate_a"`
+	FeeRateB float64 `yaml:"fee_rate_b"`
+
+	// 逐笔交易 CSV 输出路径，为空时不输出
+	OutputCSV string `yaml:"output_csv"`
+}
+
 // RiskConfig 风控配置
 type RiskConfig struct {
 	// 单日最大亏损（USDC）
@@ -132,6 +667,19 @@ type RiskConfig struct {
 
 	// 账户最低余额（USDC）
 	MinBalanceUSDC float64 `yaml:"min_balance_usdc"`
+
+	// 最大名义敞口（USDC）：(|净持仓| + 单笔下单量) × 中间价超过此值时拒绝开仓，0 表示不限制
+	MaxNotionalUSDC float64 `yaml:"max_notional_usdc"`
+
+	// 熔断后的冷却时长（秒）：冷却期内即使人工重置或日切也拒绝开仓，0 表示不冷却
+	CooldownSeconds int `yaml:"cooldown_seconds"`
+
+	// 当日统计重置所用时区（IANA 名称，如 "UTC"、"Asia/Shanghai"），默认 UTC
+	ResetTimezone string `yaml:"reset_timezone"`
+
+	// 风控状态文件路径（JSON），为空时不持久化
+	// 重启后恢复当日PnL、连续亏损次数与熔断状态，跨日则重新开始
+	StateFile string `yaml:"state_file"`
 }
 
 // Load 从 YAML 文件加载配置，支持环境变量覆盖
@@ -141,7 +689,8 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 
-	cfg := &Config{}
+	// 未配置 mode 时保持模型一（mode: 0 为只记录行情）
+	cfg := &Config{Mode: 1}
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return nil, err
 	}
@@ -165,5 +714,58 @@ func Load(path string) (*Config, error) {
 		cfg.Bybit.APISecret = v
 	}
 
+	// 环境变量优先级高于配置文件（Binance）
+	if v := os.Getenv("BINANCE_API_KEY"); v != "" {
+		cfg.Binance.APIKey = v
+	}
+	if v := os.Getenv("BINANCE_API_SECRET"); v != "" {
+		cfg.Binance.APISecret = v
+	}
+
+	// 环境变量优先级高于配置文件（告警）
+	if v := os.Getenv("ALERT_TELEGRAM_TOKEN"); v != "" {
+		cfg.Alerts.TelegramToken = v
+	}
+
+	// 环境变量优先级高于配置文件（管理接口）
+	if v := os.Getenv("ADMIN_TOKEN"); v != "" {
+		cfg.Admin.Token = v
+	}
+
+	if err := cfg.Strategy.applySpreadBand(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
+
+// applySpreadBand 将 entry_spread_usdc / exit_spread_usdc 换算为 min_spread_usdc 与 unwind 配置
+func (s *StrategyConfig) applySpreadBand() error {
+	if s.EntrySpreadUSDC != nil {
+		s.MinSpreadUSDC = *s.EntrySpreadUSDC
+	}
+	if s.ExitSpreadUSDC == nil {
+		return nil
+	}
+	if *s.ExitSpreadUSDC >= s.MinSpreadUSDC {
+		return fmt.Errorf("exit_spread_usdc (%g) 必须小于 entry_spread_usdc / min_spread_usdc (%g)", *s.ExitSpreadUSDC, s.MinSpreadUSDC)
+	}
+	// 开仓方向毛价差 ≤ exit 等价于反向毛价差 ≥ -exit（忽略两所买卖价差）
+	s.Unwind = true
+	s.UnwindSpreadUSDC = -*s.ExitSpreadUSDC
+	return nil
+}
+
+// LoggingConfig 日志配置（log/slog）
+type LoggingConfig struct {
+	// 日志级别：debug / info / warn / error，默认 info；每次盘口更新、每次检测的明细只在 debug 输出
+	Level string `yaml:"level"`
+
+	// 输出格式：text（key=value）/ json（每行一个 JSON 对象，便于 Loki/ELK 采集），默认 text
+	Format string `yaml:"format"`
+
+	// 日志文件路径，为空时输出到标准错误；日终维护时重命名为带日期后缀的备份并重新打开
+	File string `yaml:"file"`
+
+	// 保留的日志备份数量，超出时删除最旧的备份；0 表示全部保留
+	MaxBackups int `yaml:"max_backups"`
+}
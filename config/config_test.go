@@ -0,0 +1,37 @@
+package config
+
+import "testing"
+
+func TestApplySpreadBand(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	cases := []struct {
+		name       string
+		in         StrategyConfig
+		minSpread  float64
+		unwind     bool
+		unwindUSDC float64
+		wantErr    bool
+	}{
+		{"仅 min_spread_usdc（旧配置）", StrategyConfig{MinSpreadUSDC: 3}, 3, false, 0, false},
+		{"entry 覆盖 min_spread_usdc", StrategyConfig{MinSpreadUSDC: 3, EntrySpreadUSDC: f(5)}, 5, false, 0, false},
+		{"entry + exit", StrategyConfig{EntrySpreadUSDC: f(5), ExitSpreadUSDC: f(1)}, 5, true, -1, false},
+		{"exit 与旧 min_spread_usdc", StrategyConfig{MinSpreadUSDC: 4, ExitSpreadUSDC: f(-2)}, 4, true, 2, false},
+		{"exit 不小于 entry", StrategyConfig{EntrySpreadUSDC: f(5), ExitSpreadUSDC: f(5)}, 0, false, 0, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := tc.in
+			err := s.applySpreadBand()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("applySpreadBand 错误 = %v，期望出错 %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if s.MinSpreadUSDC != tc.minSpread || s.Unwind != tc.unwind || s.UnwindSpreadUSDC != tc.unwindUSDC {
+				t.Fatalf("min_spread / unwind / unwind_spread = %v / %v / %v，期望 %v / %v / %v",
+					s.MinSpreadUSDC, s.Unwind, s.UnwindSpreadUSDC, tc.minSpread, tc.unwind, tc.unwindUSDC)
+			}
+		})
+	}
+}
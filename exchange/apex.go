@@ -0,0 +1,263 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	apexPkg "arb/apex"
+	"arb/config"
+)
+
+// apexExchange Apex Pro 适配器
+type apexExchange struct {
+	symbol string
+	client *apexPkg.Client
+	ws     *apexPkg.WsClient
+	logger *slog.Logger
+}
+
+func newApex(cfg *config.Config, onThrottle func(ThrottleEvent)) *apexExchange {
+	a := &apexExchange{
+		symbol: cfg.ApexSymbol,
+		client: apexPkg.NewClient(cfg.Apex.BaseURL, cfg.Apex.APIKey, cfg.Apex.APISecret, cfg.Apex.Passphrase),
+		ws:     apexPkg.NewWsClient(cfg.Apex.WsURL),
+		logger: venueLogger(Apex, cfg.ApexSymbol),
+	}
+	a.client.SetLogger(a.logger)
+	a.ws.SetLogger(a.logger)
+	a.ws.SetMaxReconnectAttempts(cfg.Apex.FeedLoss.MaxReconnectAttempts)
+
+	attempts, base, max, jitter := retryPolicy(cfg.RestRetry)
+	a.client.SetRetryPolicy(apexPkg.RetryPolicy{MaxAttempts: attempts, BaseDelay: base, MaxDelay: max, Jitter: jitter})
+	a.client.SetTimeouts(time.Duration(cfg.RestTimeout.OrderMs)*time.Millisecond,
+		time.Duration(cfg.RestTimeout.QueryMs)*time.Millisecond)
+	a.client.SetClientIDPrefix(cfg.Strategy.ClientIDPrefix)
+
+	rl := cfg.RateLimit
+	a.client.SetRateLimit(apexPkg.RateLimit{
+		MarketRPS:  rl.MarketRPS,
+		OrderRPS:   rl.OrderRPS,
+		AccountRPS: rl.AccountRPS,
+		MaxWait:    time.Duration(rl.MaxWaitMs) * time.Millisecond,
+	}, func(ev apexPkg.ThrottleEvent) {
+		if onThrottle != nil {
+			onThrottle(ThrottleEvent{Venue: a.Name(), Group: ev.Group, Wait: ev.Wait, Rejected: ev.Rejected})
+		}
+	})
+	return a
+}
+
+func (a *apexExchange) Name() string   { return "Apex" }
+func (a *apexExchange) Symbol() string { return a.symbol }
+
+func (a *apexExchange) Instrument(ctx context.Context) (*Instrument, error) {
+	info, err := a.client.GetInstrumentInfo(ctx, a.symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &Instrument{TickSize: info.TickSize, QtyStep: info.QtyStep, MinQty: info.MinOrderQty, MaxQty: info.MaxOrderQty, MinNotional: info.MinNotional}, nil
+}
+
+func (a *apexExchange) BestPrice(ctx context.Context) (*BestPrice, error) {
+	bp, err := a.client.GetBestPrice(ctx, a.symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &BestPrice{Bid: bp.BidPrice, BidSize: bp.BidSize, Ask: bp.AskPrice, AskSize: bp.AskSize}, nil
+}
+
+// Funding 查询当期资金费率与下一次结算时间
+func (a *apexExchange) Funding(ctx context.Context) (*Funding, error) {
+	fr, err := a.client.GetFundingRate(ctx, a.symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &Funding{Rate: fr.Rate, NextTime: fr.NextFundingTime}, nil
+}
+
+func (a *apexExchange) GetAccount(ctx context.Context) (*Account, error) {
+	acc, err := a.client.GetAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{Equity: acc.EquityValue, Available: acc.AvailableValue}, nil
+}
+
+func (a *apexExchange) GetPositions(ctx context.Context) ([]Position, error) {
+	raw, err := a.client.GetPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var positions []Position
+	for _, p := range raw {
+		if p.Symbol != a.symbol {
+			continue
+		}
+		size := p.Size
+		switch p.Side {
+		case "LONG":
+		case "SHORT":
+			size = -size
+		default:
+			continue
+		}
+		positions = append(positions, Position{Size: size, EntryPrice: p.EntryPrice, UnrealizedPnL: p.UnrealizedPnl})
+	}
+	return positions, nil
+}
+
+func (a *apexExchange) PlaceOrder(ctx context.Context, req *OrderRequest) (*Order, error) {
+	tif := string(req.TimeInForce)
+	if req.TimeInForce == GTC {
+		tif = "GTT" // Apex 的长期有效单
+	}
+	r := &apexPkg.PlaceOrderReq{
+		Symbol:        a.symbol,
+		Side:          string(req.Side),
+		Type:          string(req.Type),
+		Size:          req.Qty,
+		Price:         req.Price,
+		TimeInForce:   tif,
+		ReduceOnly:    req.ReduceOnly,
+		ClientOrderID: req.ClientID,
+	}
+	if !req.ExpireAt.IsZero() {
+		r.ExpireTime = req.ExpireAt.UnixMilli()
+	}
+	o, err := a.client.PlaceOrder(ctx, r)
+	req.ClientID = r.ClientOrderID
+	if err != nil {
+		return nil, err
+	}
+	order := apexOrder(o)
+	order.ClientID = r.ClientOrderID
+	return order, nil
+}
+
+func (a *apexExchange) GetOrder(ctx context.Context, orderID string) (*Order, error) {
+	o, err := a.client.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return apexOrder(o), nil
+}
+
+func (a *apexExchange) GetOrderByClientID(ctx context.Context, clientID string) (*Order, error) {
+	o, err := a.client.GetOrderByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	order := apexOrder(o)
+	order.ClientID = clientID
+	return order, nil
+}
+
+func (a *apexExchange) CancelOrder(ctx context.Context, orderID string) error {
+	return a.client.CancelOrder(ctx, orderID)
+}
+
+func (a *apexExchange) CancelAll(ctx context.Context) error {
+	return a.client.CancelAllOrders(ctx, a.symbol)
+}
+
+func (a *apexExchange) OpenOrders(ctx context.Context) ([]Order, error) {
+	raw, err := a.client.GetOpenOrders(ctx, a.symbol)
+	if err != nil {
+		return nil, err
+	}
+	orders := make([]Order, len(raw))
+	for i := range raw {
+		orders[i] = *apexOrder(&raw[i])
+	}
+	return orders, nil
+}
+
+func (a *apexExchange) Connect() error { return a.ws.Connect() }
+
+// SubscribeOrderBook 订阅 Apex 订单簿；Apex 推送固定档位，depth 为截取的档位上限（1~200）
+// 任一档解析失败时丢弃本次更新（保留上一份有效盘口，由过期检查兜底）
+func (a *apexExchange) SubscribeOrderBook(depth int, cb func(*OrderBook)) error {
+	err := a.ws.SubscribeOrderBook(a.symbol, depth, func(ob *apexPkg.WsOrderBook) {
+		book, err := convertBook(ob.Bids, ob.Asks, ob.Ts, apexPkg.ParsePriceLevel)
+		if err != nil {
+			a.logger.Warn("[行情] 订单簿数据异常，丢弃本次更新", "err", err)
+			return
+		}
+		cb(book)
+	})
+	if errors.Is(err, apexPkg.ErrUnsupportedDepth) {
+		return fmt.Errorf("%w: %v", ErrUnsupportedDepth, err)
+	}
+	return err
+}
+
+// SubscribeTrades 订阅逐笔成交
+func (a *apexExchange) SubscribeTrades(cb func(*Trade)) error {
+	return a.ws.SubscribeTrades(a.symbol, func(t *apexPkg.WsTrade) {
+		trade, err := convertTrade(t.Price, t.Size, t.Side, t.Ts)
+		if err != nil {
+			a.logger.Warn("[行情] 成交数据异常，丢弃", "err", err)
+			return
+		}
+		cb(trade)
+	})
+}
+
+func (a *apexExchange) FeedStats() FeedStats {
+	st := a.ws.Stats()
+	return FeedStats{Connected: st.Connected, ReconnectCount: st.ReconnectCount, RTT: st.RTT, LastMessageAge: st.LastMessageAge, Failed: st.Failed, SeqGaps: st.SeqGaps}
+}
+
+func (a *apexExchange) FeedReady() bool { return a.ws.IsReady() }
+func (a *apexExchange) Close()          { a.ws.Close() }
+
+// apexOrder 转换 Apex 订单
+func apexOrder(o *apexPkg.Order) *Order {
+	return &Order{
+		ID:        o.ID,
+		Side:      Side(o.Side),
+		Price:     o.Price,
+		Qty:       o.Size,
+		FilledQty: o.FilledSize,
+		AvgPrice:  o.AvgPrice,
+		Fee:       o.Fee,
+		Status:    o.Status,
+		Final:     o.Status == "FILLED" || o.Status == "CANCELED" || o.Status == "EXPIRED",
+		CreatedAt: time.UnixMilli(o.CreatedAt),
+	}
+}
+
+// convertTrade 解析原始逐笔成交，主动方按大小写不敏感匹配 Buy / Sell
+func convertTrade(price, size, side string, ts int64) (*Trade, error) {
+	p, err := parsePrice(price)
+	if err != nil {
+		return nil, fmt.Errorf("成交价%w", err)
+	}
+	s, err := strconv.ParseFloat(size, 64)
+	if err != nil {
+		return nil, fmt.Errorf("解析成交量 %q 失败: %w", size, err)
+	}
+	t := &Trade{Price: p, Size: s, Side: Sell, Time: time.UnixMilli(ts)}
+	if strings.EqualFold(side, string(Buy)) {
+		t.Side = Buy
+	}
+	return t, nil
+}
+
+// convertBook 解析原始订单簿，任一档格式错误时返回 error
+func convertBook(bids, asks [][]string, ts int64, parse func([]string) (float64, float64, error)) (*OrderBook, error) {
+	book := &OrderBook{Ts: ts}
+	var err error
+	if book.Bids, err = parseLevels(bids, parse); err != nil {
+		return nil, fmt.Errorf("买盘%w", err)
+	}
+	if book.Asks, err = parseLevels(asks, parse); err != nil {
+		return nil, fmt.Errorf("卖盘%w", err)
+	}
+	return book, nil
+}
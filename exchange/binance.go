@@ -0,0 +1,242 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	binancePkg "arb/binance"
+	"arb/config"
+)
+
+// binanceExchange Binance U 本位合约适配器
+type binanceExchange struct {
+	symbol   string
+	leverage int
+	client   *binancePkg.Client
+	ws       *binancePkg.WsClient
+	logger   *slog.Logger
+}
+
+func newBinance(cfg *config.Config, onThrottle func(ThrottleEvent)) *binanceExchange {
+	b := &binanceExchange{
+		symbol:   binancePkg.NormalizeSymbol(cfg.BinanceSymbol),
+		leverage: cfg.Binance.Leverage,
+		client:   binancePkg.NewClient(cfg.Binance.BaseURL, cfg.Binance.APIKey, cfg.Binance.APISecret),
+		ws:       binancePkg.NewWsClient(cfg.Binance.WsURL),
+	}
+	b.logger = venueLogger(Binance, b.symbol)
+	b.client.SetLogger(b.logger)
+	b.ws.SetLogger(b.logger)
+	b.ws.SetMaxReconnectAttempts(cfg.Binance.FeedLoss.MaxReconnectAttempts)
+
+	attempts, base, max, jitter := retryPolicy(cfg.RestRetry)
+	b.client.SetRetryPolicy(binancePkg.RetryPolicy{MaxAttempts: attempts, BaseDelay: base, MaxDelay: max, Jitter: jitter})
+	b.client.SetTimeouts(time.Duration(cfg.RestTimeout.OrderMs)*time.Millisecond,
+		time.Duration(cfg.RestTimeout.QueryMs)*time.Millisecond)
+	b.client.SetClientIDPrefix(cfg.Strategy.ClientIDPrefix)
+
+	rl := cfg.RateLimit
+	b.client.SetRateLimit(binancePkg.RateLimit{
+		MarketRPS:  rl.MarketRPS,
+		OrderRPS:   rl.OrderRPS,
+		AccountRPS: rl.AccountRPS,
+		MaxWait:    time.Duration(rl.MaxWaitMs) * time.Millisecond,
+	}, func(ev binancePkg.ThrottleEvent) {
+		if onThrottle != nil {
+			onThrottle(ThrottleEvent{Venue: b.Name(), Group: ev.Group, Wait: ev.Wait, Rejected: ev.Rejected})
+		}
+	})
+	return b
+}
+
+func (b *binanceExchange) Name() string   { return "Binance" }
+func (b *binanceExchange) Symbol() string { return b.symbol }
+
+func (b *binanceExchange) Instrument(ctx context.Context) (*Instrument, error) {
+	info, err := b.client.GetInstrumentInfo(ctx, b.symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &Instrument{TickSize: info.TickSize, QtyStep: info.QtyStep, MinQty: info.MinOrderQty, MaxQty: info.MaxOrderQty, MinNotional: info.MinNotional}, nil
+}
+
+func (b *binanceExchange) BestPrice(ctx context.Context) (*BestPrice, error) {
+	bp, err := b.client.GetBestPrice(ctx, b.symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &BestPrice{Bid: bp.BidPrice, BidSize: bp.BidSize, Ask: bp.AskPrice, AskSize: bp.AskSize}, nil
+}
+
+func (b *binanceExchange) GetAccount(ctx context.Context) (*Account, error) {
+	acc, err := b.client.GetAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{Equity: acc.TotalMarginBalance, Available: acc.AvailableBalance}, nil
+}
+
+func (b *binanceExchange) GetPositions(ctx context.Context) ([]Position, error) {
+	raw, err := b.client.GetPositions(ctx, b.symbol)
+	if err != nil {
+		return nil, err
+	}
+	var positions []Position
+	for _, p := range raw {
+		// positionAmt 已带方向（单向持仓模式），数量为 0 的记录跳过
+		if p.Symbol != b.symbol || p.PositionAmt == 0 {
+			continue
+		}
+		positions = append(positions, Position{Size: p.PositionAmt, EntryPrice: p.EntryPrice, UnrealizedPnL: p.UnrealizedProfit})
+	}
+	return positions, nil
+}
+
+func (b *binanceExchange) PlaceOrder(ctx context.Context, req *OrderRequest) (*Order, error) {
+	r := &binancePkg.PlaceOrderReq{
+		Symbol:        b.symbol,
+		Side:          string(req.Side),
+		Type:          string(req.Type),
+		Quantity:      req.Qty,
+		Price:         req.Price,
+		TimeInForce:   string(req.TimeInForce),
+		ReduceOnly:    req.ReduceOnly,
+		ClientOrderID: req.ClientID,
+	}
+	if req.TimeInForce == PostOnly {
+		r.TimeInForce = "GTX" // Binance 的只做 maker
+	}
+	if req.Type == Market {
+		// Binance 市价单不接受价格与有效方式
+		r.Price, r.TimeInForce = "", ""
+	}
+	o, err := b.client.PlaceOrder(ctx, r)
+	req.ClientID = r.ClientOrderID
+	if err != nil {
+		return nil, err
+	}
+	order := binanceOrder(o)
+	order.ClientID = r.ClientOrderID
+	return order, nil
+}
+
+// GetOrder 查询订单，成交后额外查询成交明细累计手续费（订单接口不返回手续费）
+func (b *binanceExchange) GetOrder(ctx context.Context, orderID string) (*Order, error) {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Binance 订单ID %q 格式错误: %w", orderID, err)
+	}
+	o, err := b.client.GetOrder(ctx, b.symbol, id)
+	if err != nil {
+		return nil, err
+	}
+	return b.withFee(ctx, binanceOrder(o))
+}
+
+func (b *binanceExchange) GetOrderByClientID(ctx context.Context, clientID string) (*Order, error) {
+	o, err := b.client.GetOrderByClientID(ctx, b.symbol, clientID)
+	if err != nil {
+		return nil, err
+	}
+	return b.withFee(ctx, binanceOrder(o))
+}
+
+// withFee 有成交时通过成交明细补全订单手续费
+func (b *binanceExchange) withFee(ctx context.Context, order *Order) (*Order, error) {
+	if order.FilledQty == 0 {
+		return order, nil
+	}
+	id, _ := strconv.ParseInt(order.ID, 10, 64)
+	trades, err := b.client.GetOrderTrades(ctx, b.symbol, id)
+	if err != nil {
+		return nil, fmt.Errorf("查询 Binance 订单 %s 成交明细失败: %w", order.ID, err)
+	}
+	for _, t := range trades {
+		order.Fee += t.Commission
+	}
+	return order, nil
+}
+
+func (b *binanceExchange) CancelOrder(ctx context.Context, orderID string) error {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Binance 订单ID %q 格式错误: %w", orderID, err)
+	}
+	return b.client.CancelOrder(ctx, b.symbol, id)
+}
+
+func (b *binanceExchange) CancelAll(ctx context.Context) error {
+	return b.client.CancelAllOrders(ctx, b.symbol)
+}
+
+func (b *binanceExchange) OpenOrders(ctx context.Context) ([]Order, error) {
+	raw, err := b.client.GetOpenOrders(ctx, b.symbol)
+	if err != nil {
+		return nil, err
+	}
+	orders := make([]Order, 0, len(raw))
+	for i := range raw {
+		orders = append(orders, *binanceOrder(&raw[i]))
+	}
+	return orders, nil
+}
+
+// Prepare 设置杠杆，保证保证金计算与余额风控基于预期杠杆；leverage<=0 时不修改
+func (b *binanceExchange) Prepare(ctx context.Context) error {
+	if b.leverage <= 0 {
+		return nil
+	}
+	if err := b.client.SetLeverage(ctx, b.symbol, b.leverage); err != nil {
+		return fmt.Errorf("设置 Binance 杠杆 %dx 失败: %w", b.leverage, err)
+	}
+	b.logger.Info("[启动] 杠杆已设置", "leverage", b.leverage)
+	return nil
+}
+
+func (b *binanceExchange) Connect() error { return b.ws.Connect() }
+
+// SubscribeOrderBook 订阅 Binance 5 档部分深度，depth 参数忽略（固定 5 档）
+func (b *binanceExchange) SubscribeOrderBook(depth int, cb func(*OrderBook)) error {
+	return b.ws.SubscribeOrderBook(b.symbol, func(ob *binancePkg.WsOrderBook) {
+		book, err := convertBook(ob.Bids, ob.Asks, ob.Ts, binancePkg.ParsePriceLevel)
+		if err != nil {
+			b.logger.Warn("[行情] 订单簿数据异常，丢弃本次更新", "err", err)
+			return
+		}
+		cb(book)
+	})
+}
+
+func (b *binanceExchange) FeedStats() FeedStats {
+	st := b.ws.Stats()
+	return FeedStats{Connected: st.Connected, ReconnectCount: st.ReconnectCount, RTT: st.RTT, LastMessageAge: st.LastMessageAge, Failed: st.Failed}
+}
+
+func (b *binanceExchange) FeedReady() bool { return b.ws.IsReady() }
+
+func (b *binanceExchange) Close() { b.ws.Close() }
+
+// binanceOrder 转换 Binance 订单，方向取值与 Side 一致（BUY / SELL）
+func binanceOrder(o *binancePkg.Order) *Order {
+	order := &Order{
+		ID:        strconv.FormatInt(o.OrderID, 10),
+		ClientID:  o.ClientOrderID,
+		Side:      Side(o.Side),
+		Price:     o.Price,
+		Qty:       o.OrigQty,
+		FilledQty: o.ExecutedQty,
+		AvgPrice:  o.AvgPrice,
+		Status:    o.Status,
+	}
+	switch o.Status {
+	case "FILLED", "CANCELED", "EXPIRED", "REJECTED", "EXPIRED_IN_MATCH":
+		order.Final = true
+	}
+	if o.Time > 0 {
+		order.CreatedAt = time.UnixMilli(o.Time)
+	}
+	return order
+}
@@ -0,0 +1,404 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	bybitPkg "arb/bybit"
+	"arb/config"
+)
+
+// bybitCategory Bybit V5 线性合约（USDT 永续）
+const bybitCategory = "linear"
+
+// bybitExchange Bybit 适配器，配置了 private_ws_url 时支持私有频道成交推送
+type bybitExchange struct {
+	symbol    string
+	leverage  float64
+	wantHedge bool // 配置的持仓模式是否为双向持仓
+
+	// 账户当前的持仓模式（初始为配置值，持仓查询与 Prepare 切换后更新）：双向持仓下单需按仓位方向填写 positionIdx
+	hedgeMode atomic.Bool
+	modeKnown atomic.Bool // 已从持仓查询确认当前模式
+	client    *bybitPkg.Client
+	ws        *bybitPkg.WsClient
+	logger    *slog.Logger
+
+	// 私有频道（成交推送），未配置 private_ws_url 时为 nil
+	privWs *bybitPkg.WsClient
+}
+
+func newBybit(cfg *config.Config, onThrottle func(ThrottleEvent)) *bybitExchange {
+	b := &bybitExchange{
+		symbol:    cfg.BybitSymbol,
+		leverage:  cfg.Bybit.Leverage,
+		wantHedge: cfg.Bybit.PositionMode == config.PositionModeHedge,
+		client:    bybitPkg.NewClient(cfg.Bybit.BaseURL, cfg.Bybit.APIKey, cfg.Bybit.APISecret),
+		ws:        bybitPkg.NewWsClient(cfg.Bybit.WsURL),
+		logger:    venueLogger(Bybit, cfg.BybitSymbol),
+	}
+	b.hedgeMode.Store(b.wantHedge)
+	b.client.SetLogger(b.logger)
+	b.ws.SetLogger(b.logger)
+	b.ws.SetMaxReconnectAttempts(cfg.Bybit.FeedLoss.MaxReconnectAttempts)
+	if cfg.Bybit.PrivateWsURL != "" {
+		b.privWs = bybitPkg.NewPrivateWsClient(cfg.Bybit.PrivateWsURL, cfg.Bybit.APIKey, cfg.Bybit.APISecret)
+		b.privWs.SetLogger(b.logger.With("channel", "private"))
+	}
+
+	attempts, base, max, jitter := retryPolicy(cfg.RestRetry)
+	b.client.SetRetryPolicy(bybitPkg.RetryPolicy{MaxAttempts: attempts, BaseDelay: base, MaxDelay: max, Jitter: jitter})
+	b.client.SetTimeouts(time.Duration(cfg.RestTimeout.OrderMs)*time.Millisecond,
+		time.Duration(cfg.RestTimeout.QueryMs)*time.Millisecond)
+	b.client.SetClientIDPrefix(cfg.Strategy.ClientIDPrefix)
+
+	rl := cfg.RateLimit
+	b.client.SetRateLimit(bybitPkg.RateLimit{
+		MarketRPS:    rl.MarketRPS,
+		OrderRPS:     rl.OrderRPS,
+		AccountRPS:   rl.AccountRPS,
+		MaxWait:      time.Duration(rl.MaxWaitMs) * time.Millisecond,
+		MinRemaining: rl.BybitMinRemaining,
+	}, func(ev bybitPkg.ThrottleEvent) {
+		if onThrottle != nil {
+			onThrottle(ThrottleEvent{
+				Venue: b.Name(), Group: ev.Group, Wait: ev.Wait, Rejected: ev.Rejected,
+				Remaining: ev.Remaining, Limit: ev.Limit, Until: ev.Until,
+			})
+		}
+	})
+	return b
+}
+
+func (b *bybitExchange) Name() string   { return "Bybit" }
+func (b *bybitExchange) Symbol() string { return b.symbol }
+
+func (b *bybitExchange) Instrument(ctx context.Context) (*Instrument, error) {
+	info, err := b.client.GetInstrumentInfo(ctx, b.symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &Instrument{TickSize: info.TickSize, QtyStep: info.QtyStep, MinQty: info.MinOrderQty, MaxQty: info.MaxOrderQty, MinNotional: info.MinNotional}, nil
+}
+
+func (b *bybitExchange) BestPrice(ctx context.Context) (*BestPrice, error) {
+	bp, err := b.client.GetBestPrice(ctx, b.symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &BestPrice{Bid: bp.BidPrice, BidSize: bp.BidSize, Ask: bp.AskPrice, AskSize: bp.AskSize}, nil
+}
+
+// Funding 查询当期资金费率与下一次结算时间
+func (b *bybitExchange) Funding(ctx context.Context) (*Funding, error) {
+	fr, err := b.client.GetFundingRate(ctx, b.symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &Funding{Rate: fr.Rate, NextTime: fr.NextFundingTime}, nil
+}
+
+func (b *bybitExchange) GetAccount(ctx context.Context) (*Account, error) {
+	acc, err := b.client.GetAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{Equity: acc.TotalEquity, Available: acc.AvailableMargin}, nil
+}
+
+func (b *bybitExchange) GetPositions(ctx context.Context) ([]Position, error) {
+	raw, err := b.client.GetPositions(ctx, b.symbol)
+	if err != nil {
+		return nil, err
+	}
+	var positions []Position
+	for _, p := range raw {
+		if p.Symbol != b.symbol {
+			continue
+		}
+		// 交易对无持仓时也返回仓位记录，positionIdx 反映账户当前的持仓模式
+		b.hedgeMode.Store(p.PositionIdx != bybitPkg.PositionIdxOneWay)
+		b.modeKnown.Store(true)
+		size := p.SizeFloat
+		switch p.Side {
+		case "Buy":
+		case "Sell":
+			size = -size
+		default:
+			continue
+		}
+		// 开仓均价与未实现盈亏只用于展示，解析失败时记录并按 0 处理，持仓数量仍然有效
+		entry, err := strconv.ParseFloat(p.EntryPrice, 64)
+		if err != nil && size != 0 {
+			b.logger.Warn("[持仓] 解析开仓均价失败", "value", p.EntryPrice, "err", err)
+		}
+		upnl, err := strconv.ParseFloat(p.UnrealizedPnl, 64)
+		if err != nil && size != 0 {
+			b.logger.Warn("[持仓] 解析未实现盈亏失败", "value", p.UnrealizedPnl, "err", err)
+		}
+		positions = append(positions, Position{Size: size, EntryPrice: entry, UnrealizedPnL: upnl})
+	}
+	return positions, nil
+}
+
+func (b *bybitExchange) PlaceOrder(ctx context.Context, req *OrderRequest) (*Order, error) {
+	r := &bybitPkg.PlaceOrderReq{
+		Category:    bybitCategory,
+		Symbol:      b.symbol,
+		Side:        bybitSide(req.Side),
+		OrderType:   "Limit",
+		Qty:         req.Qty,
+		Price:       req.Price,
+		TimeInForce: string(req.TimeInForce),
+		ReduceOnly:  req.ReduceOnly,
+		OrderLinkID: req.ClientID,
+		PositionIdx: b.positionIdx(req.Side, req.ReduceOnly),
+	}
+	if req.TimeInForce == PostOnly {
+		r.TimeInForce = "PostOnly"
+	}
+	if req.Type == Market {
+		// Bybit 市价单不接受价格与有效方式
+		r.OrderType, r.Price, r.TimeInForce = "Market", "", ""
+	}
+	o, err := b.client.PlaceOrder(ctx, r)
+	req.ClientID = r.OrderLinkID
+	if err != nil {
+		return nil, err
+	}
+	return &Order{ID: o.OrderID, ClientID: r.OrderLinkID, Side: req.Side, Status: o.OrderStatus}, nil
+}
+
+func (b *bybitExchange) GetOrder(ctx context.Context, orderID string) (*Order, error) {
+	o, err := b.client.GetOrder(ctx, b.symbol, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return bybitOrder(o)
+}
+
+func (b *bybitExchange) GetOrderByClientID(ctx context.Context, clientID string) (*Order, error) {
+	o, err := b.client.GetOrderByLinkID(ctx, b.symbol, clientID)
+	if err != nil {
+		return nil, err
+	}
+	order, err := bybitOrder(o)
+	if err != nil {
+		return nil, err
+	}
+	order.ClientID = clientID
+	return order, nil
+}
+
+func (b *bybitExchange) CancelOrder(ctx context.Context, orderID string) error {
+	return b.client.CancelOrder(ctx, b.symbol, orderID)
+}
+
+func (b *bybitExchange) CancelAll(ctx context.Context) error {
+	return b.client.CancelAllOrders(ctx, b.symbol)
+}
+
+func (b *bybitExchange) OpenOrders(ctx context.Context) ([]Order, error) {
+	raw, err := b.client.GetOpenOrders(ctx, b.symbol)
+	if err != nil {
+		return nil, err
+	}
+	orders := make([]Order, 0, len(raw))
+	for i := range raw {
+		o, err := bybitOrder(&raw[i])
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *o)
+	}
+	return orders, nil
+}
+
+// positionIdx 按持仓模式返回下单的 positionIdx：单向持仓为 0；
+// 双向持仓时开仓买入、平仓卖出作用于多头仓位（1），开仓卖出、平仓买入作用于空头仓位（2）
+func (b *bybitExchange) positionIdx(side Side, reduceOnly bool) int {
+	if !b.hedgeMode.Load() {
+		return bybitPkg.PositionIdxOneWay
+	}
+	if (side == Buy) != reduceOnly {
+		return bybitPkg.PositionIdxLong
+	}
+	return bybitPkg.PositionIdxShort
+}
+
+// Prepare 切换到配置的持仓模式并设置杠杆，保证下单的 positionIdx 与保证金计算、余额风控基于预期设置；leverage<=0 时不修改杠杆
+// 由引擎在启动核对（撤销遗留挂单、启动平仓）之后调用：持仓查询确认已是目标模式时不切换；
+// 交易所因仍有持仓或挂单拒绝切换时按当前模式继续运行（positionIdx 随之调整）并告警
+func (b *bybitExchange) Prepare(ctx context.Context) error {
+	mode, name := bybitPkg.PositionModeOneWay, config.PositionModeOneWay
+	if b.wantHedge {
+		mode, name = bybitPkg.PositionModeHedge, config.PositionModeHedge
+	}
+	if _, err := b.GetPositions(ctx); err != nil {
+		b.logger.Warn("[启动] 查询持仓失败，无法确认当前持仓模式", "err", err)
+	}
+	current := config.PositionModeOneWay
+	if b.hedgeMode.Load() {
+		current = config.PositionModeHedge
+	}
+	if b.modeKnown.Load() && current == name {
+		b.logger.Info("[启动] 持仓模式无需切换", "position_mode", name)
+	} else if err := b.client.SwitchPositionMode(ctx, b.symbol, mode); err == nil {
+		b.hedgeMode.Store(b.wantHedge)
+		b.logger.Info("[启动] 持仓模式已设置", "position_mode", name)
+	} else if bybitPkg.IsModeLocked(err) {
+		b.logger.Warn("[启动] 存在持仓或挂单，无法切换持仓模式，按当前模式运行", "position_mode", current, "want", name, "err", err)
+	} else {
+		return fmt.Errorf("切换 Bybit 持仓模式为 %s 失败: %w", name, err)
+	}
+	if b.leverage <= 0 {
+		return nil
+	}
+	if err := b.client.SetLeverage(ctx, b.symbol, b.leverage, b.leverage); err != nil {
+		return fmt.Errorf("设置 Bybit 杠杆 %gx 失败: %w", b.leverage, err)
+	}
+	b.logger.Info("[启动] 杠杆已设置", "leverage", b.leverage)
+	return nil
+}
+
+func (b *bybitExchange) Connect() error { return b.ws.Connect() }
+
+// SubscribeOrderBook 订阅 Bybit 订单簿，depth 为订阅档位（线性合约支持 1/50/200/500）
+func (b *bybitExchange) SubscribeOrderBook(depth int, cb func(*OrderBook)) error {
+	err := b.ws.SubscribeOrderBook(b.symbol, depth, func(ob *bybitPkg.WsOrderBook) {
+		book, err := convertBook(ob.Bids, ob.Asks, ob.Ts, bybitPkg.ParsePriceLevel)
+		if err != nil {
+			b.logger.Warn("[行情] 订单簿数据异常，丢弃本次更新", "err", err)
+			return
+		}
+		cb(book)
+	})
+	if errors.Is(err, bybitPkg.ErrUnsupportedDepth) {
+		return fmt.Errorf("%w: %v", ErrUnsupportedDepth, err)
+	}
+	return err
+}
+
+// SubscribeTrades 订阅逐笔成交
+func (b *bybitExchange) SubscribeTrades(cb func(*Trade)) error {
+	return b.ws.SubscribeTrades(b.symbol, func(t *bybitPkg.WsTrade) {
+		trade, err := convertTrade(t.Price, t.Size, t.Side, t.Ts)
+		if err != nil {
+			b.logger.Warn("[行情] 成交数据异常，丢弃", "err", err)
+			return
+		}
+		cb(trade)
+	})
+}
+
+// SubscribeExecutions 连接私有频道并订阅本交易对的成交推送，未配置 private_ws_url 时返回 false
+func (b *bybitExchange) SubscribeExecutions(cb func(*Execution)) (bool, error) {
+	if b.privWs == nil {
+		return false, nil
+	}
+	// 连接失败时由重连循环继续重试，期间成交按 REST 查询（ExecutionsReady 为 false）
+	connErr := b.privWs.Connect()
+	if connErr != nil {
+		b.logger.Warn("[成交推送] 私有 WS 初次连接失败，后台重试", "err", connErr)
+	}
+	err := b.privWs.SubscribeExecutions(func(ex *bybitPkg.WsExecution) {
+		if ex.Symbol != b.symbol || ex.ExecType != "Trade" {
+			return
+		}
+
+		var exec Execution
+		var err error
+		if exec.Qty, err = strconv.ParseFloat(ex.ExecQty, 64); err != nil {
+			b.logger.Warn("[成交推送] 解析成交量失败", "value", ex.ExecQty, "err", err)
+			return
+		}
+		if exec.Price, err = parsePrice(ex.ExecPrice); err != nil {
+			b.logger.Warn("[成交推送] 成交价异常，丢弃", "value", ex.ExecPrice, "err", err)
+			return
+		}
+		// 手续费异常时不丢弃成交（成交量与价格决定对冲结果），只记录告警，本笔手续费按 0 计
+		if exec.Fee, err = strconv.ParseFloat(ex.ExecFee, 64); err != nil {
+			b.logger.Warn("[成交推送] 解析手续费失败，按 0 计入", "value", ex.ExecFee, "err", err)
+		}
+		leaves, err := strconv.ParseFloat(ex.LeavesQty, 64)
+		exec.FilledAll = err == nil && leaves == 0
+		exec.OrderID = ex.OrderID
+
+		b.logger.Info("[成交推送] 成交", "side", ex.Side, "order_id", ex.OrderID,
+			"qty", ex.ExecQty, "price", ex.ExecPrice, "fee", ex.ExecFee, "leaves", ex.LeavesQty)
+		cb(&exec)
+	})
+	if err != nil && connErr == nil {
+		return false, fmt.Errorf("Bybit 成交推送订阅失败: %w", err)
+	}
+	return true, nil
+}
+
+func (b *bybitExchange) ExecutionsReady() bool {
+	return b.privWs != nil && b.privWs.IsReady()
+}
+
+func (b *bybitExchange) FeedStats() FeedStats {
+	st := b.ws.Stats()
+	return FeedStats{Connected: st.Connected, ReconnectCount: st.ReconnectCount, RTT: st.RTT, LastMessageAge: st.LastMessageAge, Failed: st.Failed, SeqGaps: st.SeqGaps}
+}
+
+func (b *bybitExchange) FeedReady() bool { return b.ws.IsReady() }
+
+func (b *bybitExchange) Close() {
+	b.ws.Close()
+	if b.privWs != nil {
+		b.privWs.Close()
+	}
+}
+
+// bybitSide 转换为 Bybit 买卖方向（Buy / Sell）
+func bybitSide(s Side) string {
+	if s == Buy {
+		return "Buy"
+	}
+	return "Sell"
+}
+
+// bybitOrder 转换 Bybit 订单，数值字段为字符串，未成交时均价与手续费可能为空
+func bybitOrder(o *bybitPkg.Order) (*Order, error) {
+	order := &Order{ID: o.OrderID, Side: Sell, Status: o.OrderStatus}
+	if o.Side == "Buy" {
+		order.Side = Buy
+	}
+	switch o.OrderStatus {
+	case "Filled", "Cancelled", "PartiallyFilledCanceled", "Rejected", "Deactivated":
+		order.Final = true
+	}
+
+	var err error
+	if order.FilledQty, err = strconv.ParseFloat(o.CumExecQty, 64); err != nil {
+		return nil, fmt.Errorf("解析 Bybit 订单 %s 成交量失败: %w", o.OrderID, err)
+	}
+	for _, f := range []struct {
+		name string
+		raw  string
+		dst  *float64
+	}{
+		{"价格", o.Price, &order.Price},
+		{"数量", o.Qty, &order.Qty},
+		{"成交均价", o.AvgPrice, &order.AvgPrice},
+		{"手续费", o.CumExecFee, &order.Fee},
+	} {
+		if f.raw == "" {
+			continue
+		}
+		if *f.dst, err = strconv.ParseFloat(f.raw, 64); err != nil {
+			return nil, fmt.Errorf("解析 Bybit 订单 %s %s失败: %w", o.OrderID, f.name, err)
+		}
+	}
+	if ms, err := strconv.ParseInt(o.CreatedTime, 10, 64); err == nil {
+		order.CreatedAt = time.UnixMilli(ms)
+	}
+	return order, nil
+}
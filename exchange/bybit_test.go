@@ -0,0 +1,110 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	bybitPkg "arb/bybit"
+	"arb/config"
+)
+
+// bybitModeServer 模拟 Bybit 持仓查询与持仓模式切换：positionIdx 为当前模式下的仓位记录，switchCode 为切换接口返回的 retCode
+func bybitModeServer(t *testing.T, positionIdx, switchCode int, switches *atomic.Int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v5/position/list":
+			fmt.Fprintf(w, `{"retCode":0,"result":{"list":[{"symbol":"BTCUSDT","side":"","size":"0","avgPrice":"0","unrealisedPnl":"0","positionIdx":%d}]}}`, positionIdx)
+		case "/v5/position/switch-mode":
+			switches.Add(1)
+			fmt.Fprintf(w, `{"retCode":%d,"retMsg":"switch"}`, switchCode)
+		default:
+			t.Errorf("未预期的请求: %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestBybit(baseURL, mode string) *bybitExchange {
+	cfg := &config.Config{BybitSymbol: "BTCUSDT"}
+	cfg.Bybit.BaseURL = baseURL
+	cfg.Bybit.PositionMode = mode
+	return newBybit(cfg, nil)
+}
+
+func TestBybitPrepareSkipsSwitchWhenModeMatches(t *testing.T) {
+	var switches atomic.Int32
+	srv := bybitModeServer(t, 0, 0, &switches)
+	b := newTestBybit(srv.URL, config.PositionModeOneWay)
+
+	if err := b.Prepare(context.Background()); err != nil {
+		t.Fatalf("Prepare 失败: %v", err)
+	}
+	if n := switches.Load(); n != 0 {
+		t.Fatalf("已是单向持仓时不应切换，实际切换 %d 次", n)
+	}
+}
+
+func TestBybitPrepareSwitchesWhenModeDiffers(t *testing.T) {
+	var switches atomic.Int32
+	srv := bybitModeServer(t, 0, 0, &switches)
+	b := newTestBybit(srv.URL, config.PositionModeHedge)
+
+	if err := b.Prepare(context.Background()); err != nil {
+		t.Fatalf("Prepare 失败: %v", err)
+	}
+	if n := switches.Load(); n != 1 {
+		t.Fatalf("切换 %d 次，期望 1 次", n)
+	}
+	if got := b.positionIdx(Buy, false); got != 1 {
+		t.Fatalf("切换到双向持仓后开多 positionIdx = %d，期望 1", got)
+	}
+}
+
+func TestBybitPrepareKeepsModeWhenLocked(t *testing.T) {
+	var switches atomic.Int32
+	srv := bybitModeServer(t, 1, 110024, &switches)
+	b := newTestBybit(srv.URL, config.PositionModeOneWay)
+
+	if err := b.Prepare(context.Background()); err != nil {
+		t.Fatalf("有持仓拒绝切换不应视为启动失败: %v", err)
+	}
+	if got := b.positionIdx(Sell, false); got != 2 {
+		t.Fatalf("按当前双向持仓运行时开空 positionIdx = %d，期望 2", got)
+	}
+}
+
+func TestBybitPrepareFailsOnOtherErrors(t *testing.T) {
+	var switches atomic.Int32
+	srv := bybitModeServer(t, 0, 10005, &switches)
+	b := newTestBybit(srv.URL, config.PositionModeHedge)
+
+	if err := b.Prepare(context.Background()); err == nil {
+		t.Fatal("权限错误应导致 Prepare 失败")
+	}
+}
+
+// TestConvertBookRejectsMalformedLevel 任一档位格式错误时整条盘口更新作废，不会以 0 价格写入
+func TestConvertBookRejectsMalformedLevel(t *testing.T) {
+	bids := [][]string{{"100000.1", "1"}, {"99999.9", "2"}}
+	asks := [][]string{{"100000.2", "1"}}
+
+	book, err := convertBook(bids, asks, 1, bybitPkg.ParsePriceLevel)
+	if err != nil {
+		t.Fatalf("正常盘口解析失败: %v", err)
+	}
+	if len(book.Bids) != 2 || book.Bids[0].Price != 100000.1 || book.Asks[0].Size != 1 {
+		t.Fatalf("盘口解析结果不符合预期: %+v", book)
+	}
+
+	for _, bad := range [][][]string{{{"", "1"}}, {{"NaN?", "1"}}, {{"100000.2"}}} {
+		if book, err := convertBook(bids, bad, 1, bybitPkg.ParsePriceLevel); err == nil {
+			t.Fatalf("卖盘 %q 应解析失败，得到 %+v", bad, book)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+// Package exchange 定义套利引擎使用的统一交易所接口与标准化数据结构，
+// 各交易所通过适配器（apex.go / bybit.go / binance.go）接入，引擎只依赖 Exchange 接口
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	apexPkg "arb/apex"
+	binancePkg "arb/binance"
+	bybitPkg "arb/bybit"
+	"arb/config"
+)
+
+// 已支持的交易所名称（exchange_a / exchange_b 的取值）
+const (
+	Apex    = "apex"
+	Bybit   = "bybit"
+	Binance = "binance"
+)
+
+// Side 买卖方向
+type Side string
+
+const (
+	Buy  Side = "BUY"
+	Sell Side = "SELL"
+)
+
+// Opposite 返回相反方向
+func (s Side) Opposite() Side {
+	if s == Buy {
+		return Sell
+	}
+	return Buy
+}
+
+// OrderType 订单类型
+type OrderType string
+
+const (
+	Limit  OrderType = "LIMIT"
+	Market OrderType = "MARKET"
+)
+
+// TimeInForce 订单有效方式
+type TimeInForce string
+
+const (
+	GTC TimeInForce = "GTC" // 一直有效直到撤销
+	IOC TimeInForce = "IOC" // 立即成交，剩余撤销
+
+	PostOnly TimeInForce = "POST_ONLY" // 只做 maker，会立即成交时被拒绝（各适配器映射为交易所取值）
+)
+
+// Level 订单簿单档
+type Level struct {
+	Price float64
+	Size  float64
+}
+
+// OrderBook 标准化订单簿（买盘价格降序，卖盘价格升序）
+type OrderBook struct {
+	Bids []Level
+	Asks []Level
+	Ts   int64 // 交易所推送时间戳（毫秒）
+}
+
+// BestPrice 最优买卖价
+type BestPrice struct {
+	Bid, BidSize float64
+	Ask, AskSize float64
+}
+
+// Funding 资金费率
+type Funding struct {
+	Rate     float64   // 当期资金费率，正数表示多头向空头支付
+	NextTime time.Time // 下一次结算时间
+}
+
+// Instrument 交易对规格
+type Instrument struct {
+	TickSize    float64 // 价格步长
+	QtyStep     float64 // 数量步长
+	MinQty      float64 // 最小下单量
+	MaxQty      float64 // 最大下单量（0=不限制）
+	MinNotional float64 // 最小下单名义价值（0=不限制）
+}
+
+// Account 账户信息（USDC/USDT 计价）
+type Account struct {
+	Equity    float64 // 账户权益
+	Available float64 // 可用保证金
+}
+
+// Position 持仓
+type Position struct {
+	Size          float64 // 带方向的数量：多头为正，空头为负
+	EntryPrice    float64
+	UnrealizedPnL float64
+}
+
+// OrderRequest 下单请求，数量与价格由调用方按交易对步长格式化
+type OrderRequest struct {
+	Side        Side
+	Type        OrderType
+	Qty         string
+	Price       string // 市价单可为空；部分交易所市价单需要最差可接受价格
+	TimeInForce TimeInForce
+	ExpireAt    time.Time // 挂单（GTC / POST_ONLY）的到期时间，零值使用交易所默认；不支持的交易所忽略
+	ReduceOnly  bool
+	ClientID    string // 自定义订单ID，为空时由客户端生成并回写
+}
+
+// Order 订单
+type Order struct {
+	ID        string
+	ClientID  string
+	Side      Side
+	Price     float64
+	Qty       float64
+	FilledQty float64 // 累计成交量
+	AvgPrice  float64 // 成交均价（未成交时为 0）
+	Fee       float64 // 累计手续费
+	Status    string  // 交易所原始订单状态
+	Final     bool    // 订单已结束（全部成交、撤销、过期或拒绝），成交量不会再变化
+	CreatedAt time.Time
+}
+
+// FeedStats 行情连接健康状况
+type FeedStats struct {
+	Connected      bool          // 当前是否已连接
+	ReconnectCount int64         // 累计重连次数
+	RTT            time.Duration // 最近一次 ping/pong 往返时延
+	LastMessageAge time.Duration // 距最近一次消息的时长（从未收到时为 0）
+	Failed         bool          // 连续重连失败达到 feed_loss.max_reconnect_attempts，接口可能已不可用
+	SeqGaps        int64         // 订单簿序号缺口累计次数（交易所不提供序号时为 0）
+}
+
+// Execution 私有频道推送的单笔成交
+type Execution struct {
+	OrderID   string
+	Price     float64
+	Qty       float64
+	Fee       float64
+	FilledAll bool // 订单已全部成交
+}
+
+// Trade 公共频道推送的逐笔成交
+type Trade struct {
+	Price float64
+	Size  float64
+	Side  Side // 主动方
+	Time  time.Time
+}
+
+// Exchange 套利引擎所需的交易所能力，每个实例绑定一个交易对
+type Exchange interface {
+	// Name 交易所名称（用于日志）
+	Name() string
+	// Symbol 绑定的交易对
+	Symbol() string
+
+	Instrument(ctx context.Context) (*Instrument, error)
+	BestPrice(ctx context.Context) (*BestPrice, error)
+	GetAccount(ctx context.Context) (*Account, error)
+	GetPositions(ctx context.Context) ([]Position, error)
+
+	PlaceOrder(ctx context.Context, req *OrderRequest) (*Order, error)
+	GetOrder(ctx context.Context, orderID string) (*Order, error)
+	GetOrderByClientID(ctx context.Context, clientID string) (*Order, error)
+	CancelOrder(ctx context.Context, orderID string) error
+	CancelAll(ctx context.Context) error
+	OpenOrders(ctx context.Context) ([]Order, error)
+
+	// Connect 连接行情 WS（断线自动重连）
+	Connect() error
+	// SubscribeOrderBook 订阅订单簿，depth 为期望档位数（0 = 交易所默认），交易所不支持该档位时返回 error
+	SubscribeOrderBook(depth int, cb func(*OrderBook)) error
+	FeedStats() FeedStats
+	FeedReady() bool
+	// Close 关闭全部 WS 连接
+	Close()
+}
+
+// Preparer 启动下单前需要执行的交易所特定准备（如设置杠杆），可选实现
+type Preparer interface {
+	Prepare(ctx context.Context) error
+}
+
+// FundingProvider 资金费率查询，可选实现
+type FundingProvider interface {
+	Funding(ctx context.Context) (*Funding, error)
+}
+
+// ExecutionStreamer 私有频道成交推送，可选实现
+type ExecutionStreamer interface {
+	// SubscribeExecutions 连接私有频道并订阅本交易对的成交，未配置私有频道时返回 false
+	SubscribeExecutions(cb func(*Execution)) (bool, error)
+	// ExecutionsReady 私有频道当前是否可用
+	ExecutionsReady() bool
+}
+
+// TradeStreamer 公共频道逐笔成交推送，可选实现；需在 Connect 之后调用
+type TradeStreamer interface {
+	SubscribeTrades(cb func(*Trade)) error
+}
+
+// ThrottleEvent REST 本地限频事件
+type ThrottleEvent struct {
+	Venue    string
+	Group    string        // 接口分组：market / order / account
+	Wait     time.Duration // 本地等待时长（Rejected 时为需要等待的时长）
+	Rejected bool          // 等待超过上限，请求被拒绝
+
+	// 以下字段仅在按交易所响应头退避时设置
+	Remaining int
+	Limit     int
+	Until     time.Time
+}
+
+// venueLogger 返回附加交易所与交易对字段的 logger，供适配器及其 REST/WS 客户端使用
+func venueLogger(name, symbol string) *slog.Logger {
+	return slog.Default().With("exchange", name, "symbol", symbol)
+}
+
+// New 按名称创建交易所适配器，REST 重试、超时、限频与订单ID前缀取自全局配置
+// onThrottle 在 REST 请求被本地限频时调用（可为 nil）
+func New(name string, cfg *config.Config, onThrottle func(ThrottleEvent)) (Exchange, error) {
+	switch name {
+	case Apex:
+		return newApex(cfg, onThrottle), nil
+	case Bybit:
+		return newBybit(cfg, onThrottle), nil
+	case Binance:
+		return newBinance(cfg, onThrottle), nil
+	default:
+		return nil, fmt.Errorf("不支持的交易所 %q（可选: %s, %s, %s）", name, Apex, Bybit, Binance)
+	}
+}
+
+// IsRetryable 判断错误是否为可重试的瞬时错误（网络、5xx、限频）
+func IsRetryable(err error) bool {
+	return apexPkg.IsRetryable(err) || bybitPkg.IsRetryable(err) || binancePkg.IsRetryable(err)
+}
+
+// IsInsufficientBalance 判断错误是否为余额不足
+func IsInsufficientBalance(err error) bool {
+	return apexPkg.IsInsufficientBalance(err) || bybitPkg.IsInsufficientBalance(err) || binancePkg.IsInsufficientBalance(err)
+}
+
+// IsPermissionDenied 判断错误是否为鉴权或权限错误
+func IsPermissionDenied(err error) bool {
+	return apexPkg.IsPermissionDenied(err) || bybitPkg.IsPermissionDenied(err) || binancePkg.IsPermissionDenied(err)
+}
+
+// parsePrice 解析交易所返回的价格字符串：空串、非数字、NaN/Inf 与非正数都返回 error，
+// 调用方应丢弃本次更新而不是把 0 当作有效价格（0 价格会被视为行情未就绪或算出虚假的巨大价差）
+func parsePrice(s string) (float64, error) {
+	p, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析价格 %q 失败: %w", s, err)
+	}
+	if err := checkPrice(p); err != nil {
+		return 0, err
+	}
+	return p, nil
+}
+
+// checkPrice 校验已解析的价格为有限正数
+func checkPrice(p float64) error {
+	if math.IsNaN(p) || math.IsInf(p, 0) || p <= 0 {
+		return fmt.Errorf("价格无效: %v", p)
+	}
+	return nil
+}
+
+// parseLevels 将交易所原始档位 [[price, size], ...] 转为 Level，任一档格式错误或价格非正时返回 error
+func parseLevels(raw [][]string, parse func([]string) (float64, float64, error)) ([]Level, error) {
+	levels := make([]Level, len(raw))
+	for i, l := range raw {
+		price, size, err := parse(l)
+		if err == nil {
+			err = checkPrice(price)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 档%w", i+1, err)
+		}
+		levels[i] = Level{Price: price, Size: size}
+	}
+	return levels, nil
+}
+
+// retryPolicy 将 rest_retry 配置转换为各客户端的重试参数
+func retryPolicy(rc config.RetryConfig) (attempts int, base, max time.Duration, jitter float64) {
+	return rc.MaxAttempts, time.Duration(rc.BaseDelayMs) * time.Millisecond,
+		time.Duration(rc.MaxDelayMs) * time.Millisecond, rc.Jitter
+}
@@ -0,0 +1,47 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	apexPkg "arb/apex"
+	binancePkg "arb/binance"
+	bybitPkg "arb/bybit"
+)
+
+// TestErrorClassificationAcrossVenues 适配器层的错误分类对三所的 ExchangeError 一致生效
+func TestErrorClassificationAcrossVenues(t *testing.T) {
+	cases := []struct {
+		name       string
+		err        error
+		retryable  bool
+		balance    bool
+		permission bool
+	}{
+		{"Apex 5xx", &apexPkg.ExchangeError{HTTPStatus: 503}, true, false, false},
+		{"Apex 余额不足", &apexPkg.ExchangeError{HTTPStatus: 200, Code: apexPkg.CodeInsufficientBalance}, false, true, false},
+		{"Apex 401", &apexPkg.ExchangeError{HTTPStatus: 401}, false, false, true},
+		{"Bybit 限频", &bybitPkg.ExchangeError{HTTPStatus: 200, Code: bybitPkg.CodeRateLimit}, true, false, false},
+		{"Bybit 余额不足", &bybitPkg.ExchangeError{HTTPStatus: 200, Code: bybitPkg.CodeInsufficientBalance}, false, true, false},
+		{"Bybit 权限不足", &bybitPkg.ExchangeError{HTTPStatus: 200, Code: bybitPkg.CodePermissionDenied}, false, false, true},
+		{"Binance 权重超限", &binancePkg.ExchangeError{HTTPStatus: 429, Code: binancePkg.CodeTooManyRequests}, true, false, false},
+		{"Binance 保证金不足", &binancePkg.ExchangeError{HTTPStatus: 400, Code: binancePkg.CodeMarginInsufficient}, false, true, false},
+		{"Binance API Key 无效", &binancePkg.ExchangeError{HTTPStatus: 401, Code: binancePkg.CodeRejectedAPIKey}, false, false, true},
+		{"包装后的错误", fmt.Errorf("下单失败: %w", &bybitPkg.ExchangeError{HTTPStatus: 502}), true, false, false},
+		{"普通错误", errors.New("其它错误"), false, false, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsRetryable(tc.err); got != tc.retryable {
+				t.Errorf("IsRetryable = %v，期望 %v", got, tc.retryable)
+			}
+			if got := IsInsufficientBalance(tc.err); got != tc.balance {
+				t.Errorf("IsInsufficientBalance = %v，期望 %v", got, tc.balance)
+			}
+			if got := IsPermissionDenied(tc.err); got != tc.permission {
+				t.Errorf("IsPermissionDenied = %v，期望 %v", got, tc.permission)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrUnsupported 被包装的交易所不支持该可选能力
+var ErrUnsupported = errors.New("交易所不支持该操作")
+
+// ErrUnsupportedDepth 交易所不支持订阅的订单簿档位（配置错误，重连也无法恢复）
+var ErrUnsupportedDepth = errors.New("交易所不支持该订单簿档位")
+
+// scaledExchange 按数量比例与计价币汇率换算交易所单位，对外（引擎）一律使用参照单位：
+//
+//	ratio：该交易所每 1 单位参照交易所（A所）数量对应的本所数量，数量 ÷ ratio、价格 × ratio，名义价值不变
+//	rate：本所计价币兑参照计价币的汇率（如 USDC→USDT），价格、手续费 × rate，每次换算时读取最新值
+//
+// 下单时换算回本所单位，并按本所数量步长与价格步长独立取整
+type scaledExchange struct {
+	Exchange
+	ratio float64
+	rate  func() float64
+	raw   atomic.Pointer[Instrument] // 本所原始规格（首次 Instrument 查询后缓存）
+}
+
+// Scaled 返回按 ratio 换算数量与价格的交易所包装，ratio 为 1 时直接返回 ex
+func Scaled(ex Exchange, ratio float64) (Exchange, error) {
+	if ratio <= 0 || math.IsNaN(ratio) || math.IsInf(ratio, 0) {
+		return nil, fmt.Errorf("数量比例必须为正数: %g", ratio)
+	}
+	if ratio == 1 {
+		return ex, nil
+	}
+	return &scaledExchange{Exchange: ex, ratio: ratio, rate: func() float64 { return 1 }}, nil
+}
+
+// ConvertQuote 返回按计价币汇率换算价格的交易所包装，rate 返回本所计价币兑参照计价币的当前汇率
+func ConvertQuote(ex Exchange, rate func() float64) Exchange {
+	return &scaledExchange{Exchange: ex, ratio: 1, rate: rate}
+}
+
+func (s *scaledExchange) qty(v float64) float64   { return v / s.ratio }
+func (s *scaledExchange) price(v float64) float64 { return v * s.ratio * s.rate() }
+func (s *scaledExchange) fee(v float64) float64   { return v * s.rate() }
+
+func (s *scaledExchange) Instrument(ctx context.Context) (*Instrument, error) {
+	inst, err := s.Exchange.Instrument(ctx)
+	if err != nil {
+		return nil, err
+	}
+	raw := *inst
+	s.raw.Store(&raw)
+	return &Instrument{
+		TickSize:    s.price(inst.TickSize),
+		QtyStep:     s.qty(inst.QtyStep),
+		MinQty:      s.qty(inst.MinQty),
+		MaxQty:      s.qty(inst.MaxQty),
+		MinNotional: s.fee(inst.MinNotional),
+	}, nil
+}
+
+func (s *scaledExchange) BestPrice(ctx context.Context) (*BestPrice, error) {
+	bp, err := s.Exchange.BestPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &BestPrice{
+		Bid: s.price(bp.Bid), BidSize: s.qty(bp.BidSize),
+		Ask: s.price(bp.Ask), AskSize: s.qty(bp.AskSize),
+	}, nil
+}
+
+func (s *scaledExchange) GetPositions(ctx context.Context) ([]Position, error) {
+	positions, err := s.Exchange.GetPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range positions {
+		positions[i].Size = s.qty(positions[i].Size)
+		positions[i].EntryPrice = s.price(positions[i].EntryPrice)
+	}
+	return positions, nil
+}
+
+// PlaceOrder 将参照单位的数量与价格换算为本所单位：数量向下取整到本所步长，价格买单向上、卖单向下取整到本所价格步长
+func (s *scaledExchange) PlaceOrder(ctx context.Context, req *OrderRequest) (*Order, error) {
+	raw := s.raw.Load()
+	if raw == nil {
+		if _, err := s.Instrument(ctx); err != nil {
+			return nil, fmt.Errorf("查询交易对规格失败，无法换算下单数量: %w", err)
+		}
+		raw = s.raw.Load()
+	}
+
+	scaled := *req
+	qty, err := strconv.ParseFloat(req.Qty, 64)
+	if err != nil {
+		return nil, fmt.Errorf("解析下单数量 %q 失败: %w", req.Qty, err)
+	}
+	scaled.Qty = formatStep(roundStep(qty*s.ratio, raw.QtyStep, false), raw.QtyStep)
+	if req.Price != "" {
+		price, err := strconv.ParseFloat(req.Price, 64)
+		if err != nil {
+			return nil, fmt.Errorf("解析下单价格 %q 失败: %w", req.Price, err)
+		}
+		scaled.Price = formatStep(roundStep(price/(s.ratio*s.rate()), raw.TickSize, req.Side == Buy), raw.TickSize)
+	}
+
+	order, err := s.Exchange.PlaceOrder(ctx, &scaled)
+	req.ClientID = scaled.ClientID
+	if err != nil {
+		return nil, err
+	}
+	return s.order(order), nil
+}
+
+func (s *scaledExchange) GetOrder(ctx context.Context, orderID string) (*Order, error) {
+	o, err := s.Exchange.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return s.order(o), nil
+}
+
+func (s *scaledExchange) GetOrderByClientID(ctx context.Context, clientID string) (*Order, error) {
+	o, err := s.Exchange.GetOrderByClientID(ctx, clientID)
+	if err != nil || o == nil {
+		return o, err
+	}
+	return s.order(o), nil
+}
+
+func (s *scaledExchange) OpenOrders(ctx context.Context) ([]Order, error) {
+	orders, err := s.Exchange.OpenOrders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		orders[i] = *s.order(&orders[i])
+	}
+	return orders, nil
+}
+
+// order 将本所订单换算为参照单位（返回副本）
+func (s *scaledExchange) order(o *Order) *Order {
+	c := *o
+	c.Qty, c.FilledQty = s.qty(o.Qty), s.qty(o.FilledQty)
+	c.Price, c.AvgPrice = s.price(o.Price), s.price(o.AvgPrice)
+	c.Fee = s.fee(o.Fee)
+	return &c
+}
+
+func (s *scaledExchange) SubscribeOrderBook(depth int, cb func(*OrderBook)) error {
+	return s.Exchange.SubscribeOrderBook(depth, func(ob *OrderBook) {
+		book := &OrderBook{Bids: s.levels(ob.Bids), Asks: s.levels(ob.Asks), Ts: ob.Ts}
+		cb(book)
+	})
+}
+
+func (s *scaledExchange) levels(levels []Level) []Level {
+	out := make([]Level, len(levels))
+	for i, l := range levels {
+		out[i] = Level{Price: s.price(l.Price), Size: s.qty(l.Size)}
+	}
+	return out
+}
+
+// 可选能力：被包装的交易所未实现时按不支持处理
+
+func (s *scaledExchange) Prepare(ctx context.Context) error {
+	if p, ok := s.Exchange.(Preparer); ok {
+		return p.Prepare(ctx)
+	}
+	return nil
+}
+
+func (s *scaledExchange) Funding(ctx context.Context) (*Funding, error) {
+	if fp, ok := s.Exchange.(FundingProvider); ok {
+		return fp.Funding(ctx)
+	}
+	return nil, ErrUnsupported
+}
+
+func (s *scaledExchange) SubscribeExecutions(cb func(*Execution)) (bool, error) {
+	es, ok := s.Exchange.(ExecutionStreamer)
+	if !ok {
+		return false, nil
+	}
+	return es.SubscribeExecutions(func(ex *Execution) {
+		c := *ex
+		c.Qty, c.Price, c.Fee = s.qty(ex.Qty), s.price(ex.Price), s.fee(ex.Fee)
+		cb(&c)
+	})
+}
+
+func (s *scaledExchange) ExecutionsReady() bool {
+	es, ok := s.Exchange.(ExecutionStreamer)
+	return ok && es.ExecutionsReady()
+}
+
+func (s *scaledExchange) SubscribeTrades(cb func(*Trade)) error {
+	ts, ok := s.Exchange.(TradeStreamer)
+	if !ok {
+		return ErrUnsupported
+	}
+	return ts.SubscribeTrades(func(t *Trade) {
+		c := *t
+		c.Size, c.Price = s.qty(t.Size), s.price(t.Price)
+		cb(&c)
+	})
+}
+
+// roundStep 按步长取整（up 为向上，否则向下），step 无效时原样返回
+func roundStep(v, step float64, up bool) float64 {
+	if step <= 0 {
+		return v
+	}
+	if up {
+		return math.Ceil(v/step-1e-9) * step
+	}
+	return math.Floor(v/step+1e-9) * step
+}
+
+// formatStep 按步长的小数位数格式化，step 无效时保留 8 位小数
+func formatStep(v, step float64) string {
+	decimals := 8
+	if step > 0 {
+		s := strconv.FormatFloat(step, 'f', -1, 64)
+		decimals = 0
+		if i := strings.IndexByte(s, '.'); i >= 0 {
+			decimals = len(s) - i - 1
+		}
+	}
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
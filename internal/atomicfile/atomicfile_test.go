@@ -0,0 +1,52 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteReplacesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	for _, content := range []string{`{"v":1}`, `{"v":2}`} {
+		if err := Write(path, []byte(content)); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil || string(got) != content {
+			t.Fatalf("读取到 %q (err=%v)，期望 %q", got, err, content)
+		}
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Fatalf("目录中残留临时文件: %v", entries)
+	}
+}
+
+// TestWriteLeavesOldContentOnFailure 写入失败（模拟写到一半被杀）时目标文件保持原内容
+func TestWriteLeavesOldContentOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	if err := Write(path, []byte(`{"v":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 崩溃残留的半截临时文件不影响目标文件
+	if err := os.WriteFile(filepath.Join(dir, ".state.json-123"), []byte(`{"v":`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// 目标是目录时重命名失败
+	if err := Write(dir, []byte(`{"v":2}`)); err == nil {
+		t.Fatal("覆盖目录应失败")
+	}
+	if err := Write(filepath.Join(dir, "missing", "state.json"), []byte(`{"v":2}`)); err == nil {
+		t.Fatal("目录不存在时应失败")
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil || string(got) != `{"v":1}` {
+		t.Fatalf("读取到 %q (err=%v)，期望原内容", got, err)
+	}
+}
@@ -0,0 +1,149 @@
+// Package ratelimit 各交易所 REST 客户端共用的按接口分组令牌桶限频
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrExceeded 令牌不足且需要等待的时长超过 MaxWait，请求未发出
+var ErrExceeded = errors.New("本地限频，请求未发出")
+
+// Event 限频事件：请求需要等待或被拒绝
+type Event struct {
+	Group    string
+	Wait     time.Duration // 本地等待时长（Rejected 时为需要等待的时长）
+	Rejected bool          // 等待超过 MaxWait，请求被拒绝
+}
+
+// bucket 令牌桶，blockedUntil 之前不发放令牌
+type bucket struct {
+	rate         float64
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time
+}
+
+func (b *bucket) burst() float64 {
+	if b.rate < 1 {
+		return 1
+	}
+	return b.rate
+}
+
+// reserve 预占一个令牌，返回需要等待的时长；超过 maxWait 时不预占并返回 false
+func (b *bucket) reserve(now time.Time, maxWait time.Duration) (time.Duration, bool) {
+	var wait time.Duration
+	if now.Before(b.blockedUntil) {
+		wait = b.blockedUntil.Sub(now)
+	}
+	if b.rate > 0 {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst() {
+			b.tokens = b.burst()
+		}
+		b.last = now
+		if b.tokens < 1 {
+			if d := time.Duration((1 - b.tokens) / b.rate * float64(time.Second)); d > wait {
+				wait = d
+			}
+		}
+	}
+	if maxWait > 0 && wait > maxWait {
+		return wait, false
+	}
+	if b.rate > 0 {
+		b.tokens--
+	}
+	return wait, true
+}
+
+// refund 归还 reserve 预占的令牌
+func (b *bucket) refund() {
+	if b.rate <= 0 {
+		return
+	}
+	b.tokens++
+	if b.tokens > b.burst() {
+		b.tokens = b.burst()
+	}
+}
+
+// Limiter 按接口分组限频，未配置的分组不限制；nil 表示不限制
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	maxWait time.Duration
+	hook    func(Event)
+}
+
+// New 创建限频器，rates 为各分组每秒请求数（<=0 表示不限制）；
+// maxWait 为令牌不足时最长等待，0 表示一直等待；hook 可为 nil
+func New(rates map[string]float64, maxWait time.Duration, hook func(Event)) *Limiter {
+	now := time.Now()
+	l := &Limiter{
+		buckets: make(map[string]*bucket, len(rates)),
+		maxWait: maxWait,
+		hook:    hook,
+	}
+	for group, rate := range rates {
+		l.buckets[group] = &bucket{rate: rate, tokens: rate, last: now}
+	}
+	return l
+}
+
+// Wait 等待 group 的令牌，等待超过 maxWait 时返回 ErrExceeded；
+// 等待期间 ctx 取消时归还令牌并返回 ctx.Err()
+func (l *Limiter) Wait(ctx context.Context, group string) error {
+	if l == nil {
+		return nil
+	}
+	b, ok := l.buckets[group]
+	if !ok {
+		return nil
+	}
+	l.mu.Lock()
+	wait, ok := b.reserve(time.Now(), l.maxWait)
+	l.mu.Unlock()
+
+	if wait > 0 && l.hook != nil {
+		l.hook(Event{Group: group, Wait: wait, Rejected: !ok})
+	}
+	if !ok {
+		return ErrExceeded
+	}
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		l.mu.Lock()
+		b.refund()
+		l.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Block 暂停 group 到 until，返回是否延长了暂停时间
+func (l *Limiter) Block(group string, until time.Time) bool {
+	if l == nil {
+		return false
+	}
+	b, ok := l.buckets[group]
+	if !ok {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !until.After(b.blockedUntil) {
+		return false
+	}
+	b.blockedUntil = until
+	return true
+}
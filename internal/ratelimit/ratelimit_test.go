@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitRejectsBeyondMaxWait(t *testing.T) {
+	var events []Event
+	l := New(map[string]float64{"order": 1}, 100*time.Millisecond, func(ev Event) { events = append(events, ev) })
+
+	if err := l.Wait(context.Background(), "order"); err != nil {
+		t.Fatalf("首个请求应直接放行: %v", err)
+	}
+	if err := l.Wait(context.Background(), "order"); !errors.Is(err, ErrExceeded) {
+		t.Fatalf("令牌不足且超过 MaxWait 应返回 ErrExceeded，实际 %v", err)
+	}
+	if len(events) != 1 || !events[0].Rejected || events[0].Group != "order" {
+		t.Fatalf("应上报一次拒绝事件，实际 %+v", events)
+	}
+}
+
+func TestWaitRefundsTokenOnCancel(t *testing.T) {
+	l := New(map[string]float64{"order": 1}, 0, nil)
+	if err := l.Wait(context.Background(), "order"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx, "order"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ctx 取消应返回 context.Canceled，实际 %v", err)
+	}
+
+	// 取消的请求归还了令牌，下一个请求只需等待约 1 秒而不是 2 秒
+	l.mu.Lock()
+	wait, _ := l.buckets["order"].reserve(time.Now(), 0)
+	l.mu.Unlock()
+	if wait > 1100*time.Millisecond {
+		t.Fatalf("取消后令牌未归还，需等待 %v", wait)
+	}
+}
+
+func TestBlockDelaysGroup(t *testing.T) {
+	l := New(map[string]float64{"market": 0, "order": 0}, 50*time.Millisecond, nil)
+	until := time.Now().Add(time.Second)
+	if !l.Block("market", until) {
+		t.Fatal("首次 Block 应延长暂停时间")
+	}
+	if l.Block("market", until.Add(-time.Millisecond)) {
+		t.Fatal("更早的 until 不应缩短暂停时间")
+	}
+	if err := l.Wait(context.Background(), "market"); !errors.Is(err, ErrExceeded) {
+		t.Fatalf("暂停期间等待超过 MaxWait 应被拒绝，实际 %v", err)
+	}
+	if err := l.Wait(context.Background(), "order"); err != nil {
+		t.Fatalf("其他分组不受影响: %v", err)
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	var l *Limiter
+	if err := l.Wait(context.Background(), "order"); err != nil {
+		t.Fatal(err)
+	}
+	if l.Block("order", time.Now().Add(time.Second)) {
+		t.Fatal("nil 限频器不应暂停")
+	}
+}
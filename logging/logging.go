@@ -0,0 +1,173 @@
+// Package logging 按配置初始化 log/slog：日志级别（debug / info / warn / error）、输出格式（text / json）与日志文件
+//
+// Setup 将 logger 设为 slog 默认值，此后标准库 log 包的输出也经由同一 handler（info 级别）；
+// 配置了 logging.file 时输出到文件，Rotate 将其重命名为带日期后缀的备份并重新打开
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"arb/config"
+)
+
+// 输出格式
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// ParseLevel 解析日志级别，空字符串为 info
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("logging.level 取值无效: %q（可选: debug, info, warn, error）", s)
+}
+
+// New 按配置创建输出到 w 的 logger
+func New(cfg config.LoggingConfig, w io.Writer) (*slog.Logger, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch strings.ToLower(cfg.Format) {
+	case "", FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("logging.format 取值无效: %q（可选: %s, %s）", cfg.Format, FormatText, FormatJSON)
+}
+
+// Setup 按配置创建 logger 并设为默认值：配置了 logging.file 时输出到该文件，否则输出到标准错误
+// 需在创建交易所适配器前调用：适配器在创建时从默认 logger 派生带交易所字段的 logger
+func Setup(cfg config.LoggingConfig) error {
+	var w io.Writer = os.Stderr
+	var rf *rotatingFile
+	if cfg.File != "" {
+		f, err := openRotatingFile(cfg.File, cfg.MaxBackups)
+		if err != nil {
+			return err
+		}
+		w, rf = f, f
+	}
+
+	l, err := New(cfg, w)
+	if err != nil {
+		if rf != nil {
+			rf.close()
+		}
+		return err
+	}
+
+	activeMu.Lock()
+	prev := active
+	active = rf
+	activeMu.Unlock()
+	slog.SetDefault(l)
+	if prev != nil {
+		prev.close()
+	}
+	return nil
+}
+
+// Rotate 将当前日志文件重命名为 <file>.<YYYYMMDD>（同日重复轮转追加时间）并重新打开，返回备份路径
+// 未配置 logging.file 时为空操作，返回空路径
+func Rotate(day time.Time) (string, error) {
+	activeMu.Lock()
+	rf := active
+	activeMu.Unlock()
+	if rf == nil {
+		return "", nil
+	}
+	return rf.rotate(day)
+}
+
+var (
+	activeMu sync.Mutex
+	active   *rotatingFile // 当前默认 logger 使用的日志文件
+)
+
+// rotatingFile 支持轮转的日志文件，Write 与 rotate 互斥
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxBackups int
+	f          *os.File
+}
+
+func openRotatingFile(path string, maxBackups int) (*rotatingFile, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("打开日志文件 %s 失败: %w", path, err)
+	}
+	return &rotatingFile{path: path, maxBackups: maxBackups, f: f}, nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Write(p)
+}
+
+func (r *rotatingFile) rotate(day time.Time) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	backup := r.path + "." + day.Format("20060102")
+	if _, err := os.Stat(backup); err == nil {
+		backup += "-" + time.Now().Format("150405")
+	}
+	if err := os.Rename(r.path, backup); err != nil {
+		return "", fmt.Errorf("重命名日志文件 %s 失败: %w", r.path, err)
+	}
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		// 新文件打不开时继续写入已重命名的备份，不丢日志
+		return backup, fmt.Errorf("重新打开日志文件 %s 失败: %w", r.path, err)
+	}
+	r.f.Close()
+	r.f = f
+	return backup, r.pruneBackups()
+}
+
+// pruneBackups 保留最新的 maxBackups 个备份（文件名中的日期按字典序即时间顺序）
+func (r *rotatingFile) pruneBackups() error {
+	if r.maxBackups <= 0 {
+		return nil
+	}
+	backups, err := filepath.Glob(r.path + ".[0-9]*")
+	if err != nil || len(backups) <= r.maxBackups {
+		return err
+	}
+	sort.Strings(backups)
+	for _, old := range backups[:len(backups)-r.maxBackups] {
+		if err := os.Remove(old); err != nil {
+			return fmt.Errorf("删除旧日志备份 %s 失败: %w", old, err)
+		}
+	}
+	return nil
+}
+
+func (r *rotatingFile) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.f.Close()
+}
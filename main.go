@@ -1,60 +1,123 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"arb/config"
+	"arb/logging"
 	"arb/strategy"
 )
 
+// version 程序版本，构建时通过 -ldflags "-X main.version=..." 注入
+var version = "dev"
+
 func main() {
+	// 子命令：arb report --date YYYY-MM-DD 打印交易流水日汇总
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		os.Exit(runReport(os.Args[2:]))
+	}
+
+	cfgPath := flag.String("config", "config.yaml", "配置文件路径")
+	mode := flag.Int("mode", -1, "运行模式，覆盖配置文件中的 mode（0=行情记录 1=模型一 2=模型二 9=回测）")
+	showVersion := flag.Bool("version", false, "打印版本号后退出")
+	backtest := flag.Bool("backtest", false, "回测：arb -backtest file_a file_b [-v]")
+	verbose := flag.Bool("v", false, "回测时保留决策日志")
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Println(version)
+		return
+	}
+
 	// 加载配置
-	cfg, err := config.Load("config.yaml")
+	cfg, err := config.Load(*cfgPath)
 	if err != nil {
 		log.Fatalf("加载配置失败: %v", err)
 	}
+	if *mode >= 0 {
+		cfg.Mode = *mode
+	}
+	if err := logging.Setup(cfg.Logging); err != nil {
+		log.Fatalf("初始化日志失败: %v", err)
+	}
+
+	// 回测：arb -backtest file_a file_b [-v]，或 mode: 9 使用配置中的 backtest.file_a / file_b
+	if *backtest {
+		if flag.NArg() < 2 {
+			fatal("用法: " + os.Args[0] + " -backtest file_a file_b [-v]")
+		}
+		// -v 写在文件参数之后时 flag 包不再解析，这里兼容旧用法
+		v := *verbose || flag.Arg(2) == "-v"
+		os.Exit(runBacktest(cfg, flag.Arg(0), flag.Arg(1), v))
+	}
+	if cfg.Mode == 9 {
+		os.Exit(runBacktest(cfg, cfg.Backtest.FileA, cfg.Backtest.FileB, false))
+	}
 
-	log.Printf("启动 Apex-Bybit 套利程序")
-	log.Printf("A所（Apex）交易对: %s", cfg.ApexSymbol)
-	log.Printf("B所（Bybit）交易对: %s", cfg.BybitSymbol)
-	log.Printf("运行模式: %d", cfg.Mode)
+	slog.Info("[启动] Apex-Bybit 套利程序", "version", version, "config", *cfgPath,
+		"apex_symbol", cfg.ApexSymbol, "bybit_symbol", cfg.BybitSymbol, "mode", cfg.Mode)
 
 	// 等待退出信号
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
 	switch cfg.Mode {
+	case 0:
+		// 只记录行情：复用引擎的行情订阅与断线重连，不下单
+		slog.Info("=== 启动行情记录模式 ===")
+		cfg.Strategy.MonitorOnly = true
+		cfg.Recorder.Enabled = true
+		engine, err := strategy.NewArbEngine(cfg)
+		if err != nil {
+			fatal("初始化行情记录失败", "err", err)
+		}
+		if err := engine.Start(); err != nil {
+			fatal("启动行情记录失败", "err", err)
+		}
+		<-quit
+		slog.Info("收到退出信号，正在停止行情记录...")
+		engine.Stop()
+
 	case 2:
 		// 模型二：跨交易所联动套利 + 做市商被动抬价
-		log.Println("=== 启动模型二：跨交易所联动套利 + 做市商被动抬价 ===")
+		slog.Info("=== 启动模型二：跨交易所联动套利 + 做市商被动抬价 ===")
 		engine, err := strategy.NewModel2Engine(cfg)
 		if err != nil {
-			log.Fatalf("初始化模型二引擎失败: %v", err)
+			fatal("初始化模型二引擎失败", "err", err)
 		}
 		if err := engine.Start(); err != nil {
-			log.Fatalf("启动模型二引擎失败: %v", err)
+			fatal("启动模型二引擎失败", "err", err)
 		}
 		<-quit
-		log.Println("收到退出信号，正在停止模型二引擎...")
+		slog.Info("收到退出信号，正在停止模型二引擎...")
 		engine.Stop()
 
 	default:
 		// 模型一：被动价差套利（默认）
-		log.Println("=== 启动模型一：被动价差套利 ===")
+		slog.Info("=== 启动模型一：被动价差套利 ===")
 		engine, err := strategy.NewArbEngine(cfg)
 		if err != nil {
-			log.Fatalf("初始化套利引擎失败: %v", err)
+			fatal("初始化套利引擎失败", "err", err)
 		}
 		if err := engine.Start(); err != nil {
-			log.Fatalf("启动套利引擎失败: %v", err)
+			fatal("启动套利引擎失败", "err", err)
 		}
 		<-quit
-		log.Println("收到退出信号，正在停止套利引擎...")
+		slog.Info("收到退出信号，正在停止套利引擎...")
 		engine.Stop()
 	}
 
-	log.Println("程序已安全退出")
+	slog.Info("程序已安全退出")
+}
+
+// fatal 记录错误日志后退出进程（日志初始化之后使用，保证按配置的格式与级别输出）
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
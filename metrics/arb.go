@@ -0,0 +1,67 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// 套利引擎与风控指标，由各模块直接更新
+var (
+	// TradesTotal 已记账的套利交易笔数
+	TradesTotal = newCounter("arb_trades_total", "已记账的套利交易笔数")
+
+	// TradesByScenario 按场景统计的交易笔数（1=Apex 买 Bybit 卖，2=Apex 卖 Bybit 买）
+	TradesByScenario = newCounterVec("arb_trades_by_scenario_total", "按场景统计的套利交易笔数", "scenario")
+	TradesScenario1  = TradesByScenario.WithLabelValues("1")
+	TradesScenario2  = TradesByScenario.WithLabelValues("2")
+
+	// TotalPnL 累计已实现盈亏（USDC）
+	TotalPnL = newGauge("arb_pnl_total_usdc", "累计已实现盈亏（USDC）")
+
+	// DailyPnL 风控当日累计盈亏（USDC）
+	DailyPnL = newGauge("arb_pnl_daily_usdc", "风控当日累计盈亏（USDC）")
+
+	// Spread 当前两所毛价差（USDC），按场景区分
+	Spread  = newGaugeVec("arb_spread_usdc", "当前两所毛价差（USDC）", "scenario")
+	Spread1 = Spread.WithLabelValues("1")
+	Spread2 = Spread.WithLabelValues("2")
+
+	// PositionImbalance 两所真实净持仓之和（合约张数），由持仓平衡核对定时更新
+	PositionImbalance = newGauge("arb_position_imbalance", "两所真实净持仓之和（合约张数，对冲模式下应接近 0）")
+
+	// PositionImbalanceNotional 两所净持仓之和按中间价折算的名义敞口（USDC，带方向）
+	PositionImbalanceNotional = newGauge("arb_position_imbalance_usdc", "两所净持仓之和按中间价折算的名义敞口（USDC）")
+
+	// LegLatencyAvg 两腿下单返回时间差的指数移动平均（秒）
+	LegLatencyAvg = newGauge("arb_leg_latency_seconds", "两腿下单返回时间差的指数移动平均（秒）")
+
+	// LegLatencyExceeded 两腿时间差超过 max_leg_latency_ms 的次数
+	LegLatencyExceeded = newCounter("arb_leg_latency_exceeded_total", "两腿下单时间差超过上限的次数")
+
+	// CooldownSkipped 因同方向开仓冷却（trade_cooldown_ms）跳过的机会数
+	CooldownSkipped = newCounter("arb_cooldown_skipped_total", "因同方向开仓冷却跳过的机会数")
+
+	// TradeIntervalSkipped 因最小交易间隔（min_trade_interval_ms）跳过的机会数
+	TradeIntervalSkipped = newCounter("arb_trade_interval_skipped_total", "因最小交易间隔跳过的机会数")
+)
+
+func newCounter(name, help string) prometheus.Counter {
+	c := prometheus.NewCounter(prometheus.CounterOpts{Name: name, Help: help})
+	Registry.MustRegister(c)
+	return c
+}
+
+func newCounterVec(name, help string, labels ...string) *prometheus.CounterVec {
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels)
+	Registry.MustRegister(c)
+	return c
+}
+
+func newGauge(name, help string) prometheus.Gauge {
+	g := prometheus.NewGauge(prometheus.GaugeOpts{Name: name, Help: help})
+	Registry.MustRegister(g)
+	return g
+}
+
+func newGaugeVec(name, help string, labels ...string) *prometheus.GaugeVec {
+	g := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help}, labels)
+	Registry.MustRegister(g)
+	return g
+}
@@ -0,0 +1,67 @@
+// Package metrics 基于 prometheus/client_golang 暴露运行指标（/metrics）
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry 进程级指标注册表：arb.go 中由各模块直接更新的指标在包初始化时注册一次
+// 与单个引擎实例绑定的指标（持仓、WS 状况等）注册在引擎自己的注册表中，由 Serve 一并输出
+var Registry = prometheus.NewRegistry()
+
+// Handler 返回输出 Registry 与 extra 中全部指标的 HTTP 处理器
+func Handler(extra ...prometheus.Gatherer) http.Handler {
+	gatherers := append(prometheus.Gatherers{Registry}, extra...)
+	return promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{ErrorLog: errorLog{}})
+}
+
+// errorLog 将 promhttp 的采集错误写入 slog
+type errorLog struct{}
+
+func (errorLog) Println(v ...interface{}) {
+	slog.Warn("[指标] 采集失败", "err", fmt.Sprint(v...))
+}
+
+// Server 指标 HTTP 服务
+type Server struct {
+	srv *http.Server
+}
+
+// Serve 在 addr 上启动 /metrics 服务，输出 Registry 与 extra 中的指标；监听失败时返回错误
+func Serve(addr string, extra ...prometheus.Gatherer) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("指标服务监听 %s 失败: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler(extra...))
+	s := &Server{srv: &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}}
+
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("[指标] 服务异常退出", "err", err)
+		}
+	}()
+	slog.Info("[指标] Prometheus 指标已启动", "addr", ln.Addr().String(), "path", "/metrics")
+	return s, nil
+}
+
+// Close 关闭指标服务
+func (s *Server) Close() {
+	if s == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	s.srv.Shutdown(ctx)
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TestHandlerExposition 输出进程级指标与额外注册表中的指标，标签按 Prometheus 文本格式转义
+func TestHandlerExposition(t *testing.T) {
+	TradesScenario1.Inc()
+	Spread2.Set(-1.5)
+
+	extra := prometheus.NewRegistry()
+	extra.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "arb_test_gauge",
+		Help:        "测试",
+		ConstLabels: prometheus.Labels{"exchange": `a"b\c`},
+	}, func() float64 { return 42 }))
+
+	rec := httptest.NewRecorder()
+	Handler(extra).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	out := string(body)
+
+	for _, want := range []string{
+		"# TYPE arb_trades_by_scenario_total counter",
+		`arb_trades_by_scenario_total{scenario="1"} 1`,
+		"# TYPE arb_spread_usdc gauge",
+		`arb_spread_usdc{scenario="2"} -1.5`,
+		`arb_test_gauge{exchange="a\"b\\c"} 42`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("输出缺少 %q:\n%s", want, out)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Fatalf("Content-Type = %q，期望文本格式", ct)
+	}
+}
+
+// TestHandlerRejectsDuplicateSeries 不同注册表输出同名同标签的序列时采集报错，而不是输出重复样本
+func TestHandlerRejectsDuplicateSeries(t *testing.T) {
+	gauge := func() *prometheus.Registry {
+		r := prometheus.NewRegistry()
+		r.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: "arb_dup", Help: "重复"}, func() float64 { return 1 }))
+		return r
+	}
+
+	rec := httptest.NewRecorder()
+	Handler(gauge(), gauge()).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != 500 {
+		t.Fatalf("重复序列应导致采集失败，状态码 = %d", rec.Code)
+	}
+}
@@ -0,0 +1,231 @@
+package opportunity
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SpreadView 一次价差评估的快照，推送给外部信号消费者
+type SpreadView struct {
+	Seq        uint64    `json:"seq"`        // 推送序号（单调递增，可用于检测丢包）
+	Time       time.Time `json:"time"`       // 评估时间
+	Scenario   int       `json:"scenario"`   // 1=Apex 买 Bybit 卖，2=Apex 卖 Bybit 买
+	Actionable bool      `json:"actionable"` // true=达到触发阈值，false=接近阈值（near-miss）
+
+	ApexSymbol  string  `json:"apexSymbol"`
+	BybitSymbol string  `json:"bybitSymbol"`
+	ApexBid     float64 `json:"apexBid"`
+	ApexAsk     float64 `json:"apexAsk"`
+	BybitBid    float64 `json:"bybitBid"`
+	BybitAsk    float64 `json:"bybitAsk"`
+	Spread      float64 `json:"spread"`    // 毛价差（USDC）
+	NetSpread   float64 `json:"netSpread"` // 扣除两腿手续费后的净价差（USDC）
+	MinSpread   float64 `json:"minSpread"` // 触发阈值（USDC，与净价差比较）
+
+	NetSpreadBps float64 `json:"netSpreadBps"` // 净价差相对两所成交价中间价的基点数
+	MinSpreadBps float64 `json:"minSpreadBps"` // 基点触发阈值，0 表示未配置
+
+	// 两所行情时间戳（毫秒），消费者据此判断行情新鲜度
+	ApexQuoteTs     int64 `json:"apexQuoteTs"`     // Apex 推送时间戳
+	BybitQuoteTs    int64 `json:"bybitQuoteTs"`    // Bybit 推送时间戳
+	ApexReceivedAt  int64 `json:"apexReceivedAt"`  // 本地收到 Apex 行情的时间
+	BybitReceivedAt int64 `json:"bybitReceivedAt"` // 本地收到 Bybit 行情的时间
+}
+
+const defaultBufferSize = 256
+
+// subscriber 单个消费者，缓冲满时丢弃最旧的消息
+type subscriber struct {
+	ch      chan SpreadView
+	dropped atomic.Uint64
+}
+
+// Publisher 套利机会发布器
+// 支持进程内 channel 订阅，以及通过 Unix socket / TCP 推送换行分隔的 JSON
+type Publisher struct {
+	bufSize int
+
+	mu   sync.Mutex
+	seq  uint64
+	subs map[*subscriber]struct{}
+
+	// 所有消费者累计丢弃的消息数
+	dropped atomic.Uint64
+
+	ln     net.Listener
+	closed atomic.Bool
+}
+
+// NewPublisher 创建发布器，bufSize 为每个消费者的缓冲大小
+func NewPublisher(bufSize int) *Publisher {
+	if bufSize <= 0 {
+		bufSize = defaultBufferSize
+	}
+	return &Publisher{
+		bufSize: bufSize,
+		subs:    make(map[*subscriber]struct{}),
+	}
+}
+
+// Publish 分配序号并推送给所有消费者，永不阻塞调用方
+func (p *Publisher) Publish(v SpreadView) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.seq++
+	v.Seq = p.seq
+
+	for s := range p.subs {
+		select {
+		case s.ch <- v:
+			continue
+		default:
+		}
+
+		// 慢消费者：丢弃最旧的一条，为最新消息腾出位置
+		select {
+		case <-s.ch:
+		default:
+		}
+		select {
+		case s.ch <- v:
+		default:
+		}
+		s.dropped.Add(1)
+		p.dropped.Add(1)
+	}
+}
+
+// Subscribe 进程内订阅，返回消息 channel 和取消订阅函数
+func (p *Publisher) Subscribe() (<-chan SpreadView, func()) {
+	s := p.subscribe()
+	return s.ch, func() { p.unsubscribe(s) }
+}
+
+// Dropped 返回所有消费者累计丢弃的消息数
+func (p *Publisher) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// Subscribers 返回当前消费者数量
+func (p *Publisher) Subscribers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.subs)
+}
+
+// Listen 在 network（unix / tcp）上监听，为每个连接推送换行分隔的 JSON
+func (p *Publisher) Listen(network, address string) error {
+	if network == "unix" {
+		// 清理上次运行残留的 socket 文件
+		_ = os.Remove(address)
+	}
+
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return fmt.Errorf("[机会推送] 监听 %s %s 失败: %w", network, address, err)
+	}
+
+	p.mu.Lock()
+	p.ln = ln
+	p.mu.Unlock()
+
+	slog.Info("[机会推送] 开始监听", "network", network, "address", address)
+	go p.acceptLoop(ln)
+	return nil
+}
+
+// Addr 返回监听地址（未监听时为 nil）
+func (p *Publisher) Addr() net.Addr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ln == nil {
+		return nil
+	}
+	return p.ln.Addr()
+}
+
+// Close 关闭监听并断开所有消费者
+func (p *Publisher) Close() {
+	if !p.closed.CompareAndSwap(false, true) {
+		return
+	}
+
+	p.mu.Lock()
+	ln := p.ln
+	subs := p.subs
+	p.subs = make(map[*subscriber]struct{})
+	p.mu.Unlock()
+
+	if ln != nil {
+		_ = ln.Close()
+	}
+	for s := range subs {
+		close(s.ch)
+	}
+}
+
+// ---- 内部方法 ----
+
+func (p *Publisher) subscribe() *subscriber {
+	s := &subscriber{ch: make(chan SpreadView, p.bufSize)}
+	p.mu.Lock()
+	p.subs[s] = struct{}{}
+	p.mu.Unlock()
+	return s
+}
+
+func (p *Publisher) unsubscribe(s *subscriber) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.subs[s]; ok {
+		delete(p.subs, s)
+		close(s.ch)
+	}
+}
+
+func (p *Publisher) acceptLoop(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if !p.closed.Load() {
+				slog.Error("[机会推送] 接受连接失败，停止监听", "err", err)
+			}
+			return
+		}
+		go p.serveConn(conn)
+	}
+}
+
+// serveConn 向单个连接持续推送，写失败（消费者断开）时注销订阅
+func (p *Publisher) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	s := p.subscribe()
+	defer p.unsubscribe(s)
+
+	remote := conn.RemoteAddr()
+	slog.Info("[机会推送] 消费者已连接", "remote", remote.String())
+
+	w := bufio.NewWriter(conn)
+	enc := json.NewEncoder(w)
+	for v := range s.ch {
+		if err := enc.Encode(v); err != nil {
+			break
+		}
+		// 缓冲中没有积压时立即刷出，降低延迟
+		if len(s.ch) == 0 {
+			if err := w.Flush(); err != nil {
+				break
+			}
+		}
+	}
+	slog.Info("[机会推送] 消费者已断开", "remote", remote.String(), "dropped", s.dropped.Load())
+}
@@ -0,0 +1,144 @@
+package opportunity
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSubscribeDropsOldest(t *testing.T) {
+	p := NewPublisher(2)
+	defer p.Close()
+	ch, cancel := p.Subscribe()
+	defer cancel()
+
+	for i := 0; i < 5; i++ {
+		p.Publish(SpreadView{Scenario: 1})
+	}
+
+	if n := p.Dropped(); n != 3 {
+		t.Fatalf("丢弃 %d 条，期望 3 条", n)
+	}
+	// 保留最新的两条
+	for _, want := range []uint64{4, 5} {
+		if v := <-ch; v.Seq != want {
+			t.Fatalf("收到 seq=%d，期望 %d", v.Seq, want)
+		}
+	}
+}
+
+func TestUnsubscribeClosesChannel(t *testing.T) {
+	p := NewPublisher(1)
+	defer p.Close()
+	ch, cancel := p.Subscribe()
+	cancel()
+	cancel() // 重复取消不应 panic
+
+	if _, ok := <-ch; ok {
+		t.Fatal("取消订阅后 channel 应关闭")
+	}
+	if n := p.Subscribers(); n != 0 {
+		t.Fatalf("消费者数量 = %d，期望 0", n)
+	}
+}
+
+// TestTCPConsumerReconnect 消费者断开后被注销，重新连接后继续收到序号递增的推送
+func TestTCPConsumerReconnect(t *testing.T) {
+	p := NewPublisher(16)
+	defer p.Close()
+	if err := p.Listen("tcp", "127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, r := dialConsumer(t, p)
+	p.Publish(SpreadView{Scenario: 1})
+	first := readView(t, conn, r)
+	if first.Seq != 1 {
+		t.Fatalf("首条 seq=%d，期望 1", first.Seq)
+	}
+
+	// 消费者异常退出：发布端写失败后注销订阅
+	conn.Close()
+	waitFor(t, "断开的消费者被注销", func() bool {
+		p.Publish(SpreadView{Scenario: 1})
+		return p.Subscribers() == 0
+	})
+
+	conn, r = dialConsumer(t, p)
+	defer conn.Close()
+	p.Publish(SpreadView{Scenario: 2})
+	next := readView(t, conn, r)
+	if next.Seq <= first.Seq || next.Scenario != 2 {
+		t.Fatalf("重连后收到 seq=%d scenario=%d，期望 seq>%d 的新消息", next.Seq, next.Scenario, first.Seq)
+	}
+}
+
+// TestTCPSlowConsumerDrops 不读取的消费者占满缓冲后，发布端丢弃旧消息并累加计数，发布不阻塞
+func TestTCPSlowConsumerDrops(t *testing.T) {
+	p := NewPublisher(1)
+	defer p.Close()
+	if err := p.Listen("tcp", "127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, r := dialConsumer(t, p)
+	defer conn.Close()
+
+	waitFor(t, "慢消费者触发丢弃", func() bool {
+		for i := 0; i < 1000; i++ {
+			p.Publish(SpreadView{Scenario: 1, ApexSymbol: "BTC-USDC", BybitSymbol: "BTCUSDT"})
+		}
+		return p.Dropped() > 0
+	})
+	before := p.Dropped()
+	for i := 0; i < 1000; i++ {
+		p.Publish(SpreadView{Scenario: 1})
+	}
+	if p.Dropped() <= before {
+		t.Fatalf("继续发布后丢弃计数未增加: %d", p.Dropped())
+	}
+
+	// 消费者恢复读取后仍能收到消息
+	if v := readView(t, conn, r); v.Seq == 0 {
+		t.Fatal("恢复读取后应收到带序号的消息")
+	}
+}
+
+// dialConsumer 连接发布器并等待订阅生效
+func dialConsumer(t *testing.T, p *Publisher) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	want := p.Subscribers() + 1
+	conn, err := net.Dial("tcp", p.Addr().String())
+	if err != nil {
+		t.Fatalf("连接发布器失败: %v", err)
+	}
+	waitFor(t, "订阅生效", func() bool { return p.Subscribers() == want })
+	return conn, bufio.NewReader(conn)
+}
+
+func readView(t *testing.T, conn net.Conn, r *bufio.Reader) SpreadView {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("读取推送失败: %v", err)
+	}
+	var v SpreadView
+	if err := json.Unmarshal(line, &v); err != nil {
+		t.Fatalf("解析推送失败: %v: %s", err, line)
+	}
+	return v
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待%s超时", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
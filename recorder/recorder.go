@@ -0,0 +1,222 @@
+// Package recorder 行情记录：将两所最优买卖价追加写入按小时（及大小）滚动的 NDJSON 文件，供离线分析与回放
+//
+// Record 只向缓冲 channel 投递，磁盘写入卡住时丢弃数据并计数，不阻塞 WS 读循环；
+// *Recorder 为 nil 时 Record 为空操作
+package recorder
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"arb/config"
+)
+
+// Tick 一条最优买卖价记录（一行 JSON）
+type Tick struct {
+	Exchange string  `json:"exchange"`
+	Symbol   string  `json:"symbol"`
+	Bid      float64 `json:"bid"`
+	Ask      float64 `json:"ask"`
+	BidSize  float64 `json:"bidSize"`
+	AskSize  float64 `json:"askSize"`
+	Ts       int64   `json:"ts"`     // 交易所时间戳（毫秒）
+	RecvTs   int64   `json:"recvTs"` // 本地接收时间（毫秒）
+}
+
+const (
+	defaultBufferSize = 8192
+	flushInterval     = time.Second
+)
+
+// Recorder 行情记录器
+type Recorder struct {
+	dir      string
+	maxBytes int64 // 单个文件大小上限，0 表示只按小时滚动
+
+	mu     sync.RWMutex // 保护 closed 与 ch 的关闭
+	closed bool
+	ch     chan Tick
+	done   chan struct{}
+
+	// 以下字段仅由 writeLoop 访问
+	file    *os.File
+	w       *bufio.Writer
+	hour    string // 当前文件所属小时 YYYYMMDD-HH
+	part    int    // 同一小时内按大小滚动的序号
+	written int64
+
+	recorded atomic.Uint64
+	dropped  atomic.Uint64
+}
+
+// New 创建记录器并启动后台写入，cfg.Path 为输出目录（不存在时创建）
+func New(cfg config.RecorderConfig) (*Recorder, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("recorder.path 不能为空")
+	}
+	if err := os.MkdirAll(cfg.Path, 0o755); err != nil {
+		return nil, fmt.Errorf("创建行情记录目录 %s 失败: %w", cfg.Path, err)
+	}
+	bufSize := cfg.BufferSize
+	if bufSize <= 0 {
+		bufSize = defaultBufferSize
+	}
+	r := &Recorder{
+		dir:      cfg.Path,
+		maxBytes: int64(cfg.MaxFileMB) << 20,
+		ch:       make(chan Tick, bufSize),
+		done:     make(chan struct{}),
+	}
+	go r.writeLoop()
+	return r, nil
+}
+
+// Record 投递一条记录，永不阻塞；队列满时丢弃并计数
+func (r *Recorder) Record(t Tick) {
+	if r == nil {
+		return
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return
+	}
+	select {
+	case r.ch <- t:
+	default:
+		if n := r.dropped.Add(1); n == 1 || n%1000 == 0 {
+			slog.Warn("[行情记录] 写入队列已满，丢弃记录", "dropped", n)
+		}
+	}
+}
+
+// Recorded 返回已写入的记录数
+func (r *Recorder) Recorded() uint64 {
+	if r == nil {
+		return 0
+	}
+	return r.recorded.Load()
+}
+
+// Dropped 返回因队列满被丢弃的记录数
+func (r *Recorder) Dropped() uint64 {
+	if r == nil {
+		return 0
+	}
+	return r.dropped.Load()
+}
+
+// Close 停止接收新记录，写完队列中剩余记录后关闭文件
+func (r *Recorder) Close() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	close(r.ch)
+	r.mu.Unlock()
+	<-r.done
+}
+
+func (r *Recorder) writeLoop() {
+	defer close(r.done)
+	defer r.closeFile()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case t, ok := <-r.ch:
+			if !ok {
+				return
+			}
+			if err := r.write(t); err != nil {
+				slog.Error("[行情记录] 写入失败", "err", err)
+			}
+		case <-ticker.C:
+			if r.w != nil {
+				if err := r.w.Flush(); err != nil {
+					slog.Error("[行情记录] 刷新文件失败", "err", err)
+				}
+			}
+		}
+	}
+}
+
+func (r *Recorder) write(t Tick) error {
+	line, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if err := r.rotate(time.UnixMilli(t.RecvTs), int64(len(line))); err != nil {
+		return err
+	}
+	n, err := r.w.Write(line)
+	r.written += int64(n)
+	if err != nil {
+		return err
+	}
+	r.recorded.Add(1)
+	return nil
+}
+
+// rotate 跨小时或超过大小上限时切换到新文件：md-YYYYMMDD-HH.ndjson、md-YYYYMMDD-HH.1.ndjson …
+func (r *Recorder) rotate(now time.Time, next int64) error {
+	hour := now.Format("20060102-15")
+	switch {
+	case r.file == nil:
+	case hour != r.hour:
+		r.part = 0
+	case r.maxBytes > 0 && r.written+next > r.maxBytes:
+		r.part++
+	default:
+		return nil
+	}
+	r.closeFile()
+	r.hour = hour
+
+	name := fmt.Sprintf("md-%s.ndjson", hour)
+	if r.part > 0 {
+		name = fmt.Sprintf("md-%s.%d.ndjson", hour, r.part)
+	}
+	path := filepath.Join(r.dir, name)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("打开行情记录文件 %s 失败: %w", path, err)
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("读取行情记录文件 %s 失败: %w", path, err)
+	}
+	r.file, r.w, r.written = f, bufio.NewWriterSize(f, 64<<10), st.Size()
+	slog.Info("[行情记录] 写入文件", "path", path)
+	return nil
+}
+
+func (r *Recorder) closeFile() {
+	if r.file == nil {
+		return
+	}
+	if err := r.w.Flush(); err != nil {
+		slog.Error("[行情记录] 刷新文件失败", "err", err)
+	}
+	if err := r.file.Close(); err != nil {
+		slog.Error("[行情记录] 关闭文件失败", "err", err)
+	}
+	r.file, r.w = nil, nil
+}
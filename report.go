@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"arb/config"
+	"arb/store"
+)
+
+// runReport 子命令 report：打印交易流水中某一天的汇总与明细
+//
+//	arb report --date 2024-01-02 [--db trades.db] [--config config.yaml]
+func runReport(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	date := fs.String("date", time.Now().Format("2006-01-02"), "日期（YYYY-MM-DD，本地时区），默认今天")
+	dbPath := fs.String("db", "", "交易流水数据库路径，默认取配置文件中的 journal.path")
+	cfgPath := fs.String("config", "config.yaml", "配置文件路径")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	day, err := store.ParseDate(*date)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	path := *dbPath
+	if path == "" {
+		cfg, err := config.Load(*cfgPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+			return 1
+		}
+		path = cfg.Journal.Path
+	}
+	if path == "" {
+		fmt.Fprintln(os.Stderr, "未指定交易流水数据库：请使用 --db 或配置 journal.path")
+		return 2
+	}
+
+	st, err := store.OpenReadOnly(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer st.Close()
+
+	sum, err := st.DailySummary(day)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	trades, err := st.Trades(day)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Printf("=== 交易日报 %s ===\n", sum.Date)
+	fmt.Printf("套利尝试: %d  A所成交: %d  失败/未完成: %d  盈利笔数: %d\n", sum.Attempts, sum.Filled, sum.Failed, sum.Wins)
+	fmt.Printf("成交量: %.4f  手续费: %.4f USDC  PnL: %.4f USDC\n", sum.Volume, sum.Fees, sum.PnL)
+	if len(trades) == 0 {
+		return 0
+	}
+
+	fmt.Println()
+	fmt.Printf("%-8s %-5s %12s %12s %10s %10s %12s %10s %12s %10s  %s\n",
+		"时间", "方向", "A报价", "B报价", "下单量", "A成交", "A均价", "B成交", "B均价", "PnL", "备注")
+	for _, t := range trades {
+		fmt.Printf("%-8s %-5s %12.4f %12.4f %10.4f %10.4f %12.4f %10.4f %12.4f %10.4f  %s\n",
+			t.Time.Format("15:04:05"), t.Direction, t.QuoteA, t.QuoteB, t.Size,
+			t.FillQtyA, t.FillPriceA, t.FillQtyB, t.FillPriceB, t.PnL, t.Failure)
+	}
+	return 0
+}
@@ -1,12 +1,19 @@
 package risk
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
+	"os"
 	"sync"
 	"time"
 
+	"arb/alert"
 	"arb/config"
+	"arb/internal/atomicfile"
+	"arb/metrics"
 )
 
 // Controller 风控控制器
@@ -25,20 +32,45 @@ type Controller struct {
 	halted    bool
 	haltedMsg string
 
+	// 冷却截止时间：熔断时设置，期间拒绝开仓，不随人工重置或日切清除
+	haltedUntil time.Time
+
 	// 当日重置时间
 	dayStart time.Time
+
+	// 日切时区（reset_timezone，默认 UTC）
+	loc *time.Location
+
+	// 触发熔断时的回调（持有锁时调用，回调中不得调用 Controller 方法）
+	onHalt func(reason string)
+}
+
+// persistedState 持久化到状态文件的风控状态
+type persistedState struct {
+	DailyPnL        float64   `json:"daily_pnl"`
+	ConsecutiveLoss int       `json:"consecutive_loss"`
+	Halted          bool      `json:"halted"`
+	HaltedMsg       string    `json:"halted_msg"`
+	HaltedUntil     time.Time `json:"halted_until"`
+	DayStart        time.Time `json:"day_start"`
 }
 
-// NewController 创建风控控制器
+// NewController 创建风控控制器，配置了 state_file 时从文件恢复当日状态
 func NewController(cfg config.RiskConfig) *Controller {
-	return &Controller{
-		cfg:      cfg,
-		dayStart: todayStart(),
+	c := &Controller{
+		cfg: cfg,
+		loc: resetLocation(cfg.ResetTimezone),
 	}
+	c.dayStart = c.todayStart()
+	slog.Info("[风控] 日切时区", "timezone", c.loc.String(),
+		"next_reset", c.dayStart.AddDate(0, 0, 1).Format("2006-01-02 15:04:05 MST"))
+	c.loadState()
+	return c
 }
 
 // Check 检查是否允许下单，返回 nil 表示允许，否则返回拒绝原因
-func (c *Controller) Check(availableBalance float64) error {
+// position 为当前净持仓、orderSize 为拟开仓数量（合约张数），midPrice 用于计算名义敞口
+func (c *Controller) Check(availableBalance, position, orderSize, midPrice float64) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -50,6 +82,11 @@ func (c *Controller) Check(availableBalance float64) error {
 		return fmt.Errorf("熔断中: %s", c.haltedMsg)
 	}
 
+	// 冷却检查：熔断解除（人工重置或日切）后仍需等待冷却结束
+	if remaining := time.Until(c.haltedUntil); remaining > 0 {
+		return fmt.Errorf("熔断冷却中，剩余 %v", remaining.Round(time.Second))
+	}
+
 	// 账户余额检查
 	if availableBalance < c.cfg.MinBalanceUSDC {
 		msg := fmt.Sprintf("可用余额 %.2f USDC 低于最低要求 %.2f USDC", availableBalance, c.cfg.MinBalanceUSDC)
@@ -71,6 +108,14 @@ func (c *Controller) Check(availableBalance float64) error {
 		return fmt.Errorf(msg)
 	}
 
+	// 名义敞口检查：只拒绝本次开仓，不熔断（持仓减少或价格回落后自动恢复）
+	if c.cfg.MaxNotionalUSDC > 0 {
+		notional := (math.Abs(position) + orderSize) * midPrice
+		if notional > c.cfg.MaxNotionalUSDC {
+			return fmt.Errorf("开仓后名义敞口 %.2f USDC 超过限制 %.2f USDC", notional, c.cfg.MaxNotionalUSDC)
+		}
+	}
+
 	return nil
 }
 
@@ -80,14 +125,17 @@ func (c *Controller) RecordTrade(pnl float64) {
 	defer c.mu.Unlock()
 
 	c.dailyPnL += pnl
+	metrics.DailyPnL.Set(c.dailyPnL)
 
 	if pnl < 0 {
 		c.consecutiveLoss++
-		log.Printf("[风控] 亏损交易，连续亏损次数: %d，当日累计PnL: %.2f USDC", c.consecutiveLoss, c.dailyPnL)
+		slog.Info("[风控] 亏损交易", "consecutive_loss", c.consecutiveLoss, "daily_pnl", c.dailyPnL)
 	} else {
 		c.consecutiveLoss = 0
-		log.Printf("[风控] 盈利交易，当日累计PnL: %.2f USDC", c.dailyPnL)
+		slog.Info("[风控] 盈利交易", "daily_pnl", c.dailyPnL)
 	}
+
+	c.saveState()
 }
 
 // DailyPnL 返回当日累计盈亏
@@ -104,6 +152,54 @@ func (c *Controller) IsHalted() bool {
 	return c.halted
 }
 
+// HaltReason 返回熔断原因，未熔断时为空
+func (c *Controller) HaltReason() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.haltedMsg
+}
+
+// ConsecutiveLoss 返回当前连续亏损次数
+func (c *Controller) ConsecutiveLoss() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.consecutiveLoss
+}
+
+// CooldownRemaining 返回熔断冷却剩余时长，不在冷却期时为 0
+func (c *Controller) CooldownRemaining() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if remaining := time.Until(c.haltedUntil); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// NextResetTime 返回下一次当日统计重置（日切）时间
+func (c *Controller) NextResetTime() time.Time {
+	return c.todayStart().AddDate(0, 0, 1)
+}
+
+// Location 返回日切时区（reset_timezone）
+func (c *Controller) Location() *time.Location {
+	return c.loc
+}
+
+// Halt 由外部事件（如交易所返回余额不足）触发熔断
+func (c *Controller) Halt(reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.halt(reason)
+}
+
+// SetOnHalt 设置触发熔断时的回调，回调在持有锁时同步调用，不得调用 Controller 方法
+func (c *Controller) SetOnHalt(fn func(reason string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onHalt = fn
+}
+
 // Reset 人工重置熔断状态（需人工干预后调用）
 func (c *Controller) Reset() {
 	c.mu.Lock()
@@ -111,7 +207,11 @@ func (c *Controller) Reset() {
 	c.halted = false
 	c.haltedMsg = ""
 	c.consecutiveLoss = 0
-	log.Println("[风控] 熔断状态已人工重置")
+	slog.Info("[风控] 熔断状态已人工重置")
+	if remaining := time.Until(c.haltedUntil); remaining > 0 {
+		slog.Info("[风控] 冷却尚未结束，到期后恢复开仓", "remaining", remaining.Round(time.Second))
+	}
+	c.saveState()
 }
 
 // ---- 内部方法 ----
@@ -120,23 +220,110 @@ func (c *Controller) halt(msg string) {
 	if !c.halted {
 		c.halted = true
 		c.haltedMsg = msg
-		log.Printf("[风控] 触发熔断: %s", msg)
+		slog.Error("[风控] 触发熔断", "reason", msg)
+		alert.Critical("risk_halt", "风控熔断: %s", msg)
+		if c.onHalt != nil {
+			c.onHalt(msg)
+		}
+		if c.cfg.CooldownSeconds > 0 {
+			c.haltedUntil = time.Now().Add(time.Duration(c.cfg.CooldownSeconds) * time.Second)
+			slog.Warn("[风控] 熔断冷却中，期间即使人工重置也不开仓", "until", c.haltedUntil.In(c.loc).Format("2006-01-02 15:04:05 MST"))
+		}
+		c.saveState()
 	}
 }
 
 func (c *Controller) resetIfNewDay() {
-	now := time.Now()
-	if now.After(c.dayStart.Add(24 * time.Hour)) {
+	if today := c.todayStart(); today.After(c.dayStart) {
 		c.dailyPnL = 0
+		metrics.DailyPnL.Set(0)
 		c.consecutiveLoss = 0
 		c.halted = false
 		c.haltedMsg = ""
-		c.dayStart = todayStart()
-		log.Println("[风控] 新的一天，重置当日统计")
+		c.dayStart = today
+		slog.Info("[风控] 新的一天，重置当日统计", "day", today.Format("2006-01-02"))
+		c.saveState()
 	}
 }
 
-func todayStart() time.Time {
-	now := time.Now()
-	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+// loadState 从状态文件恢复风控状态，仅当保存的 dayStart 仍是今天时恢复
+func (c *Controller) loadState() {
+	if c.cfg.StateFile == "" {
+		return
+	}
+
+	data, err := os.ReadFile(c.cfg.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		slog.Warn("[风控] 读取状态文件失败，使用初始状态", "path", c.cfg.StateFile, "err", err)
+		return
+	}
+
+	var st persistedState
+	if err := json.Unmarshal(data, &st); err != nil {
+		slog.Warn("[风控] 解析状态文件失败，使用初始状态", "path", c.cfg.StateFile, "err", err)
+		return
+	}
+	// 冷却与日切无关，跨日也恢复
+	if time.Now().Before(st.HaltedUntil) {
+		c.haltedUntil = st.HaltedUntil
+		slog.Info("[风控] 已从状态文件恢复熔断冷却", "until", c.haltedUntil.In(c.loc).Format("2006-01-02 15:04:05 MST"))
+	}
+	if !st.DayStart.Equal(c.dayStart) {
+		slog.Info("[风控] 状态文件日期不是今天，使用初始状态", "day", st.DayStart.Format("2006-01-02"))
+		return
+	}
+
+	c.dailyPnL = st.DailyPnL
+	metrics.DailyPnL.Set(c.dailyPnL)
+	c.consecutiveLoss = st.ConsecutiveLoss
+	c.halted = st.Halted
+	c.haltedMsg = st.HaltedMsg
+	slog.Info("[风控] 已从状态文件恢复", "daily_pnl", c.dailyPnL, "consecutive_loss", c.consecutiveLoss, "halted", c.halted)
+}
+
+// saveState 将风控状态原子写入状态文件，避免写一半崩溃损坏文件
+// 调用方需持有 c.mu
+func (c *Controller) saveState() {
+	if c.cfg.StateFile == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(persistedState{
+		DailyPnL:        c.dailyPnL,
+		ConsecutiveLoss: c.consecutiveLoss,
+		Halted:          c.halted,
+		HaltedMsg:       c.haltedMsg,
+		HaltedUntil:     c.haltedUntil,
+		DayStart:        c.dayStart,
+	}, "", "  ")
+	if err != nil {
+		slog.Error("[风控] 序列化状态失败", "err", err)
+		return
+	}
+
+	if err := atomicfile.Write(c.cfg.StateFile, data); err != nil {
+		slog.Error("[风控] 写入状态文件失败", "path", c.cfg.StateFile, "err", err)
+	}
+}
+
+// todayStart 返回日切时区下今天 0 点
+func (c *Controller) todayStart() time.Time {
+	now := time.Now().In(c.loc)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, c.loc)
+}
+
+// resetLocation 解析 IANA 时区名，为空或无效时使用 UTC
+func resetLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		slog.Warn("[风控] 无效的 reset_timezone，使用 UTC", "reset_timezone", name, "err", err)
+		return time.UTC
+	}
+	return loc
 }
@@ -0,0 +1,104 @@
+package risk
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"arb/config"
+)
+
+func testRiskConfig(t *testing.T) config.RiskConfig {
+	return config.RiskConfig{
+		MaxDailyLossUSDC:   10,
+		MaxConsecutiveLoss: 3,
+		StateFile:          filepath.Join(t.TempDir(), "risk_state.json"),
+	}
+}
+
+// TestReloadKeepsDailyLossLimit 日内重启（重新创建 Controller）后恢复当日亏损，限额继续生效
+func TestReloadKeepsDailyLossLimit(t *testing.T) {
+	cfg := testRiskConfig(t)
+	c := NewController(cfg)
+	c.RecordTrade(-6)
+	c.RecordTrade(2)
+	if err := c.Check(1000, 0, 0.1, 100000); err != nil {
+		t.Fatalf("亏损未超限时应允许开仓: %v", err)
+	}
+
+	// 进程被杀后重启，继续亏损
+	c = NewController(cfg)
+	if pnl := c.DailyPnL(); pnl != -4 {
+		t.Fatalf("重启后当日PnL = %v，期望 -4", pnl)
+	}
+	c.RecordTrade(-7)
+	if err := c.Check(1000, 0, 0.1, 100000); err == nil {
+		t.Fatal("恢复的当日亏损超过限额，应拒绝开仓")
+	}
+	if !c.IsHalted() {
+		t.Fatal("应触发熔断")
+	}
+
+	// 熔断状态同样跨重启保留
+	c = NewController(cfg)
+	if !c.IsHalted() {
+		t.Fatal("重启后应保持熔断")
+	}
+}
+
+func TestReloadKeepsConsecutiveLoss(t *testing.T) {
+	cfg := testRiskConfig(t)
+	c := NewController(cfg)
+	c.RecordTrade(-1)
+	c.RecordTrade(-1)
+
+	c = NewController(cfg)
+	c.RecordTrade(-1)
+	if err := c.Check(1000, 0, 0.1, 100000); err == nil {
+		t.Fatal("重启前后累计连续亏损 3 次，应拒绝开仓")
+	}
+}
+
+// TestReloadIgnoresPreviousDay 状态文件属于前一个交易日时不恢复当日统计，冷却仍恢复
+func TestReloadIgnoresPreviousDay(t *testing.T) {
+	cfg := testRiskConfig(t)
+	c := NewController(cfg)
+	until := time.Now().Add(time.Hour)
+	data, _ := json.Marshal(persistedState{
+		DailyPnL:        -50,
+		ConsecutiveLoss: 5,
+		Halted:          true,
+		HaltedMsg:       "昨日熔断",
+		HaltedUntil:     until,
+		DayStart:        c.todayStart().AddDate(0, 0, -1),
+	})
+	if err := os.WriteFile(cfg.StateFile, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c = NewController(cfg)
+	if c.DailyPnL() != 0 || c.ConsecutiveLoss() != 0 || c.IsHalted() {
+		t.Fatalf("跨日不应恢复当日统计: pnl=%v loss=%d halted=%v", c.DailyPnL(), c.ConsecutiveLoss(), c.IsHalted())
+	}
+	if c.CooldownRemaining() <= 0 {
+		t.Fatal("冷却应跨日恢复")
+	}
+}
+
+// TestReloadCorruptStateFile 状态文件损坏时使用初始状态，不 panic
+func TestReloadCorruptStateFile(t *testing.T) {
+	cfg := testRiskConfig(t)
+	if err := os.WriteFile(cfg.StateFile, []byte(`{"daily_pnl": -`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := NewController(cfg)
+	if c.DailyPnL() != 0 || c.IsHalted() {
+		t.Fatalf("损坏的状态文件应被忽略: pnl=%v halted=%v", c.DailyPnL(), c.IsHalted())
+	}
+	c.RecordTrade(-1)
+	if c = NewController(cfg); c.DailyPnL() != -1 {
+		t.Fatalf("重新写入后当日PnL = %v，期望 -1", c.DailyPnL())
+	}
+}
@@ -0,0 +1,313 @@
+// Package store 基于 SQLite 的交易流水：记录每次套利尝试的报价、两腿成交、手续费、盈亏与失败原因
+//
+// 写入通过带缓冲的 channel 交给后台 goroutine 完成，磁盘延迟不会拖慢下单；
+// *Store 为 nil 时所有写入都是空操作，引擎无需判断是否启用
+package store
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	_ "modernc.org/sqlite" // 纯 Go SQLite 驱动，无需 cgo
+)
+
+// TradeRecord 一次套利尝试的完整记录
+type TradeRecord struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"` // long / short
+	VenueA    string    `json:"venue_a"`
+	VenueB    string    `json:"venue_b"`
+
+	// 触发时两所的报价与本次计划下单量
+	QuoteA float64 `json:"quote_a"`
+	QuoteB float64 `json:"quote_b"`
+	Size   float64 `json:"size"`
+
+	// 两腿订单与实际成交（未下单的腿为空）
+	OrderIDA   string  `json:"order_id_a"`
+	FillQtyA   float64 `json:"fill_qty_a"`
+	FillPriceA float64 `json:"fill_price_a"`
+	OrderIDB   string  `json:"order_id_b"`
+	FillQtyB   float64 `json:"fill_qty_b"`
+	FillPriceB float64 `json:"fill_price_b"`
+
+	Fee     float64 `json:"fee"`     // 两腿手续费合计（USDC）
+	PnL     float64 `json:"pnl"`     // 计入的盈亏（USDC），未计入时为 0
+	Failure string  `json:"failure"` // 失败或未完成原因，成功时为空
+}
+
+// DailySummary 单日交易汇总
+type DailySummary struct {
+	Date     string
+	Attempts int     // 套利尝试次数
+	Filled   int     // A所腿有成交的次数
+	Failed   int     // 带失败原因的次数
+	Wins     int     // 盈利笔数
+	Volume   float64 // A所成交量合计
+	Fees     float64
+	PnL      float64
+}
+
+const (
+	defaultBufferSize = 1024
+	dateLayout        = "2006-01-02"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS trades (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	ts           INTEGER NOT NULL, -- 毫秒时间戳
+	day          TEXT    NOT NULL, -- 本地日期 YYYY-MM-DD
+	direction    TEXT    NOT NULL,
+	venue_a      TEXT    NOT NULL,
+	venue_b      TEXT    NOT NULL,
+	quote_a      REAL    NOT NULL,
+	quote_b      REAL    NOT NULL,
+	size         REAL    NOT NULL,
+	order_id_a   TEXT    NOT NULL DEFAULT '',
+	fill_qty_a   REAL    NOT NULL DEFAULT 0,
+	fill_price_a REAL    NOT NULL DEFAULT 0,
+	order_id_b   TEXT    NOT NULL DEFAULT '',
+	fill_qty_b   REAL    NOT NULL DEFAULT 0,
+	fill_price_b REAL    NOT NULL DEFAULT 0,
+	fee          REAL    NOT NULL DEFAULT 0,
+	pnl          REAL    NOT NULL DEFAULT 0,
+	failure      TEXT    NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_trades_day ON trades(day);
+`
+
+// Store 交易流水存储
+type Store struct {
+	db *sql.DB
+
+	mu     sync.RWMutex // 保护 closed 与 ch 的关闭
+	closed bool
+	ch     chan TradeRecord
+	done   chan struct{}
+
+	dropped atomic.Uint64
+}
+
+// Open 打开（不存在时创建）path 上的 SQLite 数据库；bufSize 为写入队列长度，<=0 时默认 1024
+func Open(path string, bufSize int) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("打开交易流水数据库 %s 失败: %w", path, err)
+	}
+	// SQLite 单写者，单连接避免 database is locked
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化交易流水数据库 %s 失败: %w", path, err)
+	}
+
+	if bufSize <= 0 {
+		bufSize = defaultBufferSize
+	}
+	s := &Store{
+		db:   db,
+		ch:   make(chan TradeRecord, bufSize),
+		done: make(chan struct{}),
+	}
+	go s.writeLoop()
+	return s, nil
+}
+
+// OpenReadOnly 打开已有数据库用于查询（不启动写入协程）
+func OpenReadOnly(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("打开交易流水数据库 %s 失败: %w", path, err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("打开交易流水数据库 %s 失败: %w", path, err)
+	}
+	return &Store{db: db, closed: true}, nil
+}
+
+// RecordTrade 将记录放入写入队列，永不阻塞调用方；队列满时丢弃并计数
+func (s *Store) RecordTrade(r TradeRecord) {
+	if s == nil {
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.ch <- r:
+	default:
+		if n := s.dropped.Add(1); n == 1 || n%100 == 0 {
+			slog.Warn("[流水] 写入队列已满，丢弃记录", "dropped", n)
+		}
+	}
+}
+
+// Dropped 返回因队列满被丢弃的记录数
+func (s *Store) Dropped() uint64 {
+	if s == nil {
+		return 0
+	}
+	return s.dropped.Load()
+}
+
+// Close 停止接收新记录，写完队列中剩余记录后关闭数据库
+func (s *Store) Close() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	wasClosed := s.closed
+	s.closed = true
+	s.mu.Unlock()
+
+	if !wasClosed {
+		close(s.ch)
+		<-s.done
+	}
+	s.db.Close()
+}
+
+func (s *Store) writeLoop() {
+	defer close(s.done)
+	for r := range s.ch {
+		if err := s.insert(r); err != nil {
+			slog.Error("[流水] 写入交易记录失败", "err", err)
+		}
+	}
+}
+
+func (s *Store) insert(r TradeRecord) error {
+	_, err := s.db.Exec(`INSERT INTO trades (ts, day, direction, venue_a, venue_b, quote_a, quote_b, size,
+		order_id_a, fill_qty_a, fill_price_a, order_id_b, fill_qty_b, fill_price_b, fee, pnl, failure)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.Time.UnixMilli(), r.Time.Format(dateLayout), r.Direction, r.VenueA, r.VenueB, r.QuoteA, r.QuoteB, r.Size,
+		r.OrderIDA, r.FillQtyA, r.FillPriceA, r.OrderIDB, r.FillQtyB, r.FillPriceB, r.Fee, r.PnL, r.Failure)
+	return err
+}
+
+// ParseDate 解析 YYYY-MM-DD 格式的日期（本地时区）
+func ParseDate(s string) (time.Time, error) {
+	t, err := time.ParseInLocation(dateLayout, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("日期格式应为 YYYY-MM-DD: %q", s)
+	}
+	return t, nil
+}
+
+// DailySummary 汇总 day 当天（本地日期）的交易
+func (s *Store) DailySummary(day time.Time) (*DailySummary, error) {
+	sum := &DailySummary{Date: day.Format(dateLayout)}
+	err := s.db.QueryRow(`SELECT COUNT(*),
+		COALESCE(SUM(fill_qty_a > 0), 0),
+		COALESCE(SUM(failure != ''), 0),
+		COALESCE(SUM(pnl > 0), 0),
+		COALESCE(SUM(fill_qty_a), 0),
+		COALESCE(SUM(fee), 0),
+		COALESCE(SUM(pnl), 0)
+		FROM trades WHERE day = ?`, sum.Date).
+		Scan(&sum.Attempts, &sum.Filled, &sum.Failed, &sum.Wins, &sum.Volume, &sum.Fees, &sum.PnL)
+	if err != nil {
+		return nil, fmt.Errorf("查询 %s 交易汇总失败: %w", sum.Date, err)
+	}
+	return sum, nil
+}
+
+// Trades 返回 day 当天（本地日期）的全部记录，按时间排序
+func (s *Store) Trades(day time.Time) ([]TradeRecord, error) {
+	date := day.Format(dateLayout)
+	rows, err := s.db.Query(`SELECT ts, direction, venue_a, venue_b, quote_a, quote_b, size,
+		order_id_a, fill_qty_a, fill_price_a, order_id_b, fill_qty_b, fill_price_b, fee, pnl, failure
+		FROM trades WHERE day = ? ORDER BY ts, id`, date)
+	if err != nil {
+		return nil, fmt.Errorf("查询 %s 交易记录失败: %w", date, err)
+	}
+	defer rows.Close()
+
+	var records []TradeRecord
+	for rows.Next() {
+		var r TradeRecord
+		var ts int64
+		if err := rows.Scan(&ts, &r.Direction, &r.VenueA, &r.VenueB, &r.QuoteA, &r.QuoteB, &r.Size,
+			&r.OrderIDA, &r.FillQtyA, &r.FillPriceA, &r.OrderIDB, &r.FillQtyB, &r.FillPriceB, &r.Fee, &r.PnL, &r.Failure); err != nil {
+			return nil, fmt.Errorf("读取 %s 交易记录失败: %w", date, err)
+		}
+		r.Time = time.UnixMilli(ts)
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// Archive 将 [from, to) 时间段内的记录按时间顺序写入 path（NDJSON，每行一条记录），返回写入的记录数
+// 写入队列中尚未落盘的记录不在其中
+func (s *Store) Archive(from, to time.Time, path string) (int, error) {
+	rows, err := s.db.Query(`SELECT ts, direction, venue_a, venue_b, quote_a, quote_b, size,
+		order_id_a, fill_qty_a, fill_price_a, order_id_b, fill_qty_b, fill_price_b, fee, pnl, failure
+		FROM trades WHERE ts >= ? AND ts < ? ORDER BY ts, id`, from.UnixMilli(), to.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("查询待归档交易记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("创建归档文件 %s 失败: %w", path, err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+
+	n := 0
+	for rows.Next() {
+		var r TradeRecord
+		var ts int64
+		if err := rows.Scan(&ts, &r.Direction, &r.VenueA, &r.VenueB, &r.QuoteA, &r.QuoteB, &r.Size,
+			&r.OrderIDA, &r.FillQtyA, &r.FillPriceA, &r.OrderIDB, &r.FillQtyB, &r.FillPriceB, &r.Fee, &r.PnL, &r.Failure); err != nil {
+			f.Close()
+			return n, fmt.Errorf("读取待归档交易记录失败: %w", err)
+		}
+		r.Time = time.UnixMilli(ts)
+		if err := enc.Encode(r); err != nil {
+			f.Close()
+			return n, fmt.Errorf("写入归档文件 %s 失败: %w", path, err)
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		f.Close()
+		return n, fmt.Errorf("读取待归档交易记录失败: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return n, fmt.Errorf("写入归档文件 %s 失败: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return n, fmt.Errorf("写入归档文件 %s 失败: %w", path, err)
+	}
+	return n, nil
+}
+
+// Prune 删除 before 之前的记录（before 为零值时不删除）并执行 VACUUM 回收空间，返回删除的记录数
+func (s *Store) Prune(before time.Time) (int64, error) {
+	var deleted int64
+	if !before.IsZero() {
+		res, err := s.db.Exec(`DELETE FROM trades WHERE ts < ?`, before.UnixMilli())
+		if err != nil {
+			return 0, fmt.Errorf("删除过期交易记录失败: %w", err)
+		}
+		deleted, _ = res.RowsAffected()
+	}
+	if _, err := s.db.Exec(`VACUUM`); err != nil {
+		return deleted, fmt.Errorf("VACUUM 交易流水数据库失败: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,110 @@
+package strategy
+
+import (
+	"log/slog"
+	"math"
+	"time"
+
+	"arb/exchange"
+)
+
+const (
+	defaultAccountRefresh = 5 * time.Second
+
+	// 连续刷新失败达到此次数后视为账户数据过期
+	accountMaxFailures = 3
+)
+
+// accountSnapshot 缓存的 B所账户快照
+type accountSnapshot struct {
+	acc *exchange.Account
+	at  time.Time
+}
+
+// accountRefreshInterval 返回账户刷新间隔（account_refresh_ms，默认 5s）
+func (e *ArbEngine) accountRefreshInterval() time.Duration {
+	if e.cfg.Strategy.AccountRefreshMs <= 0 {
+		return defaultAccountRefresh
+	}
+	return time.Duration(e.cfg.Strategy.AccountRefreshMs) * time.Millisecond
+}
+
+// refreshAccount 查询 B所账户并更新缓存，失败时累加连续失败次数
+func (e *ArbEngine) refreshAccount() {
+	acc, err := e.exB.GetAccount(e.ctx)
+	if err != nil {
+		n := e.accountFailures.Add(1)
+		slog.Warn("[账户] 刷新账户信息失败", "failures", n, "err", err)
+		if n == accountMaxFailures {
+			slog.Warn("[账户] 连续刷新失败，账户数据视为过期，暂停开仓", "failures", n)
+		}
+		return
+	}
+	if e.accountFailures.Swap(0) >= accountMaxFailures {
+		slog.Info("[账户] 账户信息刷新恢复，恢复开仓")
+	}
+	e.account.Store(accountSnapshot{acc: acc, at: time.Now()})
+	// 本次运行首次取得的权益作为百分比止盈止损的基准，之后不随盈亏变化
+	if acc.Equity > 0 && e.startEquity.CompareAndSwap(0, math.Float64bits(acc.Equity)) {
+		slog.Info("[账户] 记录启动权益（take_profit_pct / stop_loss_pct 基准）", "equity", acc.Equity)
+	}
+}
+
+// accountLoop 后台定时刷新账户缓存，避免每次检测都请求私有接口
+func (e *ArbEngine) accountLoop() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.accountRefreshInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case <-ticker.C:
+			e.refreshAccount()
+		}
+	}
+}
+
+// pnlThreshold 单个盈亏阈值及与之比较的盈亏
+type pnlThreshold struct {
+	limit float64 // 阈值（USDC，正数）
+	pnl   float64 // 与阈值比较的盈亏
+	scope string  // 盈亏口径（日志与告警）
+	ok    bool    // false 表示该阈值当前不生效（不检查）
+}
+
+// pnlLimits 返回盈利目标与止损阈值：
+// 配置了 take_profit_pct / stop_loss_pct 时按本次运行首次取得的 B所权益计算（基准不随盈亏变化），
+// 权益尚未取得时退回 take_profit_usdc / stop_loss_usdc；两种阈值都与本次运行盈亏比较（不含状态文件恢复的历史盈亏，
+// 否则触发过止盈止损后每次重启都会立即再次停止开仓）；两者都未配置（为 0）时不检查，避免启动时误触发
+func (e *ArbEngine) pnlLimits() (take, stop pnlThreshold) {
+	s := &e.cfg.Strategy
+	base := math.Float64frombits(e.startEquity.Load())
+	e.pnlMu.Lock()
+	run := e.runPnL
+	e.pnlMu.Unlock()
+	return pnlLimit(s.TakeProfitUSDC, s.TakeProfitPct, base, run), pnlLimit(s.StopLossUSDC, s.StopLossPct, base, run)
+}
+
+// pnlLimit 按百分比（基准权益已知时）或 USDC 绝对值计算单个阈值，与本次运行盈亏 run 比较
+func pnlLimit(usdc, pct, base, run float64) pnlThreshold {
+	if pct > 0 && base > 0 {
+		return pnlThreshold{limit: base * pct / 100, pnl: run, scope: "本次运行", ok: true}
+	}
+	return pnlThreshold{limit: usdc, pnl: run, scope: "本次运行", ok: usdc > 0}
+}
+
+// cachedAccount 返回缓存的账户快照；从未成功、连续刷新失败或超过 3 个刷新周期未更新时 ok=false
+func (e *ArbEngine) cachedAccount() (acc *exchange.Account, age time.Duration, ok bool) {
+	snap, _ := e.account.Load().(accountSnapshot)
+	if snap.acc == nil {
+		return nil, 0, false
+	}
+	age = time.Since(snap.at)
+	if e.accountFailures.Load() >= accountMaxFailures || age > 3*e.accountRefreshInterval() {
+		return snap.acc, age, false
+	}
+	return snap.acc, age, true
+}
@@ -0,0 +1,203 @@
+package strategy
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"arb/exchange"
+)
+
+// Snapshot 引擎运行状态快照（管理接口 GET /status 返回）
+type Snapshot struct {
+	Time     time.Time     `json:"time"`
+	VenueA   VenueSnapshot `json:"venue_a"`
+	VenueB   VenueSnapshot `json:"venue_b"`
+	Spread1  float64       `json:"spread1"` // B买一 - A卖一
+	Spread2  float64       `json:"spread2"` // A买一 - B卖一
+	Position float64       `json:"position"`
+	Unhedged float64       `json:"unhedged"`
+	Hedge    float64       `json:"transferred_hedge"` // 断线处置转移到 B所的对冲（A所方向）
+	TotalPnL float64       `json:"total_pnl"`
+	DailyPnL float64       `json:"daily_pnl"`
+
+	State       string `json:"state"` // RUNNING / PAUSED / HALTED / MONITOR / WAITING_FEEDS
+	Paused      bool   `json:"paused"`
+	PauseReason string `json:"pause_reason,omitempty"`
+	LegPaused   bool   `json:"leg_latency_paused"` // 两腿下单时间差超限暂停开仓中
+	MonitorOnly bool   `json:"monitor_only"`
+	HaltReason  string `json:"halt_reason,omitempty"` // 止盈/止损等停止开仓原因
+
+	Risk RiskSnapshot `json:"risk"`
+
+	// 两所净持仓之和（最近一次持仓核对，对冲模式）
+	NetDelta         float64 `json:"net_delta"`
+	NetDeltaNotional float64 `json:"net_delta_notional_usdc"`
+	ImbalanceBlocked bool    `json:"imbalance_blocked"`
+
+	// 两腿下单返回时间差（指数移动平均，毫秒）
+	LegLatencyAvgMs int64 `json:"leg_latency_avg_ms"`
+}
+
+// VenueSnapshot 单个交易所的盘口与行情连接状态
+type VenueSnapshot struct {
+	Name           string  `json:"name"`
+	Symbol         string  `json:"symbol"`
+	Bid            float64 `json:"bid"`
+	Ask            float64 `json:"ask"`
+	QuoteAgeMs     int64   `json:"quote_age_ms"`
+	Source         string  `json:"source"` // ws / rest（WS 中断期间的 REST 兜底行情）
+	Connected      bool    `json:"connected"`
+	RTTMs          int64   `json:"rtt_ms"`
+	ReconnectCount int64   `json:"reconnect_count"`
+	SeqGaps        int64   `json:"seq_gaps"`             // 订单簿序号缺口累计次数
+	LastTrade      float64 `json:"last_trade,omitempty"` // 最新成交价（交易所支持成交推送时）
+}
+
+// RiskSnapshot 风控状态
+type RiskSnapshot struct {
+	Halted          bool      `json:"halted"`
+	HaltReason      string    `json:"halt_reason,omitempty"`
+	ConsecutiveLoss int       `json:"consecutive_loss"`
+	CooldownSec     int64     `json:"cooldown_remaining_sec"`
+	NextReset       time.Time `json:"next_reset"`
+}
+
+// Pause 暂停开仓：checkAndTrade 开头直接返回，行情、状态日志与已有持仓的管理（断线处置、资金费减仓等）照常运行
+func (e *ArbEngine) Pause() {
+	e.pause("管理接口")
+}
+
+// Resume 恢复开仓（不影响止盈/止损停止与风控熔断）
+func (e *ArbEngine) Resume() {
+	if e.paused.CompareAndSwap(true, false) {
+		e.pauseReason.Store("")
+		e.sessionPaused.Store(false)
+		slog.Info("[套利] 已恢复开仓")
+	}
+}
+
+// Flatten 暂停开仓后撤销挂单并以 reduce-only 市价单平掉两所持仓；
+// 等待进行中的套利检查结束再平仓，避免与开仓并发，超过 flatten_timeout_sec 仍未轮到则放弃
+func (e *ArbEngine) Flatten() {
+	e.pause("管理接口平仓")
+
+	ctx, cancel := context.WithTimeout(e.ctx, e.flattenTimeout())
+	defer cancel()
+	for !e.checking.CompareAndSwap(false, true) {
+		select {
+		case <-ctx.Done():
+			slog.Warn("[管理平仓] 等待进行中的交易结束超时，未平仓")
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	defer e.checking.Store(false)
+
+	slog.Info("[管理平仓] 撤单结果", "result", summarizeCancel(e.cancelAllOpenOrders(ctx)))
+	if pnl, ok := e.flattenAll(ctx, "[管理平仓]"); ok {
+		slog.Info("[管理平仓] 完成（开仓保持暂停，调用 /resume 恢复）", "pnl", pnl)
+	}
+	e.saveState()
+}
+
+// pause 以指定原因暂停开仓，已暂停时不覆盖原因
+func (e *ArbEngine) pause(reason string) {
+	if e.paused.CompareAndSwap(false, true) {
+		e.pauseReason.Store(reason)
+		slog.Warn("[套利] 已暂停开仓（调用 Resume 恢复）", "reason", reason)
+	}
+}
+
+// tradingState 返回开仓状态：HALTED（止盈/止损停止或风控熔断）优先于 PAUSED，监控模式为 MONITOR，
+// 任一所盘口尚未收到时为 WAITING_FEEDS
+func (e *ArbEngine) tradingState() string {
+	switch {
+	case e.cfg.Strategy.MonitorOnly:
+		return "MONITOR"
+	case e.tradingHalted.Load() || e.riskCtrl.IsHalted():
+		return "HALTED"
+	case e.paused.Load():
+		return "PAUSED"
+	case !e.feedsReady():
+		return "WAITING_FEEDS"
+	}
+	return "RUNNING"
+}
+
+// ResetRisk 人工重置风控熔断
+func (e *ArbEngine) ResetRisk() {
+	e.riskCtrl.Reset()
+}
+
+// Snapshot 返回当前运行状态
+func (e *ArbEngine) Snapshot() interface{} {
+	a, b := e.apexTop(), e.bybitTop()
+	ageA, ageB := e.quoteAges()
+
+	e.posMu.Lock()
+	pos, unhedged, hedge := e.position, e.unhedgedQty, e.transferredHedge
+	e.posMu.Unlock()
+	e.pnlMu.Lock()
+	pnl := e.totalPnL
+	e.pnlMu.Unlock()
+
+	reason, _ := e.haltReason.Load().(string)
+	imb := e.imbalance.Load().(imbalanceState)
+	return Snapshot{
+		Time:        time.Now(),
+		VenueA:      venueSnapshot(e.exA, a, ageA, &e.restQuoteA, &e.lastTradeA),
+		VenueB:      venueSnapshot(e.exB, b, ageB, &e.restQuoteB, &e.lastTradeB),
+		Spread1:     b.bid - a.ask,
+		Spread2:     a.bid - b.ask,
+		Position:    pos,
+		Unhedged:    unhedged,
+		Hedge:       hedge,
+		TotalPnL:    pnl,
+		DailyPnL:    e.riskCtrl.DailyPnL(),
+		State:       e.tradingState(),
+		Paused:      e.paused.Load(),
+		PauseReason: e.pauseReason.Load().(string),
+		LegPaused:   e.legLatencyPaused(),
+		MonitorOnly: e.cfg.Strategy.MonitorOnly,
+		HaltReason:  reason,
+		Risk: RiskSnapshot{
+			Halted:          e.riskCtrl.IsHalted(),
+			HaltReason:      e.riskCtrl.HaltReason(),
+			ConsecutiveLoss: e.riskCtrl.ConsecutiveLoss(),
+			CooldownSec:     int64(e.riskCtrl.CooldownRemaining().Seconds()),
+			NextReset:       e.riskCtrl.NextResetTime(),
+		},
+		NetDelta:         imb.qty,
+		NetDeltaNotional: imb.notional,
+		ImbalanceBlocked: e.imbalanceBlocked.Load(),
+		LegLatencyAvgMs:  e.legLatencyAverage().Milliseconds(),
+	}
+}
+
+// venueSnapshot 有 REST 兜底行情时展示 REST 盘口并标记来源
+func venueSnapshot(ex exchange.Exchange, q quote, age time.Duration, rest, trade *atomic.Value) VenueSnapshot {
+	st := ex.FeedStats()
+	v := VenueSnapshot{
+		Name:           ex.Name(),
+		Symbol:         ex.Symbol(),
+		Bid:            q.bid,
+		Ask:            q.ask,
+		QuoteAgeMs:     age.Milliseconds(),
+		Source:         "ws",
+		Connected:      st.Connected,
+		RTTMs:          st.RTT.Milliseconds(),
+		ReconnectCount: st.ReconnectCount,
+		SeqGaps:        st.SeqGaps,
+	}
+	if t, ok := lastTrade(trade); ok {
+		v.LastTrade = t.Price
+	}
+	if rq, ok := restQuoteOf(rest); ok {
+		v.Bid, v.Ask = rq.bid, rq.ask
+		v.QuoteAgeMs = time.Since(rq.at).Milliseconds()
+		v.Source = "rest"
+	}
+	return v
+}
@@ -0,0 +1,41 @@
+package strategy
+
+import "log/slog"
+
+// sideShare 返回吃单一侧在前 n 档挂单量中的占比：taken / (taken + other)，两侧都为空时返回 0
+// n <= 0 时使用全部已订阅档位（book_levels）
+func sideShare(taken, other []priceLevel, n int) float64 {
+	t, o := levelsDepth(topLevels(taken, n)), levelsDepth(topLevels(other, n))
+	if t+o <= 0 {
+		return 0
+	}
+	return t / (t + o)
+}
+
+// topLevels 返回前 n 档，n <= 0 或超过已有档位时返回全部
+func topLevels(levels []priceLevel, n int) []priceLevel {
+	if n <= 0 || n >= len(levels) {
+		return levels
+	}
+	return levels[:n]
+}
+
+// bookConfirms 盘口失衡过滤：开仓方向要吃的两侧挂单占比都不低于 min_imbalance_confirm 时返回 true。
+// 场景1（A所买入）看 A所卖盘与 B所买盘的占比，场景2 对称；价差因对手盘即将被扫空而出现时，
+// 被吃一侧的挂单通常已明显少于另一侧。未开启 imbalance_filter 时不过滤
+func (e *ArbEngine) bookConfirms(dir ArbDirection, apex, bybit quote) bool {
+	s := &e.cfg.Strategy
+	if !s.ImbalanceFilter {
+		return true
+	}
+	apexTaken, apexOther, bybitTaken, bybitOther := apex.asks, apex.bids, bybit.bids, bybit.asks
+	if dir == DirectionShort {
+		apexTaken, apexOther, bybitTaken, bybitOther = apex.bids, apex.asks, bybit.asks, bybit.bids
+	}
+	apexShare := sideShare(apexTaken, apexOther, s.ImbalanceLevels)
+	bybitShare := sideShare(bybitTaken, bybitOther, s.ImbalanceLevels)
+	ok := apexShare >= s.MinImbalanceConfirm && bybitShare >= s.MinImbalanceConfirm
+	slog.Debug("[套利] 盘口失衡过滤", "direction", dir.tag(), "share_a", apexShare, "share_b", bybitShare,
+		"min", s.MinImbalanceConfirm, "pass", ok)
+	return ok
+}
@@ -0,0 +1,75 @@
+package strategy
+
+import (
+	"log/slog"
+	"time"
+
+	"arb/metrics"
+)
+
+// tradeCooldown 返回同方向两次开仓之间的最小间隔，0 表示不限制
+func (e *ArbEngine) tradeCooldown() time.Duration {
+	if ms := e.cfg.Strategy.TradeCooldownMs; ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return 0
+}
+
+// inTradeCooldown 同方向上一次开仓后未满 trade_cooldown_ms 时返回 true 并计数
+// 持续存在的价差（常见于一侧行情滞后）会在每个检测周期触发下单，冷却期避免短时间内连续加仓到 max_position；
+// 只对开仓检查：减仓（反向机会减仓与价差回归平仓）不受影响
+func (e *ArbEngine) inTradeCooldown(dir ArbDirection) bool {
+	cooldown := e.tradeCooldown()
+	if cooldown <= 0 {
+		return false
+	}
+	last := e.lastEntryAt[dir].Load()
+	if last == 0 {
+		return false
+	}
+	remaining := cooldown - time.Since(time.Unix(0, last))
+	if remaining <= 0 {
+		return false
+	}
+	n := e.cooldownSkips.Add(1)
+	metrics.CooldownSkipped.Inc()
+	slog.Debug("[套利] 同方向冷却中，跳过本次机会", "direction", dir.tag(), "remaining", remaining.Round(time.Millisecond), "skipped", n)
+	return true
+}
+
+// inTradeInterval 任一方向上一次下单后未满 min_trade_interval_ms 时返回 true 并计数
+// 与 trade_cooldown_ms 不同，该限制不区分方向；每个间隔只打印一次日志，避免按检测周期刷屏
+func (e *ArbEngine) inTradeInterval() bool {
+	ms := e.cfg.Strategy.MinTradeIntervalMs
+	if ms <= 0 {
+		return false
+	}
+	var last int64
+	for i := range e.lastTradeAt {
+		if t := e.lastTradeAt[i].Load(); t > last {
+			last = t
+		}
+	}
+	if last == 0 {
+		return false
+	}
+	remaining := time.Duration(ms)*time.Millisecond - time.Since(time.Unix(0, last))
+	if remaining <= 0 {
+		return false
+	}
+	n := e.intervalSkips.Add(1)
+	metrics.TradeIntervalSkipped.Inc()
+	if e.intervalLoggedAt.Swap(last) != last {
+		slog.Info("[套利] 距上一笔交易不足 min_trade_interval_ms，跳过开仓", "remaining", remaining.Round(time.Millisecond), "skipped", n)
+	}
+	return true
+}
+
+// markTraded 记录方向的最近一次下单时间，entry 为开仓时同时开始同方向冷却（仅保存在内存，重启后重新计时）
+func (e *ArbEngine) markTraded(dir ArbDirection, entry bool) {
+	now := time.Now().UnixNano()
+	e.lastTradeAt[dir].Store(now)
+	if entry {
+		e.lastEntryAt[dir].Store(now)
+	}
+}
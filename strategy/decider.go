@@ -0,0 +1,183 @@
+package strategy
+
+import (
+	"fmt"
+	"math"
+
+	"arb/config"
+	"arb/exchange"
+)
+
+// Decider 不连接交易所、不下单的价差决策器（供回测使用）
+// 与实盘 checkAndTrade 共用 decide：价差回归平仓、止盈止损、反向机会减仓与开仓的判断顺序及
+// 净价差阈值、持仓上限、盘口深度、滑点与下单量取整逻辑完全一致
+type Decider struct {
+	e *ArbEngine
+}
+
+// Action 一次检测的决策动作
+type Action int
+
+const (
+	ActionNone       Action = iota
+	ActionUnwind            // 价差回归，reduce-only 平仓
+	ActionReduce            // 与持仓方向相反的机会，reduce-only 减仓
+	ActionEntry             // 开仓
+	ActionTakeProfit        // 达到盈利目标，停止开仓
+	ActionStopLoss          // 触发止损，停止开仓
+)
+
+// String 返回动作的英文短标签
+func (a Action) String() string {
+	switch a {
+	case ActionUnwind:
+		return "unwind"
+	case ActionReduce:
+		return "reduce"
+	case ActionEntry:
+		return "entry"
+	case ActionTakeProfit:
+		return "take_profit"
+	case ActionStopLoss:
+		return "stop_loss"
+	}
+	return "none"
+}
+
+// Trades 返回动作是否需要下单
+func (a Action) Trades() bool {
+	return a == ActionUnwind || a == ActionReduce || a == ActionEntry
+}
+
+// Decision 一次决策的结果
+type Decision struct {
+	Action    Action
+	Direction ArbDirection // 达到价差阈值的方向，DirectionNone 表示无机会
+	Size      float64      // 按深度限制并取整后的下单量（减仓不超过持仓）
+	PriceA    float64      // A所限价（吃到的最差一档）
+	PriceB    float64      // B所对冲限价（吃到的最差一档）
+	NetSpread float64      // 按两腿 VWAP 计算、扣除手续费后的每张净价差
+	Reason    string       // 止盈 / 止损原因
+}
+
+// decision 引擎内部的决策结果
+type decision struct {
+	action Action
+	dir    ArbDirection
+	plan   tradePlan
+	reason string
+}
+
+// decide 按两所盘口与当前持仓决定本次动作，实盘 checkAndTrade 与回测 Decider 共用：
+//  1. 价差回归时优先以 reduce-only 平仓，平仓降低风险，不受暂停、熔断等开仓限制
+//  2. 开仓限制生效时不做其他动作
+//  3. 达到盈利目标或止损时停止开仓
+//  4. 达到开仓阈值且深度、滑点检查通过时：与持仓方向相反的机会以 reduce-only 减仓（数量不超过持仓），
+//     剩余部分留待下一轮作为反向开仓；否则开仓
+//
+// 账户风控、下单间隔与冷却由调用方在下单前检查；达到阈值但深度不足时 action 为 ActionNone、dir 为机会方向
+func (e *ArbEngine) decide(apex, bybit quote, pos float64) decision {
+	if dir, plan, ok := e.findUnwind(apex, bybit, pos); ok {
+		return decision{action: ActionUnwind, dir: dir, plan: plan}
+	}
+	if e.entryBlocked() {
+		return decision{}
+	}
+
+	take, stop := e.pnlLimits()
+	if take.ok && take.pnl >= take.limit {
+		return decision{action: ActionTakeProfit,
+			reason: fmt.Sprintf("达到盈利目标 %.2f USDC（%sPnL=%.4f）", take.limit, take.scope, take.pnl)}
+	}
+	if stop.ok && stop.pnl <= -stop.limit {
+		return decision{action: ActionStopLoss,
+			reason: fmt.Sprintf("触发止损 %.2f USDC（%sPnL=%.4f）", stop.limit, stop.scope, stop.pnl)}
+	}
+
+	dir, plan, ok := e.findOpportunity(apex, bybit, pos)
+	if !ok {
+		return decision{dir: dir, plan: plan}
+	}
+	if e.reducesPosition(dir, pos) {
+		plan.size = math.Min(plan.size, e.roundSize(math.Abs(pos)))
+		return decision{action: ActionReduce, dir: dir, plan: plan}
+	}
+	return decision{action: ActionEntry, dir: dir, plan: plan}
+}
+
+// NewDecider 按配置创建决策器；交易对规格未知，下单量按 size_precision 取整
+func NewDecider(cfg *config.Config) (*Decider, error) {
+	nameA, nameB, err := exchangeNames(cfg)
+	if err != nil {
+		return nil, err
+	}
+	e, err := newEngine(cfg, nameA, nameB, nil)
+	if err != nil {
+		return nil, err
+	}
+	e.cancel() // 不发起任何请求
+	return &Decider{e: e}, nil
+}
+
+// FeeRates 返回决策使用的两所 taker 费率
+func (d *Decider) FeeRates() (feeA, feeB float64) {
+	return d.e.feeA, d.e.feeB
+}
+
+// SetFeeRates 覆盖两所 taker 费率（回测单独设置手续费时使用）
+func (d *Decider) SetFeeRates(feeA, feeB float64) {
+	d.e.feeA, d.e.feeB = feeA, feeB
+}
+
+// Record 计入一笔模拟成交的盈亏，止盈止损按累计盈亏判断
+func (d *Decider) Record(pnl float64) {
+	d.e.pnlMu.Lock()
+	d.e.totalPnL += pnl
+	d.e.runPnL += pnl
+	d.e.pnlMu.Unlock()
+}
+
+// Halt 停止开仓（止盈止损后调用），价差回归平仓与实盘一样不受影响
+func (d *Decider) Halt(reason string) {
+	if d.e.tradingHalted.CompareAndSwap(false, true) {
+		d.e.haltReason.Store(reason)
+	}
+}
+
+// Decide 按两所订单簿与当前净持仓决策
+// 返回的 Decision.Direction 非 DirectionNone 表示价差达到开仓或平仓阈值；ok=true 表示应当下单（Action 为平仓、减仓或开仓），
+// Action 为止盈 / 止损时调用方应 Halt
+func (d *Decider) Decide(a, b *exchange.OrderBook, position float64) (Decision, bool, error) {
+	if len(a.Bids) == 0 || len(a.Asks) == 0 || len(b.Bids) == 0 || len(b.Asks) == 0 {
+		return Decision{}, false, nil
+	}
+	qa, err := d.e.parseQuote(a.Bids, a.Asks)
+	if err != nil {
+		return Decision{}, false, fmt.Errorf("A所盘口: %w", err)
+	}
+	qb, err := d.e.parseQuote(b.Bids, b.Asks)
+	if err != nil {
+		return Decision{}, false, fmt.Errorf("B所盘口: %w", err)
+	}
+
+	dec := d.e.decide(qa, qb, position)
+	return Decision{
+		Action:    dec.action,
+		Direction: dec.dir,
+		Size:      dec.plan.size,
+		PriceA:    dec.plan.apexPrice,
+		PriceB:    dec.plan.bybitPrice,
+		NetSpread: dec.plan.net,
+		Reason:    dec.reason,
+	}, dec.action.Trades(), nil
+}
+
+// Sign 返回方向对 A所持仓的影响：场景1 为 +1，场景2 为 -1
+func (d ArbDirection) Sign() float64 {
+	return d.sign()
+}
+
+// Tag 返回方向的英文短标签（long / short）
+func (d ArbDirection) Tag() string {
+	return d.tag()
+}
@@ -0,0 +1,65 @@
+package strategy
+
+import (
+	"testing"
+
+	"arb/exchange"
+)
+
+func testBook(bid, ask float64) *exchange.OrderBook {
+	return &exchange.OrderBook{
+		Bids: []exchange.Level{{Price: bid, Size: 1}},
+		Asks: []exchange.Level{{Price: ask, Size: 1}},
+	}
+}
+
+// TestDeciderSequence 回测决策与实盘顺序一致：价差回归平仓优先，反向机会减仓不超过持仓，止盈后只允许平仓
+func TestDeciderSequence(t *testing.T) {
+	cfg := testConfig()
+	cfg.Strategy.Unwind = true
+	cfg.Strategy.UnwindSpreadUSDC = 20
+	cfg.Strategy.TakeProfitUSDC = 5
+	d, err := NewDecider(cfg)
+	if err != nil {
+		t.Fatalf("创建决策器失败: %v", err)
+	}
+
+	// 场景1 机会：A所卖一 100000 < B所买一 100010
+	entryA, entryB := testBook(99990, 100000), testBook(100010, 100020)
+	// 价差回归：A所买一 100025 - B所卖一 100005 达到 unwind_spread_usdc
+	unwindA, unwindB := testBook(100025, 100035), testBook(99995, 100005)
+
+	tests := []struct {
+		name   string
+		a, b   *exchange.OrderBook
+		pos    float64
+		action Action
+		size   float64
+	}{
+		{"空仓开仓", entryA, entryB, 0, ActionEntry, 0.1},
+		{"空头遇场景1 减仓不超过持仓", entryA, entryB, -0.05, ActionReduce, 0.05},
+		{"多头价差回归平仓", unwindA, unwindB, 0.1, ActionUnwind, 0.1},
+	}
+	for _, tt := range tests {
+		got, ok, err := d.Decide(tt.a, tt.b, tt.pos)
+		if err != nil || !ok {
+			t.Fatalf("%s: ok=%v err=%v", tt.name, ok, err)
+		}
+		if got.Action != tt.action || !approx(got.Size, tt.size) {
+			t.Fatalf("%s: 动作 / 数量 = %v / %v，期望 %v / %v", tt.name, got.Action, got.Size, tt.action, tt.size)
+		}
+	}
+
+	d.Record(6)
+	got, ok, _ := d.Decide(entryA, entryB, 0)
+	if ok || got.Action != ActionTakeProfit || got.Reason == "" {
+		t.Fatalf("累计盈利达到目标后应返回止盈，得到 %+v ok=%v", got, ok)
+	}
+	d.Halt(got.Reason)
+	if got, ok, _ := d.Decide(entryA, entryB, 0); ok {
+		t.Fatalf("停止开仓后不应开仓，得到 %+v", got)
+	}
+	if got, ok, _ := d.Decide(unwindA, unwindB, 0.1); !ok || got.Action != ActionUnwind {
+		t.Fatalf("停止开仓后价差回归仍应平仓，得到 %+v ok=%v", got, ok)
+	}
+}
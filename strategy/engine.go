@@ -1,19 +1,36 @@
 package strategy
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	apexPkg "arb/apex"
-	bybitPkg "arb/bybit"
+	"arb/admin"
+	"arb/alert"
+	"arb/bybit"
 	"arb/config"
+	"arb/exchange"
+	"arb/metrics"
+	"arb/opportunity"
+	"arb/recorder"
 	"arb/risk"
+	"arb/store"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+// defaultMaxQuoteAge 未配置 max_quote_age_ms 时的盘口最大有效时长
+const defaultMaxQuoteAge = 2000 * time.Millisecond
+
+// shutdownTimeout 停止时撤单等收尾请求的超时时间
+const shutdownTimeout = 10 * time.Second
+
 // ArbDirection 套利方向
 type ArbDirection int
 
@@ -30,84 +47,534 @@ const (
 //	B所（Bybit）= 执行套利下单
 //	共用流动性池：Apex 和 Bybit 共享深度，价差出现时立即套利
 //	赚钱方式：当两所价差 > min_spread 时，低买高卖，吃掉外部做市商的差价
+//
+// 两所通过 exchange.Exchange 接入，由 exchange_a / exchange_b 选择
 type ArbEngine struct {
-	cfg         *config.Config
-	apexClient  *apexPkg.Client
-	apexWs      *apexPkg.WsClient
-	bybitClient *bybitPkg.Client
-	bybitWs     *bybitPkg.WsClient
-	riskCtrl    *risk.Controller
+	cfg *config.Config
+
+	// A所（流动性来源，exchange_a，默认 Apex）与 B所（对冲，exchange_b，默认 Bybit）
+	// 引擎内 apex* / bybit* 命名的字段与方法分别指 A所 / B所
+	exA exchange.Exchange
+	exB exchange.Exchange
+
+	// B所成交推送是否已订阅（B所支持且配置了私有频道）
+	execStream bool
+	execs      *execTracker
+	riskCtrl   *risk.Controller
+
+	// 两所 taker 手续费率与行情中断处置策略（按所选交易所从配置取值）
+	feeA, feeB           float64
+	feedLossA, feedLossB config.FeedLossPolicy
 
 	// 最新行情（原子更新）
-	apexBid  atomic.Value // float64
-	apexAsk  atomic.Value // float64
-	bybitBid atomic.Value // float64
-	bybitAsk atomic.Value // float64
+	apexQuote  atomic.Value // quote
+	bybitQuote atomic.Value // quote
+
+	// 行情最近更新时间（断线处置用）
+	apexUpdatedAt  atomic.Value // time.Time
+	bybitUpdatedAt atomic.Value // time.Time
+
+	// 交易所推送的行情时间戳（毫秒）
+	apexQuoteTs  atomic.Int64
+	bybitQuoteTs atomic.Int64
+
+	// WS 未就绪期间的 REST 兜底行情
+	restQuoteA atomic.Value // restQuote
+	restQuoteB atomic.Value // restQuote
+
+	// 最近一次两所持仓核对结果（imbalanceState）
+	imbalance atomic.Value
+
+	// 两所最新逐笔成交（exchange.Trade，交易所支持成交推送时）
+	lastTradeA atomic.Value
+	lastTradeB atomic.Value
+
+	// 两所滚动窗口内的主动成交净额（max_adverse_flow_usdc 启用时）
+	flowA, flowB flowWindow
+
+	// 当前 USDC/USDT 汇率（rateState，启用 quote_rate 时）及查询用的 Bybit 现货行情客户端
+	quoteRate  atomic.Value
+	spotClient *bybit.Client
+
+	// 行情中断处置触发后暂停开仓
+	feedPaused atomic.Bool
 
-	// 当前持仓（Bybit B所）
+	// 套利机会发布器（进程内订阅 / 本地 socket 推送）
+	publisher *opportunity.Publisher
+
+	// 交易对规格（启动时从交易所获取）
+	spec instrumentSpec
+
+	// Bybit 账户缓存（由 accountLoop 定时刷新）
+	account         atomic.Value // accountSnapshot
+	accountFailures atomic.Int64
+	startEquity     atomic.Uint64 // 本次运行首次取得的权益（math.Float64bits），百分比止盈止损的基准
+
+	// 当前持仓（以 Apex 腿方向计）
 	posMu    sync.Mutex
 	position float64 // 正数=多头，负数=空头
 
+	// 对冲恢复后仍未对冲的 Apex 数量（受 posMu 保护，正数=多头）
+	unhedgedQty float64
+
+	// 单腿模式下 A所行情中断时转移到 B所的对冲数量（受 posMu 保护，按被对冲的 A所方向计，正数=对冲多头）
+	transferredHedge float64
+
 	// 累计盈亏
 	totalPnL float64
 	pnlMu    sync.Mutex
 
+	// 价差回归平仓（unwind）计入的盈亏与次数，开仓盈亏 = totalPnL - closePnL；只保存在内存（受 pnlMu 保护）
+	closePnL    float64
+	closeTrades int
+
+	// 盈亏归因（受 pnlMu 保护，只保存在内存）：已实现交易按报价计算的价差收益、实际手续费、报价与成交价之差的滑点；
+	// runPnL 为本次运行计入的全部盈亏，与 gross - fees - slippage 的差额来自对冲恢复与单腿预估
+	grossPnL     float64
+	feesPaid     float64
+	slippageCost float64
+	runPnL       float64
+
+	// 日内成交统计（受 pnlMu 保护）：dailyStart 为统计所属交易日的 0 点，跨过 reset_timezone 日切后首笔成交时清零
+	dailyStart  time.Time
+	dailyTrades int
+	dailyWins   int
+
+	// 未对冲事件次数（Apex 腿成交但 Bybit 对冲失败）
+	unhedgedIncidents atomic.Int64
+
+	// 行情驱动的检测信号：容量为 1，连续的行情更新合并为一次检测
+	wakeCh chan struct{}
+
+	// 检测统计：实际执行次数 / 被合并的行情信号数
+	evalCount      atomic.Int64
+	coalescedCount atomic.Int64
+
+	// 保证同一时刻只有一个 checkAndTrade 在执行
+	checking atomic.Bool
+
+	// 当前是否处于盘口过旧状态，每次停滞只告警一次
+	quoteStale atomic.Bool
+
+	// 两所连续中间价跳变次数（盘口异常检查）
+	quoteJumpsA atomic.Int32
+	quoteJumpsB atomic.Int32
+
+	// Prometheus 指标服务，未启用时为 nil
+	metricsSrv *metrics.Server
+	// 本引擎的指标（持仓、WS 状况、行情记录），与进程级 metrics.Registry 一并输出
+	metricsReg *prometheus.Registry
+
+	// 管理接口服务，未启用时为 nil
+	adminSrv *admin.Server
+
+	// 交易流水，未启用时为 nil（写入为空操作）
+	journal *store.Store
+
+	// 行情记录，未启用时为 nil（记录为空操作）
+	recorder *recorder.Recorder
+
+	// 暂停开仓开关（管理接口或风控熔断触发）与暂停原因
+	paused      atomic.Bool
+	pauseReason atomic.Value // string
+
+	// 两腿下单时间差：指数移动平均（秒）与超限后的暂停截止时间（UnixNano，0=未暂停）
+	legLatencyMu  sync.Mutex
+	legLatencyAvg float64
+	legPauseUntil atomic.Int64
+
+	// 按方向记录最近一次成交的下单时间（UnixNano，min_trade_interval_ms 使用）与最近一次开仓时间（同方向开仓冷却使用），
+	// 以及因冷却跳过的机会数
+	lastTradeAt   [3]atomic.Int64
+	lastEntryAt   [3]atomic.Int64
+	cooldownSkips atomic.Int64
+
+	// 全局最小交易间隔（min_trade_interval_ms）跳过的机会数；intervalLoggedAt 为已打印跳过日志的上一笔交易时间
+	intervalSkips    atomic.Int64
+	intervalLoggedAt atomic.Int64
+
+	// 资金费率：最近一次查询结果、结算前暂停开仓标志与已处置的结算时间（仅 fundingLoop 访问）
+	fundingMu      sync.Mutex
+	fundingA       *exchange.Funding
+	fundingB       *exchange.Funding
+	fundingBlocked atomic.Bool
+	fundingActedAt time.Time
+
+	// 两所真实持仓之和超过 max_imbalance 时暂停开仓（imbalanceLoop 设置，恢复平衡后清除）
+	imbalanceBlocked atomic.Bool
+
+	// 当前净持仓的建仓时间（UnixNano，空仓时为 0），由 holdingLoop 维护
+	positionSince atomic.Int64
+
+	// A所中间价滚动波动率（vol_multiplier 启用时），用于动态开仓阈值
+	vol volEstimator
+
+	// 交易时段统计；sessionPaused 表示当前暂停由定时平仓触发（到达 resume_at 时自动恢复）
+	session       sessionStats
+	sessionPaused atomic.Bool
+
+	// REST 本地限频统计：等待次数 / 被拒绝次数
+	throttleWaits   atomic.Int64
+	throttleRejects atomic.Int64
+
 	// 运行控制
-	stopCh chan struct{}
-	wg     sync.WaitGroup
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	// 停止开仓（止盈/止损触发后置位，进程继续运行以便查看最终状态）
+	tradingHalted atomic.Bool
+	haltReason    atomic.Value // string
+
+	// REST 调用上下文，Stop 时取消以中断进行中的请求
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-// NewArbEngine 创建套利引擎
+// NewArbEngine 创建套利引擎，按 exchange_a / exchange_b 创建两所适配器
 func NewArbEngine(cfg *config.Config) (*ArbEngine, error) {
+	nameA, nameB, err := exchangeNames(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := newEngine(cfg, nameA, nameB, risk.NewController(cfg.RiskControl))
+	if err != nil {
+		return nil, err
+	}
+	if e.exA, err = exchange.New(nameA, cfg, e.onThrottle); err != nil {
+		return nil, fmt.Errorf("exchange_a: %w", err)
+	}
+	if e.exB, err = exchange.New(nameB, cfg, e.onThrottle); err != nil {
+		return nil, fmt.Errorf("exchange_b: %w", err)
+	}
+	// B所数量单位与 A所不同时换算为 A所单位，引擎内部（盘口、持仓、成交、PnL）统一使用 A所单位
+	if e.exB, err = exchange.Scaled(e.exB, e.sizeRatio()); err != nil {
+		return nil, fmt.Errorf("size_ratio_bybit_per_apex: %w", err)
+	}
+	// A所以 USDC 计价时按 USDC/USDT 汇率换算为 B所计价币
+	if e.quoteRateEnabled() {
+		e.exA = exchange.ConvertQuote(e.exA, e.currentQuoteRate)
+	}
+	e.registerMetrics()
+	return e, nil
+}
+
+// sizeRatio 返回每 1 单位 A所数量对应的 B所数量，未配置时为 1
+func (e *ArbEngine) sizeRatio() float64 {
+	if r := e.cfg.Strategy.SizeRatioBybitPerApex; r != nil {
+		return *r
+	}
+	return 1
+}
+
+// NewArbEngineWithExchanges 使用外部提供的交易所与风控控制器创建套利引擎（用于回放、模拟撮合等场景）
+// 手续费率与行情中断策略仍按 exchange_a / exchange_b 的名称从配置中读取
+func NewArbEngineWithExchanges(cfg *config.Config, exA, exB exchange.Exchange, riskCtrl *risk.Controller) (*ArbEngine, error) {
+	if exA == nil || exB == nil || riskCtrl == nil {
+		return nil, fmt.Errorf("交易所与风控控制器不能为空")
+	}
+	nameA, nameB, err := exchangeNames(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := newEngine(cfg, nameA, nameB, riskCtrl)
+	if err != nil {
+		return nil, err
+	}
+	e.exA, e.exB = exA, exB
+	e.registerMetrics()
+	return e, nil
+}
+
+// exchangeNames 返回 A/B 两所名称，未配置时默认 apex / bybit
+func exchangeNames(cfg *config.Config) (nameA, nameB string, err error) {
+	nameA, nameB = cfg.ExchangeA, cfg.ExchangeB
+	if nameA == "" {
+		nameA = exchange.Apex
+	}
+	if nameB == "" {
+		nameB = exchange.Bybit
+	}
+	if nameA == nameB {
+		return "", "", fmt.Errorf("exchange_a 与 exchange_b 不能是同一交易所: %q", nameA)
+	}
+	return nameA, nameB, nil
+}
+
+// newEngine 创建不含交易所适配器的引擎，由调用方设置 exA / exB 后注册指标
+func newEngine(cfg *config.Config, nameA, nameB string, riskCtrl *risk.Controller) (*ArbEngine, error) {
+	if len(cfg.Strategy.ClientIDPrefix) > maxClientIDPrefix {
+		return nil, fmt.Errorf("client_id_prefix 最长 %d 个字符: %q", maxClientIDPrefix, cfg.Strategy.ClientIDPrefix)
+	}
+	switch cfg.Strategy.HedgeOrderType {
+	case "", config.HedgeOrderLimit, config.HedgeOrderMarket:
+	default:
+		return nil, fmt.Errorf("hedge_order_type 取值无效: %q（可选: %s, %s）",
+			cfg.Strategy.HedgeOrderType, config.HedgeOrderLimit, config.HedgeOrderMarket)
+	}
+	if cfg.Strategy.MaxLongPosition < 0 || cfg.Strategy.MaxShortPosition < 0 {
+		return nil, fmt.Errorf("max_long_position / max_short_position 不能为负数: %v / %v",
+			cfg.Strategy.MaxLongPosition, cfg.Strategy.MaxShortPosition)
+	}
+	if !flagEnabled(cfg.Strategy.EnableLong) && !flagEnabled(cfg.Strategy.EnableShort) {
+		slog.Warn("enable_long 与 enable_short 均已关闭，引擎不会开仓")
+	}
+	switch cfg.Strategy.ApexMakerFallback {
+	case "", config.MakerFallbackIOC, config.MakerFallbackCancel:
+	default:
+		return nil, fmt.Errorf("apex_maker_fallback 取值无效: %q（可选: %s, %s）",
+			cfg.Strategy.ApexMakerFallback, config.MakerFallbackIOC, config.MakerFallbackCancel)
+	}
+	switch cfg.Bybit.PositionMode {
+	case "", config.PositionModeOneWay, config.PositionModeHedge:
+	default:
+		return nil, fmt.Errorf("bybit.position_mode 取值无效: %q（可选: %s, %s）",
+			cfg.Bybit.PositionMode, config.PositionModeOneWay, config.PositionModeHedge)
+	}
+	switch cfg.Strategy.TimeInForce {
+	case "", config.TimeInForceIOC, config.TimeInForceGTC:
+	default:
+		return nil, fmt.Errorf("time_in_force 取值无效: %q（可选: %s, %s）",
+			cfg.Strategy.TimeInForce, config.TimeInForceIOC, config.TimeInForceGTC)
+	}
+	if cfg.Strategy.ApexMakerMode && cfg.Strategy.TimeInForce == config.TimeInForceIOC {
+		return nil, fmt.Errorf("apex_maker_mode 需要挂单，time_in_force 不能为 %s（留空即使用 POST_ONLY 挂单）", config.TimeInForceIOC)
+	}
+	if c := cfg.Strategy.MinImbalanceConfirm; c < 0 || c > 1 {
+		return nil, fmt.Errorf("min_imbalance_confirm 必须在 0 到 1 之间: %g", c)
+	}
+	if d := cfg.Strategy.OrderbookDepth; d < 0 || (d > 0 && d < cfg.Strategy.BookLevels) {
+		return nil, fmt.Errorf("orderbook_depth 不能为负数且不能小于 book_levels（%d）: %d", cfg.Strategy.BookLevels, d)
+	}
+	if cfg.Strategy.TakeProfitPct < 0 || cfg.Strategy.StopLossPct < 0 {
+		return nil, fmt.Errorf("take_profit_pct / stop_loss_pct 不能为负数: %g / %g", cfg.Strategy.TakeProfitPct, cfg.Strategy.StopLossPct)
+	}
+	if cfg.Strategy.ExpirySeconds < 0 {
+		return nil, fmt.Errorf("expiry_seconds 不能为负数: %d", cfg.Strategy.ExpirySeconds)
+	}
+	if err := validateSession(cfg.Session); err != nil {
+		return nil, err
+	}
+	if r := cfg.Strategy.SizeRatioBybitPerApex; r != nil && *r <= 0 {
+		return nil, fmt.Errorf("size_ratio_bybit_per_apex 必须为正数: %g", *r)
+	}
+	if cfg.Strategy.MinSpreadBps < 0 || cfg.Strategy.HedgeSlippageBps < 0 {
+		return nil, fmt.Errorf("min_spread_bps (%g) 与 hedge_slippage_bps (%g) 不能为负数", cfg.Strategy.MinSpreadBps, cfg.Strategy.HedgeSlippageBps)
+	}
+	if err := validateQuoteRate(cfg.QuoteRate); err != nil {
+		return nil, err
+	}
+	switch cfg.Strategy.FundingAction {
+	case "", config.FundingActionNone, config.FundingActionReduce, config.FundingActionFlatten:
+	default:
+		return nil, fmt.Errorf("funding_action 取值无效: %q（可选: %s, %s, %s）",
+			cfg.Strategy.FundingAction, config.FundingActionNone, config.FundingActionReduce, config.FundingActionFlatten)
+	}
+
 	e := &ArbEngine{
-		cfg:         cfg,
-		apexClient:  apexPkg.NewClient(cfg.Apex.BaseURL, cfg.Apex.APIKey, cfg.Apex.APISecret, cfg.Apex.Passphrase),
-		apexWs:      apexPkg.NewWsClient(cfg.Apex.WsURL),
-		bybitClient: bybitPkg.NewClient(cfg.Bybit.BaseURL, cfg.Bybit.APIKey, cfg.Bybit.APISecret),
-		bybitWs:     bybitPkg.NewWsClient(cfg.Bybit.WsURL),
-		riskCtrl:    risk.NewController(cfg.RiskControl),
-		stopCh:      make(chan struct{}),
+		cfg:       cfg,
+		execs:     newExecTracker(),
+		riskCtrl:  riskCtrl,
+		publisher: opportunity.NewPublisher(cfg.Opportunity.BufferSize),
+		wakeCh:    make(chan struct{}, 1),
+		stopCh:    make(chan struct{}),
 	}
+	e.feeA, e.feedLossA = venueSettings(cfg, nameA)
+	e.feeB, e.feedLossB = venueSettings(cfg, nameB)
+	e.ctx, e.cancel = context.WithCancel(context.Background())
 
 	// 初始化行情为 0
-	e.apexBid.Store(0.0)
-	e.apexAsk.Store(0.0)
-	e.bybitBid.Store(0.0)
-	e.bybitAsk.Store(0.0)
+	e.apexQuote.Store(quote{})
+	e.bybitQuote.Store(quote{})
+	e.apexUpdatedAt.Store(time.Time{})
+	e.bybitUpdatedAt.Store(time.Time{})
+	e.restQuoteA.Store(restQuote{})
+	e.restQuoteB.Store(restQuote{})
+	e.imbalance.Store(imbalanceState{})
+	e.lastTradeA.Store(exchange.Trade{})
+	e.quoteRate.Store(rateState{})
+	e.lastTradeB.Store(exchange.Trade{})
+	e.pauseReason.Store("")
+
+	// 风控熔断时自动暂停开仓，需人工 Resume 恢复
+	if riskCtrl != nil {
+		riskCtrl.SetOnHalt(func(reason string) { e.pause("风控熔断: " + reason) })
+	}
 
 	return e, nil
 }
 
 // Start 启动套利引擎
 func (e *ArbEngine) Start() error {
-	log.Printf("=== 套利引擎启动 ===")
-	log.Printf("A所（Apex）: %s  交易对: %s", e.cfg.Apex.BaseURL, e.cfg.ApexSymbol)
-	log.Printf("B所（Bybit）: %s  交易对: %s", e.cfg.Bybit.BaseURL, e.cfg.BybitSymbol)
-	log.Printf("最小价差: %.2f USDC  单笔量: %.4f  对冲模式: %v",
-		e.cfg.Strategy.MinSpreadUSDC, e.cfg.Strategy.OrderSize, e.cfg.Strategy.HedgeMode)
+	slog.Info("=== 套利引擎启动 ===",
+		"exchange_a", e.exA.Name(), "symbol_a", e.exA.Symbol(),
+		"exchange_b", e.exB.Name(), "symbol_b", e.exB.Symbol(),
+		"min_spread", e.cfg.Strategy.MinSpreadUSDC, "order_size", e.cfg.Strategy.OrderSize, "hedge_mode", e.cfg.Strategy.HedgeMode)
+	if e.cfg.Strategy.Unwind {
+		slog.Info("价差回归平仓已启用", "unwind_spread", e.cfg.Strategy.UnwindSpreadUSDC, "unwind_spread_bps", e.cfg.Strategy.UnwindSpreadBps,
+			"max_position", e.cfg.Strategy.MaxPosition)
+	}
+
+	if e.cfg.Strategy.MonitorOnly {
+		slog.Info("监控模式：只检测并推送套利机会，不下单")
+	}
+	if e.riskCtrl.IsHalted() {
+		e.pause("风控熔断: " + e.riskCtrl.HaltReason())
+	}
+
+	// 获取计价币汇率，之后由 quoteRateLoop 定时刷新
+	if e.quoteRateEnabled() {
+		e.refreshQuoteRate()
+		if e.cfg.QuoteRate.Source != "" {
+			e.wg.Add(1)
+			go e.quoteRateLoop()
+		}
+	}
 
-	// 连接 Apex WebSocket（A所行情）
-	if err := e.apexWs.Connect(); err != nil {
-		return fmt.Errorf("Apex WS 连接失败: %w", err)
+	// 确认两所交易对存在且为同一标的
+	if err := e.checkSymbols(); err != nil {
+		return err
 	}
-	if err := e.apexWs.SubscribeOrderBook(e.cfg.ApexSymbol, e.onApexOrderBook); err != nil {
-		return fmt.Errorf("Apex 订单簿订阅失败: %w", err)
+
+	// 初始化告警推送，每条告警附带交易对与当前累计盈亏
+	alert.SetContext(e.exA.Symbol(), func() float64 {
+		e.pnlMu.Lock()
+		defer e.pnlMu.Unlock()
+		return e.totalPnL
+	})
+	if err := alert.Setup(e.cfg.Alerts); err != nil {
+		return err
 	}
 
-	// 连接 Bybit WebSocket（B所行情）
-	if err := e.bybitWs.Connect(); err != nil {
-		return fmt.Errorf("Bybit WS 连接失败: %w", err)
+	// 启动 Prometheus 指标服务
+	if e.cfg.Metrics.Enabled {
+		srv, err := metrics.Serve(e.cfg.Metrics.Address, e.metricsReg)
+		if err != nil {
+			return err
+		}
+		e.metricsSrv = srv
 	}
-	if err := e.bybitWs.SubscribeOrderBook(e.cfg.BybitSymbol, e.onBybitOrderBook); err != nil {
-		return fmt.Errorf("Bybit 订单簿订阅失败: %w", err)
+
+	// 启动管理接口
+	if e.cfg.Admin.Enabled {
+		srv, err := admin.Serve(e.cfg.Admin.Address, e.cfg.Admin.Token, e)
+		if err != nil {
+			return err
+		}
+		e.adminSrv = srv
+	}
+
+	// 打开交易流水数据库
+	if e.cfg.Journal.Enabled {
+		j, err := store.Open(e.cfg.Journal.Path, e.cfg.Journal.BufferSize)
+		if err != nil {
+			return err
+		}
+		e.journal = j
+		slog.Info("[流水] 交易记录已启用", "path", e.cfg.Journal.Path)
+	}
+
+	// 启动行情记录
+	if e.cfg.Recorder.Enabled {
+		r, err := recorder.New(e.cfg.Recorder)
+		if err != nil {
+			return err
+		}
+		e.recorder = r
+		e.metricsReg.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "arb_recorder_dropped_total",
+			Help: "行情记录因写入队列满丢弃的条数",
+		}, func() float64 {
+			return float64(r.Dropped())
+		}))
+		slog.Info("[行情记录] 已启用", "path", e.cfg.Recorder.Path)
+	}
+
+	// 启动套利机会推送监听
+	if e.cfg.Opportunity.Enabled {
+		if err := e.publisher.Listen(e.cfg.Opportunity.Network, e.cfg.Opportunity.Address); err != nil {
+			return err
+		}
+	}
+
+	// 获取交易对规格，按交易所步长取整价格与数量
+	e.loadInstruments()
+
+	// 恢复累计盈亏（启动平仓的盈亏在此基础上累计）
+	savedPos, hasSaved := e.loadState()
+
+	// 启动核对：撤销上次运行遗留的挂单，记录两所持仓，按 startup_flatten 平仓
+	if !e.cfg.Strategy.MonitorOnly && e.startupReconcile() {
+		hasSaved = false
+	}
+
+	// 交易所特定的下单前准备（如切换持仓模式、设置杠杆），在启动核对清理挂单与持仓之后执行
+	if !e.cfg.Strategy.MonitorOnly {
+		for _, ex := range []exchange.Exchange{e.exA, e.exB} {
+			if p, ok := ex.(exchange.Preparer); ok {
+				if err := p.Prepare(e.ctx); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// 连接 A所 / B所行情 WebSocket：初次连接失败不退出，由客户端重连循环按退避重试，
+	// 订阅在连接建立后自动恢复；两所都未就绪时由 waitForMarketData 超时退出
+	for _, v := range []struct {
+		ex exchange.Exchange
+		cb func(*exchange.OrderBook)
+	}{
+		{e.exA, e.onApexOrderBook},
+		{e.exB, e.onBybitOrderBook},
+	} {
+		connErr := v.ex.Connect()
+		if connErr != nil {
+			slog.Warn("[行情] WS 初次连接失败，后台重试", "exchange", v.ex.Name(), "err", connErr)
+		}
+		// 档位不受支持属于配置错误，即使初次连接失败也直接返回（重连无法恢复）
+		if err := v.ex.SubscribeOrderBook(e.subscribeDepth(), v.cb); err != nil && (connErr == nil || errors.Is(err, exchange.ErrUnsupportedDepth)) {
+			return fmt.Errorf("%s 订单簿订阅失败: %w", v.ex.Name(), err)
+		}
+	}
+
+	// 订阅两所逐笔成交，记录最新成交价
+	e.subscribeTrades()
+
+	// 订阅 B所私有频道成交推送（交易所支持且已配置时）
+	if es, ok := e.exB.(exchange.ExecutionStreamer); ok && !e.cfg.Strategy.MonitorOnly {
+		subscribed, err := es.SubscribeExecutions(e.onBybitExecution)
+		if err != nil {
+			return err
+		}
+		e.execStream = subscribed
 	}
 
 	// 等待行情就绪
-	log.Println("等待行情数据就绪...")
+	slog.Info("等待行情数据就绪...")
 	if err := e.waitForMarketData(10 * time.Second); err != nil {
 		return err
 	}
-	log.Println("行情数据就绪，开始套利监控")
+	if e.feedsReady() {
+		slog.Info("行情数据就绪，开始套利监控")
+	}
+
+	// 从交易所恢复真实持仓，避免重启后误以为空仓而超过最大持仓
+	if !e.cfg.Strategy.MonitorOnly {
+		if err := e.reconcilePosition(); err != nil {
+			return fmt.Errorf("启动时恢复持仓失败: %w", err)
+		}
+		if hasSaved {
+			e.checkSavedPosition(savedPos)
+		}
+
+		// 首次同步获取账户信息，之后由 accountLoop 定时刷新
+		e.refreshAccount()
+		e.wg.Add(1)
+		go e.accountLoop()
+	}
 
 	// 启动套利主循环
 	e.wg.Add(1)
@@ -117,57 +584,262 @@ func (e *ArbEngine) Start() error {
 	e.wg.Add(1)
 	go e.statusLoop()
 
+	// 启动行情中断监控
+	e.wg.Add(1)
+	go e.feedGuardLoop()
+
+	// 启动 WS 中断期间的 REST 兜底行情
+	if e.restFallbackInterval() > 0 {
+		e.wg.Add(1)
+		go e.restQuoteLoop()
+	}
+
+	// 启动资金费率监控
+	if e.fundingInterval() > 0 {
+		e.wg.Add(1)
+		go e.fundingLoop()
+	}
+
+	// 启动两所持仓平衡核对（仅对冲模式，单腿模式 B所无持仓）
+	if e.cfg.Strategy.HedgeMode && !e.cfg.Strategy.MonitorOnly && e.imbalanceInterval() > 0 {
+		e.wg.Add(1)
+		go e.imbalanceLoop()
+	}
+
+	// 启动本地持仓与交易所持仓对账
+	if !e.cfg.Strategy.MonitorOnly && e.reconcileInterval() > 0 {
+		e.wg.Add(1)
+		go e.reconcileLoop()
+	}
+
+	// 启动最长持仓时间检查
+	if e.cfg.Strategy.MaxHoldingSeconds > 0 && !e.cfg.Strategy.MonitorOnly {
+		e.wg.Add(1)
+		go e.holdingLoop()
+	}
+
+	// 启动交易时段定时平仓
+	if len(e.cfg.Session.FlattenAt) > 0 && !e.cfg.Strategy.MonitorOnly {
+		e.session.reset(time.Now())
+		e.wg.Add(1)
+		go e.sessionLoop()
+	}
+
+	// 启动日终维护
+	if e.cfg.Hygiene.Enabled {
+		e.wg.Add(1)
+		go e.hygieneLoop()
+	}
+
 	return nil
 }
 
-// Stop 停止套利引擎，撤销所有挂单
+// Stop 停止套利引擎，撤销所有挂单并关闭连接（可重复、并发调用）
 func (e *ArbEngine) Stop() {
-	log.Println("正在停止套利引擎...")
+	e.stopOnce.Do(e.shutdown)
+}
+
+// HaltTrading 停止开新仓并撤销挂单，行情、状态打印等继续运行，进程不退出
+// 只有第一次调用生效
+func (e *ArbEngine) HaltTrading(reason string) {
+	if !e.tradingHalted.CompareAndSwap(false, true) {
+		return
+	}
+	e.haltReason.Store(reason)
+	slog.Warn("[套利] 停止开仓（进程继续运行，按 Ctrl+C 退出）", "reason", reason)
+
+	slog.Info("[套利] 撤单结果", "result", summarizeCancel(e.cancelAllOpenOrders(e.ctx)))
+}
+
+func (e *ArbEngine) shutdown() {
+	slog.Info("正在停止套利引擎...")
 	close(e.stopCh)
+	e.cancel() // 中断进行中的 REST 请求
 	e.wg.Wait()
 
-	// 撤销 Bybit 所有挂单
-	if err := e.bybitClient.CancelAllOrders(e.cfg.BybitSymbol); err != nil {
-		log.Printf("[停止] 撤销 Bybit 挂单失败: %v", err)
-	} else {
-		log.Println("[停止] Bybit 挂单已全部撤销")
+	// 撤销两所所有挂单并确认（引擎上下文已取消，使用独立的超时上下文）
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	cancelled := e.cancelAllOpenOrders(ctx)
+
+	// 撤单后、断开 WS 前平掉两所持仓
+	if e.cfg.Strategy.FlattenOnStop && !e.cfg.Strategy.MonitorOnly {
+		e.flattenOnStop()
 	}
 
-	e.apexWs.Close()
-	e.bybitWs.Close()
+	e.saveState()
+
+	e.exA.Close()
+	e.exB.Close()
+	e.publisher.Close()
+	e.metricsSrv.Close()
+	e.adminSrv.Close()
+	e.journal.Close()
+	e.recorder.Close()
 
 	e.pnlMu.Lock()
-	log.Printf("=== 套利引擎已停止，累计PnL: %.4f USDC ===", e.totalPnL)
+	slog.Info("=== 套利引擎已停止 ===", "total_pnl", e.totalPnL, "cancel", summarizeCancel(cancelled))
 	e.pnlMu.Unlock()
 }
 
+// registerMetrics 创建本引擎的指标注册表，注册采集时读取引擎状态的指标：净持仓与两所 WS 连接状况
+// 每个引擎使用独立的注册表，不向进程级注册表追加序列
+func (e *ArbEngine) registerMetrics() {
+	e.metricsReg = prometheus.NewRegistry()
+	e.metricsReg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "arb_position",
+		Help: "Apex 净持仓（合约张数，正数=多头）",
+	}, func() float64 {
+		e.posMu.Lock()
+		defer e.posMu.Unlock()
+		return e.position
+	}))
+	for _, ex := range []exchange.Exchange{e.exA, e.exB} {
+		ex := ex
+		labels := prometheus.Labels{"exchange": strings.ToLower(ex.Name())}
+		e.metricsReg.MustRegister(
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Name: "arb_ws_reconnects_total", Help: "WS 累计重连次数", ConstLabels: labels,
+			}, func() float64 {
+				return float64(ex.FeedStats().ReconnectCount)
+			}),
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Name: "arb_ws_seq_gaps_total", Help: "订单簿序号缺口累计次数", ConstLabels: labels,
+			}, func() float64 {
+				return float64(ex.FeedStats().SeqGaps)
+			}),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Name: "arb_ws_rtt_seconds", Help: "WS 最近一次 ping/pong 往返时延（秒）", ConstLabels: labels,
+			}, func() float64 {
+				return ex.FeedStats().RTT.Seconds()
+			}),
+		)
+	}
+}
+
 // ---- 行情回调 ----
 
-// onApexOrderBook 处理 Apex 订单簿更新（A所行情）
-func (e *ArbEngine) onApexOrderBook(ob *apexPkg.WsOrderBook) {
-	if len(ob.Bids) > 0 && len(ob.Asks) > 0 {
-		var bid, ask float64
-		fmt.Sscanf(ob.Bids[0][0], "%f", &bid)
-		fmt.Sscanf(ob.Asks[0][0], "%f", &ask)
-		e.apexBid.Store(bid)
-		e.apexAsk.Store(ask)
+// onApexOrderBook 处理 A所订单簿更新（适配器已丢弃格式错误的推送）
+func (e *ArbEngine) onApexOrderBook(ob *exchange.OrderBook) {
+	e.onOrderBook(e.exA, ob, &e.apexQuote, &e.apexUpdatedAt, &e.apexQuoteTs)
+}
+
+// onBybitOrderBook 处理 B所订单簿更新
+func (e *ArbEngine) onBybitOrderBook(ob *exchange.OrderBook) {
+	e.onOrderBook(e.exB, ob, &e.bybitQuote, &e.bybitUpdatedAt, &e.bybitQuoteTs)
+}
+
+// onOrderBook 截取前 book_levels 档写入盘口，价格非正时丢弃本次更新，保留上一份有效盘口（由过期检查兜底）
+func (e *ArbEngine) onOrderBook(ex exchange.Exchange, ob *exchange.OrderBook, q *atomic.Value, updatedAt *atomic.Value, ts *atomic.Int64) {
+	if len(ob.Bids) == 0 || len(ob.Asks) == 0 {
+		return
+	}
+	parsed, err := e.parseQuote(ob.Bids, ob.Asks)
+	if err != nil {
+		slog.Debug("[行情] 订单簿数据异常，丢弃本次更新", "exchange", ex.Name(), "err", err)
+		return
+	}
+	// 行情记录保留盘口异常检查前的原始最优价，便于离线分析异常行情
+	e.recorder.Record(recorder.Tick{
+		Exchange: ex.Name(),
+		Symbol:   ex.Symbol(),
+		Bid:      parsed.bid,
+		Ask:      parsed.ask,
+		BidSize:  parsed.bidSize,
+		AskSize:  parsed.askSize,
+		Ts:       ob.Ts,
+		RecvTs:   time.Now().UnixMilli(),
+	})
+	if err := e.checkQuoteSanity(ex, parsed, q.Load().(quote)); err != nil {
+		slog.Debug("[行情] 盘口异常，丢弃本次更新并保留上一次有效盘口", "exchange", ex.Name(), "err", err)
+		return
+	}
+	q.Store(parsed)
+	updatedAt.Store(time.Now())
+	ts.Store(ob.Ts)
+	if ex == e.exA {
+		e.recordMid(parsed)
+	}
+	e.wake()
+}
+
+// quoteJumpAccept 连续多少次跳变后视为真实行情变化并接受
+const quoteJumpAccept = 3
+
+// checkQuoteSanity 校验新盘口：买一 >= 卖一（交叉盘口）一律拒绝；
+// 中间价相对上一次有效盘口跳变超过 max_price_jump_pct 时拒绝，连续 quoteJumpAccept 次跳变后接受
+// 由各交易所行情回调调用，同一交易所的回调串行执行
+func (e *ArbEngine) checkQuoteSanity(ex exchange.Exchange, q, prev quote) error {
+	if q.bid >= q.ask {
+		return fmt.Errorf("交叉盘口 买一=%.4f >= 卖一=%.4f", q.bid, q.ask)
+	}
+
+	jumps := &e.quoteJumpsB
+	if ex == e.exA {
+		jumps = &e.quoteJumpsA
+	}
+	maxPct := e.cfg.Strategy.MaxPriceJumpPct
+	if maxPct <= 0 || prev.bid == 0 || prev.ask == 0 {
+		jumps.Store(0)
+		return nil
+	}
+
+	prevMid, mid := (prev.bid+prev.ask)/2, (q.bid+q.ask)/2
+	pct := math.Abs(mid-prevMid) / prevMid * 100
+	if pct <= maxPct {
+		jumps.Store(0)
+		return nil
+	}
+	if n := jumps.Add(1); n < quoteJumpAccept {
+		return fmt.Errorf("中间价跳变 %.2f%%（%.4f → %.4f）超过 %.2f%%", pct, prevMid, mid, maxPct)
+	}
+	jumps.Store(0)
+	slog.Info("[行情] 连续中间价跳变，视为真实行情变化", "exchange", ex.Name(), "count", quoteJumpAccept, "prev_mid", prevMid, "mid", mid)
+	return nil
+}
+
+// parseQuote 取订单簿前 book_levels 档，任一档价格非正时返回 error
+func (e *ArbEngine) parseQuote(bids, asks []exchange.Level) (quote, error) {
+	n := e.bookDepth()
+	parseSide := func(side string, raw []exchange.Level) ([]priceLevel, error) {
+		if len(raw) > n {
+			raw = raw[:n]
+		}
+		levels := make([]priceLevel, len(raw))
+		for i, l := range raw {
+			if l.Price <= 0 {
+				return nil, fmt.Errorf("%s第 %d 档价格非正: %v", side, i+1, l.Price)
+			}
+			levels[i] = priceLevel{price: l.Price, size: l.Size}
+		}
+		return levels, nil
 	}
+
+	var q quote
+	var err error
+	if q.bids, err = parseSide("买盘", bids); err != nil {
+		return quote{}, err
+	}
+	if q.asks, err = parseSide("卖盘", asks); err != nil {
+		return quote{}, err
+	}
+	q.bid, q.bidSize = q.bids[0].price, q.bids[0].size
+	q.ask, q.askSize = q.asks[0].price, q.asks[0].size
+	return q, nil
 }
 
-// onBybitOrderBook 处理 Bybit 订单簿更新（B所行情）
-func (e *ArbEngine) onBybitOrderBook(ob *bybitPkg.WsOrderBook) {
-	if len(ob.Bids) > 0 && len(ob.Asks) > 0 {
-		var bid, ask float64
-		fmt.Sscanf(ob.Bids[0][0], "%f", &bid)
-		fmt.Sscanf(ob.Asks[0][0], "%f", &ask)
-		e.bybitBid.Store(bid)
-		e.bybitAsk.Store(ask)
+// wake 通知套利主循环有新行情，已有未处理信号时合并
+func (e *ArbEngine) wake() {
+	select {
+	case e.wakeCh <- struct{}{}:
+	default:
+		e.coalescedCount.Add(1)
 	}
 }
 
 // ---- 套利主循环 ----
 
-// arbLoop 套利主循环：持续检测价差，发现机会立即下单
+// arbLoop 套利主循环：每次行情更新立即检测价差，定时器作为兜底心跳
 func (e *ArbEngine) arbLoop() {
 	defer e.wg.Done()
 
@@ -179,55 +851,139 @@ func (e *ArbEngine) arbLoop() {
 		select {
 		case <-e.stopCh:
 			return
+		case <-e.wakeCh:
+			e.evaluate()
 		case <-ticker.C:
-			e.checkAndTrade()
+			e.evaluate()
 		}
 	}
 }
 
+// evaluate 执行一次检测，若上一次检测仍在进行则合并本次
+func (e *ArbEngine) evaluate() {
+	if !e.checking.CompareAndSwap(false, true) {
+		e.coalescedCount.Add(1)
+		return
+	}
+	defer e.checking.Store(false)
+
+	e.evalCount.Add(1)
+	e.checkAndTrade()
+}
+
+// entryBlocked 返回当前是否禁止开新仓：管理接口暂停、两腿时间差超限暂停、资金费结算临近、
+// 两所真实持仓不平衡、行情中断处置或交易已熔断
+func (e *ArbEngine) entryBlocked() bool {
+	return e.paused.Load() || e.legLatencyPaused() || e.fundingBlocked.Load() ||
+		e.imbalanceBlocked.Load() || e.feedPaused.Load() || e.tradingHalted.Load()
+}
+
 // checkAndTrade 检测价差并执行套利
+// 行情有效性检查（盘口未就绪、过旧、汇率过旧、偏离成交价）之后由 decide 决定本次动作，
+// 账户风控、下单间隔与冷却在下单前检查
 func (e *ArbEngine) checkAndTrade() {
 	// 获取最新行情
-	apexBid := e.apexBid.Load().(float64)
-	apexAsk := e.apexAsk.Load().(float64)
-	bybitBid := e.bybitBid.Load().(float64)
-	bybitAsk := e.bybitAsk.Load().(float64)
+	apex := e.apexTop()
+	bybit := e.bybitTop()
+	apexBid, apexAsk := apex.bid, apex.ask
+	bybitBid, bybitAsk := bybit.bid, bybit.ask
 
 	if apexBid == 0 || apexAsk == 0 || bybitBid == 0 || bybitAsk == 0 {
 		return // 行情未就绪
 	}
 
-	// 检查风控
-	acc, err := e.bybitClient.GetAccount()
-	if err != nil {
-		log.Printf("[套利] 获取账户信息失败: %v", err)
+	// 任一所盘口过旧时不检测，避免冻结的行情产生虚假价差
+	if e.quotesStale() {
 		return
 	}
-	if err := e.riskCtrl.Check(acc.AvailableMargin); err != nil {
-		log.Printf("[风控] 拒绝下单: %v", err)
+
+	// 计价币汇率过旧时不检测，换算后的价差不可信
+	if e.quoteRateStale() {
 		return
 	}
 
-	// 检查盈亏目标
-	e.pnlMu.Lock()
-	pnl := e.totalPnL
-	e.pnlMu.Unlock()
-
-	if pnl >= e.cfg.Strategy.TakeProfitUSDC {
-		log.Printf("[套利] 达到盈利目标 %.2f USDC，停止套利", e.cfg.Strategy.TakeProfitUSDC)
-		go e.Stop()
+	// 盘口中间价偏离最新成交价过大时不检测（流动性差的盘口）
+	if !e.tradesConsistent(apex, bybit) {
 		return
 	}
-	if pnl <= -e.cfg.Strategy.StopLossUSDC {
-		log.Printf("[套利] 触发止损 %.2f USDC，停止套利", e.cfg.Strategy.StopLossUSDC)
-		go e.Stop()
+
+	// 两个方向的价差（详见下方核心套利逻辑说明），净价差已扣除两腿 taker 手续费
+	spread1 := bybitBid - apexAsk
+	spread2 := apexBid - bybitAsk
+	metrics.Spread1.Set(spread1)
+	metrics.Spread2.Set(spread2)
+	net1 := e.netSpread(spread1, apexAsk, bybitBid)
+	net2 := e.netSpread(spread2, apexBid, bybitAsk)
+
+	// 推送套利机会（与是否下单无关，监控模式下同样推送）
+	e.publishOpportunity(1, spread1, net1, apexBid, apexAsk, bybitBid, bybitAsk)
+	e.publishOpportunity(2, spread2, net2, apexBid, apexAsk, bybitBid, bybitAsk)
+
+	if e.cfg.Strategy.MonitorOnly {
 		return
 	}
 
-	// 检查持仓限制
 	e.posMu.Lock()
 	pos := e.position
 	e.posMu.Unlock()
+	d := e.decide(apex, bybit, pos)
+	switch d.action {
+	case ActionUnwind:
+		e.executeReduce(d.dir, d.plan, "价差回归平仓")
+		return
+	case ActionTakeProfit:
+		alert.Info("take_profit", "%s，停止开仓", d.reason)
+		go e.HaltTrading(d.reason)
+		return
+	case ActionStopLoss:
+		alert.Critical("stop_loss", "%s，停止开仓", d.reason)
+		e.riskCtrl.Halt(d.reason) // 进入风控熔断与冷却，重启后不会立即重新开仓
+		go e.HaltTrading(d.reason)
+		return
+	case ActionEntry:
+		// 检查风控（使用缓存的账户信息，过期时不开仓），名义敞口按 A 所中间价估算；减仓降低风险，不受此限制
+		acc, _, ok := e.cachedAccount()
+		if !ok {
+			return
+		}
+		mid := (apexBid + apexAsk) / 2
+		if err := e.riskCtrl.Check(acc.Available, pos, e.cfg.Strategy.OrderSize, mid); err != nil {
+			slog.Debug("[风控] 拒绝下单", "err", err)
+			return
+		}
+	case ActionReduce:
+	default:
+		return
+	}
+
+	// 冷却只限制同方向开仓，减仓不受限制也不开始冷却，持续价差期间可以连续减仓
+	entry := d.action == ActionEntry
+	if e.inTradeInterval() || (entry && e.inTradeCooldown(d.dir)) {
+		return
+	}
+	var traded bool
+	switch {
+	case d.action == ActionReduce:
+		traded = e.executeReduce(d.dir, d.plan, "反向机会减仓")
+	case d.dir == DirectionLong:
+		traded = e.executeLong(d.plan.apexPrice, d.plan.bybitPrice, d.plan.net, d.plan.size)
+	default:
+		traded = e.executeShort(d.plan.apexPrice, d.plan.bybitPrice, d.plan.net, d.plan.size)
+	}
+	// 两腿均未成交（IOC 落空、下单失败）时不计时，下一次机会不受冷却与下单间隔限制
+	if traded {
+		e.markTraded(d.dir, entry)
+	}
+}
+
+// findOpportunity 核心套利判断：按两所盘口与当前持仓返回套利方向与按深度计算的下单方案
+func (e *ArbEngine) findOpportunity(apex, bybit quote, pos float64) (ArbDirection, tradePlan, bool) {
+	apexBid, apexAsk := apex.bid, apex.ask
+	bybitBid, bybitAsk := bybit.bid, bybit.ask
+	spread1 := bybitBid - apexAsk
+	spread2 := apexBid - bybitAsk
+	net1 := e.netSpread(spread1, apexAsk, bybitBid) - e.fundingEntryCost(DirectionLong)
+	net2 := e.netSpread(spread2, apexBid, bybitAsk) - e.fundingEntryCost(DirectionShort)
 
 	// ============================================================
 	// 核心套利逻辑
@@ -242,150 +998,712 @@ func (e *ArbEngine) checkAndTrade() {
 	// ============================================================
 
 	// 场景1：Apex 便宜，Bybit 贵 → 在 Apex 买，Bybit 卖
-	spread1 := bybitBid - apexAsk
-	if spread1 >= e.cfg.Strategy.MinSpreadUSDC && pos < e.cfg.Strategy.MaxPosition {
-		log.Printf("[套利] 发现机会 场景1: Apex卖一=%.4f Bybit买一=%.4f 价差=%.4f USDC",
-			apexAsk, bybitBid, spread1)
-		e.executeLong(apexAsk, bybitBid, spread1)
-		return
+	if e.entrySpreadOK(net1, apexAsk, bybitBid) && e.positionCapacity(DirectionLong, pos) > 0 {
+		slog.Debug("[套利] 发现机会", "direction", DirectionLong.tag(),
+			"price_a", apexAsk, "price_b", bybitBid, "gross_spread", spread1, "spread", net1, "spread_bps", spreadBps(net1, apexAsk, bybitBid))
+		if !e.bookConfirms(DirectionLong, apex, bybit) || e.adverseFlow(DirectionLong) {
+			return DirectionNone, tradePlan{}, false
+		}
+		p, ok := e.planTrade(DirectionLong, apex.asks, bybit.bids)
+		return DirectionLong, p, ok
 	}
 
 	// 场景2：Apex 贵，Bybit 便宜 → 在 Apex 卖，Bybit 买
-	spread2 := apexBid - bybitAsk
-	if spread2 >= e.cfg.Strategy.MinSpreadUSDC && pos > -e.cfg.Strategy.MaxPosition {
-		log.Printf("[套利] 发现机会 场景2: Apex买一=%.4f Bybit卖一=%.4f 价差=%.4f USDC",
-			apexBid, bybitAsk, spread2)
-		e.executeShort(apexBid, bybitAsk, spread2)
-		return
+	if e.entrySpreadOK(net2, apexBid, bybitAsk) && e.positionCapacity(DirectionShort, pos) > 0 {
+		slog.Debug("[套利] 发现机会", "direction", DirectionShort.tag(),
+			"price_a", apexBid, "price_b", bybitAsk, "gross_spread", spread2, "spread", net2, "spread_bps", spreadBps(net2, apexBid, bybitAsk))
+		if !e.bookConfirms(DirectionShort, apex, bybit) || e.adverseFlow(DirectionShort) {
+			return DirectionNone, tradePlan{}, false
+		}
+		p, ok := e.planTrade(DirectionShort, apex.bids, bybit.asks)
+		return DirectionShort, p, ok
 	}
+	return DirectionNone, tradePlan{}, false
 }
 
-// executeLong 场景1：Apex 买入 + Bybit 卖出（对冲）
-// 利润来源：bybitBid - apexAsk - 手续费
-func (e *ArbEngine) executeLong(apexAsk, bybitBid, spread float64) {
-	size := fmt.Sprintf("%.*f", e.cfg.Strategy.SizePrecision, e.cfg.Strategy.OrderSize)
-	apexPrice := fmt.Sprintf("%.*f", e.cfg.Strategy.PricePrecision, apexAsk)
-	bybitPrice := fmt.Sprintf("%.*f", e.cfg.Strategy.PricePrecision, bybitBid)
-
-	// 腿1：在 Apex（A所）买入
-	apexOrder, err := e.apexClient.PlaceOrder(&apexPkg.PlaceOrderReq{
-		Symbol:      e.cfg.ApexSymbol,
-		Side:        "BUY",
-		Type:        "LIMIT",
-		Size:        size,
-		Price:       apexPrice,
-		TimeInForce: "IOC", // 立即成交或取消，避免挂单风险
-		ReduceOnly:  false,
-	})
-	if err != nil {
-		log.Printf("[套利] Apex 买入失败: %v", err)
-		return
+// publishOpportunity 推送达到阈值的价差机会，开启 near_miss_ratio 时也推送接近阈值的机会
+func (e *ArbEngine) publishOpportunity(scenario int, spread, net, apexBid, apexAsk, bybitBid, bybitAsk float64) {
+	a, b := apexAsk, bybitBid
+	if scenario == 2 {
+		a, b = apexBid, bybitAsk
 	}
-	log.Printf("[套利] Apex 买入成功 OrderID=%s 价格=%s 数量=%s", apexOrder.ID, apexPrice, size)
-
-	// 腿2（对冲）：在 Bybit（B所）卖出
-	if e.cfg.Strategy.HedgeMode {
-		bybitOrder, err := e.bybitClient.PlaceOrder(&bybitPkg.PlaceOrderReq{
-			Category:    "linear",
-			Symbol:      e.cfg.BybitSymbol,
-			Side:        "Sell",
-			OrderType:   "Limit",
-			Qty:         size,
-			Price:       bybitPrice,
-			TimeInForce: "IOC",
-			ReduceOnly:  false,
-		})
-		if err != nil {
-			log.Printf("[套利] Bybit 对冲卖出失败: %v（Apex 腿已成交，注意风险）", err)
+	minSpread, minBps := e.minSpread(), e.cfg.Strategy.MinSpreadBps
+	actionable := e.entrySpreadOK(net, a, b)
+	if !actionable {
+		ratio := e.cfg.Opportunity.NearMissRatio
+		if ratio <= 0 || !meetsThreshold(net, a, b, minSpread, minBps, ratio) {
 			return
 		}
-		log.Printf("[套利] Bybit 对冲卖出成功 OrderID=%s 价格=%s 数量=%s", bybitOrder.OrderID, bybitPrice, size)
 	}
 
-	// 更新持仓和盈亏
-	e.posMu.Lock()
-	e.position += e.cfg.Strategy.OrderSize
-	e.posMu.Unlock()
+	apexAt, _ := e.apexUpdatedAt.Load().(time.Time)
+	bybitAt, _ := e.bybitUpdatedAt.Load().(time.Time)
+	e.publisher.Publish(opportunity.SpreadView{
+		Time:            time.Now(),
+		Scenario:        scenario,
+		Actionable:      actionable,
+		ApexSymbol:      e.exA.Symbol(),
+		BybitSymbol:     e.exB.Symbol(),
+		ApexBid:         apexBid,
+		ApexAsk:         apexAsk,
+		BybitBid:        bybitBid,
+		BybitAsk:        bybitAsk,
+		Spread:          spread,
+		NetSpread:       net,
+		MinSpread:       minSpread,
+		NetSpreadBps:    spreadBps(net, a, b),
+		MinSpreadBps:    minBps,
+		ApexQuoteTs:     e.apexQuoteTs.Load(),
+		BybitQuoteTs:    e.bybitQuoteTs.Load(),
+		ApexReceivedAt:  apexAt.UnixMilli(),
+		BybitReceivedAt: bybitAt.UnixMilli(),
+	})
+}
 
-	estimatedPnL := spread * e.cfg.Strategy.OrderSize
-	e.pnlMu.Lock()
-	e.totalPnL += estimatedPnL
-	e.pnlMu.Unlock()
+// Opportunities 返回套利机会发布器，供进程内嵌入方直接订阅
+func (e *ArbEngine) Opportunities() *opportunity.Publisher {
+	return e.publisher
+}
 
-	e.riskCtrl.RecordTrade(estimatedPnL)
-	log.Printf("[套利] 场景1完成，预估本次PnL=%.4f USDC，累计PnL=%.4f USDC", estimatedPnL, e.totalPnL)
+// executeLong 场景1：Apex 买入 + Bybit 卖出（对冲）
+// 利润来源：bybitBid - apexAsk - 手续费
+func (e *ArbEngine) executeLong(apexAsk, bybitBid, spread, size float64) bool {
+	return e.execute(DirectionLong, apexAsk, bybitBid, spread, size, false)
 }
 
 // executeShort 场景2：Apex 卖出 + Bybit 买入（对冲）
 // 利润来源：apexBid - bybitAsk - 手续费
-func (e *ArbEngine) executeShort(apexBid, bybitAsk, spread float64) {
-	size := fmt.Sprintf("%.*f", e.cfg.Strategy.SizePrecision, e.cfg.Strategy.OrderSize)
-	apexPrice := fmt.Sprintf("%.*f", e.cfg.Strategy.PricePrecision, apexBid)
-	bybitPrice := fmt.Sprintf("%.*f", e.cfg.Strategy.PricePrecision, bybitAsk)
-
-	// 腿1：在 Apex（A所）卖出
-	apexOrder, err := e.apexClient.PlaceOrder(&apexPkg.PlaceOrderReq{
-		Symbol:      e.cfg.ApexSymbol,
-		Side:        "SELL",
-		Type:        "LIMIT",
-		Size:        size,
+func (e *ArbEngine) executeShort(apexBid, bybitAsk, spread, size float64) bool {
+	return e.execute(DirectionShort, apexBid, bybitAsk, spread, size, false)
+}
+
+// execute 执行一次双腿套利：先在 Apex 下 IOC 单，再按 Apex 实际成交量在 Bybit 对冲
+// spread 为扣除手续费后的每张净价差，qty 为按盘口深度限制后的下单量；reduceOnly 为价差回归平仓，两腿均为 reduce-only
+// 返回是否至少有一条腿成交
+func (e *ArbEngine) execute(dir ArbDirection, apexQuote, bybitQuote, spread, qty float64, reduceOnly bool) (traded bool) {
+	apexSide, _ := dir.sides()
+	size := e.formatSize(qty)
+	apexPrice := e.formatApexPrice(apexQuote, apexSide)
+
+	lg := slog.With("direction", dir.tag())
+	fee := e.estimateFee(apexQuote, bybitQuote, qty)
+	lg.Info("[套利] "+dir.String(), "size", size, "spread", spread,
+		"est_gross", spread*qty+fee, "est_fee", fee, "est_pnl", spread*qty)
+
+	// 本次机会的订单ID基准时间，两腿及恢复流程的自定义订单ID都由此生成
+	oppMs := time.Now().UnixMilli()
+
+	// 交易流水：各返回点补全成交与结果，退出时写入（后台异步落盘）
+	rec := store.TradeRecord{
+		Time:      time.UnixMilli(oppMs),
+		Direction: dir.tag(),
+		VenueA:    e.exA.Name(),
+		VenueB:    e.exB.Name(),
+		QuoteA:    apexQuote,
+		QuoteB:    bybitQuote,
+		Size:      qty,
+	}
+	defer func() {
+		e.journal.RecordTrade(rec)
+		e.addSessionFee(rec.Fee)
+		traded = rec.FillQtyA > 0 || rec.FillQtyB > 0
+	}()
+
+	// hedge_first：先下 B所对冲腿，再按对冲成交量下 A所腿
+	if e.cfg.Strategy.HedgeFirst && e.cfg.Strategy.HedgeMode {
+		e.executeHedgeFirst(dir, oppMs, apexQuote, bybitQuote, qty, reduceOnly, &rec)
+		return
+	}
+
+	// 腿1：在 A所下单
+	req := &exchange.OrderRequest{
+		Side:        apexSide,
+		Type:        exchange.Limit,
+		Qty:         size,
 		Price:       apexPrice,
-		TimeInForce: "IOC",
-		ReduceOnly:  false,
-	})
+		TimeInForce: e.apexTimeInForce(), // 默认 IOC：立即成交或取消，避免挂单风险
+		ExpireAt:    e.orderExpireAt(),
+		ReduceOnly:  reduceOnly,
+		ClientID:    e.clientID(oppMs, dir, "apex"),
+	}
+	apexFill, err := e.placeApexLeg(dir, req, qty)
 	if err != nil {
-		log.Printf("[套利] Apex 卖出失败: %v", err)
+		lg.Error("[套利] "+dir.apexAction()+"失败", "exchange", e.exA.Name(), "err", err)
+		rec.Failure = fmt.Sprintf("A所下单失败: %v", err)
 		return
 	}
-	log.Printf("[套利] Apex 卖出成功 OrderID=%s 价格=%s 数量=%s", apexOrder.ID, apexPrice, size)
-
-	// 腿2（对冲）：在 Bybit（B所）买入
-	if e.cfg.Strategy.HedgeMode {
-		bybitOrder, err := e.bybitClient.PlaceOrder(&bybitPkg.PlaceOrderReq{
-			Category:    "linear",
-			Symbol:      e.cfg.BybitSymbol,
-			Side:        "Buy",
-			OrderType:   "Limit",
-			Qty:         size,
-			Price:       bybitPrice,
-			TimeInForce: "IOC",
-			ReduceOnly:  false,
-		})
-		if err != nil {
-			log.Printf("[套利] Bybit 对冲买入失败: %v（Apex 腿已成交，注意风险）", err)
-			return
-		}
-		log.Printf("[套利] Bybit 对冲买入成功 OrderID=%s 价格=%s 数量=%s", bybitOrder.OrderID, bybitPrice, size)
+	apexPlacedAt := apexFill.placedAt
+
+	// 以 Apex 实际成交量为准，IOC 可能部分成交或完全未成交
+	filled := e.roundSize(apexFill.qty)
+	rec.OrderIDA, rec.FillQtyA, rec.FillPriceA, rec.Fee = apexFill.orderID, apexFill.qty, apexFill.avgPrice, apexFill.fee
+	if filled <= 0 {
+		rec.Failure = "A所未成交"
+		lg.Info("[套利] "+dir.apexAction()+"未成交，不计入PnL", "exchange", e.exA.Name(), "order_id", apexFill.orderID, "price", apexPrice, "size", size)
+		return
 	}
+	lg.Info("[套利] "+dir.apexAction()+"成功", "exchange", e.exA.Name(), "order_id", apexFill.orderID,
+		"price", apexPrice, "size", size, "filled", e.formatSize(filled), "avg_price", apexFill.avgPrice)
 
-	// 更新持仓和盈亏
+	// Apex 腿已成交，持仓按实际成交量更新；对冲与恢复结束前为单腿敞口
 	e.posMu.Lock()
-	e.position -= e.cfg.Strategy.OrderSize
+	e.position += dir.sign() * filled
 	e.posMu.Unlock()
+	defer e.noteUnhedgedWindow(time.Now())
 
-	estimatedPnL := spread * e.cfg.Strategy.OrderSize
+	// 单腿模式：没有对冲腿，按扣费后的报价价差预估
+	if !e.cfg.Strategy.HedgeMode {
+		rec.PnL = spread * filled
+		e.bookPnL(dir, rec.PnL, "预估")
+		return
+	}
+
+	// 腿2（对冲）：在 Bybit（B所）按 Apex 成交量反向下单
+	if filled < e.minOrderSize() {
+		lg.Warn("[套利] 成交量低于最小下单量，跳过对冲（注意风险）", "exchange", e.exA.Name(),
+			"filled", e.formatSize(filled), "min_size", e.formatSize(e.minOrderSize()), "unhedged", e.formatSize(filled))
+		rec.Failure = "A所成交量低于最小下单量，未对冲"
+		return
+	}
+
+	bybitFill, err := e.placeHedge(dir, filled, bybitQuote, reduceOnly, e.clientID(oppMs, dir, "hedge"))
+	if !bybitFill.placedAt.IsZero() {
+		e.recordLegLatency(bybitFill.placedAt.Sub(apexPlacedAt))
+	}
+	rec.OrderIDB, rec.FillQtyB, rec.FillPriceB = bybitFill.orderID, bybitFill.qty, bybitFill.avgPrice
+	rec.Fee += bybitFill.fee
+	if errors.Is(err, errFillUnknown) {
+		rec.Failure = fmt.Sprintf("对冲成交状态未知: %v", err)
+		lg.Error("[套利] 无法确认对冲成交，不计入PnL（注意核对持仓）", "exchange", e.exB.Name(), "order_id", bybitFill.orderID, "err", err)
+		alert.Critical("hedge_failed", "%s 对冲成交状态未知（%s %s）: %v，请核对持仓", e.exB.Name(), dir, e.formatSize(filled), err)
+		return
+	}
+	if err != nil {
+		lg.Error("[套利] 对冲"+dir.bybitAction()+"失败（A所腿已成交，启动对冲恢复）", "exchange", e.exB.Name(), "err", err)
+		alert.Warn("hedge_failed", "%s 对冲失败（%s %s）: %v，启动对冲恢复", e.exB.Name(), dir, e.formatSize(filled), err)
+		rec.Failure = fmt.Sprintf("对冲失败: %v（已启动对冲恢复）", err)
+		e.recoverHedge(dir, oppMs, apexFill, legFill{}, filled, reduceOnly)
+		return
+	}
+	if bybitFill.qty <= 0 {
+		lg.Warn("[套利] 对冲"+dir.bybitAction()+"未成交（A所腿已成交，启动对冲恢复）", "exchange", e.exB.Name(), "order_id", bybitFill.orderID)
+		alert.Warn("hedge_failed", "%s 对冲未成交（%s %s），启动对冲恢复", e.exB.Name(), dir, e.formatSize(filled))
+		rec.Failure = "对冲未成交（已启动对冲恢复）"
+		e.recoverHedge(dir, oppMs, apexFill, legFill{}, filled, reduceOnly)
+		return
+	}
+
+	// 对冲部分成交：两腿成交量偏差超过容忍范围时处理孤立敞口
+	if orphan := e.roundSize(filled - bybitFill.qty); orphan > e.hedgeTolerance(bybitQuote) {
+		lg.Warn("[套利] 对冲部分成交，启动对冲恢复", "exchange", e.exB.Name(), "order_id", bybitFill.orderID,
+			"filled", e.formatSize(bybitFill.qty), "target", e.formatSize(filled), "orphan", e.formatSize(orphan))
+		rec.Failure = fmt.Sprintf("对冲部分成交，孤立敞口 %s（已启动对冲恢复）", e.formatSize(orphan))
+		e.recoverHedge(dir, oppMs, apexFill, bybitFill, orphan, reduceOnly)
+		return
+	}
+
+	rec.PnL = realizedPnL(dir, apexFill, bybitFill)
+	e.attributePnL(dir, apexQuote, bybitQuote, apexFill, bybitFill)
+	e.bookPnL(dir, rec.PnL, "已实现")
+	return
+}
+
+// onThrottle 记录本地限频事件：等待只计数（状态日志汇总），拒绝与按响应头退避逐条告警
+func (e *ArbEngine) onThrottle(ev exchange.ThrottleEvent) {
+	switch {
+	case !ev.Until.IsZero():
+		slog.Warn("[限频] 接口剩余额度不足，暂停请求", "exchange", ev.Venue, "group", ev.Group,
+			"remaining", ev.Remaining, "limit", ev.Limit, "wait", ev.Wait.Round(time.Millisecond))
+	case ev.Rejected:
+		e.throttleRejects.Add(1)
+		slog.Warn("[限频] 接口令牌不足，请求已放弃", "exchange", ev.Venue, "group", ev.Group, "wait", ev.Wait.Round(time.Millisecond))
+	default:
+		e.throttleWaits.Add(1)
+	}
+}
+
+const (
+	// orderRetryDelay 下单遇到瞬时错误时，引擎层再试一次前的等待
+	orderRetryDelay = 200 * time.Millisecond
+
+	// defaultClientIDPrefix 未配置 client_id_prefix 时的自定义订单ID前缀
+	defaultClientIDPrefix = "arb"
+
+	// maxClientIDPrefix 自定义订单ID前缀最大长度（Bybit orderLinkId 上限 36 字符）
+	maxClientIDPrefix = 8
+)
+
+// retryOnce 执行下单，遇到瞬时错误（网络、5xx、限频、超时）时等待 orderRetryDelay 后再试一次
+// 超时无法确定订单是否已提交，重试前先用 lookup 按自定义订单ID查询，已存在则直接使用；
+// 重试复用同一请求（同一自定义订单ID），交易所按ID去重，不会重复下单
+func (e *ArbEngine) retryOnce(venue string, place, lookup func() error) error {
+	err := place()
+	if err == nil || !exchange.IsRetryable(err) {
+		return err
+	}
+	slog.Warn("[套利] 下单瞬时错误，稍后确认订单状态", "exchange", venue, "err", err, "delay", orderRetryDelay)
+	select {
+	case <-e.stopCh:
+		return err
+	case <-time.After(orderRetryDelay):
+	}
+	if lookup() == nil {
+		slog.Info("[套利] 订单已提交，不再重试", "exchange", venue)
+		return nil
+	}
+	return place()
+}
+
+// clientID 生成本次套利机会某条腿的自定义订单ID：{prefix}-{机会时间毫秒}-{方向}-{腿}
+// 同一机会同一条腿的ID固定，超时后可按ID确认订单是否已提交
+func (e *ArbEngine) clientID(oppMs int64, dir ArbDirection, leg string) string {
+	prefix := e.cfg.Strategy.ClientIDPrefix
+	if prefix == "" {
+		prefix = defaultClientIDPrefix
+	}
+	return fmt.Sprintf("%s-%d-%s-%s", prefix, oppMs, dir.tag(), leg)
+}
+
+// onOrderError 按错误类型处置下单失败：余额不足或鉴权/权限错误继续交易只会反复失败，触发风控熔断
+func (e *ArbEngine) onOrderError(venue string, err error) {
+	switch {
+	case exchange.IsInsufficientBalance(err):
+		e.riskCtrl.Halt(fmt.Sprintf("%s 余额不足: %v", venue, err))
+	case exchange.IsPermissionDenied(err):
+		e.riskCtrl.Halt(fmt.Sprintf("%s 鉴权或权限错误: %v", venue, err))
+	}
+}
+
+// placeOrder 在 ex 下单（瞬时错误时按自定义订单ID确认后再试一次），失败时按错误类型处置
+func (e *ArbEngine) placeOrder(ex exchange.Exchange, req *exchange.OrderRequest) (*exchange.Order, error) {
+	var order *exchange.Order
+	err := e.retryOnce(ex.Name(), func() (err error) {
+		order, err = ex.PlaceOrder(e.ctx, req)
+		return err
+	}, func() (err error) {
+		order, err = ex.GetOrderByClientID(e.ctx, req.ClientID)
+		return err
+	})
+	if err != nil {
+		e.onOrderError(ex.Name(), err)
+		return nil, err
+	}
+	return order, nil
+}
+
+// placeHedge 在 Bybit 以 IOC 限价单对冲 qty（linkID 为自定义订单ID，reduceOnly 用于平仓），返回实际成交
+// hedge_order_type=market 时忽略 price，改用最新盘口加滑点上限作为保护价，以少量滑点换取成交确定性
+// 下单成功但成交查询失败时返回 errFillUnknown，此时不能重试以免重复对冲
+func (e *ArbEngine) placeHedge(dir ArbDirection, qty, price float64, reduceOnly bool, linkID string) (legFill, error) {
+	_, bybitSide := dir.sides()
+	hedgeSize := e.formatSize(qty)
+	if e.cfg.Strategy.HedgeOrderType == config.HedgeOrderMarket {
+		price = e.marketHedgePrice(bybitSide)
+	}
+	bybitPrice := e.formatBybitPrice(price, bybitSide)
+
+	bybitOrder, err := e.placeOrder(e.exB, &exchange.OrderRequest{
+		Side:        bybitSide,
+		Type:        exchange.Limit,
+		Qty:         hedgeSize,
+		Price:       bybitPrice,
+		TimeInForce: exchange.IOC,
+		ReduceOnly:  reduceOnly,
+		ClientID:    linkID,
+	})
+	placedAt := time.Now()
+	if err != nil {
+		return legFill{}, err
+	}
+
+	fill, err := e.bybitFill(bybitOrder.ID)
+	if err != nil {
+		return legFill{orderID: bybitOrder.ID, placedAt: placedAt}, fmt.Errorf("%w: %v", errFillUnknown, err)
+	}
+	fill.orderID, fill.placedAt = bybitOrder.ID, placedAt
+	if fill.qty > 0 {
+		slog.Info("[套利] 对冲"+dir.bybitAction()+"成功", "direction", dir.tag(), "exchange", e.exB.Name(), "order_id", bybitOrder.ID,
+			"price", bybitPrice, "size", hedgeSize, "filled", e.formatSize(fill.qty), "avg_price", fill.avgPrice)
+	} else {
+		slog.Info("[套利] 对冲"+dir.bybitAction()+"未成交", "direction", dir.tag(), "exchange", e.exB.Name(), "order_id", bybitOrder.ID,
+			"price", bybitPrice, "size", hedgeSize)
+	}
+	return fill, nil
+}
+
+// marketHedgePrice 返回 market 对冲的保护价：买单为卖一加滑点上限，卖单为买一减滑点上限
+func (e *ArbEngine) marketHedgePrice(side exchange.Side) float64 {
+	q := e.bybitTop()
+	price := q.bid
+	if side == exchange.Buy {
+		price = q.ask
+	}
+	slip := e.cfg.Strategy.SlippageToleranceUSDC
+	if slip <= 0 {
+		slip = e.hedgeSlippage(price)
+	}
+	if side == exchange.Buy {
+		return price + slip
+	}
+	return price - slip
+}
+
+// bookPnL 记录一笔交易的盈亏并通知风控
+func (e *ArbEngine) bookPnL(dir ArbDirection, pnl float64, kind string) {
 	e.pnlMu.Lock()
-	e.totalPnL += estimatedPnL
+	e.totalPnL += pnl
+	e.runPnL += pnl
+	totalPnL := e.totalPnL
+	if day := e.tradingDayStart(time.Now()); day.After(e.dailyStart) {
+		e.dailyStart, e.dailyTrades, e.dailyWins = day, 0, 0
+	}
+	e.dailyTrades++
+	if pnl > 0 {
+		e.dailyWins++
+	}
 	e.pnlMu.Unlock()
 
-	e.riskCtrl.RecordTrade(estimatedPnL)
-	log.Printf("[套利] 场景2完成，预估本次PnL=%.4f USDC，累计PnL=%.4f USDC", estimatedPnL, e.totalPnL)
+	e.posMu.Lock()
+	pos := e.position
+	e.posMu.Unlock()
+	e.recordSessionTrade(pnl, pos)
+
+	e.riskCtrl.RecordTrade(pnl)
+	metrics.TradesTotal.Inc()
+	if dir == DirectionShort {
+		metrics.TradesScenario2.Inc()
+	} else {
+		metrics.TradesScenario1.Inc()
+	}
+	metrics.TotalPnL.Set(totalPnL)
+	slog.Info("[套利] "+dir.String()+"完成", "direction", dir.tag(), "pnl_kind", kind, "pnl", pnl, "total_pnl", totalPnL)
+	e.saveState()
 }
 
 // ---- 辅助方法 ----
 
+// quote 单个交易所的最优买卖价与对应挂单量
+type quote struct {
+	bid, bidSize float64
+	ask, askSize float64
+
+	// 前 book_levels 档订单簿，用于逐档计算可执行价格
+	bids, asks []priceLevel
+}
+
+// priceLevel 订单簿单档
+type priceLevel struct {
+	price, size float64
+}
+
+// executablePrice 逐档吃单 size 张，返回成交 VWAP 与吃到的最差一档价格
+// 订单簿深度不足 size 时返回 false
+func executablePrice(levels []priceLevel, size float64) (vwap, worst float64, ok bool) {
+	remaining := size
+	var notional float64
+	for _, l := range levels {
+		if remaining <= 0 {
+			break
+		}
+		if l.size <= 0 {
+			continue
+		}
+		take := math.Min(l.size, remaining)
+		notional += take * l.price
+		remaining -= take
+		worst = l.price
+	}
+	if size <= 0 || remaining > 1e-9 {
+		return 0, 0, false
+	}
+	return notional / size, worst, true
+}
+
+// levelsDepth 返回各档挂单量之和
+func levelsDepth(levels []priceLevel) float64 {
+	var depth float64
+	for _, l := range levels {
+		depth += l.size
+	}
+	return depth
+}
+
+// bookDepth 返回计算可执行价格使用的档位数（未配置时只用最优一档）
+func (e *ArbEngine) bookDepth() int {
+	if e.cfg.Strategy.BookLevels <= 0 {
+		return 1
+	}
+	return e.cfg.Strategy.BookLevels
+}
+
+// subscribeDepth 返回订单簿订阅档位：配置了 orderbook_depth 时按配置，
+// 否则只用一档时订阅 1 档、多档时订阅 50 档
+func (e *ArbEngine) subscribeDepth() int {
+	if e.cfg.Strategy.OrderbookDepth > 0 {
+		return e.cfg.Strategy.OrderbookDepth
+	}
+	if e.cfg.Strategy.BookLevels <= 1 {
+		return 1
+	}
+	return 50
+}
+
+func (e *ArbEngine) apexTop() quote {
+	return e.apexQuote.Load().(quote)
+}
+
+func (e *ArbEngine) bybitTop() quote {
+	return e.bybitQuote.Load().(quote)
+}
+
+// sides 返回该方向下 Apex 腿与 Bybit 对冲腿的买卖方向
+func (d ArbDirection) sides() (apexSide, bybitSide exchange.Side) {
+	if d == DirectionShort {
+		return exchange.Sell, exchange.Buy
+	}
+	return exchange.Buy, exchange.Sell
+}
+
+// sign 返回该方向对持仓的影响：做多为 +1，做空为 -1
+func (d ArbDirection) sign() float64 {
+	if d == DirectionShort {
+		return -1
+	}
+	return 1
+}
+
+func (d ArbDirection) apexAction() string {
+	if d == DirectionShort {
+		return "卖出"
+	}
+	return "买入"
+}
+
+func (d ArbDirection) bybitAction() string {
+	if d == DirectionShort {
+		return "买入"
+	}
+	return "卖出"
+}
+
+// tag 返回方向的英文短标签，用于自定义订单ID
+func (d ArbDirection) tag() string {
+	if d == DirectionShort {
+		return "short"
+	}
+	return "long"
+}
+
+func (d ArbDirection) String() string {
+	switch d {
+	case DirectionLong:
+		return "场景1"
+	case DirectionShort:
+		return "场景2"
+	default:
+		return "无"
+	}
+}
+
+// quotesStale 任一所盘口距最近更新超过 max_quote_age_ms 时返回 true（<0 表示不检查）
+// 进入停滞时告警一次，恢复时再打印一次
+func (e *ArbEngine) quotesStale() bool {
+	maxAge := e.maxQuoteAge()
+	if maxAge < 0 {
+		return false
+	}
+
+	apexAge, bybitAge := e.quoteAges()
+	stale := apexAge > maxAge || bybitAge > maxAge
+
+	if was := e.quoteStale.Swap(stale); stale && !was {
+		slog.Warn("[套利] 行情停滞，暂停检测", "age_a", apexAge.Round(time.Millisecond), "age_b", bybitAge.Round(time.Millisecond), "max_age", maxAge)
+	} else if !stale && was {
+		slog.Info("[套利] 行情恢复更新，继续检测")
+	}
+	return stale
+}
+
+// maxQuoteAge 返回盘口最大有效时长：0 使用默认值，负数表示不检查
+func (e *ArbEngine) maxQuoteAge() time.Duration {
+	ms := e.cfg.Strategy.MaxQuoteAgeMs
+	if ms == 0 {
+		return defaultMaxQuoteAge
+	}
+	if ms < 0 {
+		return -1
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// quoteAges 返回两所盘口距最近一次更新的时长
+func (e *ArbEngine) quoteAges() (apexAge, bybitAge time.Duration) {
+	now := time.Now()
+	apexAt, _ := e.apexUpdatedAt.Load().(time.Time)
+	bybitAt, _ := e.bybitUpdatedAt.Load().(time.Time)
+	return now.Sub(apexAt), now.Sub(bybitAt)
+}
+
+// feedLag 返回最近一次盘口的传输延迟（本地接收时间 - 交易所时间戳 Ts），无数据时为 0
+func feedLag(receivedAt *atomic.Value, ts *atomic.Int64) time.Duration {
+	at, _ := receivedAt.Load().(time.Time)
+	ms := ts.Load()
+	if at.IsZero() || ms <= 0 {
+		return 0
+	}
+	return at.Sub(time.UnixMilli(ms))
+}
+
+// tradePlan 按订单簿深度计算出的可执行下单方案
+type tradePlan struct {
+	size       float64 // 下单量
+	apexPrice  float64 // Apex 限价（吃到的最差一档）
+	bybitPrice float64 // Bybit 对冲限价（吃到的最差一档）
+	net        float64 // 按两腿 VWAP 计算的每张净价差
+}
+
+// planTrade 在前 book_levels 档内计算两腿可执行 VWAP，并据此重新校验价差
+// apexLevels / bybitLevels 为吃单方向的对手盘（Apex 买入吃卖盘，Bybit 卖出吃买盘）
+func (e *ArbEngine) planTrade(dir ArbDirection, apexLevels, bybitLevels []priceLevel) (tradePlan, bool) {
+	size, ok := e.tradeSize(dir, levelsDepth(apexLevels), levelsDepth(bybitLevels),
+		e.profitableDepth(dir, apexLevels, bybitLevels))
+	if !ok {
+		return tradePlan{}, false
+	}
+
+	apexVWAP, apexWorst, ok1 := executablePrice(apexLevels, size)
+	bybitVWAP, bybitWorst, ok2 := executablePrice(bybitLevels, size)
+	if !ok1 || !ok2 {
+		return tradePlan{}, false
+	}
+
+	// 吃到的最差一档偏离最优价超过允许滑点时放弃
+	apexTop, bybitTop := apexLevels[0].price, bybitLevels[0].price
+	slip := e.hedgeSlippage((apexTop + bybitTop) / 2)
+	if math.Abs(apexWorst-apexTop) > slip || math.Abs(bybitWorst-bybitTop) > slip {
+		slog.Debug("[套利] 订单簿无法在滑点内吸收下单量，放弃本次机会", "direction", dir.tag(), "slippage", slip, "size", e.formatSize(size),
+			"worst_a", apexWorst, "top_a", apexTop, "worst_b", bybitWorst, "top_b", bybitTop)
+		return tradePlan{}, false
+	}
+
+	gross := (bybitVWAP - apexVWAP) * dir.sign()
+	net := e.netSpread(gross, apexVWAP, bybitVWAP) - e.fundingEntryCost(dir)
+	if !e.entrySpreadOK(net, apexVWAP, bybitVWAP) {
+		slog.Debug("[套利] 按深度加权后价差不足", "direction", dir.tag(), "vwap_a", apexVWAP, "vwap_b", bybitVWAP,
+			"gross_spread", gross, "spread", net, "spread_bps", spreadBps(net, apexVWAP, bybitVWAP))
+		return tradePlan{}, false
+	}
+	if size > levelsDepth(apexLevels[:1]) || size > levelsDepth(bybitLevels[:1]) {
+		slog.Debug("[套利] 跨档成交", "direction", dir.tag(), "vwap_a", apexVWAP, "vwap_b", bybitVWAP, "spread", net)
+	}
+
+	return tradePlan{size: size, apexPrice: apexWorst, bybitPrice: bybitWorst, net: net}, true
+}
+
+// profitableDepth 逐档撮合两所对手盘，返回每一档边际净价差仍满足开仓阈值（entrySpreadOK）的累计数量
+// 超过该数量继续吃单，新增部分的价差将低于阈值
+func (e *ArbEngine) profitableDepth(dir ArbDirection, apexLevels, bybitLevels []priceLevel) float64 {
+	var depth float64
+	i, j := 0, 0
+	apexLeft, bybitLeft := 0.0, 0.0
+	if len(apexLevels) > 0 && len(bybitLevels) > 0 {
+		apexLeft, bybitLeft = apexLevels[0].size, bybitLevels[0].size
+	}
+	for i < len(apexLevels) && j < len(bybitLevels) {
+		a, b := apexLevels[i].price, bybitLevels[j].price
+		if !e.entrySpreadOK(e.netSpread((b-a)*dir.sign(), a, b), a, b) {
+			break
+		}
+		take := math.Min(apexLeft, bybitLeft)
+		depth += take
+		apexLeft -= take
+		bybitLeft -= take
+		if apexLeft <= 0 {
+			if i++; i < len(apexLevels) {
+				apexLeft = apexLevels[i].size
+			}
+		}
+		if bybitLeft <= 0 {
+			if j++; j < len(bybitLevels) {
+				bybitLeft = bybitLevels[j].size
+			}
+		}
+	}
+	return depth
+}
+
+// tradeSize 按两所订单簿深度限制下单量：min(OrderSize, Apex 对手盘量, Bybit 对手盘量, 可盈利深度)
+// 限制后低于 min_fill_size 或交易所最小下单量时返回 false，放弃本次机会
+func (e *ArbEngine) tradeSize(dir ArbDirection, apexDepth, bybitDepth, profitable float64) (float64, bool) {
+	size := math.Min(e.cfg.Strategy.OrderSize, math.Min(apexDepth, bybitDepth))
+	size = math.Min(size, profitable)
+	if e.spec.maxQty > 0 {
+		size = math.Min(size, e.spec.maxQty)
+	}
+	size = e.roundSize(size)
+
+	if size < e.cfg.Strategy.OrderSize {
+		slog.Debug("[套利] 盘口深度不足，限制下单量", "direction", dir.tag(), "order_size", e.formatSize(e.cfg.Strategy.OrderSize),
+			"size", e.formatSize(size), "depth_a", apexDepth, "depth_b", bybitDepth, "profitable", profitable)
+	}
+	floor := math.Max(e.minOrderSize(), e.cfg.Strategy.MinFillSize)
+	if size <= 0 || size < floor {
+		slog.Debug("[套利] 限制后下单量低于最小成交量，放弃本次机会", "direction", dir.tag(),
+			"size", e.formatSize(size), "min_size", e.formatSize(floor))
+		return 0, false
+	}
+	return size, true
+}
+
+// netSpread 从毛价差中扣除两腿 taker 手续费（每张合约，按各自成交价计算）
+func (e *ArbEngine) netSpread(gross, apexPrice, bybitPrice float64) float64 {
+	return gross - e.estimateFee(apexPrice, bybitPrice, 1)
+}
+
+// estimateFee 按 taker 费率估算两腿成交 size 张的手续费（fee_rate * price * size）
+func (e *ArbEngine) estimateFee(apexPrice, bybitPrice, size float64) float64 {
+	return (apexPrice*e.feeA + bybitPrice*e.feeB) * size
+}
+
+// venueSettings 返回交易所对应的 taker 费率与行情中断处置配置
+func venueSettings(cfg *config.Config, name string) (takerFee float64, feedLoss config.FeedLossPolicy) {
+	switch name {
+	case exchange.Bybit:
+		return cfg.Strategy.BybitTakerFeeRate, cfg.Bybit.FeedLoss
+	case exchange.Binance:
+		return cfg.Strategy.BinanceTakerFeeRate, cfg.Binance.FeedLoss
+	}
+	return cfg.Strategy.ApexTakerFeeRate, cfg.Apex.FeedLoss
+}
+
 // waitForMarketData 等待两所行情数据都就绪
 func (e *ArbEngine) waitForMarketData(timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 	for time.Now().Before(deadline) {
-		apexBid := e.apexBid.Load().(float64)
-		bybitBid := e.bybitBid.Load().(float64)
-		if apexBid > 0 && bybitBid > 0 {
+		if e.feedsReady() {
 			return nil
 		}
 		time.Sleep(200 * time.Millisecond)
 	}
-	return fmt.Errorf("等待行情超时（%v），请检查 WebSocket 连接", timeout)
+	// 只有一所就绪时继续启动，进入等待行情状态：行情未就绪时 checkAndTrade 不开仓，另一所由重连循环继续重试
+	for _, v := range []struct {
+		ex    exchange.Exchange
+		ready bool
+	}{
+		{e.exA, e.apexTop().bid > 0},
+		{e.exB, e.bybitTop().bid > 0},
+	} {
+		if v.ready {
+			slog.Warn("[行情] 部分行情未就绪，继续启动并等待重连", "timeout", timeout, "ready", v.ex.Name())
+			return nil
+		}
+	}
+	return fmt.Errorf("等待行情超时（%v），两所行情均未就绪，请检查 WebSocket 连接", timeout)
+}
+
+// feedsReady 两所盘口是否都已收到
+func (e *ArbEngine) feedsReady() bool {
+	return e.apexTop().bid > 0 && e.bybitTop().bid > 0
 }
 
 // statusLoop 定期打印运行状态
@@ -400,27 +1718,137 @@ func (e *ArbEngine) statusLoop() {
 		case <-e.stopCh:
 			return
 		case <-ticker.C:
-			apexBid := e.apexBid.Load().(float64)
-			apexAsk := e.apexAsk.Load().(float64)
-			bybitBid := e.bybitBid.Load().(float64)
-			bybitAsk := e.bybitAsk.Load().(float64)
+			apex := e.apexTop()
+			bybit := e.bybitTop()
+			apexBid, apexAsk := apex.bid, apex.ask
+			bybitBid, bybitAsk := bybit.bid, bybit.ask
 
 			e.posMu.Lock()
 			pos := e.position
+			unhedged, transferred := e.unhedgedQty, e.transferredHedge
 			e.posMu.Unlock()
 
 			e.pnlMu.Lock()
 			pnl := e.totalPnL
+			closePnL, closeTrades := e.closePnL, e.closeTrades
+			gross, fees, slippage, runPnL := e.grossPnL, e.feesPaid, e.slippageCost, e.runPnL
 			e.pnlMu.Unlock()
 
 			// 计算当前两所价差
 			spread1 := bybitBid - apexAsk
 			spread2 := apexBid - bybitAsk
 
-			log.Printf("[状态] Apex: bid=%.4f ask=%.4f | Bybit: bid=%.4f ask=%.4f | 价差1=%.4f 价差2=%.4f | 持仓=%.4f | 累计PnL=%.4f USDC | 日PnL=%.4f USDC",
-				apexBid, apexAsk, bybitBid, bybitAsk,
-				spread1, spread2,
-				math.Abs(pos), pnl, e.riskCtrl.DailyPnL())
+			slog.Info("[状态] "+e.tradingState(),
+				"bid_a", apexBid, "ask_a", apexAsk, "bid_b", bybitBid, "ask_b", bybitAsk,
+				"spread1", spread1, "spread2", spread2,
+				"spread1_bps", spreadBps(spread1, apexAsk, bybitBid), "spread2_bps", spreadBps(spread2, apexBid, bybitAsk),
+				"position", math.Abs(pos), "position_b", math.Abs(pos)*e.sizeRatio(), "capacity_long", e.positionCapacity(DirectionLong, pos),
+				"capacity_short", e.positionCapacity(DirectionShort, pos), "total_pnl", pnl, "daily_pnl", e.riskCtrl.DailyPnL(),
+				"unhedged_incidents", e.unhedgedIncidents.Load(), "unhedged", unhedged,
+				"evaluations", e.evalCount.Load(), "coalesced", e.coalescedCount.Load())
+
+			if transferred != 0 {
+				slog.Warn("[状态] B所持有断线处置转移的对冲仓位", "hedge", transferred)
+			}
+
+			if st := e.imbalance.Load().(imbalanceState); !st.at.IsZero() {
+				slog.Info("[状态] 净 delta", "contracts", st.qty, "notional_usdc", st.notional, "age", time.Since(st.at).Round(time.Second))
+			}
+			if e.imbalanceBlocked.Load() {
+				slog.Warn("[状态] 两所持仓不平衡，暂停开仓")
+			}
+			if closeTrades > 0 {
+				slog.Info("[状态] 开平仓盈亏", "open_pnl", pnl-closePnL, "close_pnl", closePnL, "close_trades", closeTrades)
+			}
+			if runPnL != 0 || gross != 0 {
+				slog.Info("[状态] 盈亏归因", "gross", gross, "fees", fees, "slippage", slippage,
+					"net", gross-fees-slippage, "other", runPnL-(gross-fees-slippage), "run_pnl", runPnL)
+			}
+			if e.paused.Load() {
+				slog.Info("[状态] 已暂停开仓（行情、持仓管理照常运行）", "reason", e.pauseReason.Load().(string))
+			}
+			if reason, _ := e.haltReason.Load().(string); reason != "" {
+				slog.Info("[状态] 已停止开仓", "reason", reason)
+			}
+			if reason := e.riskCtrl.HaltReason(); reason != "" {
+				slog.Warn("[状态] 风控熔断中（需人工重置或等待日切）", "reason", reason)
+			}
+			if remaining := e.riskCtrl.CooldownRemaining(); remaining > 0 {
+				slog.Info("[状态] 风控冷却中", "remaining", remaining.Round(time.Second))
+			}
+			slog.Info("[状态] 风控",
+				"consecutive_loss", e.riskCtrl.ConsecutiveLoss(), "max_consecutive_loss", e.cfg.RiskControl.MaxConsecutiveLoss,
+				"next_reset", e.riskCtrl.NextResetTime().Format("2006-01-02 15:04:05 MST"))
+			if avg := e.legLatencyAverage(); avg > 0 {
+				slog.Info("[状态] 两腿下单时间差", "avg", avg)
+			}
+			if e.legLatencyPaused() {
+				slog.Info("[状态] 两腿时间差超限，暂停开仓", "until", time.Unix(0, e.legPauseUntil.Load()).Format("15:04:05"))
+			}
+			e.logFunding()
+			if n := e.cooldownSkips.Load(); n > 0 {
+				slog.Info("[状态] 同方向冷却跳过", "skipped", n, "cooldown", e.tradeCooldown())
+			}
+			if n := e.intervalSkips.Load(); n > 0 {
+				slog.Info("[状态] 最小交易间隔跳过", "skipped", n, "interval_ms", e.cfg.Strategy.MinTradeIntervalMs)
+			}
+			if e.recorder != nil {
+				slog.Info("[状态] 行情记录", "recorded", e.recorder.Recorded(), "dropped", e.recorder.Dropped())
+			}
+			if waits, rejects := e.throttleWaits.Load(), e.throttleRejects.Load(); waits+rejects > 0 {
+				slog.Info("[状态] REST 限频", "waits", waits, "rejects", rejects)
+			}
+			if _, age, ok := e.cachedAccount(); age > 0 {
+				slog.Info("[状态] 账户缓存", "age", age.Round(time.Millisecond), "valid", ok)
+			}
+
+			for _, v := range []struct {
+				ex   exchange.Exchange
+				rest *atomic.Value
+			}{{e.exA, &e.restQuoteA}, {e.exB, &e.restQuoteB}} {
+				if rq, ok := restQuoteOf(v.rest); ok {
+					slog.Info("[状态] WS 中断，使用 REST 盘口", "exchange", v.ex.Name(), "bid", rq.bid, "ask", rq.ask,
+						"age", time.Since(rq.at).Round(time.Millisecond), "usage", restUsage(e.cfg.Strategy.AllowRestTrading))
+				}
+			}
+
+			apexSt := e.exA.FeedStats()
+			bybitSt := e.exB.FeedStats()
+			apexAge, bybitAge := e.quoteAges()
+			for _, v := range []struct {
+				ex  exchange.Exchange
+				st  exchange.FeedStats
+				age time.Duration
+				lag time.Duration
+			}{
+				{e.exA, apexSt, apexAge, feedLag(&e.apexUpdatedAt, &e.apexQuoteTs)},
+				{e.exB, bybitSt, bybitAge, feedLag(&e.bybitUpdatedAt, &e.bybitQuoteTs)},
+			} {
+				slog.Info("[状态] 行情健康", "exchange", v.ex.Name(), "connected", v.st.Connected,
+					"rtt", v.st.RTT.Round(time.Millisecond), "last_message_age", v.st.LastMessageAge.Round(time.Millisecond),
+					"quote_age", v.age.Round(time.Millisecond), "lag", v.lag.Round(time.Millisecond), "reconnects", v.st.ReconnectCount, "seq_gaps", v.st.SeqGaps)
+			}
+			for _, v := range []struct {
+				ex   exchange.Exchange
+				q    quote
+				last *atomic.Value
+			}{{e.exA, e.apexTop(), &e.lastTradeA}, {e.exB, e.bybitTop(), &e.lastTradeB}} {
+				if t, ok := lastTrade(v.last); ok {
+					dev, _ := tradeDeviationPct(v.q, v.last)
+					slog.Info("[状态] 最新成交", "exchange", v.ex.Name(), "price", t.Price, "size", t.Size,
+						"side", t.Side, "age", time.Since(t.Time).Round(time.Millisecond), "mid_deviation_pct", dev)
+				}
+			}
+			if e.cfg.Strategy.VolMultiplier > 0 {
+				sigma, n, ok := e.vol.sigma(time.Now(), e.volWindow())
+				slog.Info("[状态] 开仓阈值", "effective", e.minSpread(), "static", e.cfg.Strategy.MinSpreadUSDC,
+					"sigma", sigma, "vol_multiplier", e.cfg.Strategy.VolMultiplier, "samples", n, "warmed_up", ok)
+			}
+			if e.quoteRateEnabled() {
+				r := e.rateState()
+				slog.Info("[状态] 计价汇率", "usdc_usdt", r.rate, "source", e.quoteRateSource(),
+					"age", time.Since(r.at).Round(time.Second), "stale", e.quoteRateStale())
+			}
 		}
 	}
 }
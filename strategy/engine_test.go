@@ -0,0 +1,481 @@
+package strategy
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	bybitPkg "arb/bybit"
+	"arb/config"
+	"arb/exchange"
+)
+
+func TestScenario1Entry(t *testing.T) {
+	e, exA, exB := newTestEngine(t, testConfig())
+	// 场景1：Bybit 买一 100010 - Apex 卖一 100000 = 10 ≥ 5
+	setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+
+	e.checkAndTrade()
+
+	reqA, reqB := exA.placed(), exB.placed()
+	if len(reqA) != 1 || reqA[0].Side != exchange.Buy || reqA[0].Qty != "0.100" {
+		t.Fatalf("A所下单不符合预期: %+v", reqA)
+	}
+	if len(reqB) != 1 || reqB[0].Side != exchange.Sell || reqB[0].Qty != "0.100" {
+		t.Fatalf("B所对冲不符合预期: %+v", reqB)
+	}
+	if pos, _ := enginePosition(e); !approx(pos, 0.1) {
+		t.Fatalf("引擎持仓 = %v，期望 0.1", pos)
+	}
+	if !approx(exA.netPosition(), 0.1) || !approx(exB.netPosition(), -0.1) {
+		t.Fatalf("两所持仓 = %v / %v，期望 0.1 / -0.1", exA.netPosition(), exB.netPosition())
+	}
+	if pnl := engineTotalPnL(e); !approx(pnl, 1) {
+		t.Fatalf("累计PnL = %v，期望 1", pnl)
+	}
+}
+
+func TestScenario2Entry(t *testing.T) {
+	e, exA, exB := newTestEngine(t, testConfig())
+	// 场景2：Apex 买一 100020 - Bybit 卖一 100010 = 10 ≥ 5
+	setQuotes(e, exA, exB, 100020, 100030, 100000, 100010)
+
+	e.checkAndTrade()
+
+	reqA, reqB := exA.placed(), exB.placed()
+	if len(reqA) != 1 || reqA[0].Side != exchange.Sell {
+		t.Fatalf("A所下单不符合预期: %+v", reqA)
+	}
+	if len(reqB) != 1 || reqB[0].Side != exchange.Buy {
+		t.Fatalf("B所对冲不符合预期: %+v", reqB)
+	}
+	if pos, _ := enginePosition(e); !approx(pos, -0.1) {
+		t.Fatalf("引擎持仓 = %v，期望 -0.1", pos)
+	}
+	if pnl := engineTotalPnL(e); !approx(pnl, 1) {
+		t.Fatalf("累计PnL = %v，期望 1", pnl)
+	}
+}
+
+func TestBelowThresholdNoTrade(t *testing.T) {
+	e, exA, exB := newTestEngine(t, testConfig())
+	// 两个方向价差均为 3，低于 5
+	setQuotes(e, exA, exB, 99990, 100000, 100003, 100013)
+	e.checkAndTrade()
+	setQuotes(e, exA, exB, 100013, 100023, 100000, 100010)
+	e.checkAndTrade()
+
+	if n := len(exA.placed()) + len(exB.placed()); n != 0 {
+		t.Fatalf("价差低于阈值时不应下单，实际下单 %d 笔", n)
+	}
+	if pos, _ := enginePosition(e); pos != 0 {
+		t.Fatalf("引擎持仓 = %v，期望 0", pos)
+	}
+}
+
+func TestPositionLimit(t *testing.T) {
+	e, exA, exB := newTestEngine(t, testConfig())
+	setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+
+	for i := 0; i < 5; i++ {
+		e.checkAndTrade()
+	}
+
+	if n := len(exA.placed()); n != 3 {
+		t.Fatalf("A所下单 %d 笔，期望 3 笔（max_position=0.3）", n)
+	}
+	if pos, _ := enginePosition(e); !approx(pos, 0.3) {
+		t.Fatalf("引擎持仓 = %v，期望 0.3", pos)
+	}
+}
+
+func TestTakeProfitHalt(t *testing.T) {
+	cfg := testConfig()
+	cfg.Strategy.TakeProfitUSDC = 0.5
+	e, exA, exB := newTestEngine(t, cfg)
+	setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+
+	e.checkAndTrade() // PnL = 1，超过盈利目标
+	e.checkAndTrade()
+	waitHalted(t, e)
+
+	e.checkAndTrade()
+	if n := len(exA.placed()); n != 1 {
+		t.Fatalf("达到盈利目标后不应继续开仓，A所下单 %d 笔", n)
+	}
+}
+
+func TestStopLossHalt(t *testing.T) {
+	cfg := testConfig()
+	cfg.Strategy.StopLossUSDC = 0.5
+	e, exA, exB := newTestEngine(t, cfg)
+	e.bookPnL(DirectionLong, -1, "测试")
+	setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+
+	e.checkAndTrade()
+	waitHalted(t, e)
+
+	if !e.riskCtrl.IsHalted() {
+		t.Fatal("触发止损后风控应进入熔断")
+	}
+	e.checkAndTrade()
+	if n := len(exA.placed()); n != 0 {
+		t.Fatalf("触发止损后不应开仓，A所下单 %d 笔", n)
+	}
+}
+
+func TestHedgeRetryRecovers(t *testing.T) {
+	e, exA, exB := newTestEngine(t, testConfig())
+	exB.queueErrs(errors.New("拒单"))
+	setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+
+	e.checkAndTrade()
+
+	if n := len(exB.placed()); n != 2 {
+		t.Fatalf("B所下单 %d 笔，期望首次对冲 + 1 次重试", n)
+	}
+	if pos, unhedged := enginePosition(e); !approx(pos, 0.1) || unhedged != 0 {
+		t.Fatalf("引擎持仓 / 未对冲 = %v / %v，期望 0.1 / 0", pos, unhedged)
+	}
+	if !approx(exB.netPosition(), -0.1) {
+		t.Fatalf("B所持仓 = %v，期望 -0.1", exB.netPosition())
+	}
+}
+
+func TestHedgeFailureUnwindsApexLeg(t *testing.T) {
+	e, exA, exB := newTestEngine(t, testConfig())
+	exB.queueErrs(errors.New("拒单"), errors.New("拒单"), errors.New("拒单"))
+	setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+
+	e.checkAndTrade()
+
+	if pos, unhedged := enginePosition(e); !approx(pos, 0) || unhedged != 0 {
+		t.Fatalf("引擎持仓 / 未对冲 = %v / %v，期望 0 / 0", pos, unhedged)
+	}
+	if exA.netPosition() != 0 || exB.netPosition() != 0 {
+		t.Fatalf("两所持仓 = %v / %v，期望均为 0", exA.netPosition(), exB.netPosition())
+	}
+	// A所 100000 买入、99990 卖出平仓
+	if pnl := engineTotalPnL(e); !approx(pnl, -1) {
+		t.Fatalf("累计PnL = %v，期望 -1", pnl)
+	}
+}
+
+func TestPctLimitsIgnoreRestoredPnL(t *testing.T) {
+	cfg := testConfig()
+	cfg.Strategy.TakeProfitPct = 2
+	cfg.Strategy.StopLossPct = 2
+	e, exA, exB := newTestEngine(t, cfg)
+	e.startEquity.Store(math.Float64bits(10000))
+	// 状态文件恢复的历史盈亏超过权益的 2%，本次运行尚无盈亏
+	e.totalPnL = -500
+	setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+
+	e.checkAndTrade()
+	if e.tradingHalted.Load() || e.riskCtrl.IsHalted() {
+		t.Fatal("历史盈亏不应触发按本次运行权益计算的止损")
+	}
+	if n := len(exA.placed()); n != 1 {
+		t.Fatalf("A所下单 %d 笔，期望 1", n)
+	}
+
+	e.bookPnL(DirectionLong, 250, "测试")
+	e.checkAndTrade()
+	waitHalted(t, e)
+}
+
+// TestOrderErrorClassification A所下单错误按类型处置：瞬时错误重试一次，余额/权限错误熔断，其它错误放弃本次机会
+func TestOrderErrorClassification(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		orders int
+		halted bool
+		pos    float64
+	}{
+		{"限频重试", &bybitPkg.ExchangeError{HTTPStatus: 200, Code: bybitPkg.CodeRateLimit}, 2, false, 0.1},
+		{"余额不足", &bybitPkg.ExchangeError{HTTPStatus: 200, Code: bybitPkg.CodeInsufficientBalance}, 1, true, 0},
+		{"权限不足", &bybitPkg.ExchangeError{HTTPStatus: 200, Code: bybitPkg.CodePermissionDenied}, 1, true, 0},
+		{"数量无效", &bybitPkg.ExchangeError{HTTPStatus: 200, Code: 10001, Msg: "invalid qty"}, 1, false, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, exA, exB := newTestEngine(t, testConfig())
+			exA.queueErrs(tc.err)
+			setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+
+			e.checkAndTrade()
+
+			if n := len(exA.placed()); n != tc.orders {
+				t.Fatalf("A所下单 %d 次，期望 %d 次", n, tc.orders)
+			}
+			if halted := e.riskCtrl.IsHalted(); halted != tc.halted {
+				t.Fatalf("风控熔断 = %v，期望 %v", halted, tc.halted)
+			}
+			if pos, _ := enginePosition(e); !approx(pos, tc.pos) {
+				t.Fatalf("引擎持仓 = %v，期望 %v", pos, tc.pos)
+			}
+		})
+	}
+}
+
+// TestStopIdempotent 重复及并发调用 Stop 不应 panic（stopCh 只关闭一次）
+func TestStopIdempotent(t *testing.T) {
+	e, _, _ := newTestEngine(t, testConfig())
+
+	e.Stop()
+	e.Stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.Stop()
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-e.stopCh:
+	default:
+		t.Fatal("Stop 后 stopCh 应已关闭")
+	}
+}
+
+// TestHaltTradingKeepsRunning 达到止盈/止损只停止开仓，不关闭引擎
+func TestHaltTradingKeepsRunning(t *testing.T) {
+	e, _, _ := newTestEngine(t, testConfig())
+	t.Cleanup(e.Stop)
+
+	e.HaltTrading("测试")
+	e.HaltTrading("测试")
+
+	if !e.tradingHalted.Load() {
+		t.Fatal("HaltTrading 后应停止开仓")
+	}
+	select {
+	case <-e.stopCh:
+		t.Fatal("HaltTrading 不应关闭引擎")
+	default:
+	}
+}
+
+// waitHalted 等待异步执行的 HaltTrading 生效
+func waitHalted(t *testing.T, e *ArbEngine) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !e.tradingHalted.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("等待停止开仓超时")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestHedgeMatchesApexFill A所腿成交 0% / 30% / 100% 时，B所按实际成交量对冲；低于最小下单量时不对冲
+func TestHedgeMatchesApexFill(t *testing.T) {
+	cases := []struct {
+		name   string
+		ratio  float64
+		hedges int
+		qty    string
+		pos    float64 // A所持仓
+		hedged float64 // B所对冲持仓（绝对值）
+	}{
+		{"未成交", 0, 0, "", 0, 0},
+		{"部分成交30%", 0.3, 1, "0.030", 0.03, 0.03},
+		{"全部成交", 1, 1, "0.100", 0.1, 0.1},
+		{"低于最小下单量", 0.05, 0, "", 0.005, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Strategy.MinOrderSize = 0.01
+			e, exA, exB := newTestEngine(t, cfg)
+			exA.queueFills(tc.ratio)
+			setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+
+			e.checkAndTrade()
+
+			reqB := exB.placed()
+			if len(reqB) != tc.hedges {
+				t.Fatalf("B所下单 %d 笔，期望 %d 笔: %+v", len(reqB), tc.hedges, reqB)
+			}
+			if tc.hedges > 0 && reqB[0].Qty != tc.qty {
+				t.Fatalf("B所对冲数量 = %s，期望 %s", reqB[0].Qty, tc.qty)
+			}
+			if pos, _ := enginePosition(e); !approx(pos, tc.pos) {
+				t.Fatalf("引擎持仓 = %v，期望 %v", pos, tc.pos)
+			}
+			if !approx(exA.netPosition(), tc.pos) || !approx(exB.netPosition(), -tc.hedged) {
+				t.Fatalf("两所持仓 = %v / %v，期望 %v / %v", exA.netPosition(), exB.netPosition(), tc.pos, -tc.hedged)
+			}
+		})
+	}
+}
+
+// TestSpreadHysteresis 价差序列穿过 entry / exit 区间：高于 entry 开仓或加仓，区间内不动作，反向价差达到 exit 才平仓
+func TestSpreadHysteresis(t *testing.T) {
+	cfg := testConfig()
+	// 等价于 entry_spread_usdc: 5, exit_spread_usdc: 1
+	cfg.Strategy.MinSpreadUSDC = 5
+	cfg.Strategy.Unwind = true
+	cfg.Strategy.UnwindSpreadUSDC = -1
+	e, exA, exB := newTestEngine(t, cfg)
+
+	// A所盘口固定 99990 / 100000，B所盘口变化；场景1价差 = B买一 - 100000，平多价差 = 99990 - B卖一
+	steps := []struct {
+		name               string
+		bybitBid, bybitAsk float64
+		orders             int
+		pos                float64
+	}{
+		{"价差 10 ≥ entry，开仓", 100010, 100020, 1, 0.1},
+		{"价差 3 在区间内，不加仓", 100003, 100013, 1, 0.1},
+		{"价差 6 ≥ entry，加仓", 100006, 100016, 2, 0.2},
+		{"价差回落但平仓价差 -5 < -exit，持有", 99985, 99995, 2, 0.2},
+		{"平仓价差 0 ≥ -exit，平仓一笔", 99980, 99990, 3, 0.1},
+		{"继续平仓", 99980, 99990, 4, 0},
+		{"已平仓，不反向开仓", 99980, 99990, 4, 0},
+		{"价差 4 低于 entry，不开仓", 100004, 100014, 4, 0},
+	}
+	for _, s := range steps {
+		setQuotes(e, exA, exB, 99990, 100000, s.bybitBid, s.bybitAsk)
+		e.checkAndTrade()
+
+		if n := len(exA.placed()); n != s.orders {
+			t.Fatalf("%s：A所累计下单 %d 笔，期望 %d 笔", s.name, n, s.orders)
+		}
+		if pos, _ := enginePosition(e); !approx(pos, s.pos) {
+			t.Fatalf("%s：引擎持仓 = %v，期望 %v", s.name, pos, s.pos)
+		}
+	}
+	if exA.netPosition() != 0 || exB.netPosition() != 0 {
+		t.Fatalf("两所持仓 = %v / %v，期望均为 0", exA.netPosition(), exB.netPosition())
+	}
+}
+
+// TestCooldownStartsAfterFill A所腿 IOC 未成交时不开始冷却，成交后同方向机会进入冷却
+func TestCooldownStartsAfterFill(t *testing.T) {
+	cfg := testConfig()
+	cfg.Strategy.TradeCooldownMs = 60000
+	e, exA, exB := newTestEngine(t, cfg)
+	exA.queueFills(0)
+
+	setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+	e.checkAndTrade() // 未成交
+	e.checkAndTrade() // 不受冷却限制，成交
+	if pos, _ := enginePosition(e); !approx(pos, 0.1) {
+		t.Fatalf("未成交的机会不应开始冷却，持仓 = %v，期望 0.1", pos)
+	}
+
+	e.checkAndTrade() // 冷却中
+	if n := len(exA.placed()); n != 2 {
+		t.Fatalf("A所下单 %d 笔，期望 2 笔（成交后进入冷却）", n)
+	}
+	if n := e.cooldownSkips.Load(); n != 1 {
+		t.Fatalf("冷却跳过 %d 次，期望 1 次", n)
+	}
+}
+
+// TestHedgeFirstApexTimeInForce hedge_first 模式下 A所腿同样按 time_in_force / apex_maker_mode 下单
+func TestHedgeFirstApexTimeInForce(t *testing.T) {
+	tests := []struct {
+		name  string
+		tif   string
+		maker bool
+		want  exchange.TimeInForce
+	}{
+		{"默认 IOC", "", false, exchange.IOC},
+		{"gtc 挂单", config.TimeInForceGTC, false, exchange.GTC},
+		{"maker 模式", "", true, exchange.PostOnly},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Strategy.HedgeFirst = true
+			cfg.Strategy.TimeInForce = tt.tif
+			cfg.Strategy.ApexMakerMode = tt.maker
+			cfg.Strategy.ExpirySeconds = 30
+			e, exA, exB := newTestEngine(t, cfg)
+			setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+			e.checkAndTrade()
+
+			// maker 挂单未成交时剩余部分按 apex_maker_fallback 改用 IOC，只检查首笔
+			placed := exA.placed()
+			if len(placed) == 0 || placed[0].TimeInForce != tt.want {
+				t.Fatalf("A所下单 %+v，首笔期望 %v", placed, tt.want)
+			}
+			if tt.want != exchange.IOC && placed[0].ExpireAt.IsZero() {
+				t.Fatal("挂单应带 expiry_seconds 到期时间")
+			}
+			if pos, unhedged := enginePosition(e); !approx(pos, 0.1) || unhedged != 0 {
+				t.Fatalf("持仓 / 未对冲 = %v / %v，期望 0.1 / 0", pos, unhedged)
+			}
+		})
+	}
+}
+
+// TestEngineMetricsPerEngine 每个引擎的指标注册在自己的注册表中，创建多个引擎不会向同一指标追加序列
+func TestEngineMetricsPerEngine(t *testing.T) {
+	e1, exA, exB := newTestEngine(t, testConfig())
+	e2, _, _ := newTestEngine(t, testConfig())
+	setQuotes(e1, exA, exB, 99990, 100000, 100010, 100020)
+	e1.checkAndTrade()
+
+	for _, tt := range []struct {
+		e    *ArbEngine
+		want float64
+	}{{e1, 0.1}, {e2, 0}} {
+		families, err := tt.e.metricsReg.Gather()
+		if err != nil {
+			t.Fatalf("采集引擎指标失败: %v", err)
+		}
+		found := false
+		for _, f := range families {
+			if f.GetName() != "arb_position" {
+				continue
+			}
+			if n := len(f.GetMetric()); n != 1 {
+				t.Fatalf("arb_position 有 %d 条序列，期望 1 条", n)
+			}
+			if got := f.GetMetric()[0].GetGauge().GetValue(); !approx(got, tt.want) {
+				t.Fatalf("arb_position = %v，期望 %v", got, tt.want)
+			}
+			found = true
+		}
+		if !found {
+			t.Fatal("引擎注册表缺少 arb_position")
+		}
+	}
+}
+
+// TestCooldownSkipsReduce 冷却只限制同方向开仓：持有空头时连续出现的场景1 机会逐笔减仓，不进入冷却
+func TestCooldownSkipsReduce(t *testing.T) {
+	cfg := testConfig()
+	cfg.Strategy.TradeCooldownMs = 60000
+	e, exA, exB := newTestEngine(t, cfg)
+	e.posMu.Lock()
+	e.position = -0.3
+	e.posMu.Unlock()
+	exA.setPosition(-0.3)
+	exB.setPosition(0.3)
+
+	setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+	e.checkAndTrade()
+	e.checkAndTrade()
+	if pos, _ := enginePosition(e); !approx(pos, -0.1) {
+		t.Fatalf("连续减仓后持仓 = %v，期望 -0.1", pos)
+	}
+	if n := e.cooldownSkips.Load(); n != 0 {
+		t.Fatalf("减仓不应受冷却限制，跳过 %d 次", n)
+	}
+	for _, req := range exA.placed() {
+		if !req.ReduceOnly {
+			t.Fatalf("A所下单 %+v，期望均为 reduce-only", req)
+		}
+	}
+}
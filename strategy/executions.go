@@ -0,0 +1,92 @@
+package strategy
+
+import (
+	"sync"
+	"time"
+
+	"arb/exchange"
+)
+
+const (
+	// execWaitTimeout 等待成交推送确认订单全部成交的最长时间，超时后回退到 REST 查询
+	execWaitTimeout = 500 * time.Millisecond
+
+	// execRetention 未被查询的成交记录保留时长（如手动下单产生的成交）
+	execRetention = time.Minute
+)
+
+// execAgg 单个订单的成交推送累计
+type execAgg struct {
+	fill   legFill
+	done   bool          // leavesQty=0，订单已全部成交
+	doneCh chan struct{} // done 时关闭
+	at     time.Time     // 最近一次更新时间
+}
+
+// execTracker 按 OrderID 累计 B所私有频道推送的成交
+type execTracker struct {
+	mu     sync.Mutex
+	orders map[string]*execAgg
+}
+
+func newExecTracker() *execTracker {
+	return &execTracker{orders: make(map[string]*execAgg)}
+}
+
+// get 返回订单的累计记录，不存在时创建（调用方需持有 mu）
+func (t *execTracker) get(orderID string) *execAgg {
+	agg, ok := t.orders[orderID]
+	if !ok {
+		agg = &execAgg{doneCh: make(chan struct{}), at: time.Now()}
+		t.orders[orderID] = agg
+	}
+	return agg
+}
+
+// record 累计一笔成交推送，并清理过期记录
+func (t *execTracker) record(orderID string, fill legFill, filledAll bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	agg := t.get(orderID)
+	agg.fill.add(fill)
+	agg.at = time.Now()
+	if filledAll && !agg.done {
+		agg.done = true
+		close(agg.doneCh)
+	}
+
+	for id, a := range t.orders {
+		if time.Since(a.at) > execRetention {
+			delete(t.orders, id)
+		}
+	}
+}
+
+// wait 等待订单全部成交的推送，返回累计成交；超时（部分成交/未成交/推送延迟）时 ok=false
+func (t *execTracker) wait(orderID string, timeout time.Duration) (legFill, bool) {
+	t.mu.Lock()
+	doneCh := t.get(orderID).doneCh
+	t.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-doneCh:
+	case <-timer.C:
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	agg := t.orders[orderID]
+	delete(t.orders, orderID)
+	if agg == nil || !agg.done {
+		return legFill{}, false
+	}
+	return agg.fill, true
+}
+
+// onBybitExecution 处理 B所成交推送（私有频道，适配器已按交易对与成交类型过滤）
+func (e *ArbEngine) onBybitExecution(ex *exchange.Execution) {
+	e.execs.record(ex.OrderID, legFill{qty: ex.Qty, avgPrice: ex.Price, fee: ex.Fee}, ex.FilledAll)
+}
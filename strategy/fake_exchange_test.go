@@ -0,0 +1,349 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"arb/config"
+	"arb/exchange"
+	"arb/risk"
+)
+
+// fakeExchange 测试用交易所：订单按盘口立即撮合（IOC 语义），记录下单请求并维护净持仓
+// 成交比例与下单错误按下单顺序从 fills / errs 队列依次取出，队列为空时全部成交、不报错
+type fakeExchange struct {
+	name string
+
+	mu       sync.Mutex
+	bid, ask float64
+	feeRate  float64
+	fills    []float64 // 每笔订单的成交比例（0~1）
+	errs     []error   // 每笔订单的下单错误
+	requests []exchange.OrderRequest
+	orders   map[string]*exchange.Order
+	open     []exchange.Order // 挂单（OpenOrders 返回、CancelOrder 移除）
+	canceled []string
+	pos      exchange.Position
+	account  exchange.Account
+	seq      int
+	onTrade  func(*exchange.Trade)
+}
+
+func newFakeExchange(name string, bid, ask float64) *fakeExchange {
+	return &fakeExchange{
+		name:    name,
+		bid:     bid,
+		ask:     ask,
+		orders:  make(map[string]*exchange.Order),
+		account: exchange.Account{Equity: 10000, Available: 10000},
+	}
+}
+
+// setBook 更新撮合使用的最优买卖价
+func (f *fakeExchange) setBook(bid, ask float64) {
+	f.mu.Lock()
+	f.bid, f.ask = bid, ask
+	f.mu.Unlock()
+}
+
+// queueFills 追加后续订单的成交比例
+func (f *fakeExchange) queueFills(ratios ...float64) {
+	f.mu.Lock()
+	f.fills = append(f.fills, ratios...)
+	f.mu.Unlock()
+}
+
+// queueErrs 追加后续订单的下单错误（nil 表示正常下单）
+func (f *fakeExchange) queueErrs(errs ...error) {
+	f.mu.Lock()
+	f.errs = append(f.errs, errs...)
+	f.mu.Unlock()
+}
+
+// placed 返回已收到的下单请求
+func (f *fakeExchange) placed() []exchange.OrderRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]exchange.OrderRequest(nil), f.requests...)
+}
+
+// addOpenOrder 添加一笔创建于 createdAt 的挂单
+func (f *fakeExchange) addOpenOrder(id string, createdAt time.Time) {
+	f.mu.Lock()
+	f.open = append(f.open, exchange.Order{ID: id, Status: "NEW", CreatedAt: createdAt})
+	f.mu.Unlock()
+}
+
+// canceledIDs 返回已撤销的订单 ID
+func (f *fakeExchange) canceledIDs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.canceled...)
+}
+
+// setPosition 直接设置交易所净持仓（模拟未记录的成交）
+func (f *fakeExchange) setPosition(size float64) {
+	f.mu.Lock()
+	f.pos.Size = size
+	f.mu.Unlock()
+}
+
+// netPosition 返回当前净持仓
+func (f *fakeExchange) netPosition() float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pos.Size
+}
+
+func (f *fakeExchange) Name() string   { return f.name }
+func (f *fakeExchange) Symbol() string { return "BTC-USDT" }
+
+func (f *fakeExchange) Instrument(context.Context) (*exchange.Instrument, error) {
+	return &exchange.Instrument{TickSize: 0.1, QtyStep: 0.001, MinQty: 0.001}, nil
+}
+
+func (f *fakeExchange) BestPrice(context.Context) (*exchange.BestPrice, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &exchange.BestPrice{Bid: f.bid, BidSize: 10, Ask: f.ask, AskSize: 10}, nil
+}
+
+func (f *fakeExchange) GetAccount(context.Context) (*exchange.Account, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	acc := f.account
+	return &acc, nil
+}
+
+func (f *fakeExchange) GetPositions(context.Context) ([]exchange.Position, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.pos.Size == 0 {
+		return nil, nil
+	}
+	return []exchange.Position{f.pos}, nil
+}
+
+// PlaceOrder 按对手价撮合：限价劣于对手价时不成交，成交量按 fills 队列取比例
+func (f *fakeExchange) PlaceOrder(_ context.Context, req *exchange.OrderRequest) (*exchange.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, *req)
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	qty, err := strconv.ParseFloat(req.Qty, 64)
+	if err != nil {
+		return nil, fmt.Errorf("数量格式错误: %q", req.Qty)
+	}
+	limit, _ := strconv.ParseFloat(req.Price, 64)
+
+	ratio := 1.0
+	if len(f.fills) > 0 {
+		ratio, f.fills = f.fills[0], f.fills[1:]
+	}
+	price := f.ask
+	if req.Side == exchange.Sell {
+		price = f.bid
+	}
+	if limit > 0 && ((req.Side == exchange.Buy && price > limit) || (req.Side == exchange.Sell && price < limit)) {
+		ratio = 0
+	}
+	filled := math.Round(qty*ratio*1e9) / 1e9
+	if req.ReduceOnly {
+		filled = math.Min(filled, math.Abs(f.pos.Size))
+	}
+
+	f.seq++
+	o := &exchange.Order{
+		ID:        fmt.Sprintf("%s-%d", f.name, f.seq),
+		ClientID:  req.ClientID,
+		Side:      req.Side,
+		Price:     limit,
+		Qty:       qty,
+		FilledQty: filled,
+		Status:    "FILLED",
+		Final:     true,
+		CreatedAt: time.Now(),
+	}
+	if filled > 0 {
+		o.AvgPrice = price
+		o.Fee = price * filled * f.feeRate
+		f.fill(req.Side, filled, price)
+	}
+	if filled < qty {
+		o.Status = "CANCELED"
+	}
+	f.orders[o.ID] = o
+	cp := *o
+	return &cp, nil
+}
+
+// fill 按成交更新净持仓与持仓均价
+func (f *fakeExchange) fill(side exchange.Side, qty, price float64) {
+	signed := qty
+	if side == exchange.Sell {
+		signed = -qty
+	}
+	switch {
+	case f.pos.Size == 0 || f.pos.Size*signed > 0:
+		f.pos.EntryPrice = (f.pos.EntryPrice*math.Abs(f.pos.Size) + price*qty) / (math.Abs(f.pos.Size) + qty)
+	case math.Abs(signed) > math.Abs(f.pos.Size):
+		f.pos.EntryPrice = price
+	}
+	f.pos.Size = math.Round((f.pos.Size+signed)*1e9) / 1e9
+	if f.pos.Size == 0 {
+		f.pos.EntryPrice = 0
+	}
+}
+
+func (f *fakeExchange) GetOrder(_ context.Context, orderID string) (*exchange.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	o, ok := f.orders[orderID]
+	if !ok {
+		return nil, fmt.Errorf("订单不存在: %s", orderID)
+	}
+	cp := *o
+	return &cp, nil
+}
+
+func (f *fakeExchange) GetOrderByClientID(_ context.Context, clientID string) (*exchange.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, o := range f.orders {
+		if o.ClientID == clientID {
+			cp := *o
+			return &cp, nil
+		}
+	}
+	return nil, fmt.Errorf("订单不存在: %s", clientID)
+}
+
+func (f *fakeExchange) CancelOrder(_ context.Context, orderID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, o := range f.open {
+		if o.ID == orderID {
+			f.open = append(f.open[:i], f.open[i+1:]...)
+			f.canceled = append(f.canceled, orderID)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (f *fakeExchange) CancelAll(context.Context) error { return nil }
+
+func (f *fakeExchange) OpenOrders(context.Context) ([]exchange.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]exchange.Order(nil), f.open...), nil
+}
+
+func (f *fakeExchange) Connect() error { return nil }
+func (f *fakeExchange) SubscribeOrderBook(int, func(*exchange.OrderBook)) error {
+	return nil
+}
+
+// SubscribeTrades 记录成交回调，由 pushTrade 推送
+func (f *fakeExchange) SubscribeTrades(cb func(*exchange.Trade)) error {
+	f.mu.Lock()
+	f.onTrade = cb
+	f.mu.Unlock()
+	return nil
+}
+
+// pushTrade 推送一笔逐笔成交（需先 SubscribeTrades）
+func (f *fakeExchange) pushTrade(side exchange.Side, price, size float64, at time.Time) {
+	f.mu.Lock()
+	cb := f.onTrade
+	f.mu.Unlock()
+	if cb != nil {
+		cb(&exchange.Trade{Price: price, Size: size, Side: side, Time: at})
+	}
+}
+
+func (f *fakeExchange) FeedStats() exchange.FeedStats { return exchange.FeedStats{Connected: true} }
+func (f *fakeExchange) FeedReady() bool               { return true }
+func (f *fakeExchange) Close()                        {}
+
+// testConfig 返回测试用最小配置：单笔 0.1，最大持仓 0.3，净价差阈值 5 USDC，不计手续费
+func testConfig() *config.Config {
+	return &config.Config{
+		Strategy: config.StrategyConfig{
+			OrderSize:         0.1,
+			MaxPosition:       0.3,
+			MinSpreadUSDC:     5,
+			HedgeMode:         true,
+			PricePrecision:    1,
+			SizePrecision:     3,
+			CheckIntervalMs:   100,
+			HedgeSlippageUSDC: 10,
+			HedgeRetryCount:   2,
+		},
+		RiskControl: config.RiskConfig{
+			MaxDailyLossUSDC:   1000,
+			MaxConsecutiveLoss: 100,
+		},
+	}
+}
+
+// newTestEngine 用两个 fakeExchange 创建引擎，并写入可用的账户缓存
+func newTestEngine(t *testing.T, cfg *config.Config) (*ArbEngine, *fakeExchange, *fakeExchange) {
+	t.Helper()
+	exA := newFakeExchange(exchange.Apex, 99990, 100000)
+	exB := newFakeExchange(exchange.Bybit, 99990, 100000)
+	e, err := NewArbEngineWithExchanges(cfg, exA, exB, risk.NewController(cfg.RiskControl))
+	if err != nil {
+		t.Fatalf("创建引擎失败: %v", err)
+	}
+	t.Cleanup(e.cancel)
+	e.account.Store(accountSnapshot{acc: &exchange.Account{Equity: 10000, Available: 10000}, at: time.Now()})
+	return e, exA, exB
+}
+
+// setQuotes 同时更新引擎盘口与两个 fakeExchange 的撮合价格
+func setQuotes(e *ArbEngine, exA, exB *fakeExchange, apexBid, apexAsk, bybitBid, bybitAsk float64) {
+	exA.setBook(apexBid, apexAsk)
+	exB.setBook(bybitBid, bybitAsk)
+	e.onApexOrderBook(&exchange.OrderBook{
+		Bids: []exchange.Level{{Price: apexBid, Size: 1}},
+		Asks: []exchange.Level{{Price: apexAsk, Size: 1}},
+		Ts:   time.Now().UnixMilli(),
+	})
+	e.onBybitOrderBook(&exchange.OrderBook{
+		Bids: []exchange.Level{{Price: bybitBid, Size: 1}},
+		Asks: []exchange.Level{{Price: bybitAsk, Size: 1}},
+		Ts:   time.Now().UnixMilli(),
+	})
+}
+
+// enginePosition 返回引擎记录的 A所方向持仓与未对冲数量
+func enginePosition(e *ArbEngine) (pos, unhedged float64) {
+	e.posMu.Lock()
+	defer e.posMu.Unlock()
+	return e.position, e.unhedgedQty
+}
+
+// engineTotalPnL 返回引擎累计盈亏
+func engineTotalPnL(e *ArbEngine) float64 {
+	e.pnlMu.Lock()
+	defer e.pnlMu.Unlock()
+	return e.totalPnL
+}
+
+// approx 浮点比较
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
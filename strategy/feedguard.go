@@ -0,0 +1,476 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"arb/alert"
+	"arb/config"
+	"arb/exchange"
+	"arb/store"
+)
+
+const (
+	defaultFeedLossTimeoutSec = 10
+	defaultFlattenAfterSec    = 30
+	feedGuardInterval         = 1 * time.Second
+
+	// feedDownAlertAfter 行情中断超过该时长时发送告警
+	feedDownAlertAfter = time.Minute
+
+	// WS 频繁重连告警的默认阈值：defaultStormMinutes 分钟内重连超过 defaultStormCount 次
+	defaultStormCount   = 5
+	defaultStormMinutes = 5
+)
+
+// 行情中断处置阶段
+const (
+	feedStageHealthy = iota // 行情正常
+	feedStagePaused         // 已暂停开仓
+	feedStageHandled        // 已执行平仓/转移对冲
+)
+
+// feedVenue 单个交易所的行情健康状态（仅由 feedGuardLoop 访问）
+type feedVenue struct {
+	name       string
+	policy     config.FeedLossPolicy
+	isReady    func() bool
+	reconnects func() int64
+	failed     func() bool
+	updatedAt  *atomic.Value // time.Time
+
+	stage       int
+	downSince   time.Time
+	downAlerted bool
+
+	// 频繁重连检测：上次观察到的累计重连次数与窗口内的重连时间
+	lastReconnects int64
+	reconnectAt    []time.Time
+}
+
+// observeReconnects 记录新增的重连并返回窗口内的重连次数
+func (v *feedVenue) observeReconnects(now time.Time, window time.Duration) int {
+	total := v.reconnects()
+	for n := total - v.lastReconnects; n > 0; n-- {
+		v.reconnectAt = append(v.reconnectAt, now)
+	}
+	v.lastReconnects = total
+
+	cut := 0
+	for cut < len(v.reconnectAt) && now.Sub(v.reconnectAt[cut]) > window {
+		cut++
+	}
+	v.reconnectAt = v.reconnectAt[cut:]
+	return len(v.reconnectAt)
+}
+
+// silentFor 返回距离最近一次有效行情的时长，从未收到行情时以 since 为起点
+func (v *feedVenue) silentFor(now, since time.Time) time.Duration {
+	last, _ := v.updatedAt.Load().(time.Time)
+	if last.IsZero() {
+		last = since
+	}
+	return now.Sub(last)
+}
+
+func (v *feedVenue) timeout() time.Duration {
+	sec := v.policy.TimeoutSec
+	if sec <= 0 {
+		sec = defaultFeedLossTimeoutSec
+	}
+	return time.Duration(sec) * time.Second
+}
+
+// flattenAfter 返回 flatten_after_seconds 模式下中断多久后平仓（flatten_after_sec，默认 30 秒）
+func (v *feedVenue) flattenAfter() time.Duration {
+	sec := v.policy.FlattenAfterSec
+	if sec <= 0 {
+		sec = defaultFlattenAfterSec
+	}
+	return time.Duration(sec) * time.Second
+}
+
+func (v *feedVenue) action() string {
+	switch v.policy.OnFeedLoss {
+	case config.FeedLossPause, config.FeedLossFlatten, config.FeedLossHedgeElse:
+		return v.policy.OnFeedLoss
+	case "":
+		return config.FeedLossPause
+	default:
+		slog.Warn("[断线处置] 未知的 on_feed_loss，按 pause 处理", "exchange", v.name, "on_feed_loss", v.policy.OnFeedLoss)
+		return config.FeedLossPause
+	}
+}
+
+// feedGuardLoop 监控两所行情，任一所中断超过阈值时按配置执行处置
+func (e *ArbEngine) feedGuardLoop() {
+	defer e.wg.Done()
+
+	venues := e.feedVenues()
+	startedAt := time.Now()
+
+	ticker := time.NewTicker(feedGuardInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case now := <-ticker.C:
+			e.evaluateFeeds(venues, now, startedAt)
+		}
+	}
+}
+
+// feedVenues 返回 A、B 两所的行情健康状态
+func (e *ArbEngine) feedVenues() []*feedVenue {
+	return []*feedVenue{
+		{
+			name:       e.exA.Name(),
+			policy:     e.feedLossA,
+			isReady:    func() bool { return e.exA.FeedReady() || e.restTradable(&e.restQuoteA) },
+			reconnects: func() int64 { return e.exA.FeedStats().ReconnectCount },
+			failed:     func() bool { return e.exA.FeedStats().Failed },
+			updatedAt:  &e.apexUpdatedAt,
+		},
+		{
+			name:       e.exB.Name(),
+			policy:     e.feedLossB,
+			isReady:    func() bool { return e.exB.FeedReady() || e.restTradable(&e.restQuoteB) },
+			reconnects: func() int64 { return e.exB.FeedStats().ReconnectCount },
+			failed:     func() bool { return e.exB.FeedStats().Failed },
+			updatedAt:  &e.bybitUpdatedAt,
+		},
+	}
+}
+
+// evaluateFeeds 评估每个交易所的行情状态并推进处置阶段
+func (e *ArbEngine) evaluateFeeds(venues []*feedVenue, now, startedAt time.Time) {
+	stormCount, stormMinutes := e.cfg.Alerts.ReconnectStormCount, e.cfg.Alerts.ReconnectStormMinutes
+	if stormCount <= 0 {
+		stormCount = defaultStormCount
+	}
+	if stormMinutes <= 0 {
+		stormMinutes = defaultStormMinutes
+	}
+
+	paused := false
+	for i, v := range venues {
+		if n := v.observeReconnects(now, time.Duration(stormMinutes)*time.Minute); n > stormCount {
+			alert.Warn("ws_reconnect_storm:"+v.name, "%s WS 频繁重连：%d 分钟内重连 %d 次", v.name, stormMinutes, n)
+		}
+
+		// 连续重连失败达到上限：交易所接口可能已不可用，熔断停止开仓，避免只凭另一所冻结的行情交易
+		if v.failed() && !e.riskCtrl.IsHalted() {
+			reason := fmt.Sprintf("%s WS 连续重连失败 %d 次，交易所接口可能不可用", v.name, v.policy.MaxReconnectAttempts)
+			alert.Critical("feed_failed:"+v.name, "%s，已触发风控熔断，恢复后请人工重置风控", reason)
+			e.riskCtrl.Halt(reason)
+			e.pause("风控熔断: " + reason)
+		}
+
+		healthy := v.isReady() && v.silentFor(now, startedAt) < v.timeout()
+
+		if healthy {
+			if v.stage != feedStageHealthy {
+				down := now.Sub(v.downSince).Round(time.Second)
+				slog.Info("[断线处置] 行情恢复，解除处置", "exchange", v.name, "down", down)
+				alert.Info("feed_loss:"+v.name, "%s 行情恢复，中断时长 %v", v.name, down)
+				if hedge := e.transferredHedgeQty(); hedge != 0 {
+					slog.Warn("[断线处置] B所仍持有转移的对冲仓位，确认后调用 /flatten 平仓", "exchange", e.exB.Name(), "hedge", e.formatSize(hedge))
+				}
+			}
+			v.stage = feedStageHealthy
+			v.downSince = time.Time{}
+			v.downAlerted = false
+			continue
+		}
+
+		if v.stage == feedStageHealthy {
+			v.downSince = now.Add(-v.silentFor(now, startedAt))
+			v.stage = feedStagePaused
+			slog.Warn("[断线处置] 行情中断，暂停开仓", "exchange", v.name, "connected", v.isReady(),
+				"silent", v.silentFor(now, startedAt).Round(time.Second), "action", v.action())
+			alert.Warn("feed_loss:"+v.name, "%s 行情中断（%v 无有效行情），处置策略=%s，已暂停开仓",
+				v.name, v.silentFor(now, startedAt).Round(time.Second), v.action())
+		}
+		paused = true
+
+		if down := now.Sub(v.downSince); down >= feedDownAlertAfter && !v.downAlerted {
+			v.downAlerted = true
+			alert.Critical("feed_down:"+v.name, "%s 行情中断已 %v（处置策略=%s），已暂停开仓", v.name, down.Round(time.Second), v.action())
+		}
+
+		if v.stage != feedStagePaused {
+			continue
+		}
+
+		other := venues[1-i]
+		switch v.action() {
+		case config.FeedLossFlatten:
+			flattenAfter := v.flattenAfter()
+			if now.Sub(v.downSince) < flattenAfter {
+				continue
+			}
+			slog.Warn("[断线处置] 中断超过 flatten_after_sec，执行双腿平仓", "exchange", v.name, "flatten_after", flattenAfter)
+			e.flattenOnFeedLoss(v.name, other.name, false)
+			v.stage = feedStageHandled
+		case config.FeedLossHedgeElse:
+			slog.Warn("[断线处置] 行情中断，尝试 REST 处理失联腿，必要时转移对冲", "exchange", v.name, "hedge_venue", other.name)
+			e.flattenOnFeedLoss(v.name, other.name, true)
+			v.stage = feedStageHandled
+		}
+	}
+
+	if was := e.feedPaused.Swap(paused); was != paused && !paused {
+		slog.Info("[断线处置] 两所行情均已恢复，恢复开仓")
+	}
+}
+
+// flattenOnFeedLoss 行情中断时处理现有持仓
+//
+//	dark    = 行情中断的交易所（通过 REST 获取价格下单）
+//	healthy = 行情正常的交易所（使用 WS 行情下单）
+//	keepHedge=false：两腿都平掉
+//	keepHedge=true ：健康腿只平掉失联腿实际平掉的数量；失联腿无法处理的部分对冲留在/转移到健康所
+//
+// 持仓按 A所平仓单的实际成交更新；两腿实际平仓数量不一致时差额记入未对冲敞口。
+// 平仓盈亏与 flattenAll 相同按 flattenPnL 计算并经 bookPnL 计入累计PnL与风控；处置结果告警并写入交易流水
+func (e *ArbEngine) flattenOnFeedLoss(dark, healthy string, keepHedge bool) {
+	e.posMu.Lock()
+	pos := e.position
+	e.posMu.Unlock()
+
+	if pos == 0 {
+		slog.Info("[断线处置] 当前无持仓，无需平仓")
+		return
+	}
+
+	// 平仓方向与持仓相反：多头持仓按场景2方向（A所卖出）平仓
+	dir := DirectionShort
+	if pos < 0 {
+		dir = DirectionLong
+	}
+	full := math.Abs(pos)
+	rec := store.TradeRecord{
+		Time:      time.Now(),
+		Direction: dir.tag(),
+		VenueA:    e.exA.Name(),
+		VenueB:    e.exB.Name(),
+		Size:      full,
+		Failure:   fmt.Sprintf("断线处置：%s 行情中断", dark),
+	}
+	defer func() { e.journal.RecordTrade(rec) }()
+
+	hasBybitLeg := e.cfg.Strategy.HedgeMode
+	// 平仓前两腿的持仓均价，用于只平掉一腿的数量（见 flattenPnL）；查询失败时该部分不计盈亏
+	apexX, bybitX := exposure{net: pos}, exposure{}
+	if x, err := e.apexExposure(e.ctx); err == nil {
+		apexX.entry = x.entry
+	}
+	if hasBybitLeg {
+		bybitX.net = -pos
+		if x, err := e.bybitExposure(e.ctx); err == nil {
+			bybitX.entry = x.entry
+		}
+	}
+	var apexFill, bybitFill legFill
+	var apexClosed, bybitClosed, hedgeFee float64
+	// closeLeg 平掉 venue 上 qty 数量的腿，返回实际平仓数量；失联所通过 REST 获取保护价
+	closeLeg := func(venue string, qty float64) (float64, error) {
+		useREST := venue == dark
+		if venue == e.exA.Name() {
+			order, err := e.closeApexLeg(math.Copysign(qty, pos), useREST)
+			if err != nil {
+				return 0, err
+			}
+			fill := e.apexFill(e.ctx, order)
+			rec.OrderIDA, rec.FillQtyA, rec.FillPriceA = order.ID, fill.qty, fill.avgPrice
+			rec.Fee += fill.fee
+			e.posMu.Lock()
+			e.position -= math.Copysign(fill.qty, pos)
+			e.posMu.Unlock()
+			apexFill.add(fill)
+			apexClosed = fill.qty
+			return fill.qty, nil
+		}
+		if !hasBybitLeg {
+			return qty, nil
+		}
+		order, err := e.closeBybitLeg(math.Copysign(qty, pos), useREST)
+		if err != nil {
+			return 0, err
+		}
+		rec.OrderIDB = order.ID
+		fill, err := e.bybitFill(order.ID)
+		if err != nil {
+			// 下单已成功但成交未知：按全部平仓处理，避免重复记入敞口，由日终持仓核对兜底
+			slog.Warn("[断线处置] 平仓单成交未知，注意核对持仓", "exchange", venue, "order_id", order.ID, "err", err)
+			bybitClosed = qty
+			return qty, nil
+		}
+		rec.FillQtyB, rec.FillPriceB = fill.qty, fill.avgPrice
+		rec.Fee += fill.fee
+		bybitFill.add(fill)
+		bybitClosed = fill.qty
+		return fill.qty, nil
+	}
+
+	darkQty, darkErr := closeLeg(dark, full)
+	switch {
+	case darkErr != nil:
+		slog.Error("[断线处置] 失联腿 REST 平仓失败", "exchange", dark, "err", darkErr)
+	case e.roundSize(full-darkQty) > 0:
+		slog.Warn("[断线处置] 失联腿部分平仓", "exchange", dark, "closed", e.formatSize(darkQty), "size", e.formatSize(full))
+	default:
+		slog.Info("[断线处置] 失联腿已平仓", "exchange", dark)
+	}
+
+	healthyQty := full
+	if keepHedge {
+		// 健康腿只平掉与失联腿相同的数量，其余保留作为对冲
+		healthyQty = darkQty
+		if left := e.roundSize(full - darkQty); left > 0 {
+			if dark == e.exA.Name() && !hasBybitLeg {
+				if fill, err := e.hedgeOnBybit(math.Copysign(left, pos)); err != nil {
+					slog.Error("[断线处置] 对冲转移失败，失联腿持仓无对冲，注意风险", "exchange", healthy, "dark", dark, "size", e.formatSize(left), "err", err)
+					alert.Critical("feed_loss_flatten", "%s 行情中断：对冲转移至 %s 失败，%s 无对冲，请人工处理", dark, healthy, e.formatSize(left))
+				} else {
+					rec.Fee += fill.fee
+					hedgeFee = fill.fee
+					slog.Info("[断线处置] 对冲已转移", "exchange", healthy, "size", e.formatSize(fill.qty))
+					alert.Warn("feed_loss_flatten", "%s 行情中断：%s 对冲已转移至 %s", dark, e.formatSize(fill.qty), healthy)
+				}
+			} else {
+				slog.Warn("[断线处置] 保留健康腿作为对冲，待失联腿恢复后再处理", "exchange", healthy, "size", e.formatSize(left), "dark", dark)
+				alert.Warn("feed_loss_flatten", "%s 行情中断：%s 未能平仓，保留 %s 腿作为对冲", dark, e.formatSize(left), healthy)
+			}
+		}
+	}
+
+	if healthyQty > 0 {
+		if _, err := closeLeg(healthy, healthyQty); err != nil {
+			slog.Error("[断线处置] 健康腿平仓失败，注意风险", "exchange", healthy, "err", err)
+		} else {
+			slog.Info("[断线处置] 健康腿已平仓", "exchange", healthy)
+		}
+	}
+
+	// 两腿实际平仓数量不一致：多平的一腿留下单边敞口（A所方向：B所多平 = A所持仓未被抵消）
+	if hasBybitLeg {
+		if diff := e.roundSize(bybitClosed - apexClosed); diff != 0 {
+			e.addUnhedged(dir, -diff)
+			alert.Critical("feed_loss_flatten", "%s 行情中断平仓两腿成交不一致（%s=%s，%s=%s），请核对持仓",
+				dark, e.exA.Name(), e.formatSize(apexClosed), e.exB.Name(), e.formatSize(bybitClosed))
+		}
+	}
+
+	if apexFill.qty > 0 || bybitFill.qty > 0 || hedgeFee > 0 {
+		rec.PnL = flattenPnL(dir, apexX, apexFill, bybitX, bybitFill) - hedgeFee
+		e.bookPnL(dir, rec.PnL, "断线平仓")
+	}
+
+	e.posMu.Lock()
+	left := e.position
+	e.posMu.Unlock()
+	slog.Info("[断线处置] 平仓完成", "from", pos, "to", left, "pnl", rec.PnL)
+	alert.Warn("feed_loss_flatten", "%s 行情中断处置完成，持仓 %s → %s", dark, e.formatSize(pos), e.formatSize(left))
+}
+
+// closeApexLeg 以 reduce-only IOC 限价单平掉 A所持仓 pos（正数=多头），useREST=true 时通过 REST 获取价格
+func (e *ArbEngine) closeApexLeg(pos float64, useREST bool) (*exchange.Order, error) {
+	var bid, ask float64
+	if useREST {
+		bp, err := e.exA.BestPrice(e.ctx)
+		if err != nil {
+			return nil, fmt.Errorf("REST 获取 %s 价格失败: %w", e.exA.Name(), err)
+		}
+		bid, ask = bp.Bid, bp.Ask
+	} else {
+		q := e.apexTop()
+		bid, ask = q.bid, q.ask
+	}
+
+	side, price := exchange.Sell, bid-e.hedgeSlippage(bid)
+	if pos < 0 {
+		side, price = exchange.Buy, ask+e.hedgeSlippage(ask)
+	}
+
+	return e.exA.PlaceOrder(e.ctx, &exchange.OrderRequest{
+		Side:        side,
+		Type:        exchange.Limit,
+		Qty:         e.formatSize(math.Abs(pos)),
+		Price:       e.formatApexPrice(price, side),
+		TimeInForce: exchange.IOC,
+		ReduceOnly:  true,
+	})
+}
+
+// closeBybitLeg 以市价单平掉 A所持仓 pos 对应的 B所对冲腿（多 A所对应空 B所），useREST=true 时通过 REST 获取保护价
+func (e *ArbEngine) closeBybitLeg(pos float64, useREST bool) (*exchange.Order, error) {
+	side := exchange.Buy
+	if pos < 0 {
+		side = exchange.Sell
+	}
+	return e.bybitMarketOrder(e.ctx, side, math.Abs(pos), true, useREST)
+}
+
+// hedgeOnBybit 单腿模式下 A所失联时，在 B所开反向仓位对冲，返回实际成交
+// 成交计入转移对冲数量（transferredHedge），由 flattenAll 随两所持仓一并平掉；成交查询失败时按下单数量记录
+func (e *ArbEngine) hedgeOnBybit(pos float64) (legFill, error) {
+	side := exchange.Sell
+	if pos < 0 {
+		side = exchange.Buy
+	}
+	order, err := e.bybitMarketOrder(e.ctx, side, math.Abs(pos), false, false)
+	if err != nil {
+		return legFill{}, err
+	}
+	fill, err := e.bybitFill(order.ID)
+	if err != nil {
+		slog.Warn("[断线处置] 对冲单成交未知，按下单数量记录，注意核对持仓", "exchange", e.exB.Name(), "order_id", order.ID, "err", err)
+		fill = legFill{qty: math.Abs(pos)}
+	}
+	e.posMu.Lock()
+	e.transferredHedge += math.Copysign(fill.qty, pos)
+	e.posMu.Unlock()
+	return fill, nil
+}
+
+// transferredHedgeQty 返回转移到 B所的对冲数量（A所方向）
+func (e *ArbEngine) transferredHedgeQty() float64 {
+	e.posMu.Lock()
+	defer e.posMu.Unlock()
+	return e.transferredHedge
+}
+
+// bybitMarketOrder 在 B所下市价单，附带按最新盘口加 hedge_slippage_usdc 计算的保护价（不需要的交易所忽略）
+// useREST=true 时通过 REST 获取盘口（B所行情中断时 WS 盘口已冻结）
+func (e *ArbEngine) bybitMarketOrder(ctx context.Context, side exchange.Side, qty float64, reduceOnly, useREST bool) (*exchange.Order, error) {
+	var bid, ask float64
+	if useREST {
+		bp, err := e.exB.BestPrice(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("REST 获取 %s 价格失败: %w", e.exB.Name(), err)
+		}
+		bid, ask = bp.Bid, bp.Ask
+	} else {
+		q := e.bybitTop()
+		bid, ask = q.bid, q.ask
+	}
+	price := bid - e.hedgeSlippage(bid)
+	if side == exchange.Buy {
+		price = ask + e.hedgeSlippage(ask)
+	}
+	return e.exB.PlaceOrder(ctx, &exchange.OrderRequest{
+		Side:        side,
+		Type:        exchange.Market,
+		Qty:         e.formatSize(qty),
+		Price:       e.formatBybitPrice(price, side),
+		TimeInForce: exchange.IOC,
+		ReduceOnly:  reduceOnly,
+	})
+}
@@ -0,0 +1,92 @@
+package strategy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"arb/config"
+)
+
+// TestFeedLossFlattenOutage 模拟 B所行情中断超过 flatten_after_sec：双腿平仓，平仓价差计入累计PnL与风控
+func TestFeedLossFlattenOutage(t *testing.T) {
+	cfg := testConfig()
+	cfg.Bybit.FeedLoss = config.FeedLossPolicy{OnFeedLoss: config.FeedLossFlatten, FlattenAfterSec: 30}
+	e, exA, exB := newTestEngine(t, cfg)
+	setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+	e.checkAndTrade() // 开仓价差计入 1
+
+	// 两所行情停止推送，撮合价格继续变化：A所 100050 卖出、B所 100065 买入
+	exA.setBook(100050, 100060)
+	exB.setBook(100055, 100065)
+	venues := e.feedVenues()
+	start := time.Now()
+
+	e.evaluateFeeds(venues, start.Add(11*time.Second), start)
+	if !e.feedPaused.Load() {
+		t.Fatal("行情中断后应暂停开仓")
+	}
+	if pos, _ := enginePosition(e); !approx(pos, 0.1) {
+		t.Fatalf("未到 flatten_after_sec 不应平仓，持仓 = %v", pos)
+	}
+
+	e.evaluateFeeds(venues, start.Add(31*time.Second), start)
+	if pos, unhedged := enginePosition(e); pos != 0 || unhedged != 0 {
+		t.Fatalf("平仓后引擎持仓 / 未对冲 = %v / %v，期望 0 / 0", pos, unhedged)
+	}
+	if exA.netPosition() != 0 || exB.netPosition() != 0 {
+		t.Fatalf("两所持仓 = %v / %v，期望均为 0", exA.netPosition(), exB.netPosition())
+	}
+	if total := engineTotalPnL(e); !approx(total, -0.5) {
+		t.Fatalf("累计PnL = %v，期望 -0.5", total)
+	}
+	if daily := e.riskCtrl.DailyPnL(); !approx(daily, -0.5) {
+		t.Fatalf("风控当日PnL = %v，期望 -0.5", daily)
+	}
+
+	// 已处置的中断不重复平仓
+	e.evaluateFeeds(venues, start.Add(40*time.Second), start)
+	if n := len(exA.placed()); n != 2 {
+		t.Fatalf("A所下单 %d 笔，期望开仓 + 平仓 2 笔", n)
+	}
+}
+
+// TestFeedLossHedgeElsewhere 单腿模式下 A所中断且 REST 平仓失败：对冲转移到 B所并记录，flattenAll 一并平掉
+func TestFeedLossHedgeElsewhere(t *testing.T) {
+	cfg := testConfig()
+	cfg.Strategy.HedgeMode = false
+	cfg.Apex.FeedLoss = config.FeedLossPolicy{OnFeedLoss: config.FeedLossHedgeElse}
+	e, exA, exB := newTestEngine(t, cfg)
+	exB.feeRate = 0.0001
+	setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+	e.checkAndTrade()
+	before := engineTotalPnL(e)
+
+	exA.queueErrs(errors.New("网关超时"))
+	start := time.Now()
+	e.evaluateFeeds(e.feedVenues(), start.Add(11*time.Second), start)
+
+	if hedge := e.transferredHedgeQty(); !approx(hedge, 0.1) {
+		t.Fatalf("转移对冲 = %v，期望 0.1", hedge)
+	}
+	if !approx(exB.netPosition(), -0.1) {
+		t.Fatalf("B所持仓 = %v，期望 -0.1", exB.netPosition())
+	}
+	if pos, _ := enginePosition(e); !approx(pos, 0.1) {
+		t.Fatalf("A所腿未平掉，引擎持仓 = %v，期望 0.1", pos)
+	}
+	// 对冲单手续费 100010 × 0.1 × 0.0001
+	if total := engineTotalPnL(e); !approx(total, before-1.0001) {
+		t.Fatalf("累计PnL = %v，期望 %v", total, before-1.0001)
+	}
+
+	if _, ok := e.flattenAll(e.ctx, "[测试平仓]"); !ok {
+		t.Fatal("flattenAll 应平掉两所持仓")
+	}
+	if hedge := e.transferredHedgeQty(); hedge != 0 {
+		t.Fatalf("平仓后转移对冲 = %v，期望 0", hedge)
+	}
+	if exA.netPosition() != 0 || exB.netPosition() != 0 {
+		t.Fatalf("两所持仓 = %v / %v，期望均为 0", exA.netPosition(), exB.netPosition())
+	}
+}
@@ -0,0 +1,124 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"arb/exchange"
+)
+
+// legFill 单腿实际成交结果
+type legFill struct {
+	qty      float64 // 实际成交量
+	avgPrice float64 // 成交均价
+	fee      float64 // 手续费（USDC）
+	orderID  string  // 订单ID（仅 placeHedge / placeApexLeg 返回时填写，用于交易流水）
+
+	placedAt time.Time // 下单请求返回时间（仅 placeHedge / placeApexLeg 返回时填写，用于统计两腿时间差）
+}
+
+// apexFill 查询 A所订单的实际成交，查询失败时退回下单响应中的成交信息
+func (e *ArbEngine) apexFill(ctx context.Context, order *exchange.Order) legFill {
+	if o, err := e.finalOrder(ctx, e.exA, order.ID); err != nil {
+		slog.Warn("[成交] 查询订单失败，使用下单响应", "exchange", e.exA.Name(), "order_id", order.ID, "err", err)
+	} else {
+		order = o
+	}
+
+	fill := legFill{qty: order.FilledQty, avgPrice: order.AvgPrice, fee: order.Fee}
+	if fill.avgPrice == 0 && fill.qty > 0 {
+		fill.avgPrice = order.Price
+	}
+	return fill
+}
+
+// bybitFill 获取 B所订单的实际成交
+// 启用私有频道时优先使用成交推送（订单全部成交即返回），否则或推送未确认时回退到 REST 查询
+func (e *ArbEngine) bybitFill(orderID string) (legFill, error) {
+	if e.execStream && e.exB.(exchange.ExecutionStreamer).ExecutionsReady() {
+		if fill, ok := e.execs.wait(orderID, execWaitTimeout); ok {
+			return fill, nil
+		}
+	}
+
+	return e.bybitOrderFill(e.ctx, orderID)
+}
+
+// bybitOrderFill 通过 REST 查询 B所订单的实际成交
+func (e *ArbEngine) bybitOrderFill(ctx context.Context, orderID string) (legFill, error) {
+	o, err := e.finalOrder(ctx, e.exB, orderID)
+	if err != nil {
+		return legFill{}, fmt.Errorf("查询 %s 订单 %s 失败: %w", e.exB.Name(), orderID, err)
+	}
+	return legFill{qty: o.FilledQty, avgPrice: o.AvgPrice, fee: o.Fee}, nil
+}
+
+const (
+	// orderPollAttempts IOC 订单查询到非最终状态时的最多查询次数（含首次）
+	orderPollAttempts = 3
+	// orderPollDelay 两次订单查询之间的等待
+	orderPollDelay = 100 * time.Millisecond
+)
+
+// finalOrder 查询订单，IOC 订单尚未结束（撮合未完成）时短暂等待后重查，保证成交量是最终值
+// 多次查询仍未结束时返回最后一次结果并记录日志
+func (e *ArbEngine) finalOrder(ctx context.Context, ex exchange.Exchange, orderID string) (*exchange.Order, error) {
+	for n := 1; ; n++ {
+		o, err := ex.GetOrder(ctx, orderID)
+		if err != nil || o.Final {
+			return o, err
+		}
+		if n >= orderPollAttempts {
+			slog.Warn("[成交] 订单查询多次仍未结束，按当前成交量计算", "exchange", ex.Name(), "order_id", orderID,
+				"attempts", n, "status", o.Status, "filled", e.formatSize(o.FilledQty))
+			return o, nil
+		}
+		select {
+		case <-ctx.Done():
+			return o, nil
+		case <-time.After(orderPollDelay):
+		}
+	}
+}
+
+// realizedPnL 根据两腿实际成交计算已实现盈亏（扣除两腿手续费）
+// 只有两腿都成交的部分计入价差收益
+func realizedPnL(dir ArbDirection, apex, bybit legFill) float64 {
+	matched := apex.qty
+	if bybit.qty < matched {
+		matched = bybit.qty
+	}
+
+	// 场景1：Apex 买、Bybit 卖；场景2：Apex 卖、Bybit 买
+	gross := (bybit.avgPrice - apex.avgPrice) * matched
+	if dir == DirectionShort {
+		gross = -gross
+	}
+	return gross - apex.fee - bybit.fee
+}
+
+// pnlAttribution 将两腿成交的盈亏拆分为报价价差收益、手续费与滑点：
+// gross 按下单时的报价价格计算，slippage 为报价价差与实际成交价差之差（正数为损失），
+// gross - fees - slippage 等于 realizedPnL
+func pnlAttribution(dir ArbDirection, apexQuote, bybitQuote float64, apex, bybit legFill) (gross, fees, slippage float64) {
+	matched := math.Min(apex.qty, bybit.qty)
+	quoted := (bybitQuote - apexQuote) * matched
+	actual := (bybit.avgPrice - apex.avgPrice) * matched
+	if dir == DirectionShort {
+		quoted, actual = -quoted, -actual
+	}
+	return quoted, apex.fee + bybit.fee, quoted - actual
+}
+
+// attributePnL 累计一笔已实现交易的盈亏归因（状态日志 [状态] 盈亏归因）
+func (e *ArbEngine) attributePnL(dir ArbDirection, apexQuote, bybitQuote float64, apex, bybit legFill) {
+	gross, fees, slippage := pnlAttribution(dir, apexQuote, bybitQuote, apex, bybit)
+	e.pnlMu.Lock()
+	e.grossPnL += gross
+	e.feesPaid += fees
+	e.slippageCost += slippage
+	e.pnlMu.Unlock()
+}
@@ -0,0 +1,208 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"arb/exchange"
+)
+
+const (
+	// defaultFlattenTimeout 未配置 flatten_timeout_sec 时停止平仓的总超时
+	defaultFlattenTimeout = 15 * time.Second
+
+	// flattenPollInterval 等待平仓确认时查询持仓的间隔
+	flattenPollInterval = 500 * time.Millisecond
+)
+
+// flattenOnStop 停止时按两所真实持仓以 reduce-only 市价单平仓，并等待持仓归零确认
+// 在 WS 断开前执行，整个流程受 flatten_timeout_sec 限制；平仓盈亏计入累计PnL与风控
+func (e *ArbEngine) flattenOnStop() {
+	timeout := e.flattenTimeout()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	slog.Info("[停止平仓] 开始平掉两所持仓", "timeout", timeout)
+	e.flattenAll(ctx, "[停止平仓]")
+}
+
+// flattenTimeout 返回平仓流程的总超时（flatten_timeout_sec）
+func (e *ArbEngine) flattenTimeout() time.Duration {
+	if sec := e.cfg.Strategy.FlattenTimeoutSec; sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	return defaultFlattenTimeout
+}
+
+// flattenAll 按两所真实持仓以 reduce-only 市价单平仓并等待持仓归零确认，tag 为日志前缀
+// 平仓盈亏（见 flattenPnL）经 bookPnL 计入累计PnL、风控与会话统计；两所均无持仓时 ok=false
+func (e *ArbEngine) flattenAll(ctx context.Context, tag string) (pnl float64, ok bool) {
+	var legs [2]struct {
+		x    exposure
+		fill legFill
+	}
+	var closed int
+	for i, leg := range []struct {
+		venue   string
+		flatten func(context.Context) (exposure, legFill, bool, error)
+	}{
+		{e.exA.Name(), e.flattenApex},
+		{e.exB.Name(), e.flattenBybit},
+	} {
+		x, fill, ok, err := leg.flatten(ctx)
+		if err != nil {
+			slog.Error(tag+" 平仓失败，注意核对持仓", "exchange", leg.venue, "err", err)
+			continue
+		}
+		if ok {
+			legs[i].x, legs[i].fill = x, fill
+			closed++
+		}
+	}
+
+	if closed == 0 {
+		slog.Info(tag + " 两所均无持仓需要平仓")
+		return 0, false
+	}
+
+	if err := e.waitFlat(ctx); err != nil {
+		slog.Error(tag+" 未能确认持仓归零，注意核对持仓", "err", err)
+	} else {
+		e.posMu.Lock()
+		e.position = 0
+		e.unhedgedQty = 0
+		e.transferredHedge = 0
+		e.posMu.Unlock()
+		slog.Info(tag + " 已确认两所持仓归零")
+	}
+
+	// 平仓方向与平仓前的 A所持仓相反（多头持仓按场景2方向平仓），A所无持仓时按 B所持仓推断
+	side := legs[0].x.net
+	if side == 0 {
+		side = -legs[1].x.net
+	}
+	dir := DirectionShort
+	if side < 0 {
+		dir = DirectionLong
+	}
+	pnl = flattenPnL(dir, legs[0].x, legs[0].fill, legs[1].x, legs[1].fill)
+	e.bookPnL(dir, pnl, "平仓")
+	slog.Info(tag+" 平仓完成", "pnl", pnl)
+	return pnl, true
+}
+
+// flattenPnL 计算平仓盈亏：两腿互为对冲的部分只计平仓价差（两腿平仓成交价相减），开仓价差已在开仓时计入；
+// 只有一所平仓的剩余数量（未对冲敞口）开仓时未计价差，按该所持仓均价计算（均价未知时不计）。两腿平仓手续费全部扣除
+func flattenPnL(dir ArbDirection, apexX exposure, apex legFill, bybitX exposure, bybit legFill) float64 {
+	var matched float64
+	if apexX.net*bybitX.net < 0 {
+		matched = math.Min(apex.qty, bybit.qty)
+	}
+	pnl := dir.sign()*(bybit.avgPrice-apex.avgPrice)*matched - apex.fee - bybit.fee
+	for _, leg := range []struct {
+		x    exposure
+		fill legFill
+	}{{apexX, apex}, {bybitX, bybit}} {
+		if left := leg.fill.qty - matched; left > 0 && leg.x.entry > 0 {
+			pnl += math.Copysign(1, leg.x.net) * (leg.fill.avgPrice - leg.x.entry) * left
+		}
+	}
+	return pnl
+}
+
+// flattenApex 平掉 A所全部持仓，返回平仓前的持仓与平仓成交；无持仓时 ok=false
+// 部分交易所（如 Apex）市价单需提供可接受的最差价格，按 REST 盘口加 hedge_slippage_usdc 计算
+func (e *ArbEngine) flattenApex(ctx context.Context) (x exposure, fill legFill, ok bool, err error) {
+	x, err = e.apexExposure(ctx)
+	if err != nil {
+		return x, fill, false, err
+	}
+	qty := e.roundSize(math.Abs(x.net))
+	if qty < e.sizeStep() {
+		return x, fill, false, nil
+	}
+
+	bp, err := e.exA.BestPrice(ctx)
+	if err != nil {
+		return x, fill, false, fmt.Errorf("REST 获取 %s 价格失败: %w", e.exA.Name(), err)
+	}
+	side, price := exchange.Sell, bp.Bid-e.hedgeSlippage(bp.Bid)
+	if x.net < 0 {
+		side, price = exchange.Buy, bp.Ask+e.hedgeSlippage(bp.Ask)
+	}
+
+	order, err := e.exA.PlaceOrder(ctx, &exchange.OrderRequest{
+		Side:        side,
+		Type:        exchange.Market,
+		Qty:         e.formatSize(qty),
+		Price:       e.formatApexPrice(price, side),
+		TimeInForce: exchange.IOC,
+		ReduceOnly:  true,
+	})
+	if err != nil {
+		return x, fill, false, err
+	}
+
+	fill = e.apexFill(ctx, order)
+	slog.Info("[平仓] 平仓成交", "exchange", e.exA.Name(), "order_id", order.ID, "position", x.net, "entry", x.entry,
+		"filled", e.formatSize(fill.qty), "avg_price", fill.avgPrice, "fee", fill.fee)
+	return x, fill, true, nil
+}
+
+// flattenBybit 平掉 B所全部持仓，返回平仓前的持仓与平仓成交；无持仓时 ok=false
+func (e *ArbEngine) flattenBybit(ctx context.Context) (x exposure, fill legFill, ok bool, err error) {
+	x, err = e.bybitExposure(ctx)
+	if err != nil {
+		return x, fill, false, err
+	}
+	qty := e.roundSize(math.Abs(x.net))
+	if qty < e.sizeStep() {
+		return x, fill, false, nil
+	}
+
+	side := exchange.Sell
+	if x.net < 0 {
+		side = exchange.Buy
+	}
+	order, err := e.bybitMarketOrder(ctx, side, qty, true, true)
+	if err != nil {
+		return x, fill, false, err
+	}
+
+	fill, err = e.bybitOrderFill(ctx, order.ID)
+	if err != nil {
+		// 下单已成功，成交未知时仍由 waitFlat 确认持仓
+		slog.Warn("[平仓] 平仓单已提交，查询成交失败", "exchange", e.exB.Name(), "order_id", order.ID, "err", err)
+		return x, legFill{}, true, nil
+	}
+	slog.Info("[平仓] 平仓成交", "exchange", e.exB.Name(), "order_id", order.ID, "position", x.net, "entry", x.entry,
+		"filled", e.formatSize(fill.qty), "avg_price", fill.avgPrice, "fee", fill.fee)
+	return x, fill, true, nil
+}
+
+// waitFlat 轮询两所持仓直到都归零，ctx 超时返回最后一次查询结果
+func (e *ArbEngine) waitFlat(ctx context.Context) error {
+	for {
+		apexNet, apexErr := e.apexNetPosition(ctx)
+		bybitNet, bybitErr := e.bybitNetPosition(ctx)
+		if apexErr == nil && bybitErr == nil &&
+			math.Abs(apexNet) < e.sizeStep() && math.Abs(bybitNet) < e.sizeStep() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if apexErr != nil {
+				return apexErr
+			}
+			if bybitErr != nil {
+				return bybitErr
+			}
+			return fmt.Errorf("超时，剩余持仓 %s=%.4f %s=%.4f", e.exA.Name(), apexNet, e.exB.Name(), bybitNet)
+		case <-time.After(flattenPollInterval):
+		}
+	}
+}
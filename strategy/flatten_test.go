@@ -0,0 +1,50 @@
+package strategy
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFlattenBooksCloseSpreadOnly(t *testing.T) {
+	e, exA, exB := newTestEngine(t, testConfig())
+	// 开仓：A所 100000 买入、B所 100010 卖出，开仓价差计入 1
+	setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+	e.checkAndTrade()
+	if pnl := engineTotalPnL(e); !approx(pnl, 1) {
+		t.Fatalf("开仓后累计PnL = %v，期望 1", pnl)
+	}
+
+	// 平仓：A所 100050 卖出、B所 100065 买入，平仓价差 -1.5
+	exA.setBook(100050, 100060)
+	exB.setBook(100055, 100065)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	pnl, ok := e.flattenAll(ctx, "[测试平仓]")
+	if !ok {
+		t.Fatal("有持仓时 flattenAll 应返回 ok")
+	}
+	if !approx(pnl, -1.5) {
+		t.Fatalf("平仓PnL = %v，期望 -1.5", pnl)
+	}
+	// 两腿实际盈亏：A所 +5，B所 -5.5
+	if total := engineTotalPnL(e); !approx(total, -0.5) {
+		t.Fatalf("累计PnL = %v，期望 -0.5", total)
+	}
+	if pos, _ := enginePosition(e); pos != 0 {
+		t.Fatalf("平仓后引擎持仓 = %v，期望 0", pos)
+	}
+}
+
+func TestFlattenPnLUnhedgedRemainder(t *testing.T) {
+	// A所多 0.2（均价 100000），B所空 0.1；平仓 A所 100050 卖出、B所 100065 买入
+	apexX := exposure{net: 0.2, entry: 100000}
+	bybitX := exposure{net: -0.1, entry: 100010}
+	apex := legFill{qty: 0.2, avgPrice: 100050, fee: 0.2}
+	bybit := legFill{qty: 0.1, avgPrice: 100065, fee: 0.1}
+
+	// 对冲部分 (100050-100065)*0.1 = -1.5，未对冲的 0.1 按均价 (100050-100000)*0.1 = 5，手续费 0.3
+	if pnl := flattenPnL(DirectionShort, apexX, apex, bybitX, bybit); !approx(pnl, 3.2) {
+		t.Fatalf("flattenPnL = %v，期望 3.2", pnl)
+	}
+}
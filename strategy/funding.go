@@ -0,0 +1,299 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"arb/alert"
+	"arb/config"
+	"arb/exchange"
+)
+
+const (
+	// defaultFundingCheckInterval 未配置 funding_check_interval_sec 时查询资金费率的间隔
+	defaultFundingCheckInterval = 60 * time.Second
+
+	// defaultFundingWindow 未配置 funding_window_sec 时结算前执行资金费处置的窗口
+	defaultFundingWindow = 10 * time.Minute
+
+	// defaultFundingReduceRatio 未配置 funding_reduce_ratio 时 reduce 动作的减仓比例
+	defaultFundingReduceRatio = 0.5
+)
+
+// fundingInterval 返回资金费率查询间隔：0 使用默认值，负数表示不启用
+func (e *ArbEngine) fundingInterval() time.Duration {
+	sec := e.cfg.Strategy.FundingCheckIntervalSec
+	if sec == 0 {
+		return defaultFundingCheckInterval
+	}
+	if sec < 0 {
+		return -1
+	}
+	return time.Duration(sec) * time.Second
+}
+
+// fundingWindow 返回结算前执行资金费处置的窗口
+func (e *ArbEngine) fundingWindow() time.Duration {
+	if sec := e.cfg.Strategy.FundingWindowSec; sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	return defaultFundingWindow
+}
+
+// fundingAction 返回资金费处置动作，未配置时为 none
+func (e *ArbEngine) fundingAction() string {
+	if a := e.cfg.Strategy.FundingAction; a != "" {
+		return a
+	}
+	return config.FundingActionNone
+}
+
+// fundingLoop 定时查询两所资金费率；结算临近且资金费方向对当前持仓不利时暂停开仓，并按 funding_action 减仓或平仓
+func (e *ArbEngine) fundingLoop() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.fundingInterval())
+	defer ticker.Stop()
+
+	e.pollFunding()
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case <-ticker.C:
+			e.pollFunding()
+		}
+	}
+}
+
+// pollFunding 查询一次两所资金费率并检查是否需要处置
+func (e *ArbEngine) pollFunding() {
+	for _, v := range []struct {
+		ex   exchange.Exchange
+		dest **exchange.Funding
+	}{
+		{e.exA, &e.fundingA},
+		{e.exB, &e.fundingB},
+	} {
+		fp, ok := v.ex.(exchange.FundingProvider)
+		if !ok {
+			continue
+		}
+		f, err := fp.Funding(e.ctx)
+		if errors.Is(err, exchange.ErrUnsupported) {
+			continue
+		}
+		if err != nil {
+			slog.Warn("[资金费] 查询资金费率失败", "exchange", v.ex.Name(), "err", err)
+			continue
+		}
+		e.fundingMu.Lock()
+		*v.dest = f
+		e.fundingMu.Unlock()
+	}
+	e.checkFunding()
+}
+
+// fundingRates 返回最近一次查询到的两所资金费率，未查询到时为 nil
+func (e *ArbEngine) fundingRates() (a, b *exchange.Funding) {
+	e.fundingMu.Lock()
+	defer e.fundingMu.Unlock()
+	return e.fundingA, e.fundingB
+}
+
+// upcomingFundingCost 估算窗口内即将结算的资金费（USDC，正数表示支付）与最近的结算时间
+// A所持仓为 pos；对冲模式下 B所持仓为 -pos。资金费率为正时多头支付、空头收取
+func (e *ArbEngine) upcomingFundingCost(pos float64, now time.Time) (cost float64, next time.Time, ok bool) {
+	fa, fb := e.fundingRates()
+	a, b := e.apexTop(), e.bybitTop()
+	window := e.fundingWindow()
+
+	legs := []struct {
+		f   *exchange.Funding
+		pos float64
+		mid float64
+	}{
+		{fa, pos, (a.bid + a.ask) / 2},
+	}
+	if e.cfg.Strategy.HedgeMode {
+		legs = append(legs, struct {
+			f   *exchange.Funding
+			pos float64
+			mid float64
+		}{fb, -pos, (b.bid + b.ask) / 2})
+	}
+
+	for _, leg := range legs {
+		if leg.f == nil || leg.mid <= 0 {
+			continue
+		}
+		until := leg.f.NextTime.Sub(now)
+		if until < 0 || until > window {
+			continue
+		}
+		cost += leg.pos * leg.f.Rate * leg.mid
+		if !ok || leg.f.NextTime.Before(next) {
+			next = leg.f.NextTime
+		}
+		ok = true
+	}
+	return cost, next, ok
+}
+
+// fundingEntryCost funding_aware 开启时，估算按 dir 开仓 1 张并持有 max_holding_seconds 期间需支付的资金费（USDC，负数为收取）
+// 只计算持有期内到达的下一次结算；未配置 max_holding_seconds 时按持有到下一次结算计算。未开启或无资金费数据时为 0
+func (e *ArbEngine) fundingEntryCost(dir ArbDirection) float64 {
+	if !e.cfg.Strategy.FundingAware {
+		return 0
+	}
+	fa, fb := e.fundingRates()
+	a, b := e.apexTop(), e.bybitTop()
+	horizon := time.Duration(e.cfg.Strategy.MaxHoldingSeconds) * time.Second
+	now := time.Now()
+
+	legs := []struct {
+		f   *exchange.Funding
+		pos float64
+		mid float64
+	}{
+		{fa, dir.sign(), (a.bid + a.ask) / 2},
+	}
+	if e.cfg.Strategy.HedgeMode {
+		legs = append(legs, struct {
+			f   *exchange.Funding
+			pos float64
+			mid float64
+		}{fb, -dir.sign(), (b.bid + b.ask) / 2})
+	}
+
+	var cost float64
+	for _, leg := range legs {
+		if leg.f == nil || leg.mid <= 0 {
+			continue
+		}
+		if until := leg.f.NextTime.Sub(now); until < 0 || (horizon > 0 && until > horizon) {
+			continue
+		}
+		// 资金费率为正时多头支付、空头收取
+		cost += leg.pos * leg.f.Rate * leg.mid
+	}
+	return cost
+}
+
+// checkFunding 结算窗口内资金费对当前持仓不利时暂停开仓，并对每个结算时间执行一次处置动作
+func (e *ArbEngine) checkFunding() {
+	e.posMu.Lock()
+	pos := e.position
+	e.posMu.Unlock()
+
+	cost, next, ok := e.upcomingFundingCost(pos, time.Now())
+	adverse := ok && cost > 0 && math.Abs(pos) >= e.sizeStep()
+	action := e.fundingAction()
+
+	block := adverse && action != config.FundingActionNone
+	if e.fundingBlocked.Swap(block) != block {
+		if block {
+			slog.Warn("[资金费] 结算预计支付资金费，结算前暂停开仓", "next", next.Format("15:04:05"), "cost", cost, "position", pos)
+		} else {
+			slog.Info("[资金费] 结算窗口结束或资金费不再不利，恢复开仓")
+		}
+	}
+	if !adverse {
+		return
+	}
+	if next.Equal(e.fundingActedAt) {
+		return // 本次结算已处置
+	}
+	if action == config.FundingActionNone || e.cfg.Strategy.MonitorOnly {
+		e.fundingActedAt = next
+		slog.Info("[资金费] 结算预计支付资金费，funding_action=none 不处置", "next", next.Format("15:04:05"), "cost", cost, "position", pos)
+		return
+	}
+
+	// 与套利检测互斥，避免减仓与开仓交叉；检测进行中时下个周期重试
+	if !e.checking.CompareAndSwap(false, true) {
+		return
+	}
+	defer e.checking.Store(false)
+
+	if err := e.reduceForFunding(action, cost, next); err != nil {
+		slog.Warn("[资金费] 处置失败，下个周期重试", "err", err)
+		return
+	}
+	e.fundingActedAt = next
+}
+
+// reduceForFunding 按处置动作以 reduce-only 单减少 A所持仓，对冲模式下同时平掉对应数量的 B所对冲腿
+func (e *ArbEngine) reduceForFunding(action string, cost float64, next time.Time) error {
+	e.posMu.Lock()
+	pos := e.position
+	e.posMu.Unlock()
+
+	qty := math.Abs(pos)
+	if action == config.FundingActionReduce {
+		ratio := e.cfg.Strategy.FundingReduceRatio
+		if ratio <= 0 || ratio > 1 {
+			ratio = defaultFundingReduceRatio
+		}
+		qty = e.roundSize(qty * ratio)
+	}
+	if qty < e.sizeStep() {
+		return nil
+	}
+
+	order, err := e.closeApexLeg(math.Copysign(qty, pos), false)
+	if err != nil {
+		return fmt.Errorf("%s 减仓失败: %w", e.exA.Name(), err)
+	}
+	fill := e.apexFill(e.ctx, order)
+	if fill.qty <= 0 {
+		return fmt.Errorf("%s 减仓单 OrderID=%s 未成交", e.exA.Name(), order.ID)
+	}
+	reduced := math.Copysign(fill.qty, pos)
+
+	e.posMu.Lock()
+	e.position -= reduced
+	remaining := e.position
+	e.posMu.Unlock()
+
+	if e.cfg.Strategy.HedgeMode {
+		if _, err := e.closeBybitLeg(reduced, false); err != nil {
+			alert.Warn("funding_reduce", "资金费减仓 %s 对冲腿平仓失败: %v", e.exB.Name(), err)
+			slog.Error("[资金费] 对冲腿平仓失败，A所已减仓，注意核对持仓", "exchange", e.exB.Name(), "reduced", e.formatSize(fill.qty), "err", err)
+		}
+	}
+	e.saveState()
+
+	slog.Info("[资金费] 结算前处置完成", "next", next.Format("15:04:05"), "cost", cost, "action", fundingActionName(action),
+		"size", e.formatSize(fill.qty), "avg_price", fill.avgPrice, "fee", fill.fee, "remaining", remaining)
+	alert.Info("funding_reduce", "资金费结算前%s %s，剩余持仓 %.4f", fundingActionName(action), e.formatSize(fill.qty), remaining)
+	return nil
+}
+
+func fundingActionName(action string) string {
+	if action == config.FundingActionFlatten {
+		return "平仓"
+	}
+	return "减仓"
+}
+
+// logFunding 状态日志：打印两所下一次资金费结算时间与费率
+func (e *ArbEngine) logFunding() {
+	fa, fb := e.fundingRates()
+	for _, v := range []struct {
+		name string
+		f    *exchange.Funding
+	}{{e.exA.Name(), fa}, {e.exB.Name(), fb}} {
+		if v.f == nil {
+			continue
+		}
+		slog.Info("[状态] 资金费", "exchange", v.name, "rate_pct", v.f.Rate*100,
+			"next", v.f.NextTime.Local().Format("15:04:05"), "in", time.Until(v.f.NextTime).Round(time.Second))
+	}
+	if (fa != nil || fb != nil) && e.fundingBlocked.Load() {
+		slog.Warn("[状态] 资金费结算临近且方向不利，暂停开仓")
+	}
+}
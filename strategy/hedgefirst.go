@@ -0,0 +1,216 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"arb/alert"
+	"arb/exchange"
+	"arb/store"
+)
+
+// executeHedgeFirst hedge_first 模式：先在 B所下对冲腿并确认成交，再按实际对冲成交量在 A所下单（有效方式与 maker 模式同 A所先行）
+// 执行风险由 A所承担：A所腿未能完全成交时，以最新报价重试，仍未成交的部分平掉 B所腿
+func (e *ArbEngine) executeHedgeFirst(dir ArbDirection, oppMs int64, apexQuote, bybitQuote, qty float64, reduceOnly bool, rec *store.TradeRecord) {
+	lg := slog.With("direction", dir.tag(), "order", "hedge_first")
+
+	// 腿1：在 B所对冲
+	bybitFill, err := e.placeHedge(dir, qty, bybitQuote, reduceOnly, e.clientID(oppMs, dir, "hedge"))
+	rec.OrderIDB, rec.FillQtyB, rec.FillPriceB, rec.Fee = bybitFill.orderID, bybitFill.qty, bybitFill.avgPrice, bybitFill.fee
+	if errors.Is(err, errFillUnknown) {
+		rec.Failure = fmt.Sprintf("B所成交状态未知: %v", err)
+		lg.Error("[套利] 无法确认 B所成交，停止本次机会（注意核对持仓）", "exchange", e.exB.Name(), "order_id", bybitFill.orderID, "err", err)
+		alert.Critical("hedge_failed", "%s 先行对冲成交状态未知（%s %s）: %v，请核对持仓", e.exB.Name(), dir, e.formatSize(qty), err)
+		return
+	}
+	if err != nil {
+		rec.Failure = fmt.Sprintf("B所下单失败: %v", err)
+		lg.Error("[套利] 先行对冲"+dir.bybitAction()+"失败", "exchange", e.exB.Name(), "err", err)
+		return
+	}
+	hedged := e.roundSize(bybitFill.qty)
+	if hedged <= 0 {
+		rec.Failure = "B所未成交"
+		return
+	}
+	if hedged < e.minOrderSize() && reduceOnly {
+		// 减仓交易的 B所腿是平仓单，不能再反向平掉
+		rec.Failure = "B所成交量低于 A所最小下单量，记入未对冲敞口"
+		lg.Warn("[套利] 减仓对冲成交量低于 A所最小下单量，记入未对冲敞口", "filled", e.formatSize(hedged), "min_size", e.formatSize(e.minOrderSize()))
+		e.addUnhedged(dir, -hedged)
+		return
+	}
+	if hedged < e.minOrderSize() {
+		rec.Failure = "B所成交量低于 A所最小下单量，已平掉 B所腿"
+		lg.Warn("[套利] 对冲成交量低于 A所最小下单量，平掉 B所腿", "filled", e.formatSize(hedged), "min_size", e.formatSize(e.minOrderSize()))
+		pnl, _ := e.unwindBybitLeg(dir, bybitFill, hedged)
+		e.bookPnL(dir, pnl, "对冲恢复")
+		return
+	}
+
+	// 腿2：在 A所按实际对冲成交量下单；A所腿与恢复结束前为单腿敞口
+	defer e.noteUnhedgedWindow(time.Now())
+	apexSide, _ := dir.sides()
+	size := e.formatSize(hedged)
+	apexPrice := e.formatApexPrice(apexQuote, apexSide)
+	// 与 A所先行时相同，按 time_in_force / apex_maker_mode 下单
+	apexFill, err := e.placeApexLeg(dir, &exchange.OrderRequest{
+		Side:        apexSide,
+		Type:        exchange.Limit,
+		Qty:         size,
+		Price:       apexPrice,
+		TimeInForce: e.apexTimeInForce(),
+		ExpireAt:    e.orderExpireAt(),
+		ReduceOnly:  reduceOnly,
+		ClientID:    e.clientID(oppMs, dir, "apex"),
+	}, hedged)
+	if !bybitFill.placedAt.IsZero() {
+		e.recordLegLatency(time.Since(bybitFill.placedAt))
+	}
+
+	if err != nil {
+		lg.Error("[套利] "+dir.apexAction()+"失败（B所腿已成交，启动恢复）", "exchange", e.exA.Name(), "err", err)
+	} else {
+		rec.OrderIDA, rec.FillQtyA, rec.FillPriceA = apexFill.orderID, apexFill.qty, apexFill.avgPrice
+		rec.Fee += apexFill.fee
+		lg.Info("[套利] "+dir.apexAction()+"完成", "exchange", e.exA.Name(), "order_id", apexFill.orderID,
+			"price", apexPrice, "size", size, "filled", e.formatSize(apexFill.qty), "avg_price", apexFill.avgPrice)
+	}
+
+	if apexFill.qty > 0 {
+		e.posMu.Lock()
+		e.position += dir.sign() * apexFill.qty
+		e.posMu.Unlock()
+	}
+
+	if orphan := e.roundSize(hedged - apexFill.qty); orphan > e.hedgeTolerance(apexQuote) {
+		lg.Warn("[套利] A所腿未完全成交，启动恢复", "exchange", e.exA.Name(),
+			"filled", e.formatSize(apexFill.qty), "target", size, "orphan", e.formatSize(orphan))
+		alert.Warn("hedge_failed", "%s 腿未完全成交（%s %s/%s），启动恢复", e.exA.Name(), dir, e.formatSize(apexFill.qty), size)
+		rec.Failure = fmt.Sprintf("A所腿未完全成交，孤立对冲 %s（已启动恢复）", e.formatSize(orphan))
+		e.recoverApexLeg(dir, oppMs, apexFill, bybitFill, orphan, reduceOnly)
+		return
+	}
+
+	rec.PnL = realizedPnL(dir, apexFill, bybitFill)
+	e.attributePnL(dir, apexQuote, bybitQuote, apexFill, bybitFill)
+	e.bookPnL(dir, rec.PnL, "已实现")
+}
+
+// recoverApexLeg hedge_first 模式下 A所腿未完全成交后的恢复流程：
+//  1. 以最新 A所报价（含 hedge_slippage_usdc）重试剩余数量，最多 hedge_retry_count 次
+//  2. 仍未成交的部分以 reduce-only 市价单平掉 B所腿
+//  3. 平仓后仍残留的数量记入未对冲敞口
+//
+// reduceOnly 为减仓交易：A所重试同样使用 reduce-only，B所腿本身是平仓单，不再反向平掉，未完成的数量直接记入未对冲敞口
+func (e *ArbEngine) recoverApexLeg(dir ArbDirection, oppMs int64, apexFill, bybitFill legFill, remaining float64, reduceOnly bool) {
+	incidents := e.unhedgedIncidents.Add(1)
+	lg := slog.With("direction", dir.tag(), "incident", incidents)
+	lg.Warn("[对冲恢复] B所对冲腿孤立", "exchange", e.exB.Name(), "remaining", e.formatSize(remaining))
+
+	apexSide, _ := dir.sides()
+	delay := time.Duration(e.cfg.Strategy.HedgeRetryDelayMs) * time.Millisecond
+
+	for i := 1; i <= e.cfg.Strategy.HedgeRetryCount && remaining > 0; i++ {
+		select {
+		case <-e.stopCh:
+			lg.Warn("[对冲恢复] 引擎停止，中断恢复流程（注意风险）", "remaining", e.formatSize(remaining))
+			e.addUnhedged(dir, -remaining)
+			return
+		case <-time.After(delay):
+		}
+
+		order, err := e.placeOrder(e.exA, &exchange.OrderRequest{
+			Side:        apexSide,
+			Type:        exchange.Limit,
+			Qty:         e.formatSize(remaining),
+			Price:       e.formatApexPrice(e.apexRetryPrice(dir), apexSide),
+			TimeInForce: exchange.IOC,
+			ReduceOnly:  reduceOnly,
+			ClientID:    e.clientID(oppMs, dir, fmt.Sprintf("apex%d", i)),
+		})
+		if err != nil {
+			lg.Warn("[对冲恢复] 重试 A所腿失败", "attempt", i, "max", e.cfg.Strategy.HedgeRetryCount, "err", err)
+			continue
+		}
+		fill := e.apexFill(e.ctx, order)
+		if fill.qty <= 0 {
+			lg.Info("[对冲恢复] 重试 A所腿未成交", "attempt", i, "max", e.cfg.Strategy.HedgeRetryCount, "order_id", order.ID)
+			continue
+		}
+
+		e.posMu.Lock()
+		e.position += dir.sign() * fill.qty
+		e.posMu.Unlock()
+		apexFill.add(fill)
+		remaining = e.roundSize(remaining - fill.qty)
+		lg.Info("[对冲恢复] 重试 A所腿成交", "attempt", i, "order_id", order.ID,
+			"filled", e.formatSize(fill.qty), "remaining", e.formatSize(remaining))
+	}
+
+	// 两腿都成交的部分按实际成交计算；A所完全未成交时仍需计入 B所开仓手续费
+	pnl := -bybitFill.fee
+	if apexFill.qty > 0 {
+		pnl = realizedPnL(dir, apexFill, bybitFill)
+	}
+
+	if remaining > 0 && reduceOnly {
+		lg.Error("[对冲恢复] 减仓 A所腿未完成，B所腿已平仓不可撤回，记入未对冲敞口（注意风险）", "remaining", e.formatSize(remaining))
+		alert.Critical("unhedged", "减仓恢复失败：%s %s 腿仍有 %s 未平，请人工处理", dir, e.exA.Name(), e.formatSize(remaining))
+		// B所腿已平而 A所腿未平：等价于 A所在 dir 反方向的持仓未被抵消
+		e.addUnhedged(dir, -remaining)
+	} else if remaining > 0 {
+		lg.Warn("[对冲恢复] 重试 A所腿未完成，平掉 B所腿剩余", "remaining", e.formatSize(remaining))
+		closed, closedQty := e.unwindBybitLeg(dir, bybitFill, remaining)
+		pnl += closed
+		if left := e.roundSize(remaining - closedQty); left > 0 {
+			lg.Error("[对冲恢复] B所腿仍有未平数量，记入未对冲敞口（注意风险）", "left", e.formatSize(left))
+			alert.Critical("unhedged", "对冲恢复失败：%s %s 腿仍有 %s 未平，请人工处理", dir, e.exB.Name(), e.formatSize(left))
+			// 未对冲敞口按 A所方向记录：孤立的 B所腿等价于 A所反向持仓
+			e.addUnhedged(dir, -left)
+		}
+	}
+
+	e.bookPnL(dir, pnl, "对冲恢复")
+}
+
+// apexRetryPrice 按最新 A所报价计算重试价，允许 hedge_slippage_usdc 的滑点
+func (e *ArbEngine) apexRetryPrice(dir ArbDirection) float64 {
+	q := e.apexTop()
+	if dir == DirectionLong {
+		// A所买入：吃卖一
+		return q.ask + e.hedgeSlippage(q.ask)
+	}
+	// A所卖出：吃买一
+	return q.bid - e.hedgeSlippage(q.bid)
+}
+
+// unwindBybitLeg 以 reduce-only 市价单平掉 B所对冲腿 qty，返回平仓盈亏（含平仓手续费）与实际平仓数量
+// 失败时记录日志并返回已知的结果，由调用方记入未对冲敞口
+func (e *ArbEngine) unwindBybitLeg(dir ArbDirection, entry legFill, qty float64) (float64, float64) {
+	_, bybitSide := dir.sides()
+	side := exchange.Buy
+	if bybitSide == exchange.Buy {
+		side = exchange.Sell
+	}
+	order, err := e.bybitMarketOrder(e.ctx, side, qty, true, false)
+	if err != nil {
+		slog.Error("[对冲恢复] B所腿平仓失败", "exchange", e.exB.Name(), "err", err)
+		return 0, 0
+	}
+	fill, err := e.bybitFill(order.ID)
+	if err != nil {
+		// 下单已成功但成交未知：按全部平仓处理，避免重复记入敞口，由日终持仓核对兜底
+		slog.Error("[对冲恢复] B所平仓单成交未知（注意核对持仓）", "exchange", e.exB.Name(), "order_id", order.ID, "err", err)
+		return 0, qty
+	}
+
+	// B所腿方向与 A所相反：场景1 B所为空头，平仓盈亏 = (开仓价 - 平仓价) × 数量
+	pnl := -dir.sign()*(fill.avgPrice-entry.avgPrice)*fill.qty - fill.fee
+	slog.Info("[对冲恢复] B所平仓成交", "exchange", e.exB.Name(), "order_id", order.ID,
+		"filled", e.formatSize(fill.qty), "avg_price", fill.avgPrice, "pnl", pnl)
+	return pnl, math.Min(fill.qty, qty)
+}
@@ -0,0 +1,78 @@
+package strategy
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"arb/alert"
+)
+
+// holdingCheckInterval 检查持仓时长的间隔，也是记录建仓时间的精度
+const holdingCheckInterval = time.Second
+
+// holdingLoop 跟踪当前净持仓的建仓时间（从空仓变为有持仓的时刻），持仓时长超过 max_holding_seconds 时
+// 不论价差如何，以 reduce-only 市价单平掉两所持仓；暂只按整个净持仓中最早的一笔计算时长
+func (e *ArbEngine) holdingLoop() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(holdingCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case now := <-ticker.C:
+			e.checkHolding(now)
+		}
+	}
+}
+
+// checkHolding 更新建仓时间并在超时后强制平仓
+func (e *ArbEngine) checkHolding(now time.Time) {
+	e.posMu.Lock()
+	pos := e.position
+	e.posMu.Unlock()
+
+	if math.Abs(pos) < e.sizeStep() {
+		e.positionSince.Store(0)
+		return
+	}
+	since := e.positionSince.Load()
+	if since == 0 {
+		// 启动时恢复的持仓无法得知真实建仓时间，从首次观察到的时刻开始计时
+		e.positionSince.Store(now.UnixNano())
+		return
+	}
+
+	maxHolding := time.Duration(e.cfg.Strategy.MaxHoldingSeconds) * time.Second
+	held := now.Sub(time.Unix(0, since))
+	if held < maxHolding {
+		return
+	}
+
+	// 与套利检测互斥，避免平仓与开仓交叉；检测进行中时下个周期重试
+	if !e.checking.CompareAndSwap(false, true) {
+		return
+	}
+	defer e.checking.Store(false)
+
+	slog.Warn("[持仓超时] 持仓时间超过 max_holding_seconds，强制平仓", "position", pos, "held", held.Round(time.Second),
+		"max_holding_seconds", e.cfg.Strategy.MaxHoldingSeconds)
+	ctx, cancel := context.WithTimeout(e.ctx, e.flattenTimeout())
+	defer cancel()
+	pnl, ok := e.flattenAll(ctx, "[持仓超时]")
+	e.positionSince.Store(0)
+	if !ok {
+		// 交易所无持仓：本地记录已过期，以交易所为准
+		e.posMu.Lock()
+		e.position = 0
+		e.posMu.Unlock()
+		return
+	}
+	e.saveState()
+	alert.Warn("max_holding", "持仓 %.4f 已持有 %v，超过最长持仓时间，已强制平仓（PnL=%.4f USDC）",
+		pos, held.Round(time.Second), pnl)
+}
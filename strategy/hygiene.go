@@ -0,0 +1,270 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"arb/alert"
+	"arb/exchange"
+	"arb/internal/atomicfile"
+	"arb/logging"
+)
+
+// hygieneLoop 每日在 hygiene.run_at（reset_timezone 时区）执行一次日终维护
+func (e *ArbEngine) hygieneLoop() {
+	defer e.wg.Done()
+
+	for {
+		next, err := nextDailyRun(time.Now().In(e.riskCtrl.Location()), e.cfg.Hygiene.RunAt)
+		if err != nil {
+			slog.Error("[日终] run_at 配置无效，日终维护已禁用", "err", err)
+			return
+		}
+		slog.Info("[日终] 下次日终维护时间", "at", next.Format("2006-01-02 15:04:05 MST"))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-e.stopCh:
+			timer.Stop()
+			return
+		case <-timer.C:
+			e.runHygiene(next)
+		}
+	}
+}
+
+// hygieneStep 日终维护步骤：run 返回用于告警的结果摘要
+type hygieneStep struct {
+	name string
+	run  func() (string, error)
+}
+
+// runHygiene 依次执行日终维护步骤，各步骤独立记录日志并推送告警，失败不影响后续步骤
+// now 所在交易日（reset_timezone 日切）为本次维护的交易日
+func (e *ArbEngine) runHygiene(now time.Time) {
+	if e.riskCtrl.IsHalted() {
+		slog.Warn("[日终] 引擎处于熔断状态，跳过本次日终维护")
+		alert.Warn("hygiene", "引擎处于熔断状态，跳过日终维护")
+		return
+	}
+
+	day := e.tradingDayStart(now)
+	slog.Info("[日终] 开始日终维护", "day", day.Format("2006-01-02"))
+	steps := []hygieneStep{
+		{"撤销过期挂单", e.cancelStaleOrders},
+		{"核对持仓", e.repairPositionDelta},
+	}
+	if e.journal != nil {
+		steps = append(steps, hygieneStep{"归档交易流水", func() (string, error) { return e.archiveJournal(day) }})
+	}
+	steps = append(steps, hygieneStep{"日报", func() (string, error) { return e.emitDailyReport(day) }})
+	if e.cfg.Logging.File != "" {
+		steps = append(steps, hygieneStep{"轮转日志", func() (string, error) { return rotateLogs(day) }})
+	}
+	if e.journal != nil {
+		steps = append(steps, hygieneStep{"清理交易流水库", func() (string, error) { return e.pruneJournal(day) }})
+	}
+	if e.cfg.Strategy.StateFile != "" || e.cfg.RiskControl.StateFile != "" {
+		steps = append(steps, hygieneStep{"状态文件快照", func() (string, error) { return e.snapshotState(day) }})
+	}
+
+	failed := 0
+	for _, step := range steps {
+		summary, err := step.run()
+		if err != nil {
+			failed++
+			slog.Error("[日终] 维护步骤失败", "step", step.name, "err", err)
+			alert.Warn("hygiene:"+step.name, "日终维护「%s」失败: %v", step.name, err)
+			continue
+		}
+		slog.Info("[日终] 维护步骤成功", "step", step.name, "result", summary)
+		alert.Info("hygiene:"+step.name, "日终维护「%s」完成: %s", step.name, summary)
+	}
+	slog.Info("[日终] 日终维护结束", "succeeded", len(steps)-failed, "failed", failed)
+}
+
+// cancelStaleOrders 撤销两所挂单时间超过 stale_order_sec 的订单
+func (e *ArbEngine) cancelStaleOrders() (string, error) {
+	maxAge := time.Duration(e.cfg.Hygiene.StaleOrderSec) * time.Second
+	now := time.Now()
+	var errs []error
+	canceled := 0
+
+	for _, ex := range []exchange.Exchange{e.exB, e.exA} {
+		orders, err := ex.OpenOrders(e.ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("查询 %s 挂单失败: %w", ex.Name(), err))
+		}
+		for _, o := range orders {
+			if now.Sub(o.CreatedAt) < maxAge {
+				continue
+			}
+			if err := ex.CancelOrder(e.ctx, o.ID); err != nil {
+				errs = append(errs, fmt.Errorf("撤销 %s 订单 %s 失败: %w", ex.Name(), o.ID, err))
+				continue
+			}
+			canceled++
+			slog.Info("[日终] 已撤销过期挂单", "exchange", ex.Name(), "order_id", o.ID)
+		}
+	}
+
+	return fmt.Sprintf("撤销 %d 笔过期挂单", canceled), errors.Join(errs...)
+}
+
+// repairPositionDelta 对比本地持仓与交易所持仓，偏差不超过 max_repair_delta 时以交易所为准修正
+func (e *ArbEngine) repairPositionDelta() (string, error) {
+	apexNet, err := e.apexNetPosition(e.ctx)
+	if err != nil {
+		return "", err
+	}
+	bybitNet, err := e.bybitNetPosition(e.ctx)
+	if err != nil {
+		return "", err
+	}
+
+	e.posMu.Lock()
+	defer e.posMu.Unlock()
+
+	delta := apexNet - e.position
+	slog.Info("[日终] 持仓核对", "local", e.position, "net_a", apexNet, "net_b", bybitNet, "diff", delta)
+
+	if math.Abs(delta) < e.sizeStep() {
+		return fmt.Sprintf("持仓一致（%s）", e.formatSize(apexNet)), nil
+	}
+	if math.Abs(delta) > e.cfg.Hygiene.MaxRepairDelta {
+		return "", fmt.Errorf("持仓偏差 %.4f 超过自动修正上限 %.4f，需人工核对", delta, e.cfg.Hygiene.MaxRepairDelta)
+	}
+
+	slog.Warn("[日终] 修正本地持仓", "from", e.position, "to", apexNet)
+	summary := fmt.Sprintf("本地持仓 %s 修正为 %s", e.formatSize(e.position), e.formatSize(apexNet))
+	e.position = apexNet
+	return summary, nil
+}
+
+// archiveJournal 将交易日 day 的交易流水导出到 archive_dir/journal-YYYYMMDD.ndjson
+func (e *ArbEngine) archiveJournal(day time.Time) (string, error) {
+	dir := e.hygieneArchiveDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("创建归档目录 %s 失败: %w", dir, err)
+	}
+	path := filepath.Join(dir, "journal-"+day.Format("20060102")+".ndjson")
+	n, err := e.journal.Archive(day, day.AddDate(0, 0, 1), path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("归档 %d 条记录到 %s", n, path), nil
+}
+
+// emitDailyReport 输出交易日 day 的统计；日内计数由日切清零，这里只读取
+func (e *ArbEngine) emitDailyReport(day time.Time) (string, error) {
+	e.pnlMu.Lock()
+	trades, wins := e.dailyTrades, e.dailyWins
+	if e.dailyStart.Before(day) {
+		// 当日尚无成交，计数仍属于之前的交易日
+		trades, wins = 0, 0
+	}
+	totalPnL := e.totalPnL
+	e.pnlMu.Unlock()
+
+	e.posMu.Lock()
+	pos := e.position
+	e.posMu.Unlock()
+
+	winRate := 0.0
+	if trades > 0 {
+		winRate = float64(wins) / float64(trades) * 100
+	}
+	dailyPnL := e.riskCtrl.DailyPnL()
+	slog.Info("[日报] 当日汇总", "day", day.Format("2006-01-02"), "trades", trades, "win_rate_pct", winRate, "daily_pnl", dailyPnL,
+		"total_pnl", totalPnL, "position", pos, "unhedged_incidents", e.unhedgedIncidents.Load())
+	return fmt.Sprintf("%s 成交 %d 笔，胜率 %.1f%%，当日PnL %.4f，累计PnL %.4f，持仓 %s",
+		day.Format("2006-01-02"), trades, winRate, dailyPnL, totalPnL, e.formatSize(pos)), nil
+}
+
+// rotateLogs 将日志文件重命名为带交易日日期的备份并重新打开
+func rotateLogs(day time.Time) (string, error) {
+	backup, err := logging.Rotate(day)
+	if err != nil {
+		return "", err
+	}
+	return "日志已轮转到 " + backup, nil
+}
+
+// pruneJournal 删除 journal_retention_days 之前的交易流水并 VACUUM
+func (e *ArbEngine) pruneJournal(day time.Time) (string, error) {
+	var before time.Time
+	if days := e.cfg.Hygiene.JournalRetentionDays; days > 0 {
+		before = day.AddDate(0, 0, 1-days)
+	}
+	n, err := e.journal.Prune(before)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("删除 %d 条过期记录并 VACUUM", n), nil
+}
+
+// snapshotState 将引擎与风控状态文件复制为 archive_dir/<文件名>.YYYYMMDD
+func (e *ArbEngine) snapshotState(day time.Time) (string, error) {
+	// 先落盘当前状态，快照与内存一致
+	e.saveState()
+
+	dir := e.hygieneArchiveDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("创建归档目录 %s 失败: %w", dir, err)
+	}
+	var saved []string
+	var errs []error
+	for _, path := range []string{e.cfg.Strategy.StateFile, e.cfg.RiskControl.StateFile} {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("读取状态文件 %s 失败: %w", path, err))
+			continue
+		}
+		dst := filepath.Join(dir, filepath.Base(path)+"."+day.Format("20060102"))
+		if err := atomicfile.Write(dst, data); err != nil {
+			errs = append(errs, fmt.Errorf("写入状态快照 %s 失败: %w", dst, err))
+			continue
+		}
+		saved = append(saved, dst)
+	}
+	return "快照: " + strings.Join(saved, ", "), errors.Join(errs...)
+}
+
+// hygieneArchiveDir 返回归档目录，未配置时为 archive
+func (e *ArbEngine) hygieneArchiveDir() string {
+	if e.cfg.Hygiene.ArchiveDir == "" {
+		return "archive"
+	}
+	return e.cfg.Hygiene.ArchiveDir
+}
+
+// tradingDayStart 返回 t 所在交易日（reset_timezone 日切）的 0 点
+func (e *ArbEngine) tradingDayStart(t time.Time) time.Time {
+	t = t.In(e.riskCtrl.Location())
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// nextDailyRun 计算 now 所在时区下一个 HH:MM 的时间点
+func nextDailyRun(now time.Time, hhmm string) (time.Time, error) {
+	t, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next, nil
+}
@@ -0,0 +1,176 @@
+package strategy
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"arb/config"
+	"arb/logging"
+	"arb/store"
+)
+
+// TestRunHygiene 日终维护：撤销过期挂单、修正小额持仓偏差、归档流水、日报、轮转日志、清理流水库、状态快照
+func TestRunHygiene(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig()
+	cfg.Hygiene = config.HygieneConfig{
+		StaleOrderSec:        300,
+		MaxRepairDelta:       0.002,
+		ArchiveDir:           filepath.Join(dir, "archive"),
+		JournalRetentionDays: 1,
+	}
+	cfg.Logging.File = filepath.Join(dir, "arb.log")
+	cfg.Strategy.StateFile = filepath.Join(dir, "engine_state.json")
+	cfg.RiskControl.StateFile = filepath.Join(dir, "risk_state.json")
+
+	if err := logging.Setup(cfg.Logging); err != nil {
+		t.Fatalf("初始化日志失败: %v", err)
+	}
+	t.Cleanup(func() { logging.Setup(config.LoggingConfig{}) })
+
+	e, exA, exB := newTestEngine(t, cfg)
+	j, err := store.Open(filepath.Join(dir, "journal.db"), 0)
+	if err != nil {
+		t.Fatalf("打开交易流水失败: %v", err)
+	}
+	t.Cleanup(j.Close)
+	e.journal = j
+
+	now := time.Now()
+	old := now.AddDate(0, 0, -3)
+	j.RecordTrade(store.TradeRecord{Time: old, Direction: "long", VenueA: exA.Name(), VenueB: exB.Name()})
+	setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+	e.checkAndTrade()
+	waitJournal(t, j, old, 1)
+	waitJournal(t, j, now, 1)
+
+	exA.addOpenOrder("a-stale", now.Add(-10*time.Minute))
+	exB.addOpenOrder("b-stale", now.Add(-10*time.Minute))
+	exB.addOpenOrder("b-fresh", now.Add(-time.Minute))
+	// A所有一笔未记录的 0.001 成交
+	exA.setPosition(0.101)
+
+	e.runHygiene(now)
+
+	if got := exA.canceledIDs(); !slices.Equal(got, []string{"a-stale"}) {
+		t.Fatalf("A所撤单 = %v，期望 [a-stale]", got)
+	}
+	if got := exB.canceledIDs(); !slices.Equal(got, []string{"b-stale"}) {
+		t.Fatalf("B所撤单 = %v，期望 [b-stale]", got)
+	}
+	if pos, _ := enginePosition(e); !approx(pos, 0.101) {
+		t.Fatalf("修正后引擎持仓 = %v，期望 0.101", pos)
+	}
+
+	date := now.UTC().Format("20060102")
+	if n := countLines(t, filepath.Join(cfg.Hygiene.ArchiveDir, "journal-"+date+".ndjson")); n != 1 {
+		t.Fatalf("归档记录 %d 条，期望当日 1 条", n)
+	}
+	if records, err := j.Trades(old); err != nil || len(records) != 0 {
+		t.Fatalf("超过保留天数的记录应被删除: %d 条, err=%v", len(records), err)
+	}
+	if records, err := j.Trades(now); err != nil || len(records) != 1 {
+		t.Fatalf("当日记录应保留: %d 条, err=%v", len(records), err)
+	}
+
+	e.pnlMu.Lock()
+	trades := e.dailyTrades
+	e.pnlMu.Unlock()
+	if trades != 1 {
+		t.Fatalf("日报后日内成交数 = %d，不应清零", trades)
+	}
+
+	for _, path := range []string{
+		cfg.Logging.File + "." + date,
+		cfg.Logging.File,
+		filepath.Join(cfg.Hygiene.ArchiveDir, "engine_state.json."+date),
+		filepath.Join(cfg.Hygiene.ArchiveDir, "risk_state.json."+date),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("缺少日终维护产物 %s: %v", path, err)
+		}
+	}
+}
+
+func TestRunHygieneSkipsWhenHalted(t *testing.T) {
+	cfg := testConfig()
+	cfg.Hygiene.StaleOrderSec = 300
+	e, exA, _ := newTestEngine(t, cfg)
+	exA.addOpenOrder("a-stale", time.Now().Add(-time.Hour))
+	e.riskCtrl.Halt("测试")
+
+	e.runHygiene(time.Now())
+
+	if got := exA.canceledIDs(); len(got) != 0 {
+		t.Fatalf("熔断时不应执行日终维护，实际撤单 %v", got)
+	}
+}
+
+func TestNextDailyRunUsesResetTimezone(t *testing.T) {
+	shanghai := time.FixedZone("UTC+8", 8*3600)
+	// UTC 15:30 即日切时区 23:30，当日 23:55 尚未到
+	now := time.Date(2026, 10, 15, 15, 30, 0, 0, time.UTC).In(shanghai)
+	next, err := nextDailyRun(now, "23:55")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 10, 15, 15, 55, 0, 0, time.UTC); !next.Equal(want) {
+		t.Fatalf("下次执行 = %v，期望 %v", next.UTC(), want)
+	}
+
+	// UTC 16:30 已是日切时区次日 00:30
+	next, _ = nextDailyRun(now.Add(time.Hour), "23:55")
+	if want := time.Date(2026, 10, 16, 15, 55, 0, 0, time.UTC); !next.Equal(want) {
+		t.Fatalf("下次执行 = %v，期望 %v", next.UTC(), want)
+	}
+}
+
+func TestDailyStatsRollAtDayBoundary(t *testing.T) {
+	e, _, _ := newTestEngine(t, testConfig())
+	e.pnlMu.Lock()
+	e.dailyStart = e.tradingDayStart(time.Now()).AddDate(0, 0, -1)
+	e.dailyTrades, e.dailyWins = 5, 3
+	e.pnlMu.Unlock()
+
+	e.bookPnL(DirectionLong, -1, "测试")
+
+	e.pnlMu.Lock()
+	defer e.pnlMu.Unlock()
+	if e.dailyTrades != 1 || e.dailyWins != 0 {
+		t.Fatalf("跨日后日内成交 / 盈利 = %d / %d，期望 1 / 0", e.dailyTrades, e.dailyWins)
+	}
+}
+
+// waitJournal 等待异步写入的流水在 day 当天至少有 n 条
+func waitJournal(t *testing.T, j *store.Store, day time.Time, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		records, err := j.Trades(day)
+		if err == nil && len(records) >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("等待交易流水写入超时（%s）", day.Format("2006-01-02"))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("打开 %s 失败: %v", path, err)
+	}
+	defer f.Close()
+	n := 0
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,200 @@
+package strategy
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"arb/alert"
+	"arb/exchange"
+	"arb/metrics"
+)
+
+// defaultImbalanceCheckInterval 未配置 imbalance_check_interval_sec 时核对两所真实持仓的间隔
+const defaultImbalanceCheckInterval = 30 * time.Second
+
+// imbalanceInterval 返回两所持仓核对间隔：0 使用默认值，负数表示不启用
+func (e *ArbEngine) imbalanceInterval() time.Duration {
+	sec := e.cfg.Strategy.ImbalanceCheckIntervalSec
+	if sec == 0 {
+		return defaultImbalanceCheckInterval
+	}
+	if sec < 0 {
+		return -1
+	}
+	return time.Duration(sec) * time.Second
+}
+
+// maxImbalance 返回允许的两所净持仓之和上限，未配置时为一个数量步长（任何偏差都视为不平衡）
+func (e *ArbEngine) maxImbalance() float64 {
+	if v := e.cfg.Strategy.MaxImbalance; v > 0 {
+		return v
+	}
+	return e.sizeStep()
+}
+
+// imbalanceState 最近一次两所持仓核对结果（状态日志与管理接口展示）
+type imbalanceState struct {
+	qty      float64 // 两所净持仓之和（合约张数）
+	notional float64 // 按两所中间价折算的名义敞口（USDC，带方向）
+	at       time.Time
+}
+
+// imbalanceLoop 对冲模式下定时查询两所真实持仓：A所与 B所净持仓之和（净 delta）应接近 0，
+// 合约数超过 max_imbalance 或名义敞口超过 max_unhedged_notional_usdc 时暂停开仓并告警，恢复平衡后自动解除
+func (e *ArbEngine) imbalanceLoop() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.imbalanceInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case <-ticker.C:
+			e.checkImbalance()
+		}
+	}
+}
+
+// checkImbalance 核对一次两所真实持仓
+func (e *ArbEngine) checkImbalance() {
+	// 与套利检测互斥：下单过程中两腿先后成交，此时查询会得到暂时的不平衡；检测进行中时跳过本轮
+	if !e.checking.CompareAndSwap(false, true) {
+		return
+	}
+	defer e.checking.Store(false)
+
+	apexNet, err := e.apexNetPosition(e.ctx)
+	if err != nil {
+		slog.Warn("[持仓核对] 查询持仓失败", "exchange", e.exA.Name(), "err", err)
+		return
+	}
+	bybitNet, err := e.bybitNetPosition(e.ctx)
+	if err != nil {
+		slog.Warn("[持仓核对] 查询持仓失败", "exchange", e.exB.Name(), "err", err)
+		return
+	}
+
+	e.posMu.Lock()
+	local := e.position
+	e.posMu.Unlock()
+
+	imbalance := e.roundSize(apexNet + bybitNet)
+	notional := imbalance * e.midPrice()
+	metrics.PositionImbalance.Set(imbalance)
+	metrics.PositionImbalanceNotional.Set(notional)
+	e.imbalance.Store(imbalanceState{qty: imbalance, notional: notional, at: time.Now()})
+
+	maxNotional := e.cfg.Strategy.MaxUnhedgedNotionalUSDC
+	overNotional := maxNotional > 0 && math.Abs(notional) > maxNotional
+	if overNotional && e.cfg.Strategy.AutoRehedge {
+		e.rehedge(apexNet, bybitNet, imbalance)
+	}
+
+	block := math.Abs(imbalance) >= e.maxImbalance() || overNotional
+	if e.imbalanceBlocked.Swap(block) == block {
+		return
+	}
+	if block {
+		slog.Warn("[持仓核对] 两所持仓不平衡，暂停开仓", "net_a", apexNet, "net_b", bybitNet, "imbalance", imbalance,
+			"notional_usdc", notional, "max_imbalance", e.maxImbalance(), "max_notional_usdc", maxNotional, "local", local)
+		alert.Critical("imbalance", "两所持仓不平衡 %.4f（名义 %.2f USDC，%s=%.4f %s=%.4f），已暂停开仓，请核对持仓",
+			imbalance, notional, e.exA.Name(), apexNet, e.exB.Name(), bybitNet)
+		return
+	}
+	slog.Info("[持仓核对] 两所持仓恢复平衡，恢复开仓", "net_a", apexNet, "net_b", bybitNet)
+}
+
+// midPrice 返回两所盘口中间价的均值，只有一所盘口可用时使用该所
+func (e *ArbEngine) midPrice() float64 {
+	var sum float64
+	var n int
+	for _, q := range []quote{e.apexTop(), e.bybitTop()} {
+		if q.bid > 0 && q.ask > 0 {
+			sum += (q.bid + q.ask) / 2
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// rehedge 以 reduce-only 市价单平掉多出的敞口：净多头时在持多头的一所卖出，净空头时在持空头的一所买入；
+// 两所都可减仓时选择成交价更优的一所（卖出取买一较高者，买入取卖一较低者）。
+// 下单结果由下一轮核对确认，本地持仓记录由持仓对账修正
+func (e *ArbEngine) rehedge(apexNet, bybitNet, imbalance float64) {
+	side := exchange.Sell
+	if imbalance < 0 {
+		side = exchange.Buy
+	}
+
+	type candidate struct {
+		ex    exchange.Exchange
+		net   float64
+		price float64 // 对手价
+	}
+	var best *candidate
+	for _, c := range []candidate{
+		{e.exA, apexNet, sidePrice(e.apexTop(), side)},
+		{e.exB, bybitNet, sidePrice(e.bybitTop(), side)},
+	} {
+		// reduce-only 只能减少与下单方向相反的持仓
+		if c.price <= 0 || c.net == 0 || (c.net > 0) != (side == exchange.Sell) {
+			continue
+		}
+		if best == nil || (side == exchange.Sell && c.price > best.price) || (side == exchange.Buy && c.price < best.price) {
+			c := c
+			best = &c
+		}
+	}
+	if best == nil {
+		slog.Warn("[持仓核对] 自动补对冲：没有可 reduce-only 的持仓，需人工处理", "side", side)
+		return
+	}
+
+	qty := e.roundSize(math.Min(math.Abs(imbalance), math.Abs(best.net)))
+	if qty < e.minOrderSize() {
+		slog.Info("[持仓核对] 自动补对冲：数量低于最小下单量，跳过", "size", qty, "min_size", e.minOrderSize())
+		return
+	}
+	price := best.price - e.hedgeSlippage(best.price)
+	if side == exchange.Buy {
+		price = best.price + e.hedgeSlippage(best.price)
+	}
+	formatted := e.formatBybitPrice(price, side)
+	if best.ex == e.exA {
+		formatted = e.formatApexPrice(price, side)
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, e.flattenTimeout())
+	defer cancel()
+	order, err := best.ex.PlaceOrder(ctx, &exchange.OrderRequest{
+		Side:        side,
+		Type:        exchange.Market,
+		Qty:         e.formatSize(qty),
+		Price:       formatted,
+		TimeInForce: exchange.IOC,
+		ReduceOnly:  true,
+	})
+	if err != nil {
+		slog.Error("[持仓核对] 自动补对冲下单失败", "exchange", best.ex.Name(), "side", side, "size", e.formatSize(qty), "err", err)
+		alert.Critical("rehedge", "%s 自动补对冲 %s %s 失败: %v，请人工处理", best.ex.Name(), side, e.formatSize(qty), err)
+		return
+	}
+	slog.Info("[持仓核对] 自动补对冲 reduce-only 已下单", "exchange", best.ex.Name(), "side", side, "size", e.formatSize(qty),
+		"price", formatted, "order_id", order.ID, "imbalance", imbalance)
+	alert.Warn("rehedge", "%s 自动补对冲 reduce-only %s %s（净敞口 %.4f）", best.ex.Name(), side, e.formatSize(qty), imbalance)
+}
+
+// sidePrice 返回 side 方向吃单的对手价：卖出取买一，买入取卖一
+func sidePrice(q quote, side exchange.Side) float64 {
+	if side == exchange.Sell {
+		return q.bid
+	}
+	return q.ask
+}
@@ -0,0 +1,142 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"arb/exchange"
+)
+
+// instrumentTimeout 启动时查询交易对规格的超时时间
+const instrumentTimeout = 10 * time.Second
+
+// instrumentSpec 两所交易对规格合并后的下单约束（启动时写入，之后只读）
+type instrumentSpec struct {
+	apexTick    float64 // A所价格步长
+	bybitTick   float64 // B所价格步长
+	qtyStep     float64 // 两所数量步长中较大者，下单量同时满足两所
+	minQty      float64 // 两所最小下单量中较大者
+	maxQty      float64 // 两所最大下单量中较小者（0=不限制）
+	minNotional float64 // 两所最小下单名义价值中较大者（0=不限制）
+}
+
+// loadInstruments 查询两所交易对规格，失败时回退到配置中的 price_precision / size_precision
+func (e *ArbEngine) loadInstruments() {
+	ctx, cancel := context.WithTimeout(e.ctx, instrumentTimeout)
+	defer cancel()
+
+	apexInfo, err := e.exA.Instrument(ctx)
+	if err != nil {
+		slog.Warn("[规格] 获取交易对规格失败，使用配置精度", "exchange", e.exA.Name(), "err", err)
+		return
+	}
+	bybitInfo, err := e.exB.Instrument(ctx)
+	if err != nil {
+		slog.Warn("[规格] 获取交易对规格失败，使用配置精度", "exchange", e.exB.Name(), "err", err)
+		return
+	}
+
+	spec := instrumentSpec{
+		apexTick:    apexInfo.TickSize,
+		bybitTick:   bybitInfo.TickSize,
+		qtyStep:     math.Max(apexInfo.QtyStep, bybitInfo.QtyStep),
+		minQty:      math.Max(apexInfo.MinQty, bybitInfo.MinQty),
+		maxQty:      apexInfo.MaxQty,
+		minNotional: math.Max(apexInfo.MinNotional, bybitInfo.MinNotional),
+	}
+	if spec.maxQty <= 0 || (bybitInfo.MaxQty > 0 && bybitInfo.MaxQty < spec.maxQty) {
+		spec.maxQty = bybitInfo.MaxQty
+	}
+	e.spec = spec
+
+	for _, v := range []struct {
+		ex   exchange.Exchange
+		info *exchange.Instrument
+	}{{e.exA, apexInfo}, {e.exB, bybitInfo}} {
+		slog.Info("[规格] 交易对规格", "exchange", v.ex.Name(), "symbol", v.ex.Symbol(), "tick_size", v.info.TickSize,
+			"qty_step", v.info.QtyStep, "min_qty", v.info.MinQty, "max_qty", v.info.MaxQty)
+	}
+	if e.cfg.Strategy.OrderSize < spec.minQty {
+		slog.Warn("[规格] order_size 低于交易所最小下单量，将无法下单", "order_size", e.cfg.Strategy.OrderSize, "min_qty", spec.minQty)
+	}
+	e.checkMinNotional(ctx)
+}
+
+// checkMinNotional 按 A所当前中间价估算 order_size 的名义价值，低于交易所最小名义价值时告警
+func (e *ArbEngine) checkMinNotional(ctx context.Context) {
+	if e.spec.minNotional <= 0 {
+		return
+	}
+	bp, err := e.exA.BestPrice(ctx)
+	if err != nil || bp.Bid <= 0 || bp.Ask <= 0 {
+		slog.Warn("[规格] 获取盘口失败，跳过最小名义价值校验", "exchange", e.exA.Name(), "err", err)
+		return
+	}
+	mid := (bp.Bid + bp.Ask) / 2
+	if notional := e.cfg.Strategy.OrderSize * mid; notional < e.spec.minNotional {
+		slog.Warn("[规格] order_size 名义价值低于交易所最小名义价值，将无法下单", "order_size", e.cfg.Strategy.OrderSize,
+			"mid", mid, "notional", notional, "min_notional", e.spec.minNotional)
+	}
+}
+
+// sizeStep 返回数量步长：优先使用交易所规格，否则按 size_precision
+func (e *ArbEngine) sizeStep() float64 {
+	if e.spec.qtyStep > 0 {
+		return e.spec.qtyStep
+	}
+	return math.Pow(10, -float64(e.cfg.Strategy.SizePrecision))
+}
+
+// minOrderSize 返回有效最小下单量：配置值与交易所最小下单量取较大者
+func (e *ArbEngine) minOrderSize() float64 {
+	return math.Max(e.cfg.Strategy.MinOrderSize, e.spec.minQty)
+}
+
+// roundSize 将数量向下取整到数量步长，避免对冲量超过实际成交量
+func (e *ArbEngine) roundSize(size float64) float64 {
+	step := e.sizeStep()
+	return math.Floor(size/step+1e-9) * step
+}
+
+func (e *ArbEngine) formatSize(size float64) string {
+	return fmt.Sprintf("%.*f", stepDecimals(e.sizeStep(), e.cfg.Strategy.SizePrecision), size)
+}
+
+// formatApexPrice 按 A所价格步长取整，买单向上、卖单向下，保证 IOC 限价不弱于报价
+func (e *ArbEngine) formatApexPrice(price float64, side exchange.Side) string {
+	return e.formatPrice(price, e.spec.apexTick, side)
+}
+
+// formatBybitPrice 按 B所价格步长取整，规则同 formatApexPrice
+func (e *ArbEngine) formatBybitPrice(price float64, side exchange.Side) string {
+	return e.formatPrice(price, e.spec.bybitTick, side)
+}
+
+func (e *ArbEngine) formatPrice(price, tick float64, side exchange.Side) string {
+	if tick <= 0 {
+		return fmt.Sprintf("%.*f", e.cfg.Strategy.PricePrecision, price)
+	}
+	if side == exchange.Buy {
+		price = math.Ceil(price/tick-1e-9) * tick
+	} else {
+		price = math.Floor(price/tick+1e-9) * tick
+	}
+	return fmt.Sprintf("%.*f", stepDecimals(tick, e.cfg.Strategy.PricePrecision), price)
+}
+
+// stepDecimals 返回步长的小数位数（如 0.001 → 3，0.5 → 1），step 无效时返回 fallback
+func stepDecimals(step float64, fallback int) int {
+	if step <= 0 {
+		return fallback
+	}
+	s := strconv.FormatFloat(step, 'f', -1, 64)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
@@ -0,0 +1,104 @@
+package strategy
+
+import (
+	"testing"
+
+	"arb/config"
+	"arb/exchange"
+)
+
+// specEngine 仅带规格与精度配置的引擎，用于取整测试
+func specEngine(spec instrumentSpec) *ArbEngine {
+	return &ArbEngine{
+		cfg:  &config.Config{Strategy: config.StrategyConfig{PricePrecision: 1, SizePrecision: 3}},
+		spec: spec,
+	}
+}
+
+func TestRoundSize(t *testing.T) {
+	cases := []struct {
+		step float64
+		in   float64
+		want string
+	}{
+		{0.001, 0.3, "0.300"},
+		{0.001, 0.1 + 0.2, "0.300"}, // 0.30000000000000004
+		{0.001, 0.123456, "0.123"},
+		{0.001, 1.0005, "1.000"},
+		{0.001, 0.0029999999, "0.002"}, // 向下取整，不超过实际成交量
+		{0.001, 0.0009, "0.000"},
+		{0.001, 3 * 0.1, "0.300"}, // 0.30000000000000004
+		{0.0001, 0.1234567, "0.1234"},
+		{0.0001, 0.0003, "0.0003"},    // 0.0003/0.0001 = 2.9999999999999996
+		{0.0001, 0.7 + 0.1, "0.8000"}, // 0.7999999999999999
+		{0.0001, 1.1 * 0.3, "0.3300"}, // 0.33000000000000007
+		{0.0001, 0.00019999, "0.0001"},
+		{0.0001, 12345.6789, "12345.6789"},
+	}
+	for _, tc := range cases {
+		e := specEngine(instrumentSpec{qtyStep: tc.step})
+		got := e.roundSize(tc.in)
+		if s := e.formatSize(got); s != tc.want {
+			t.Errorf("step=%v roundSize(%v) = %s，期望 %s", tc.step, tc.in, s, tc.want)
+		}
+		if got > tc.in+1e-12 {
+			t.Errorf("step=%v roundSize(%v) = %v，不应超过原数量", tc.step, tc.in, got)
+		}
+	}
+}
+
+func TestRoundSizeFallsBackToPrecision(t *testing.T) {
+	e := specEngine(instrumentSpec{})
+	e.cfg.Strategy.SizePrecision = 4
+	if s := e.formatSize(e.roundSize(0.12349)); s != "0.1234" {
+		t.Fatalf("未获取规格时按 size_precision 取整 = %s，期望 0.1234", s)
+	}
+}
+
+func TestFormatPrice(t *testing.T) {
+	cases := []struct {
+		tick  float64
+		price float64
+		side  exchange.Side
+		want  string
+	}{
+		{0.1, 100000.01, exchange.Buy, "100000.1"},  // 买单向上
+		{0.1, 100000.09, exchange.Sell, "100000.0"}, // 卖单向下
+		{0.1, 100000.3, exchange.Buy, "100000.3"},   // 已在步长上不进位
+		{0.1, 100000.3, exchange.Sell, "100000.3"},
+		{0.5, 100000.2, exchange.Buy, "100000.5"},
+		{0.5, 100000.2, exchange.Sell, "100000.0"},
+		{0.01, 0.07, exchange.Sell, "0.07"}, // 0.07/0.01 = 7.000000000000001
+		{0.01, 0.07, exchange.Buy, "0.07"},
+		{0.001, 1.0005, exchange.Buy, "1.001"},
+		{0.0001, 0.12345, exchange.Sell, "0.1234"},
+		{0.0001, 0.0003, exchange.Buy, "0.0003"},
+	}
+	for _, tc := range cases {
+		e := specEngine(instrumentSpec{apexTick: tc.tick})
+		if got := e.formatApexPrice(tc.price, tc.side); got != tc.want {
+			t.Errorf("tick=%v %s formatApexPrice(%v) = %s，期望 %s", tc.tick, tc.side, tc.price, got, tc.want)
+		}
+	}
+}
+
+func TestStepDecimals(t *testing.T) {
+	cases := []struct {
+		step float64
+		want int
+	}{
+		{0.001, 3},
+		{0.0001, 4},
+		{0.00001, 5},
+		{0.5, 1},
+		{0.25, 2},
+		{1, 0},
+		{10, 0},
+		{0, 7}, // 无效步长使用 fallback
+	}
+	for _, tc := range cases {
+		if got := stepDecimals(tc.step, 7); got != tc.want {
+			t.Errorf("stepDecimals(%v) = %d，期望 %d", tc.step, got, tc.want)
+		}
+	}
+}
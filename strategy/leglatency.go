@@ -0,0 +1,72 @@
+package strategy
+
+import (
+	"log/slog"
+	"time"
+
+	"arb/alert"
+	"arb/metrics"
+)
+
+const (
+	// defaultLegLatencyPause 未配置 leg_latency_pause_sec 时两腿时间差超限后的暂停时长
+	defaultLegLatencyPause = 60 * time.Second
+
+	// legLatencyAlpha 两腿时间差指数移动平均的新样本权重
+	legLatencyAlpha = 0.2
+)
+
+// recordLegLatency 记录一次套利两腿下单返回的时间差（A所下单返回 → B所对冲下单返回）
+// 超过 max_leg_latency_ms 时暂停开仓 leg_latency_pause_sec 秒：交易所往返过慢，两腿之间价格可能已经变化
+func (e *ArbEngine) recordLegLatency(skew time.Duration) {
+	e.legLatencyMu.Lock()
+	if e.legLatencyAvg == 0 {
+		e.legLatencyAvg = skew.Seconds()
+	} else {
+		e.legLatencyAvg += legLatencyAlpha * (skew.Seconds() - e.legLatencyAvg)
+	}
+	avg := e.legLatencyAvg
+	e.legLatencyMu.Unlock()
+	metrics.LegLatencyAvg.Set(avg)
+
+	maxMs := e.cfg.Strategy.MaxLegLatencyMs
+	if maxMs <= 0 || skew <= time.Duration(maxMs)*time.Millisecond {
+		return
+	}
+
+	pause := defaultLegLatencyPause
+	if sec := e.cfg.Strategy.LegLatencyPauseSec; sec > 0 {
+		pause = time.Duration(sec) * time.Second
+	}
+	e.legPauseUntil.Store(time.Now().Add(pause).UnixNano())
+	metrics.LegLatencyExceeded.Inc()
+	slog.Warn("[套利] 两腿下单时间差超过上限，暂停开仓", "skew", skew.Round(time.Millisecond), "max_ms", maxMs,
+		"avg", legLatencyDuration(avg), "pause", pause)
+	alert.Warn("leg_latency", "两腿下单时间差 %v 超过上限 %dms，暂停开仓 %v", skew.Round(time.Millisecond), maxMs, pause)
+}
+
+// legLatencyPaused 两腿时间差超限后的暂停期内返回 true，暂停结束时打印一次恢复日志
+func (e *ArbEngine) legLatencyPaused() bool {
+	until := e.legPauseUntil.Load()
+	if until == 0 {
+		return false
+	}
+	if time.Now().UnixNano() < until {
+		return true
+	}
+	if e.legPauseUntil.CompareAndSwap(until, 0) {
+		slog.Info("[套利] 两腿时间差暂停结束，恢复开仓")
+	}
+	return false
+}
+
+// legLatencyAverage 返回两腿下单时间差的指数移动平均，尚无样本时为 0
+func (e *ArbEngine) legLatencyAverage() time.Duration {
+	e.legLatencyMu.Lock()
+	defer e.legLatencyMu.Unlock()
+	return legLatencyDuration(e.legLatencyAvg)
+}
+
+func legLatencyDuration(sec float64) time.Duration {
+	return time.Duration(sec * float64(time.Second)).Round(time.Millisecond)
+}
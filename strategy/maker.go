@@ -0,0 +1,134 @@
+package strategy
+
+import (
+	"log/slog"
+	"time"
+
+	"arb/config"
+	"arb/exchange"
+)
+
+const (
+	// defaultMakerWait 未配置 apex_maker_wait_ms 时 maker 挂单等待成交的时长
+	defaultMakerWait = 500 * time.Millisecond
+
+	// makerPollInterval maker 挂单等待期间查询订单的间隔
+	makerPollInterval = 50 * time.Millisecond
+)
+
+// makerWait 返回 maker 挂单等待成交的时长
+func (e *ArbEngine) makerWait() time.Duration {
+	if ms := e.cfg.Strategy.ApexMakerWaitMs; ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return defaultMakerWait
+}
+
+// apexTimeInForce 返回 A所开仓腿的有效方式（time_in_force），默认 IOC
+func (e *ArbEngine) apexTimeInForce() exchange.TimeInForce {
+	if e.cfg.Strategy.TimeInForce == config.TimeInForceGTC {
+		return exchange.GTC
+	}
+	return exchange.IOC
+}
+
+// orderExpireAt 返回 A所挂单在交易所的到期时间，未配置 expiry_seconds 时返回零值（交易所默认有效期）
+func (e *ArbEngine) orderExpireAt() time.Time {
+	if sec := e.cfg.Strategy.ExpirySeconds; sec > 0 {
+		return time.Now().Add(time.Duration(sec) * time.Second)
+	}
+	return time.Time{}
+}
+
+// placeApexLeg 在 A所下 qty 张开仓腿并返回实际成交（orderID 为最后一笔订单，placedAt 为该腿结束时间）
+// 默认以 IOC 吃单；time_in_force 为 gtc 时按报价挂单，等待 apex_maker_wait_ms 后撤单；
+// apex_maker_mode 开启时先以 POST_ONLY 挂在己方最优价等待 maker 成交，超时撤单后剩余部分按 apex_maker_fallback 改用 IOC 吃单或放弃
+func (e *ArbEngine) placeApexLeg(dir ArbDirection, req *exchange.OrderRequest, qty float64) (legFill, error) {
+	if !e.cfg.Strategy.ApexMakerMode {
+		order, err := e.placeOrder(e.exA, req)
+		if err != nil {
+			return legFill{}, err
+		}
+		placedAt := time.Now()
+		var fill legFill
+		if req.TimeInForce == exchange.IOC {
+			fill = e.apexFill(e.ctx, order)
+		} else {
+			fill = e.waitMakerFill(order)
+		}
+		fill.orderID, fill.placedAt = order.ID, placedAt
+		return fill, nil
+	}
+
+	lg := slog.With("direction", dir.tag(), "exchange", e.exA.Name())
+	apexSide, _ := dir.sides()
+	top := e.apexTop()
+	passive := top.bid
+	if apexSide == exchange.Sell {
+		passive = top.ask
+	}
+
+	maker := *req
+	maker.Price = e.formatApexPrice(passive, apexSide)
+	maker.TimeInForce = exchange.PostOnly
+	maker.ExpireAt = e.orderExpireAt()
+	maker.ClientID = req.ClientID + "m"
+	order, err := e.placeOrder(e.exA, &maker)
+	if err != nil {
+		return legFill{}, err
+	}
+
+	fill := e.waitMakerFill(order)
+	fill.orderID, fill.placedAt = order.ID, time.Now()
+	remaining := e.roundSize(qty - fill.qty)
+	lg.Info("[套利] A所 maker 挂单结束", "order_id", order.ID, "price", maker.Price,
+		"filled", e.formatSize(fill.qty), "remaining", e.formatSize(remaining))
+	if remaining < e.sizeStep() || e.cfg.Strategy.ApexMakerFallback == config.MakerFallbackCancel {
+		return fill, nil
+	}
+
+	// 剩余部分按原报价 IOC 吃单，价差仍满足开仓条件时才成交
+	ioc := *req
+	ioc.Qty = e.formatSize(remaining)
+	ioc.TimeInForce, ioc.ExpireAt = exchange.IOC, time.Time{}
+	taker, err := e.placeOrder(e.exA, &ioc)
+	if err != nil {
+		if fill.qty > 0 {
+			// maker 部分已成交，按已成交部分继续对冲
+			lg.Warn("[套利] A所 maker 剩余部分 IOC 下单失败，按已成交部分继续", "err", err)
+			return fill, nil
+		}
+		return legFill{}, err
+	}
+	takerFill := e.apexFill(e.ctx, taker)
+	fill.add(takerFill)
+	fill.orderID, fill.placedAt = taker.ID, time.Now()
+	return fill, nil
+}
+
+// waitMakerFill 等待 A所挂单（POST_ONLY / GTC）成交：全部成交或订单结束（包括 post-only 被拒）即返回，
+// 超过 apex_maker_wait_ms 后撤单并查询最终成交
+func (e *ArbEngine) waitMakerFill(order *exchange.Order) legFill {
+	deadline := time.Now().Add(e.makerWait())
+	for !order.Final && time.Now().Before(deadline) {
+		select {
+		case <-e.stopCh:
+			deadline = time.Now()
+		case <-time.After(makerPollInterval):
+		}
+		o, err := e.exA.GetOrder(e.ctx, order.ID)
+		if err != nil {
+			slog.Debug("[套利] 查询 A所 maker 挂单失败", "order_id", order.ID, "err", err)
+			continue
+		}
+		order = o
+	}
+
+	if !order.Final {
+		if err := e.exA.CancelOrder(e.ctx, order.ID); err != nil {
+			// 撤单失败可能是刚好成交，以随后查询到的最终状态为准
+			slog.Warn("[套利] 撤销 A所 maker 挂单失败", "exchange", e.exA.Name(), "order_id", order.ID, "err", err)
+		}
+	}
+	return e.apexFill(e.ctx, order)
+}
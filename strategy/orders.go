@@ -0,0 +1,98 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"arb/exchange"
+)
+
+const (
+	cancelAttempts   = 3
+	cancelRetryDelay = 300 * time.Millisecond
+)
+
+// cancelResult 单个交易所的撤单结果
+type cancelResult struct {
+	venue     string
+	cancelled int  // 撤销的挂单数（撤单前挂单数 - 剩余挂单数）
+	remaining int  // 最后一次查询的剩余挂单数
+	confirmed bool // 已通过 GetOpenOrders 确认无剩余挂单
+}
+
+func (r cancelResult) String() string {
+	if r.confirmed {
+		return fmt.Sprintf("%s 撤销 %d 笔（已确认无挂单）", r.venue, r.cancelled)
+	}
+	return fmt.Sprintf("%s 撤销 %d 笔（未能确认，剩余 %d 笔，请人工检查）", r.venue, r.cancelled, r.remaining)
+}
+
+// cancelAllOpenOrders 撤销两所全部挂单，并通过查询挂单确认，失败时重试
+func (e *ArbEngine) cancelAllOpenOrders(ctx context.Context) []cancelResult {
+	return []cancelResult{
+		cancelAndVerify(ctx, e.exA.Name(), e.exA.CancelAll, openCount(e.exA)),
+		cancelAndVerify(ctx, e.exB.Name(), e.exB.CancelAll, openCount(e.exB)),
+	}
+}
+
+// openCount 返回查询交易所当前挂单数的函数
+func openCount(ex exchange.Exchange) func(context.Context) (int, error) {
+	return func(ctx context.Context) (int, error) {
+		orders, err := ex.OpenOrders(ctx)
+		return len(orders), err
+	}
+}
+
+// cancelAndVerify 撤销单个交易所的全部挂单，直到查询确认无剩余挂单或重试次数用尽
+func cancelAndVerify(ctx context.Context, venue string,
+	cancelAll func(context.Context) error, openCount func(context.Context) (int, error)) cancelResult {
+
+	res := cancelResult{venue: venue, remaining: -1}
+	before, err := openCount(ctx)
+	if err != nil {
+		slog.Warn("[撤单] 查询挂单失败", "exchange", venue, "err", err)
+		before = 0
+	}
+
+	for attempt := 1; attempt <= cancelAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return res
+			case <-time.After(cancelRetryDelay):
+			}
+		}
+
+		if err := cancelAll(ctx); err != nil {
+			slog.Warn("[撤单] 撤销挂单失败", "exchange", venue, "attempt", attempt, "err", err)
+			continue
+		}
+		remaining, err := openCount(ctx)
+		if err != nil {
+			slog.Warn("[撤单] 确认挂单失败", "exchange", venue, "attempt", attempt, "err", err)
+			continue
+		}
+		res.remaining = remaining
+		if before > remaining {
+			res.cancelled = before - remaining
+		}
+		if remaining == 0 {
+			res.confirmed = true
+			return res
+		}
+		slog.Warn("[撤单] 撤销后仍有挂单", "exchange", venue, "attempt", attempt, "remaining", remaining)
+	}
+	return res
+}
+
+// summarizeCancel 汇总撤单结果为一行日志
+func summarizeCancel(results []cancelResult) string {
+	parts := make([]string, len(results))
+	for i, r := range results {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, " | ")
+}
@@ -0,0 +1,129 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+
+	"arb/exchange"
+)
+
+// reconcilePosition 启动时用交易所真实持仓初始化 e.position（Apex 方向）
+//
+//	对冲模式：Bybit 腿与 Apex 方向相反，position = -Bybit 净持仓（Buy 为正，Sell 为负）
+//	单腿模式：Bybit 无持仓，直接使用 Apex 净持仓
+//
+// 恢复出的持仓已超过对应方向的持仓上限时，checkAndTrade 的持仓限制会拒绝同向开仓，直到持仓降下来
+func (e *ArbEngine) reconcilePosition() error {
+	var pos float64
+	if e.cfg.Strategy.HedgeMode {
+		net, err := e.bybitNetPosition(e.ctx)
+		if err != nil {
+			return err
+		}
+		pos = -net
+		slog.Info("[持仓] 从 B所恢复持仓（换算为 A所方向）", "exchange", e.exB.Name(), "net", net, "position", pos)
+	} else {
+		net, err := e.apexNetPosition(e.ctx)
+		if err != nil {
+			return err
+		}
+		pos = net
+		slog.Info("[持仓] 从 A所恢复持仓", "exchange", e.exA.Name(), "position", pos)
+	}
+
+	e.posMu.Lock()
+	e.position = pos
+	e.posMu.Unlock()
+
+	dir := DirectionLong
+	if pos < 0 {
+		dir = DirectionShort
+	}
+	if pos != 0 && e.positionCapacity(dir, pos) <= 0 {
+		slog.Warn("[持仓] 恢复的持仓已达到该方向最大持仓，暂停同向开仓直到持仓降低", "position", pos,
+			"direction", dir.tag(), "max_position", e.maxPosition(dir))
+	}
+	return nil
+}
+
+// maxPosition 返回方向的持仓上限：max_long_position / max_short_position，未配置时为 max_position
+func (e *ArbEngine) maxPosition(dir ArbDirection) float64 {
+	limit := e.cfg.Strategy.MaxLongPosition
+	if dir == DirectionShort {
+		limit = e.cfg.Strategy.MaxShortPosition
+	}
+	if limit > 0 {
+		return limit
+	}
+	return e.cfg.Strategy.MaxPosition
+}
+
+// positionCapacity 返回方向剩余可开仓数量（合约张数），方向被 enable_long / enable_short 关闭时为 0
+// pos 为 A所方向持仓：多头方向容量 = 上限 - pos，空头方向容量 = 上限 + pos
+func (e *ArbEngine) positionCapacity(dir ArbDirection, pos float64) float64 {
+	enabled := e.cfg.Strategy.EnableLong
+	if dir == DirectionShort {
+		enabled = e.cfg.Strategy.EnableShort
+	}
+	if !flagEnabled(enabled) {
+		return 0
+	}
+	return math.Max(e.maxPosition(dir)-dir.sign()*pos, 0)
+}
+
+// flagEnabled 未配置的开关按开启处理
+func flagEnabled(b *bool) bool {
+	return b == nil || *b
+}
+
+// exposure 单个交易所的真实净持仓
+type exposure struct {
+	net   float64 // 净持仓（多头为正，空头为负）
+	entry float64 // 持仓均价（按数量加权）
+}
+
+// add 合并一笔同交易对持仓，signed 为带方向的数量
+func (x *exposure) add(signed, entry float64) {
+	if total := math.Abs(x.net) + math.Abs(signed); total > 0 {
+		x.entry = (x.entry*math.Abs(x.net) + entry*math.Abs(signed)) / total
+	}
+	x.net += signed
+}
+
+// bybitNetPosition 查询 B所真实净持仓（多头为正，空头为负）
+func (e *ArbEngine) bybitNetPosition(ctx context.Context) (float64, error) {
+	x, err := e.bybitExposure(ctx)
+	return x.net, err
+}
+
+// apexNetPosition 查询 A所真实净持仓（多头为正，空头为负）
+func (e *ArbEngine) apexNetPosition(ctx context.Context) (float64, error) {
+	x, err := e.apexExposure(ctx)
+	return x.net, err
+}
+
+// bybitExposure 查询 B所真实净持仓与持仓均价
+func (e *ArbEngine) bybitExposure(ctx context.Context) (exposure, error) {
+	return venueExposure(ctx, e.exB)
+}
+
+// apexExposure 查询 A所真实净持仓与持仓均价
+func (e *ArbEngine) apexExposure(ctx context.Context) (exposure, error) {
+	return venueExposure(ctx, e.exA)
+}
+
+// venueExposure 合并交易所本交易对的全部持仓（单向/双向持仓模式均适用）
+func venueExposure(ctx context.Context, ex exchange.Exchange) (exposure, error) {
+	positions, err := ex.GetPositions(ctx)
+	if err != nil {
+		return exposure{}, fmt.Errorf("查询 %s 持仓失败: %w", ex.Name(), err)
+	}
+
+	var x exposure
+	for _, p := range positions {
+		x.add(p.Size, p.EntryPrice)
+	}
+	return x, nil
+}
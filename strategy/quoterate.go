@@ -0,0 +1,146 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"arb/alert"
+	"arb/bybit"
+	"arb/config"
+)
+
+const (
+	// defaultQuoteRateRefresh 未配置 quote_rate.refresh_sec 时刷新汇率的间隔
+	defaultQuoteRateRefresh = 60 * time.Second
+
+	// defaultQuoteRateMaxAge 未配置 quote_rate.max_age_sec 时汇率的最长有效期
+	defaultQuoteRateMaxAge = 300 * time.Second
+
+	// quoteRateSymbol 汇率查询使用的 Bybit 现货交易对
+	quoteRateSymbol = "USDCUSDT"
+)
+
+// rateState 当前汇率及最近一次成功刷新的时间
+type rateState struct {
+	rate float64
+	at   time.Time
+}
+
+// validateQuoteRate 校验计价币汇率配置：固定汇率与汇率来源二选一
+func validateQuoteRate(c config.QuoteRateConfig) error {
+	if c.USDCUSDTRate < 0 {
+		return fmt.Errorf("quote_rate.usdc_usdt_rate 不能为负数: %g", c.USDCUSDTRate)
+	}
+	switch c.Source {
+	case "":
+	case config.QuoteRateSourceBybitSpot:
+		if c.USDCUSDTRate > 0 {
+			return fmt.Errorf("quote_rate.usdc_usdt_rate 与 quote_rate.source 不能同时配置")
+		}
+	default:
+		return fmt.Errorf("quote_rate.source 取值无效: %q（可选: %s）", c.Source, config.QuoteRateSourceBybitSpot)
+	}
+	return nil
+}
+
+// quoteRateEnabled 是否启用计价币汇率换算
+func (e *ArbEngine) quoteRateEnabled() bool {
+	return e.cfg.QuoteRate.USDCUSDTRate > 0 || e.cfg.QuoteRate.Source != ""
+}
+
+// quoteRateSource 返回汇率来源，用于状态输出
+func (e *ArbEngine) quoteRateSource() string {
+	if e.cfg.QuoteRate.Source != "" {
+		return e.cfg.QuoteRate.Source
+	}
+	return "fixed"
+}
+
+func (e *ArbEngine) rateState() rateState {
+	r, _ := e.quoteRate.Load().(rateState)
+	return r
+}
+
+// currentQuoteRate 返回当前 USDC/USDT 汇率，尚未获取到时按 1 处理（此时 quoteRateStale 阻止开仓）
+func (e *ArbEngine) currentQuoteRate() float64 {
+	if r := e.rateState(); r.rate > 0 {
+		return r.rate
+	}
+	return 1
+}
+
+// quoteRateRefresh 返回汇率刷新间隔
+func (e *ArbEngine) quoteRateRefresh() time.Duration {
+	if sec := e.cfg.QuoteRate.RefreshSec; sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	return defaultQuoteRateRefresh
+}
+
+// quoteRateMaxAge 返回汇率的最长有效期
+func (e *ArbEngine) quoteRateMaxAge() time.Duration {
+	if sec := e.cfg.QuoteRate.MaxAgeSec; sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	return defaultQuoteRateMaxAge
+}
+
+// quoteRateStale 汇率来源为定时查询且超过 max_age_sec 未刷新成功时返回 true（固定汇率永不过期）
+func (e *ArbEngine) quoteRateStale() bool {
+	if e.cfg.QuoteRate.Source == "" {
+		return false
+	}
+	r := e.rateState()
+	return r.rate <= 0 || time.Since(r.at) > e.quoteRateMaxAge()
+}
+
+// refreshQuoteRate 更新一次汇率：固定汇率直接写入，bybit_spot 查询现货最新成交价，失败时保留上次的值
+func (e *ArbEngine) refreshQuoteRate() {
+	if rate := e.cfg.QuoteRate.USDCUSDTRate; rate > 0 {
+		e.quoteRate.Store(rateState{rate: rate, at: time.Now()})
+		slog.Info("[汇率] 使用固定 USDC/USDT 汇率", "rate", rate)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, 10*time.Second)
+	defer cancel()
+	if e.spotClient == nil {
+		e.spotClient = bybit.NewClient(e.cfg.Bybit.BaseURL, "", "")
+	}
+	rate, err := e.spotClient.GetSpotPrice(ctx, quoteRateSymbol)
+	if err != nil || rate <= 0 {
+		if err == nil {
+			err = fmt.Errorf("汇率无效: %g", rate)
+		}
+		slog.Warn("[汇率] 查询失败，沿用上次汇率", "symbol", quoteRateSymbol, "rate", e.currentQuoteRate(), "err", err)
+		return
+	}
+	e.quoteRate.Store(rateState{rate: rate, at: time.Now()})
+}
+
+// quoteRateLoop 定时刷新汇率，汇率过期与恢复时各告警一次（过期期间 checkAndTrade 停止开仓）
+func (e *ArbEngine) quoteRateLoop() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.quoteRateRefresh())
+	defer ticker.Stop()
+
+	var alerted bool
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case <-ticker.C:
+			e.refreshQuoteRate()
+			stale := e.quoteRateStale()
+			if stale && !alerted {
+				alert.Warn("quote_rate", "USDC/USDT 汇率超过 %s 未刷新成功，暂停开仓", e.quoteRateMaxAge())
+			} else if !stale && alerted {
+				alert.Info("quote_rate", "USDC/USDT 汇率已恢复刷新: %.6f，恢复开仓", e.currentQuoteRate())
+			}
+			alerted = stale
+		}
+	}
+}
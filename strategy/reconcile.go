@@ -0,0 +1,115 @@
+package strategy
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"arb/alert"
+)
+
+const (
+	// defaultReconcileInterval 未配置 reconcile_interval_sec 时核对本地持仓与交易所持仓的间隔
+	defaultReconcileInterval = 60 * time.Second
+
+	// defaultReconcileHaltCount 未配置 reconcile_halt_count 时触发风控熔断的连续不一致次数
+	defaultReconcileHaltCount = 3
+)
+
+// reconcileInterval 返回本地持仓核对间隔：0 使用默认值，负数表示不启用
+func (e *ArbEngine) reconcileInterval() time.Duration {
+	sec := e.cfg.Strategy.ReconcileIntervalSec
+	if sec == 0 {
+		return defaultReconcileInterval
+	}
+	if sec < 0 {
+		return -1
+	}
+	return time.Duration(sec) * time.Second
+}
+
+// reconcileHaltCount 返回触发风控熔断的连续不一致次数：0 使用默认值，负数表示只告警不熔断
+func (e *ArbEngine) reconcileHaltCount() int {
+	if n := e.cfg.Strategy.ReconcileHaltCount; n != 0 {
+		return n
+	}
+	return defaultReconcileHaltCount
+}
+
+// reconcileLoop 定时比较本地记录的持仓 e.position 与交易所真实持仓，
+// 连续不一致说明成交记账有误，达到 reconcile_halt_count 次时触发风控熔断
+func (e *ArbEngine) reconcileLoop() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.reconcileInterval())
+	defer ticker.Stop()
+
+	var mismatches int
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case <-ticker.C:
+			ok, checked := e.reconcileOnce()
+			if !checked {
+				continue
+			}
+			if ok {
+				mismatches = 0
+				continue
+			}
+			mismatches++
+			if limit := e.reconcileHaltCount(); limit > 0 && mismatches >= limit {
+				reason := fmt.Sprintf("本地持仓与交易所持仓连续 %d 次不一致，成交记账可能有误", mismatches)
+				alert.Critical("reconcile", "%s，已触发风控熔断，请核对持仓", reason)
+				e.riskCtrl.Halt(reason)
+				e.pause("风控熔断: " + reason)
+				mismatches = 0
+			}
+		}
+	}
+}
+
+// reconcileOnce 核对一次持仓：checked=false 表示本轮跳过（交易进行中或查询失败），ok 表示一致
+// 对冲模式以 B所净持仓的相反数为 A所方向持仓（与 reconcilePosition 一致），单腿模式使用 A所净持仓
+func (e *ArbEngine) reconcileOnce() (ok, checked bool) {
+	// 与套利检测互斥：下单过程中本地记录与交易所持仓暂时不一致
+	if !e.checking.CompareAndSwap(false, true) {
+		return false, false
+	}
+	defer e.checking.Store(false)
+
+	apexNet, err := e.apexNetPosition(e.ctx)
+	if err != nil {
+		slog.Warn("[持仓对账] 查询持仓失败", "exchange", e.exA.Name(), "err", err)
+		return false, false
+	}
+	expected := apexNet
+	if e.cfg.Strategy.HedgeMode {
+		bybitNet, err := e.bybitNetPosition(e.ctx)
+		if err != nil {
+			slog.Warn("[持仓对账] 查询持仓失败", "exchange", e.exB.Name(), "err", err)
+			return false, false
+		}
+		expected = -bybitNet
+	}
+
+	e.posMu.Lock()
+	local := e.position
+	e.posMu.Unlock()
+
+	diff := local - expected
+	if math.Abs(diff) < e.sizeStep() {
+		return true, true
+	}
+
+	slog.Warn("[持仓对账] 本地持仓与交易所持仓不一致", "local", local, "expected", expected, "diff", diff, "net_a", apexNet)
+	if e.cfg.Strategy.ReconcileAutocorrect {
+		e.posMu.Lock()
+		e.position = expected
+		e.posMu.Unlock()
+		slog.Info("[持仓对账] 已按交易所持仓修正本地持仓", "position", expected)
+	}
+	return false, true
+}
@@ -0,0 +1,149 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"arb/alert"
+)
+
+// errFillUnknown 订单已提交但无法确认成交，不能重试以免重复下单
+var errFillUnknown = errors.New("成交状态未知")
+
+// recoverHedge 对冲腿失败或部分成交后的恢复流程：
+//  1. 以最新 Bybit 报价（含滑点容忍）重试对冲剩余数量，最多 hedge_retry_count 次
+//  2. 仍有未对冲数量时，以 reduce-only IOC 单平掉 Apex 腿
+//  3. 平仓后仍残留的数量记入未对冲敞口，单独跟踪
+//
+// reduceOnly 为减仓交易（价差回归平仓或反向机会减仓）：对冲重试同样使用 reduce-only，
+// Apex 腿本身是平仓单，不能再反向"平掉"（会被交易所拒绝或重新开仓），未完成的对冲数量直接记入未对冲敞口。
+// oppMs 为本次机会的订单ID基准时间，hedged 为已成交的对冲部分（对冲完全失败时为零值），
+// remaining 为待处理的孤立数量。恢复过程中的实际盈亏（含平仓亏损）计入风控
+func (e *ArbEngine) recoverHedge(dir ArbDirection, oppMs int64, apexFill, hedged legFill, remaining float64, reduceOnly bool) {
+	incidents := e.unhedgedIncidents.Add(1)
+	slog.Warn("[对冲恢复] A所腿未对冲", "incident", incidents, "direction", dir.tag(), "remaining", e.formatSize(remaining), "reduce_only", reduceOnly)
+
+	delay := time.Duration(e.cfg.Strategy.HedgeRetryDelayMs) * time.Millisecond
+
+	for i := 1; i <= e.cfg.Strategy.HedgeRetryCount && remaining > 0; i++ {
+		select {
+		case <-e.stopCh:
+			slog.Warn("[对冲恢复] 引擎停止，中断恢复流程，注意风险", "unhedged", e.formatSize(remaining))
+			e.addUnhedged(dir, remaining)
+			return
+		case <-time.After(delay):
+		}
+
+		price := e.hedgeRetryPrice(dir)
+		fill, err := e.placeHedge(dir, remaining, price, reduceOnly, e.clientID(oppMs, dir, fmt.Sprintf("hedge%d", i)))
+		if errors.Is(err, errFillUnknown) {
+			slog.Error("[对冲恢复] 重试成交状态未知，停止恢复，注意核对 B所持仓", "attempt", i, "exchange", e.exB.Name(), "err", err)
+			alert.Critical("unhedged", "对冲恢复中断：成交状态未知，%s 待处理数量 %s，请核对持仓", dir, e.formatSize(remaining))
+			e.addUnhedged(dir, remaining)
+			return
+		}
+		if err != nil {
+			slog.Warn("[对冲恢复] 重试对冲失败", "attempt", i, "max_attempts", e.cfg.Strategy.HedgeRetryCount, "err", err)
+			continue
+		}
+		if fill.qty <= 0 {
+			slog.Warn("[对冲恢复] 重试对冲未成交", "attempt", i, "max_attempts", e.cfg.Strategy.HedgeRetryCount)
+			continue
+		}
+
+		hedged.add(fill)
+		remaining = e.roundSize(remaining - fill.qty)
+		slog.Info("[对冲恢复] 重试对冲成交", "attempt", i, "filled", e.formatSize(fill.qty), "remaining", e.formatSize(remaining))
+	}
+
+	// 已对冲部分按两腿实际成交计算；完全未对冲时仍需计入 Apex 开仓手续费
+	pnl := -apexFill.fee
+	if hedged.qty > 0 {
+		pnl = realizedPnL(dir, apexFill, hedged)
+	}
+
+	if remaining > 0 && reduceOnly {
+		slog.Error("[对冲恢复] 减仓对冲未完成，A所腿已平仓不可撤回，记入未对冲敞口", "unhedged", e.formatSize(remaining))
+		alert.Critical("unhedged", "减仓对冲失败：%s 仍有 %s 对冲腿未平，请人工处理", dir, e.formatSize(remaining))
+		e.addUnhedged(dir, remaining)
+	} else if remaining > 0 {
+		slog.Warn("[对冲恢复] 重试对冲未完成，平掉 A所腿剩余数量", "remaining", e.formatSize(remaining))
+		closed, closedQty, err := e.unwindApexLeg(dir, apexFill, remaining)
+		if err != nil {
+			slog.Error("[对冲恢复] A所腿平仓失败", "exchange", e.exA.Name(), "err", err)
+		}
+		pnl += closed
+		if left := e.roundSize(remaining - closedQty); left > 0 {
+			slog.Error("[对冲恢复] 仍有未对冲数量，记入未对冲敞口", "unhedged", e.formatSize(left))
+			alert.Critical("unhedged", "对冲恢复失败：%s 仍有 %s 未对冲，请人工处理", dir, e.formatSize(left))
+			e.addUnhedged(dir, left)
+		}
+	}
+
+	e.bookPnL(dir, pnl, "对冲恢复")
+}
+
+// hedgeRetryPrice 按最新 Bybit 报价计算重试对冲价，允许 hedge_slippage_usdc 的滑点
+func (e *ArbEngine) hedgeRetryPrice(dir ArbDirection) float64 {
+	q := e.bybitTop()
+	if dir == DirectionShort {
+		// 对冲买入：吃 Bybit 卖一
+		return q.ask + e.hedgeSlippage(q.ask)
+	}
+	// 对冲卖出：吃 Bybit 买一
+	return q.bid - e.hedgeSlippage(q.bid)
+}
+
+// unwindApexLeg 以 reduce-only IOC 单平掉 Apex 腿 qty，返回平仓盈亏（含平仓手续费）与实际平仓数量
+func (e *ArbEngine) unwindApexLeg(dir ArbDirection, entry legFill, qty float64) (float64, float64, error) {
+	order, err := e.closeApexLeg(dir.sign()*qty, false)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	fill := e.apexFill(e.ctx, order)
+	if fill.qty <= 0 {
+		slog.Warn("[对冲恢复] A所平仓单未成交", "exchange", e.exA.Name(), "order_id", order.ID)
+		return 0, 0, nil
+	}
+
+	e.posMu.Lock()
+	e.position -= dir.sign() * fill.qty
+	e.posMu.Unlock()
+
+	pnl := dir.sign()*(fill.avgPrice-entry.avgPrice)*fill.qty - fill.fee
+	slog.Info("[对冲恢复] A所平仓成交", "exchange", e.exA.Name(), "order_id", order.ID, "filled", e.formatSize(fill.qty),
+		"avg_price", fill.avgPrice, "pnl", pnl)
+	return pnl, fill.qty, nil
+}
+
+// addUnhedged 记录恢复流程结束后仍未对冲的 Apex 数量（按方向带符号）
+func (e *ArbEngine) addUnhedged(dir ArbDirection, qty float64) {
+	e.posMu.Lock()
+	e.unhedgedQty += dir.sign() * qty
+	total := e.unhedgedQty
+	e.posMu.Unlock()
+	slog.Warn("[对冲恢复] 累计未对冲敞口（A所方向，正数=多头）", "unhedged", e.formatSize(total))
+}
+
+// hedgeTolerance 两腿成交量允许的偏差：不小于数量精度，且不超过对冲滑点容忍对应的数量
+func (e *ArbEngine) hedgeTolerance(price float64) float64 {
+	tol := e.sizeStep()
+	if price > 0 {
+		tol = math.Max(tol, e.hedgeSlippage(price)/price)
+	}
+	return tol
+}
+
+// add 合并多次成交，成交均价按数量加权
+func (f *legFill) add(o legFill) {
+	total := f.qty + o.qty
+	if total > 0 {
+		f.avgPrice = (f.avgPrice*f.qty + o.avgPrice*o.qty) / total
+	}
+	f.qty = total
+	f.fee += o.fee
+}
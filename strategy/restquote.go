@@ -0,0 +1,133 @@
+package strategy
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"arb/exchange"
+)
+
+// defaultRestFallbackInterval 未配置 rest_fallback_interval_ms 时 REST 兜底行情的轮询间隔
+const defaultRestFallbackInterval = 2 * time.Second
+
+// restQuote WS 中断期间通过 REST 轮询得到的最优价（仅一档）
+type restQuote struct {
+	bid, ask         float64
+	bidSize, askSize float64
+	at               time.Time
+}
+
+// restVenue 单个交易所的 REST 兜底状态（仅由 restQuoteLoop 访问 active）
+type restVenue struct {
+	ex        exchange.Exchange
+	rest      *atomic.Value // restQuote
+	q         *atomic.Value // quote（allow_rest_trading 时写入）
+	updatedAt *atomic.Value // time.Time
+	active    bool
+}
+
+// restFallbackInterval 返回 REST 兜底轮询间隔：0 使用默认值，负数表示不启用
+func (e *ArbEngine) restFallbackInterval() time.Duration {
+	ms := e.cfg.Strategy.RestFallbackIntervalMs
+	if ms == 0 {
+		return defaultRestFallbackInterval
+	}
+	if ms < 0 {
+		return -1
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// restQuoteLoop WS 未就绪时以较低频率通过 REST 查询最优价，保持降级的行情视图
+// 默认只用于状态展示；allow_rest_trading 时写入交易盘口，断线处置视其为有效行情
+func (e *ArbEngine) restQuoteLoop() {
+	defer e.wg.Done()
+
+	venues := []*restVenue{
+		{ex: e.exA, rest: &e.restQuoteA, q: &e.apexQuote, updatedAt: &e.apexUpdatedAt},
+		{ex: e.exB, rest: &e.restQuoteB, q: &e.bybitQuote, updatedAt: &e.bybitUpdatedAt},
+	}
+
+	ticker := time.NewTicker(e.restFallbackInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case <-ticker.C:
+			for _, v := range venues {
+				e.pollRestQuote(v)
+			}
+		}
+	}
+}
+
+// pollRestQuote WS 就绪时清除 REST 行情，否则查询一次最优价
+func (e *ArbEngine) pollRestQuote(v *restVenue) {
+	if v.ex.FeedReady() {
+		if v.active {
+			v.active = false
+			v.rest.Store(restQuote{})
+			slog.Info("[行情] WS 已恢复，停止 REST 轮询盘口", "exchange", v.ex.Name())
+		}
+		return
+	}
+	if !v.active {
+		v.active = true
+		slog.Warn("[行情] WS 未就绪，改用 REST 轮询盘口", "exchange", v.ex.Name(), "interval", e.restFallbackInterval(),
+			"usage", restUsage(e.cfg.Strategy.AllowRestTrading))
+	}
+
+	bp, err := v.ex.BestPrice(e.ctx)
+	if err != nil {
+		slog.Warn("[行情] REST 查询盘口失败", "exchange", v.ex.Name(), "err", err)
+		return
+	}
+	if bp.Bid <= 0 || bp.Ask <= 0 || bp.Bid >= bp.Ask {
+		slog.Warn("[行情] REST 盘口异常，丢弃", "exchange", v.ex.Name(), "bid", bp.Bid, "ask", bp.Ask)
+		return
+	}
+	now := time.Now()
+	v.rest.Store(restQuote{bid: bp.Bid, ask: bp.Ask, bidSize: bp.BidSize, askSize: bp.AskSize, at: now})
+
+	if !e.cfg.Strategy.AllowRestTrading {
+		return
+	}
+	q := quote{
+		bid: bp.Bid, ask: bp.Ask, bidSize: bp.BidSize, askSize: bp.AskSize,
+		bids: []priceLevel{{price: bp.Bid, size: bp.BidSize}},
+		asks: []priceLevel{{price: bp.Ask, size: bp.AskSize}},
+	}
+	if err := e.checkQuoteSanity(v.ex, q, v.q.Load().(quote)); err != nil {
+		slog.Warn("[行情] REST 盘口异常，丢弃", "exchange", v.ex.Name(), "err", err)
+		return
+	}
+	v.q.Store(q)
+	v.updatedAt.Store(now)
+	e.wake()
+}
+
+func restUsage(allowTrading bool) string {
+	if allowTrading {
+		return "allow_rest_trading 已开启，参与交易"
+	}
+	return "仅用于展示，不参与交易"
+}
+
+// restQuoteOf 返回交易所当前的 REST 兜底行情，WS 正常或尚未查询到时 ok=false
+func restQuoteOf(v *atomic.Value) (restQuote, bool) {
+	rq, _ := v.Load().(restQuote)
+	return rq, !rq.at.IsZero()
+}
+
+// restTradable allow_rest_trading 开启且最近两个轮询周期内查询到 REST 行情时返回 true（断线处置据此视为行情正常）
+func (e *ArbEngine) restTradable(v *atomic.Value) bool {
+	interval := e.restFallbackInterval()
+	if !e.cfg.Strategy.AllowRestTrading || interval < 0 {
+		return false
+	}
+	rq, ok := restQuoteOf(v)
+	return ok && time.Since(rq.at) < 2*interval
+}
@@ -0,0 +1,189 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"arb/alert"
+	"arb/config"
+)
+
+// sessionTick 交易时段检查间隔
+const sessionTick = time.Second
+
+// sessionStats 当前交易时段（上次定时平仓之后）的统计，受 mu 保护
+type sessionStats struct {
+	mu            sync.Mutex
+	start         time.Time
+	trades, wins  int
+	netPnL        float64 // 计入的盈亏（已扣手续费）
+	fees          float64 // 交易流水记录的两腿手续费
+	maxPosition   float64 // 最大绝对持仓
+	worstUnhedged time.Duration
+}
+
+// reset 开始新的交易时段
+func (s *sessionStats) reset(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	*s = sessionStats{start: now}
+}
+
+// recordSessionTrade 记录一笔计入盈亏的交易（bookPnL 调用）
+func (e *ArbEngine) recordSessionTrade(pnl, pos float64) {
+	s := &e.session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trades++
+	if pnl > 0 {
+		s.wins++
+	}
+	s.netPnL += pnl
+	s.maxPosition = math.Max(s.maxPosition, math.Abs(pos))
+}
+
+// addSessionFee 累计交易流水中的两腿手续费，用于计算时段毛盈亏
+func (e *ArbEngine) addSessionFee(fee float64) {
+	e.session.mu.Lock()
+	e.session.fees += fee
+	e.session.mu.Unlock()
+}
+
+// noteUnhedgedWindow 记录一次单腿敞口时长（第一条腿成交到两腿配平或恢复结束），保留时段内最长的一次
+func (e *ArbEngine) noteUnhedgedWindow(since time.Time) {
+	d := time.Since(since)
+	e.session.mu.Lock()
+	if d > e.session.worstUnhedged {
+		e.session.worstUnhedged = d
+	}
+	e.session.mu.Unlock()
+}
+
+// sessionLocation 返回 session.timezone 对应的时区，未配置时为本地时区
+func sessionLocation(cfg config.SessionConfig) (*time.Location, error) {
+	if cfg.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(cfg.Timezone)
+}
+
+// validateSession 启动前检查 session 配置中的时区与时间格式
+func validateSession(cfg config.SessionConfig) error {
+	if _, err := sessionLocation(cfg); err != nil {
+		return fmt.Errorf("session.timezone 无效: %w", err)
+	}
+	for _, hhmm := range append(append([]string{}, cfg.FlattenAt...), cfg.ResumeAt...) {
+		if _, err := time.Parse("15:04", hhmm); err != nil {
+			return fmt.Errorf("session 时间 %q 无效（格式 HH:MM）: %w", hhmm, err)
+		}
+	}
+	return nil
+}
+
+// nextSessionTime 返回 times 中距 now 最近的下一个时刻（按 loc 时区计算）
+func nextSessionTime(now time.Time, loc *time.Location, times []string) (next time.Time, label string) {
+	for _, hhmm := range times {
+		t, err := nextDailyRun(now.In(loc), hhmm)
+		if err != nil {
+			continue
+		}
+		if next.IsZero() || t.Before(next) {
+			next, label = t, hhmm
+		}
+	}
+	return next, label
+}
+
+// sessionLoop 到达 session.flatten_at 时停止开仓、撤单、平仓并输出时段汇总，之后保持暂停，
+// 直到人工恢复或到达 session.resume_at
+func (e *ArbEngine) sessionLoop() {
+	defer e.wg.Done()
+
+	loc, _ := sessionLocation(e.cfg.Session) // 已在 newEngine 校验
+	now := time.Now()
+	nextFlatten, flattenLabel := nextSessionTime(now, loc, e.cfg.Session.FlattenAt)
+	nextResume, resumeLabel := nextSessionTime(now, loc, e.cfg.Session.ResumeAt)
+	slog.Info("[交易时段] 下次定时平仓", "at", nextFlatten.Format("2006-01-02 15:04 MST"))
+
+	ticker := time.NewTicker(sessionTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case now := <-ticker.C:
+			if !nextFlatten.IsZero() && !now.Before(nextFlatten) {
+				if !e.flattenSession(flattenLabel) {
+					continue // 套利检测进行中，下个周期重试
+				}
+				nextFlatten, flattenLabel = nextSessionTime(now, loc, e.cfg.Session.FlattenAt)
+				slog.Info("[交易时段] 下次定时平仓", "at", nextFlatten.Format("2006-01-02 15:04 MST"))
+			}
+			if !nextResume.IsZero() && !now.Before(nextResume) {
+				if e.sessionPaused.Swap(false) && e.paused.Load() {
+					slog.Info("[交易时段] 到达 resume_at，恢复开仓", "resume_at", resumeLabel)
+					e.Resume()
+				}
+				nextResume, resumeLabel = nextSessionTime(now, loc, e.cfg.Session.ResumeAt)
+			}
+		}
+	}
+}
+
+// flattenSession 定时平仓：暂停开仓、撤销两所挂单、平掉两所持仓并输出时段汇总
+// 与套利检测互斥，检测进行中时返回 false 由调用方稍后重试
+func (e *ArbEngine) flattenSession(label string) bool {
+	if e.pauseForSession(label) {
+		slog.Info("[交易时段] 到达 flatten_at，停止开仓并平仓", "flatten_at", label)
+	}
+	if !e.checking.CompareAndSwap(false, true) {
+		return false
+	}
+	defer e.checking.Store(false)
+
+	ctx, cancel := context.WithTimeout(e.ctx, e.flattenTimeout())
+	defer cancel()
+
+	slog.Info("[交易时段] 撤单结果", "result", summarizeCancel(e.cancelAllOpenOrders(ctx)))
+	flattenPnL, _ := e.flattenAll(ctx, "[交易时段]")
+	e.saveState()
+	e.logSessionSummary(label, flattenPnL)
+	e.session.reset(time.Now())
+	return true
+}
+
+// pauseForSession 以定时平仓为原因暂停开仓；已因其他原因暂停时不标记，resume_at 不会覆盖人工或风控暂停
+func (e *ArbEngine) pauseForSession(label string) bool {
+	if e.paused.Load() {
+		return false
+	}
+	e.pause("定时平仓 " + label)
+	e.sessionPaused.Store(true)
+	return true
+}
+
+// logSessionSummary 打印并推送交易时段汇总，flattenPnL 为定时平仓本身的盈亏
+func (e *ArbEngine) logSessionSummary(label string, flattenPnL float64) {
+	s := &e.session
+	s.mu.Lock()
+	start, trades, wins := s.start, s.trades, s.wins
+	net, fees := s.netPnL+flattenPnL, s.fees
+	maxPos, worst := s.maxPosition, s.worstUnhedged
+	s.mu.Unlock()
+
+	winRate := 0.0
+	if trades > 0 {
+		winRate = float64(wins) / float64(trades) * 100
+	}
+	slog.Info("[交易时段] 汇总", "from", start.Format("01-02 15:04"), "to", label, "trades", trades, "win_rate_pct", winRate,
+		"gross", net+fees, "fees", fees, "net", net, "flatten_pnl", flattenPnL, "max_position", maxPos,
+		"worst_unhedged", worst.Round(time.Millisecond))
+	summary := fmt.Sprintf("成交 %d 笔 | 胜率 %.1f%% | 毛PnL=%.4f 手续费=%.4f 净PnL=%.4f USDC（含平仓 %.4f）| 最大持仓=%.4f | 最长单腿敞口=%v",
+		trades, winRate, net+fees, fees, net, flattenPnL, maxPos, worst.Round(time.Millisecond))
+	alert.Info("session_summary", "交易时段汇总（%s）: %s", label, summary)
+}
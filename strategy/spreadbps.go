@@ -0,0 +1,48 @@
+package strategy
+
+// 价差阈值可按绝对值（USDC）或基点（相对两所中间价）配置：阈值非 0 视为已配置，两者都配置时须同时满足，
+// 都未配置时按绝对阈值 0 判断。基点阈值不随标的价格变化，同一配置可用于价格相差很大的交易对
+
+// spreadBps 返回价差相对中间价的基点数，中间价无效时返回 0
+func spreadBps(spread, a, b float64) float64 {
+	mid := (a + b) / 2
+	if mid <= 0 {
+		return 0
+	}
+	return spread / mid * 10000
+}
+
+// meetsThreshold 判断价差是否同时满足已配置的绝对阈值与基点阈值（ratio 按比例放宽两个阈值，用于近似机会推送）
+func meetsThreshold(spread, a, b, usdc, bps, ratio float64) bool {
+	if usdc != 0 || bps == 0 {
+		if spread < usdc*ratio {
+			return false
+		}
+	}
+	return bps == 0 || spreadBps(spread, a, b) >= bps*ratio
+}
+
+// entrySpreadOK 开仓净价差是否达到有效开仓阈值（minSpread，含波动率调整）/ min_spread_bps，a、b 为两所成交价
+func (e *ArbEngine) entrySpreadOK(net, a, b float64) bool {
+	s := &e.cfg.Strategy
+	return meetsThreshold(net, a, b, e.minSpread(), s.MinSpreadBps, 1)
+}
+
+// unwindSpreadOK 平仓毛价差是否达到 unwind_spread_usdc / unwind_spread_bps
+func (e *ArbEngine) unwindSpreadOK(spread, a, b float64) bool {
+	s := &e.cfg.Strategy
+	return meetsThreshold(spread, a, b, s.UnwindSpreadUSDC, s.UnwindSpreadBps, 1)
+}
+
+// hedgeSlippage 返回价格 price 处允许的对冲滑点（USDC）：hedge_slippage_usdc 与 hedge_slippage_bps 都配置时取较小者
+func (e *ArbEngine) hedgeSlippage(price float64) float64 {
+	s := &e.cfg.Strategy
+	if s.HedgeSlippageBps <= 0 {
+		return s.HedgeSlippageUSDC
+	}
+	slip := price * s.HedgeSlippageBps / 10000
+	if s.HedgeSlippageUSDC > 0 && s.HedgeSlippageUSDC < slip {
+		return s.HedgeSlippageUSDC
+	}
+	return slip
+}
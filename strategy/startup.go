@@ -0,0 +1,83 @@
+package strategy
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"arb/exchange"
+)
+
+// startupReconcile 启动核对：订阅行情前处理上次运行（如崩溃退出）遗留的挂单与持仓
+//
+//  1. 撤销两所自定义订单ID带本程序前缀（client_id_prefix）的挂单，其他挂单只记录不处理
+//  2. 查询并记录两所真实持仓
+//  3. startup_flatten 开启时以 reduce-only 市价单平掉两所持仓
+//
+// 返回是否执行了启动平仓；e.position 仍由随后的 reconcilePosition 按交易所持仓初始化
+func (e *ArbEngine) startupReconcile() (flattened bool) {
+	ctx, cancel := context.WithTimeout(e.ctx, e.flattenTimeout())
+	defer cancel()
+
+	slog.Info("[启动核对] 开始")
+	defer slog.Info("[启动核对] 结束")
+
+	for _, ex := range []exchange.Exchange{e.exA, e.exB} {
+		e.cancelOrphanOrders(ctx, ex)
+	}
+
+	apexNet, apexErr := e.apexNetPosition(ctx)
+	bybitNet, bybitErr := e.bybitNetPosition(ctx)
+	if apexErr != nil {
+		slog.Error("[启动核对] 查询持仓失败", "exchange", e.exA.Name(), "err", apexErr)
+	}
+	if bybitErr != nil {
+		slog.Error("[启动核对] 查询持仓失败", "exchange", e.exB.Name(), "err", bybitErr)
+	}
+	if apexErr != nil || bybitErr != nil {
+		return false
+	}
+	slog.Info("[启动核对] 持仓", "symbol_a", e.exA.Symbol(), "net_a", apexNet, "symbol_b", e.exB.Symbol(), "net_b", bybitNet)
+
+	if !e.cfg.Strategy.StartupFlatten {
+		return false
+	}
+	pnl, ok := e.flattenAll(ctx, "[启动核对]")
+	if ok {
+		slog.Info("[启动核对] 遗留持仓已平仓", "pnl", pnl)
+	}
+	return ok
+}
+
+// cancelOrphanOrders 撤销单个交易所上带本程序订单ID前缀的挂单
+func (e *ArbEngine) cancelOrphanOrders(ctx context.Context, ex exchange.Exchange) {
+	orders, err := ex.OpenOrders(ctx)
+	if err != nil {
+		slog.Error("[启动核对] 查询挂单失败", "exchange", ex.Name(), "err", err)
+		return
+	}
+
+	prefix := e.cfg.Strategy.ClientIDPrefix
+	if prefix == "" {
+		prefix = defaultClientIDPrefix
+	}
+	prefix += "-"
+
+	var cancelled, failed, foreign int
+	for _, o := range orders {
+		if !strings.HasPrefix(o.ClientID, prefix) {
+			foreign++
+			continue
+		}
+		if err := ex.CancelOrder(ctx, o.ID); err != nil {
+			slog.Error("[启动核对] 撤销遗留挂单失败", "exchange", ex.Name(), "order_id", o.ID, "client_id", o.ClientID, "err", err)
+			failed++
+			continue
+		}
+		slog.Info("[启动核对] 已撤销遗留挂单", "exchange", ex.Name(), "order_id", o.ID, "client_id", o.ClientID,
+			"side", o.Side, "size", o.Qty, "price", o.Price)
+		cancelled++
+	}
+	slog.Info("[启动核对] 挂单处理完成", "exchange", ex.Name(), "orders", len(orders), "cancelled", cancelled,
+		"failed", failed, "foreign", foreign)
+}
@@ -0,0 +1,87 @@
+package strategy
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"os"
+	"time"
+
+	"arb/internal/atomicfile"
+	"arb/metrics"
+)
+
+// engineState 持久化到 strategy.state_file 的引擎状态（当日风控统计由 risk_control.state_file 保存）
+type engineState struct {
+	TotalPnL float64   `json:"total_pnl"`
+	Position float64   `json:"position"` // A所方向净持仓
+	SavedAt  time.Time `json:"saved_at"`
+}
+
+// loadState 启动时从状态文件恢复累计盈亏，返回上次保存的持仓用于与交易所核对
+func (e *ArbEngine) loadState() (pos float64, ok bool) {
+	path := e.cfg.Strategy.StateFile
+	if path == "" {
+		return 0, false
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false
+	}
+	if err != nil {
+		slog.Warn("[状态] 读取引擎状态文件失败，累计PnL 从 0 开始", "err", err)
+		return 0, false
+	}
+	var st engineState
+	if err := json.Unmarshal(data, &st); err != nil {
+		slog.Warn("[状态] 解析引擎状态文件失败，累计PnL 从 0 开始", "err", err)
+		return 0, false
+	}
+
+	e.pnlMu.Lock()
+	e.totalPnL = st.TotalPnL
+	e.pnlMu.Unlock()
+	metrics.TotalPnL.Set(st.TotalPnL)
+	slog.Info("[状态] 已从状态文件恢复", "total_pnl", st.TotalPnL, "position", st.Position,
+		"saved_at", st.SavedAt.Format("2006-01-02 15:04:05"))
+	return st.Position, true
+}
+
+// checkSavedPosition 比较状态文件中的持仓与交易所恢复的持仓，不一致时告警（以交易所为准）
+func (e *ArbEngine) checkSavedPosition(saved float64) {
+	e.posMu.Lock()
+	pos := e.position
+	e.posMu.Unlock()
+
+	if math.Abs(pos-saved) > e.sizeStep() {
+		slog.Warn("[状态] 状态文件持仓与交易所持仓不一致（可能有手动交易或未记录的成交），以交易所为准",
+			"saved", saved, "exchange_position", pos)
+	}
+}
+
+// saveState 将累计盈亏与持仓原子写入状态文件，避免写一半崩溃损坏文件
+func (e *ArbEngine) saveState() {
+	path := e.cfg.Strategy.StateFile
+	if path == "" {
+		return
+	}
+
+	e.pnlMu.Lock()
+	pnl := e.totalPnL
+	e.pnlMu.Unlock()
+	e.posMu.Lock()
+	pos := e.position
+	e.posMu.Unlock()
+
+	data, err := json.MarshalIndent(engineState{TotalPnL: pnl, Position: pos, SavedAt: time.Now()}, "", "  ")
+	if err != nil {
+		slog.Error("[状态] 序列化引擎状态失败", "err", err)
+		return
+	}
+
+	if err := atomicfile.Write(path, data); err != nil {
+		slog.Error("[状态] 写入引擎状态文件失败", "err", err)
+	}
+}
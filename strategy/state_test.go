@@ -0,0 +1,103 @@
+package strategy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestStateReloadRestoresPnLAndPosition 重启后从状态文件恢复累计盈亏，并返回保存的持仓用于核对
+func TestStateReloadRestoresPnLAndPosition(t *testing.T) {
+	cfg := testConfig()
+	cfg.Strategy.StateFile = filepath.Join(t.TempDir(), "engine_state.json")
+	e, exA, exB := newTestEngine(t, cfg)
+	setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+	e.checkAndTrade()
+
+	// 模拟进程被杀：不调用 Stop，直接用同一状态文件创建新引擎
+	e2, _, _ := newTestEngine(t, cfg)
+	pos, ok := e2.loadState()
+	if !ok || !approx(pos, 0.1) {
+		t.Fatalf("恢复的持仓 = %v (ok=%v)，期望 0.1", pos, ok)
+	}
+	if total := engineTotalPnL(e2); !approx(total, 1) {
+		t.Fatalf("恢复的累计PnL = %v，期望 1", total)
+	}
+}
+
+// TestStateReloadMidDayKeepsDailyLimit 日内重启后当日亏损限额仍然生效
+func TestStateReloadMidDayKeepsDailyLimit(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig()
+	cfg.Strategy.StateFile = filepath.Join(dir, "engine_state.json")
+	cfg.RiskControl.StateFile = filepath.Join(dir, "risk_state.json")
+	cfg.RiskControl.MaxDailyLossUSDC = 2
+
+	e, _, _ := newTestEngine(t, cfg)
+	e.bookPnL(DirectionLong, -1.5, "测试")
+	e.bookPnL(DirectionLong, -1, "测试")
+
+	e2, exA, exB := newTestEngine(t, cfg)
+	e2.loadState()
+	if daily := e2.riskCtrl.DailyPnL(); !approx(daily, -2.5) {
+		t.Fatalf("重启后当日PnL = %v，期望 -2.5", daily)
+	}
+	setQuotes(e2, exA, exB, 99990, 100000, 100010, 100020)
+	e2.checkAndTrade()
+
+	if n := len(exA.placed()); n != 0 {
+		t.Fatalf("当日亏损已超限，重启后不应开仓，A所下单 %d 笔", n)
+	}
+	if !e2.riskCtrl.IsHalted() {
+		t.Fatal("重启后应触发当日亏损熔断")
+	}
+}
+
+// TestStateReloadIgnoresCorruptFile 状态文件损坏（如非原子写入中途被杀）时从 0 开始，不影响启动
+func TestStateReloadIgnoresCorruptFile(t *testing.T) {
+	cfg := testConfig()
+	cfg.Strategy.StateFile = filepath.Join(t.TempDir(), "engine_state.json")
+	if err := os.WriteFile(cfg.Strategy.StateFile, []byte(`{"total_pnl": 12.`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	e, _, _ := newTestEngine(t, cfg)
+	if _, ok := e.loadState(); ok {
+		t.Fatal("损坏的状态文件不应恢复")
+	}
+	if total := engineTotalPnL(e); total != 0 {
+		t.Fatalf("累计PnL = %v，期望 0", total)
+	}
+
+	e.bookPnL(DirectionLong, 1, "测试")
+	e2, _, _ := newTestEngine(t, cfg)
+	if _, ok := e2.loadState(); !ok || !approx(engineTotalPnL(e2), 1) {
+		t.Fatalf("重新保存后应能恢复，累计PnL = %v", engineTotalPnL(e2))
+	}
+}
+
+// TestStateReloadAfterTakeProfit 止盈后重启：take_profit_usdc 与本次运行盈亏比较，恢复的历史盈亏不会立即再次触发
+func TestStateReloadAfterTakeProfit(t *testing.T) {
+	cfg := testConfig()
+	cfg.Strategy.StateFile = filepath.Join(t.TempDir(), "engine_state.json")
+	cfg.Strategy.TakeProfitUSDC = 0.5
+	e, exA, exB := newTestEngine(t, cfg)
+	setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+	e.checkAndTrade() // PnL = 1，超过盈利目标
+	e.checkAndTrade()
+	waitHalted(t, e)
+
+	e2, exA2, exB2 := newTestEngine(t, cfg)
+	e2.loadState()
+	if total := engineTotalPnL(e2); !approx(total, 1) {
+		t.Fatalf("恢复的累计PnL = %v，期望 1", total)
+	}
+	setQuotes(e2, exA2, exB2, 99990, 100000, 100010, 100020)
+	e2.checkAndTrade()
+	if e2.tradingHalted.Load() {
+		t.Fatal("恢复的历史盈亏不应在重启后立即触发止盈")
+	}
+	if n := len(exA2.placed()); n != 1 {
+		t.Fatalf("重启后 A所下单 %d 笔，期望 1", n)
+	}
+}
@@ -0,0 +1,68 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"arb/exchange"
+)
+
+const (
+	// symbolCheckTimeout 启动时交易对一致性检查的 REST 超时
+	symbolCheckTimeout = 10 * time.Second
+
+	// defaultSymbolPriceDiffPct 两所中间价默认最大偏差（百分比）
+	defaultSymbolPriceDiffPct = 5.0
+)
+
+// symbolPriceDiffPct 返回两所中间价允许的最大偏差（%），未配置时使用默认值
+func (e *ArbEngine) symbolPriceDiffPct() float64 {
+	if e.cfg.Strategy.SymbolPriceDiffPct > 0 {
+		return e.cfg.Strategy.SymbolPriceDiffPct
+	}
+	return defaultSymbolPriceDiffPct
+}
+
+// checkSymbols 启动前通过 REST 各查询一次盘口，确认两所交易对存在且标的一致：
+// 中间价偏差超过 symbol_price_diff_pct 时视为配置错误（如 ETH-USDC 对 BTCUSDT），拒绝启动
+func (e *ArbEngine) checkSymbols() error {
+	if e.cfg.Strategy.SkipSymbolCheck {
+		slog.Info("[交易对检查] 已跳过（skip_symbol_check）")
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(e.ctx, symbolCheckTimeout)
+	defer cancel()
+
+	midA, err := restMid(ctx, e.exA)
+	if err != nil {
+		return fmt.Errorf("交易对检查: %s 交易对 %s 查询盘口失败: %w", e.exA.Name(), e.exA.Symbol(), err)
+	}
+	midB, err := restMid(ctx, e.exB)
+	if err != nil {
+		return fmt.Errorf("交易对检查: %s 交易对 %s 查询盘口失败: %w", e.exB.Name(), e.exB.Symbol(), err)
+	}
+
+	diffPct := math.Abs(midA-midB) / math.Min(midA, midB) * 100
+	if diffPct > e.symbolPriceDiffPct() {
+		return fmt.Errorf("交易对检查: %s %s 中间价 %.4f 与 %s %s 中间价 %.4f 偏差 %.2f%% 超过 %.2f%%，请确认两所交易对为同一标的（确为不同价格标的时可设置 skip_symbol_check）",
+			e.exA.Name(), e.exA.Symbol(), midA, e.exB.Name(), e.exB.Symbol(), midB, diffPct, e.symbolPriceDiffPct())
+	}
+	slog.Info("[交易对检查] 通过", "symbol_a", e.exA.Symbol(), "mid_a", midA, "symbol_b", e.exB.Symbol(), "mid_b", midB,
+		"diff_pct", diffPct)
+	return nil
+}
+
+// restMid 通过 REST 查询最优买卖价并返回中间价，盘口为空时返回错误
+func restMid(ctx context.Context, ex exchange.Exchange) (float64, error) {
+	bp, err := ex.BestPrice(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if bp.Bid <= 0 || bp.Ask <= 0 {
+		return 0, fmt.Errorf("盘口为空（bid=%g ask=%g）", bp.Bid, bp.Ask)
+	}
+	return (bp.Bid + bp.Ask) / 2, nil
+}
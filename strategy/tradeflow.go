@@ -0,0 +1,96 @@
+package strategy
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"arb/exchange"
+)
+
+// defaultFlowWindow 未配置 adverse_flow_window_sec 时统计主动成交净额的窗口
+const defaultFlowWindow = 5 * time.Second
+
+// flowSample 一笔成交的带方向名义价值：主动买为正，主动卖为负
+type flowSample struct {
+	at       time.Time
+	notional float64
+}
+
+// flowWindow 滚动窗口内的主动成交净额（USDC）
+type flowWindow struct {
+	mu      sync.Mutex
+	samples []flowSample
+}
+
+// add 记录一笔成交
+func (f *flowWindow) add(t exchange.Trade, window time.Duration) {
+	n := t.Price * t.Size
+	if t.Side == exchange.Sell {
+		n = -n
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.samples = append(f.samples, flowSample{at: t.Time, notional: n})
+	f.prune(time.Now(), window)
+}
+
+// prune 丢弃窗口外的成交
+func (f *flowWindow) prune(now time.Time, window time.Duration) {
+	cutoff := now.Add(-window)
+	i := 0
+	for i < len(f.samples) && f.samples[i].at.Before(cutoff) {
+		i++
+	}
+	if i > 0 {
+		f.samples = append(f.samples[:0], f.samples[i:]...)
+	}
+}
+
+// net 返回窗口内主动买入减主动卖出的名义价值
+func (f *flowWindow) net(window time.Duration) float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prune(time.Now(), window)
+	var sum float64
+	for _, s := range f.samples {
+		sum += s.notional
+	}
+	return sum
+}
+
+// flowWindowLen 返回主动成交净额的统计窗口
+func (e *ArbEngine) flowWindowLen() time.Duration {
+	if sec := e.cfg.Strategy.AdverseFlowWindowSec; sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	return defaultFlowWindow
+}
+
+// recordFlow 记录一笔成交到对应交易所的净额窗口（未启用 max_adverse_flow_usdc 时不记录）
+func (e *ArbEngine) recordFlow(flow *flowWindow, t exchange.Trade) {
+	if e.cfg.Strategy.MaxAdverseFlowUSDC <= 0 {
+		return
+	}
+	flow.add(t, e.flowWindowLen())
+}
+
+// adverseFlow 动量过滤：开仓方向买入的交易所（场景1 为 A所，场景2 为 B所）在窗口内的主动卖出净额
+// 超过 max_adverse_flow_usdc 时返回 true，避免在单边下跌中接飞刀；未配置或交易所不支持成交推送时不过滤
+func (e *ArbEngine) adverseFlow(dir ArbDirection) bool {
+	limit := e.cfg.Strategy.MaxAdverseFlowUSDC
+	if limit <= 0 {
+		return false
+	}
+	ex, flow := e.exA, &e.flowA
+	if dir == DirectionShort {
+		ex, flow = e.exB, &e.flowB
+	}
+	selling := -flow.net(e.flowWindowLen())
+	if selling > limit {
+		slog.Debug("[套利] 买入方主动卖出过多，放弃本次机会", "direction", dir.tag(), "exchange", ex.Name(),
+			"net_sell_usdc", selling, "limit", limit, "window", e.flowWindowLen())
+		return true
+	}
+	return false
+}
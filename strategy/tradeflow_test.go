@@ -0,0 +1,62 @@
+package strategy
+
+import (
+	"testing"
+	"time"
+
+	"arb/exchange"
+)
+
+func TestFlowWindowNetAndPrune(t *testing.T) {
+	var f flowWindow
+	now := time.Now()
+	window := 5 * time.Second
+	f.add(exchange.Trade{Price: 100000, Size: 0.5, Side: exchange.Sell, Time: now.Add(-10 * time.Second)}, window)
+	f.add(exchange.Trade{Price: 100000, Size: 0.2, Side: exchange.Buy, Time: now}, window)
+	f.add(exchange.Trade{Price: 100000, Size: 0.3, Side: exchange.Sell, Time: now}, window)
+
+	// 窗口外的 0.5 卖出已被丢弃
+	if got := f.net(window); !approx(got, -10000) {
+		t.Fatalf("窗口内净额 = %v，期望 -10000", got)
+	}
+}
+
+// TestMomentumFilter 场景1 在 A所买入：A所主动卖出超过 max_adverse_flow_usdc 时放弃开仓，B所卖出或窗口外成交不影响
+func TestMomentumFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		venue  string
+		age    time.Duration
+		traded bool
+	}{
+		{"A所主动卖出超限", exchange.Apex, 0, false},
+		{"B所主动卖出不影响场景1", exchange.Bybit, 0, true},
+		{"窗口外的卖出忽略", exchange.Apex, 10 * time.Second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Strategy.MaxAdverseFlowUSDC = 50000
+			cfg.Strategy.AdverseFlowWindowSec = 5
+			e, exA, exB := newTestEngine(t, cfg)
+			e.subscribeTrades()
+
+			ex := exA
+			if tt.venue == exchange.Bybit {
+				ex = exB
+			}
+			// 两笔共 1 BTC 的主动卖出，名义价值 100000 USDC
+			at := time.Now().Add(-tt.age)
+			ex.pushTrade(exchange.Sell, 100000, 0.6, at)
+			ex.pushTrade(exchange.Sell, 100000, 0.4, at)
+
+			setQuotes(e, exA, exB, 99990, 100000, 100010, 100020)
+			e.checkAndTrade()
+
+			pos, _ := enginePosition(e)
+			if traded := pos != 0; traded != tt.traded {
+				t.Fatalf("开仓 = %v，期望 %v（持仓 %v）", traded, tt.traded, pos)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package strategy
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"arb/exchange"
+)
+
+// tradeMaxAge 最新成交价超过此时长未更新时不参与盘口校验（成交稀疏的市场成交价可能早已过时）
+const tradeMaxAge = time.Minute
+
+// subscribeTrades 订阅两所逐笔成交（交易所支持时），记录最新成交价与主动成交净额；订阅失败不影响套利
+func (e *ArbEngine) subscribeTrades() {
+	for _, v := range []struct {
+		ex   exchange.Exchange
+		last *atomic.Value
+		flow *flowWindow
+	}{
+		{e.exA, &e.lastTradeA, &e.flowA},
+		{e.exB, &e.lastTradeB, &e.flowB},
+	} {
+		ts, ok := v.ex.(exchange.TradeStreamer)
+		if !ok {
+			continue
+		}
+		last, flow := v.last, v.flow
+		err := ts.SubscribeTrades(func(t *exchange.Trade) {
+			last.Store(*t)
+			e.recordFlow(flow, *t)
+		})
+		if err != nil && !errors.Is(err, exchange.ErrUnsupported) {
+			slog.Warn("[成交] 订阅逐笔成交失败，最新成交价不可用", "exchange", v.ex.Name(), "err", err)
+		}
+	}
+}
+
+// lastTrade 返回最新成交，尚未收到时 ok=false
+func lastTrade(v *atomic.Value) (exchange.Trade, bool) {
+	t, _ := v.Load().(exchange.Trade)
+	return t, t.Price > 0
+}
+
+// tradeDeviationPct 返回盘口中间价相对最新成交价的偏离（%），成交价缺失或过旧时 ok=false
+func tradeDeviationPct(q quote, v *atomic.Value) (pct float64, ok bool) {
+	t, ok := lastTrade(v)
+	if !ok || time.Since(t.Time) > tradeMaxAge || q.bid <= 0 || q.ask <= 0 {
+		return 0, false
+	}
+	mid := (q.bid + q.ask) / 2
+	return math.Abs(mid-t.Price) / t.Price * 100, true
+}
+
+// tradesConsistent 盘口校验：任一所中间价偏离最新成交价超过 max_trade_deviation_pct 时返回 false，
+// 用于过滤流动性差的盘口（挂单稀疏、价差被个别挂单拉开）；未配置或成交价不可用时不检查
+func (e *ArbEngine) tradesConsistent(apex, bybit quote) bool {
+	limit := e.cfg.Strategy.MaxTradeDeviationPct
+	if limit <= 0 {
+		return true
+	}
+	for _, v := range []struct {
+		q    quote
+		last *atomic.Value
+	}{
+		{apex, &e.lastTradeA},
+		{bybit, &e.lastTradeB},
+	} {
+		if pct, ok := tradeDeviationPct(v.q, v.last); ok && pct > limit {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package strategy
+
+import (
+	"log/slog"
+	"math"
+)
+
+// findUnwind 价差回归时的平仓判断：持有场景1建立的多头（pos > 0）且反向价差 apexBid - bybitAsk 达到 unwind_spread_usdc / unwind_spread_bps 时，
+// 以场景2方向平仓；持有空头时对称地检查 bybitBid - apexAsk。价差为未扣手续费的毛价差，阈值可为 0 或小幅负数
+func (e *ArbEngine) findUnwind(apex, bybit quote, pos float64) (ArbDirection, tradePlan, bool) {
+	if !e.cfg.Strategy.Unwind || math.Abs(pos) < e.sizeStep() {
+		return DirectionNone, tradePlan{}, false
+	}
+
+	dir := DirectionShort
+	apexLevels, bybitLevels := apex.bids, bybit.asks
+	spread := apex.bid - bybit.ask
+	if pos < 0 {
+		dir = DirectionLong
+		apexLevels, bybitLevels = apex.asks, bybit.bids
+		spread = bybit.bid - apex.ask
+	}
+	if len(apexLevels) == 0 || len(bybitLevels) == 0 || !e.unwindSpreadOK(spread, apexLevels[0].price, bybitLevels[0].price) {
+		return DirectionNone, tradePlan{}, false
+	}
+
+	// 平仓只吃最优一档，数量不超过当前持仓
+	size := math.Min(e.cfg.Strategy.OrderSize, math.Abs(pos))
+	size = e.roundSize(math.Min(size, math.Min(apexLevels[0].size, bybitLevels[0].size)))
+	if size <= 0 || size < e.minOrderSize() {
+		slog.Debug("[平仓] 最优档深度不足，放弃本次平仓", "direction", dir.tag(), "size", e.formatSize(size))
+		return DirectionNone, tradePlan{}, false
+	}
+
+	slog.Debug("[平仓] 价差回归", "direction", dir.tag(), "position", pos, "spread", spread,
+		"spread_bps", spreadBps(spread, apexLevels[0].price, bybitLevels[0].price),
+		"threshold", e.cfg.Strategy.UnwindSpreadUSDC, "threshold_bps", e.cfg.Strategy.UnwindSpreadBps)
+	net := e.netSpread(spread, apexLevels[0].price, bybitLevels[0].price)
+	return dir, tradePlan{size: size, apexPrice: apexLevels[0].price, bybitPrice: bybitLevels[0].price, net: net}, true
+}
+
+// reducesPosition 判断方向是否与当前持仓相反且持仓足以按最小下单量减仓
+func (e *ArbEngine) reducesPosition(dir ArbDirection, pos float64) bool {
+	return dir.sign()*pos < 0 && e.roundSize(math.Abs(pos)) >= e.minOrderSize()
+}
+
+// executeReduce 以 reduce-only 双腿执行减仓（价差回归平仓或反向机会减仓），成交核对、对冲恢复与开仓相同
+// 本次计入的盈亏（含恢复流程）单独累计到平仓盈亏，开仓盈亏 = 累计PnL - 平仓盈亏；返回是否至少有一条腿成交
+func (e *ArbEngine) executeReduce(dir ArbDirection, plan tradePlan, reason string) bool {
+	e.pnlMu.Lock()
+	before := e.totalPnL
+	e.pnlMu.Unlock()
+
+	traded := e.execute(dir, plan.apexPrice, plan.bybitPrice, plan.net, plan.size, true)
+
+	// execute 在 checking 互斥内同步执行，期间累计PnL的变化全部来自本次平仓
+	e.pnlMu.Lock()
+	closed := e.totalPnL - before
+	e.closePnL += closed
+	e.closeTrades++
+	total, closePnL := e.totalPnL, e.closePnL
+	e.pnlMu.Unlock()
+	slog.Info("[平仓] "+reason+"完成", "direction", dir.tag(), "size", e.formatSize(plan.size), "pnl", closed,
+		"open_pnl", total-closePnL, "close_pnl", closePnL)
+	return traded
+}
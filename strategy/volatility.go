@@ -0,0 +1,107 @@
+package strategy
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultVolWindow 未配置 vol_window_sec 时波动率统计窗口
+	defaultVolWindow = 60 * time.Second
+
+	// volSampleInterval 中间价采样间隔：盘口推送频率因交易所与行情而异，按固定间隔采样使 sigma 与推送频率无关
+	volSampleInterval = time.Second
+
+	// volMinSamples 窗口内少于该数量的价格变化样本时视为样本不足，使用静态阈值
+	volMinSamples = 10
+)
+
+// volSample 一次采样的中间价变化
+type volSample struct {
+	at    time.Time
+	delta float64
+}
+
+// volEstimator 滚动波动率：窗口内相邻采样中间价变化的标准差（USDC）
+type volEstimator struct {
+	mu      sync.Mutex
+	lastMid float64
+	lastAt  time.Time
+	samples []volSample
+}
+
+// add 记录一次中间价，距上次采样不足 volSampleInterval 时忽略
+func (v *volEstimator) add(mid float64, now time.Time, window time.Duration) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if now.Sub(v.lastAt) < volSampleInterval {
+		return
+	}
+	if v.lastMid > 0 {
+		v.samples = append(v.samples, volSample{at: now, delta: mid - v.lastMid})
+	}
+	v.lastMid, v.lastAt = mid, now
+	v.prune(now, window)
+}
+
+// prune 丢弃窗口外的样本
+func (v *volEstimator) prune(now time.Time, window time.Duration) {
+	cutoff := now.Add(-window)
+	i := 0
+	for i < len(v.samples) && v.samples[i].at.Before(cutoff) {
+		i++
+	}
+	if i > 0 {
+		v.samples = append(v.samples[:0], v.samples[i:]...)
+	}
+}
+
+// sigma 返回窗口内中间价变化的标准差，样本不足时 ok=false
+func (v *volEstimator) sigma(now time.Time, window time.Duration) (sigma float64, n int, ok bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.prune(now, window)
+	n = len(v.samples)
+	if n < volMinSamples {
+		return 0, n, false
+	}
+	var sum, sumSq float64
+	for _, s := range v.samples {
+		sum += s.delta
+		sumSq += s.delta * s.delta
+	}
+	mean := sum / float64(n)
+	return math.Sqrt(math.Max(sumSq/float64(n)-mean*mean, 0)), n, true
+}
+
+// volWindow 返回波动率统计窗口
+func (e *ArbEngine) volWindow() time.Duration {
+	if sec := e.cfg.Strategy.VolWindowSec; sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	return defaultVolWindow
+}
+
+// recordMid 记录 A所中间价用于波动率统计（未启用 vol_multiplier 时不记录）
+func (e *ArbEngine) recordMid(q quote) {
+	if e.cfg.Strategy.VolMultiplier <= 0 {
+		return
+	}
+	e.vol.add((q.bid+q.ask)/2, time.Now(), e.volWindow())
+}
+
+// minSpread 返回当前有效的开仓净价差阈值：max(min_spread_usdc, vol_multiplier × sigma)，
+// 未启用或样本不足（启动初期、行情中断后）时使用静态的 min_spread_usdc
+func (e *ArbEngine) minSpread() float64 {
+	static := e.cfg.Strategy.MinSpreadUSDC
+	mult := e.cfg.Strategy.VolMultiplier
+	if mult <= 0 {
+		return static
+	}
+	sigma, _, ok := e.vol.sigma(time.Now(), e.volWindow())
+	if !ok {
+		return static
+	}
+	return math.Max(static, mult*sigma)
+}